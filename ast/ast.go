@@ -13,10 +13,23 @@ const (
 	IntType    TYPE = "int"
 	FloatType  TYPE = "float"
 	StringType TYPE = "string"
+	CharType   TYPE = "char"
 	BoolType   TYPE = "bool"
+	ArrayType  TYPE = "array"
+	MapType    TYPE = "map"
+	FnType     TYPE = "fn"
+	NulType    TYPE = "nul"
 	VoidType   TYPE = "void"
+	// takes any value, the checks are left to the runtime
+	AnyType TYPE = "any"
+	// the results of ok and err
+	ResultType TYPE = "Result"
 )
 
+// the type names the annotations can use on top of the structs and the enums, void is
+// only a return type
+var BuiltinTypes = []TYPE{IntType, FloatType, StringType, CharType, BoolType, ArrayType, MapType, FnType, NulType, AnyType, ResultType}
+
 type Node interface {
 	TokenLiteral() string
 	String() string
@@ -54,11 +67,31 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+type Annotation struct {
+	Token lexer.Token // the @ token
+	Name  *Identifier
+	Args  []Expression
+}
+
+func (an *Annotation) String() string {
+	var out bytes.Buffer
+	out.WriteString("@" + an.Name.String())
+	if len(an.Args) > 0 {
+		args := []string{}
+		for _, arg := range an.Args {
+			args = append(args, arg.String())
+		}
+		out.WriteString("(" + strings.Join(args, ", ") + ")")
+	}
+	return out.String()
+}
+
 type VarDeclaration struct {
-	Token   lexer.Token // the token.LET token
-	Mutable bool        // indicates if the vars are mutable or not
-	Name    []*Identifier
-	Value   Expression
+	Token       lexer.Token // the token.LET token
+	Mutable     bool        // indicates if the vars are mutable or not
+	Name        []*Identifier
+	Value       Expression
+	Annotations []*Annotation // annotations attached to the declaration, an example of this @deprecated("use new_fn")
 }
 
 func (ls *VarDeclaration) statementNode()        {}
@@ -66,6 +99,9 @@ func (ls *VarDeclaration) TokenLiteral() string  { return ls.Token.Text }
 func (nt *VarDeclaration) GetToken() lexer.Token { return nt.Token }
 func (ls *VarDeclaration) String() string {
 	var out bytes.Buffer
+	for _, annotation := range ls.Annotations {
+		out.WriteString(annotation.String() + " ")
+	}
 	out.WriteString(ls.TokenLiteral() + " ")
 	for idx, name := range ls.Name {
 		out.WriteString(name.String())
@@ -99,6 +135,25 @@ func (ls *ImportStatement) String() string {
 	return out.String()
 }
 
+type PragmaStatement struct {
+	Token lexer.Token // the token.PRAGMA token
+	Name  string      // directive name, an example of this (feature)
+	Args  []string
+}
+
+func (ps *PragmaStatement) statementNode()        {}
+func (ps *PragmaStatement) TokenLiteral() string  { return ps.Token.Text }
+func (nt *PragmaStatement) GetToken() lexer.Token { return nt.Token }
+func (ps *PragmaStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(lexer.PragmaPrefix)
+	out.WriteString(ps.Name)
+	if len(ps.Args) > 0 {
+		out.WriteString(" " + strings.Join(ps.Args, ","))
+	}
+	return out.String()
+}
+
 type Method struct {
 	Key   *Identifier
 	Value *FunctionExpression // any value type
@@ -142,7 +197,21 @@ func (ss *StructExpression) String() string {
 
 type EnumExpression struct {
 	Token lexer.Token // the token.LET token
-	Body  []*Identifier
+	Body  []*EnumField
+}
+
+// a member of an enum, its value is the explicit one or the one of the previous member plus one
+type EnumField struct {
+	Name     *Identifier
+	Value    int64
+	Explicit bool
+}
+
+func (ef *EnumField) String() string {
+	if ef.Explicit {
+		return fmt.Sprintf("%s = %d", ef.Name.String(), ef.Value)
+	}
+	return ef.Name.String()
 }
 
 func (ss *EnumExpression) expressionNode()       {}
@@ -164,6 +233,35 @@ func (ss *EnumExpression) String() string {
 	return out.String()
 }
 
+type PatternKind = string
+
+const (
+	PatternPrefix   PatternKind = "prefix"   // "img_*"
+	PatternSuffix   PatternKind = "suffix"   // "*.mp4"
+	PatternContains PatternKind = "contains" // "*draft*"
+	PatternGlob     PatternKind = "glob"     // any other mix of * and ?, an example of this: "img_??.*"
+)
+
+// string pattern of a match arm, string literals holding a * or ? wildcard are compiled into one
+// * matches any run of characters, ? matches exactly one
+type StringPattern struct {
+	Token   lexer.Token
+	Value   string // the raw pattern
+	Kind    PatternKind
+	Literal string // the part without the wildcards, for the prefix, suffix and contains kinds
+}
+
+func (sp *StringPattern) expressionNode()       {}
+func (sp *StringPattern) TokenLiteral() string  { return sp.Token.Text }
+func (nt *StringPattern) GetToken() lexer.Token { return nt.Token }
+func (sp *StringPattern) String() string {
+	var out bytes.Buffer
+	out.WriteString(`"`)
+	out.WriteString(sp.Value)
+	out.WriteString(`"`)
+	return out.String()
+}
+
 type MatchArm struct {
 	Token   lexer.Token
 	Pattern Expression
@@ -173,8 +271,10 @@ type MatchArm struct {
 type MatchExpression struct {
 	Token    lexer.Token
 	MatchKey Expression // mainly identifiers of different type
-	Arms     []MatchArm
-	Default  *MatchArm
+	// match type x, the patterns of the arms are type names
+	OnType  bool
+	Arms    []MatchArm
+	Default *MatchArm
 }
 
 func (rs *MatchExpression) expressionNode()       {}
@@ -183,6 +283,9 @@ func (nt *MatchExpression) GetToken() lexer.Token { return nt.Token }
 func (rs *MatchExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString(rs.TokenLiteral() + " ")
+	if rs.OnType {
+		out.WriteString("type ")
+	}
 	out.WriteString(rs.MatchKey.String())
 	out.WriteString(" { ")
 	if rs.Arms != nil {
@@ -207,6 +310,31 @@ func (rs *MatchExpression) String() string {
 	return out.String()
 }
 
+// let Ok(data) = read(path) else { ... }, binds the value of an ok result, or the message of
+// an err one with Err, the else branch runs for the other results and exits
+type LetElseStatement struct {
+	Token   lexer.Token // the 'let' token
+	Variant *Identifier // Ok or Err
+	Name    *Identifier
+	Value   Expression
+	Else    *BlockStatement
+}
+
+func (ls *LetElseStatement) statementNode()        {}
+func (ls *LetElseStatement) TokenLiteral() string  { return ls.Token.Text }
+func (ls *LetElseStatement) GetToken() lexer.Token { return ls.Token }
+func (ls *LetElseStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ls.TokenLiteral() + " ")
+	out.WriteString(ls.Variant.String() + "(" + ls.Name.String() + ")")
+	out.WriteString(" = ")
+	out.WriteString(ls.Value.String())
+	out.WriteString(" else {")
+	out.WriteString(ls.Else.String())
+	out.WriteString(" }")
+	return out.String()
+}
+
 type ReturnStatement struct {
 	Token        lexer.Token // the 'return' token
 	ReturnValues []Expression
@@ -328,7 +456,24 @@ type FunctionExpression struct {
 	Token lexer.Token
 	Self  *Identifier // this indicates the self key
 	Args  []*Identifier
-	Body  *BlockStatement
+	// the type annotations of the args, by position, nil for the args without one
+	// an example of this: fn add(a: int, b) gives [int, nil]
+	Types      []*Identifier
+	ReturnType *Identifier
+	Body       *BlockStatement
+}
+
+// whether every arg and the return value have a type annotation
+func (fn *FunctionExpression) Annotated() bool {
+	if fn.ReturnType == nil {
+		return false
+	}
+	for idx := range fn.Args {
+		if idx >= len(fn.Types) || fn.Types[idx] == nil {
+			return false
+		}
+	}
+	return true
 }
 
 func (fn *FunctionExpression) expressionNode()       {}
@@ -337,16 +482,24 @@ func (nt *FunctionExpression) GetToken() lexer.Token { return nt.Token }
 func (fn *FunctionExpression) String() string {
 	var out bytes.Buffer
 	params := []string{}
-	if fn.Self != nil {
+	// the parser leaves an empty self on the functions that aren't methods
+	if fn.Self != nil && len(fn.Self.Value) > 0 {
 		params = append(params, fn.Self.String())
 	}
-	for _, p := range fn.Args {
+	for idx, p := range fn.Args {
+		if idx < len(fn.Types) && fn.Types[idx] != nil {
+			params = append(params, p.String()+": "+fn.Types[idx].String())
+			continue
+		}
 		params = append(params, p.String())
 	}
 	out.WriteString(fn.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(")")
+	if fn.ReturnType != nil {
+		out.WriteString(": " + fn.ReturnType.String())
+	}
 	out.WriteString("{ ")
 	out.WriteString(fn.Body.String())
 	out.WriteString(" }")
@@ -375,6 +528,19 @@ func (ss *ScopeStatement) String() string {
 type Identifier struct {
 	Token lexer.Token // the token.IDENT token
 	Value string
+
+	symbol lexer.Symbol
+}
+
+// the symbol the scopes bind the identifier with, looked up once per node
+func (i *Identifier) Symbol() lexer.Symbol {
+	if i.symbol == 0 {
+		i.symbol = i.Token.Symbol()
+		if i.symbol == 0 || i.Token.Text != i.Value {
+			i.symbol = lexer.Intern(i.Value)
+		}
+	}
+	return i.symbol
 }
 
 func (i *Identifier) expressionNode()        {}
@@ -418,6 +584,20 @@ func (sl *StringLiteral) String() string {
 	return out.String()
 }
 
+// the content of a file read by include_bytes while parsing, it evaluates to an array of
+// the bytes as ints
+type BytesLiteral struct {
+	Token lexer.Token
+	Value []byte
+}
+
+func (bl *BytesLiteral) expressionNode()       {}
+func (bl *BytesLiteral) TokenLiteral() string  { return bl.Token.Text }
+func (nt *BytesLiteral) GetToken() lexer.Token { return nt.Token }
+func (bl *BytesLiteral) String() string {
+	return fmt.Sprintf("include_bytes(<%d bytes>)", len(bl.Value))
+}
+
 type CharLiteral struct {
 	Token lexer.Token
 	Value rune
@@ -483,9 +663,14 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+type MapPair struct {
+	Key   Expression
+	Value Expression
+}
+
 type MapLiteral struct {
 	Token lexer.Token
-	Pairs map[Expression]Expression
+	Pairs []MapPair // in source order
 }
 
 func (ml *MapLiteral) expressionNode()       {}
@@ -494,8 +679,8 @@ func (nt *MapLiteral) GetToken() lexer.Token { return nt.Token }
 func (ml *MapLiteral) String() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for key, value := range ml.Pairs {
-		pairs = append(pairs, key.String()+": "+value.String())
+	for _, pair := range ml.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -613,6 +798,29 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// ternary form of the if, an example of this: if cond ? a : b, or if cond use a else b
+type ConditionalExpression struct {
+	Token       lexer.Token // The 'if' token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()       {}
+func (ce *ConditionalExpression) TokenLiteral() string  { return ce.Token.Text }
+func (ce *ConditionalExpression) GetToken() lexer.Token { return ce.Token }
+func (ce *ConditionalExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(if ")
+	out.WriteString(ce.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(ce.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(ce.Alternative.String())
+	out.WriteString(")")
+	return out.String()
+}
+
 type CallExpression struct {
 	Token    lexer.Token // The '(' token
 	Function Identifier  // Identifier
@@ -635,6 +843,47 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// x as int, the value converted to the type, an error when it doesn't fit it
+type CastExpression struct {
+	Token lexer.Token // the 'as' token
+	Value Expression
+	Type  *Identifier
+}
+
+func (ce *CastExpression) expressionNode()       {}
+func (ce *CastExpression) TokenLiteral() string  { return ce.Token.Text }
+func (ce *CastExpression) GetToken() lexer.Token { return ce.Token }
+func (ce *CastExpression) String() string {
+	return "(" + ce.Value.String() + " as " + ce.Type.String() + ")"
+}
+
+// read(path)!, the value of an ok result, an err result is returned from the function
+type PropagateExpression struct {
+	Token lexer.Token // the '!' token
+	Value Expression
+}
+
+func (pe *PropagateExpression) expressionNode()       {}
+func (pe *PropagateExpression) TokenLiteral() string  { return pe.Token.Text }
+func (pe *PropagateExpression) GetToken() lexer.Token { return pe.Token }
+func (pe *PropagateExpression) String() string {
+	return "(" + pe.Value.String() + "!)"
+}
+
+// x is Vec2, whether the value is of the type
+type IsExpression struct {
+	Token lexer.Token // the 'is' token
+	Value Expression
+	Type  *Identifier
+}
+
+func (ie *IsExpression) expressionNode()       {}
+func (ie *IsExpression) TokenLiteral() string  { return ie.Token.Text }
+func (ie *IsExpression) GetToken() lexer.Token { return ie.Token }
+func (ie *IsExpression) String() string {
+	return "(" + ie.Value.String() + " is " + ie.Type.String() + ")"
+}
+
 type IndexExpression struct {
 	Token lexer.Token // The [ token
 	Left  Expression
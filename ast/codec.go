@@ -0,0 +1,87 @@
+package ast
+
+import (
+	"bufio"
+	"compress/flate"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// compiled program layout: magic, format version, then the gob encoded program compressed with flate
+const (
+	CompiledMagic   = "BLKC"
+	CompiledVersion = byte(2)
+)
+
+// what gets stored in a .blkc file
+type CompiledProgram struct {
+	Source  string // name of the source file, used for error positions
+	Program *Program
+}
+
+func init() {
+	// every implementation of Statement and Expression, gob needs them to encode the interfaces
+	nodes := []Node{
+		&VarDeclaration{}, &LetElseStatement{}, &ImportStatement{}, &PragmaStatement{}, &ReturnStatement{},
+		&ExpressionStatement{}, &WhileStatement{}, &ForStatement{}, &NextStatement{},
+		&BreakStatement{}, &ScopeStatement{}, &AssignStatement{}, &BlockStatement{},
+		&StructExpression{}, &EnumExpression{}, &StringPattern{}, &MatchExpression{},
+		&RangePattern{}, &FunctionExpression{}, &Identifier{}, &IntegerLiteral{},
+		&FloatLiteral{}, &StringLiteral{}, &BytesLiteral{}, &CharLiteral{}, &NulLiteral{},
+		&BooleanLiteral{}, &ArrayLiteral{}, &MapLiteral{}, &UnaryExpression{},
+		&BinaryExpression{}, &IfExpression{}, &ConditionalExpression{}, &CallExpression{},
+		&IndexExpression{}, &MemberShipExpression{}, &StructInstanceExpression{}, &CastExpression{},
+		&IsExpression{}, &PropagateExpression{},
+	}
+	for _, node := range nodes {
+		gob.Register(node)
+	}
+}
+
+// writes the compiled form of a parsed program
+func EncodeProgram(w io.Writer, compiled *CompiledProgram) error {
+	if _, err := io.WriteString(w, CompiledMagic); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte{CompiledVersion}); err != nil {
+		return err
+	}
+
+	// tokens repeat their text and kind a lot, so the payload compresses well
+	zw, err := flate.NewWriter(w, flate.BestSpeed)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(zw).Encode(compiled); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// reads back a program written by EncodeProgram
+func DecodeProgram(r io.Reader) (*CompiledProgram, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(CompiledMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, errors.New("not a compiled blk program")
+	}
+	if string(header[:len(CompiledMagic)]) != CompiledMagic {
+		return nil, errors.New("not a compiled blk program")
+	}
+	if version := header[len(CompiledMagic)]; version != CompiledVersion {
+		return nil, fmt.Errorf("compiled with format version %d, this blk reads version %d, compile the program again", version, CompiledVersion)
+	}
+
+	compiled := &CompiledProgram{}
+	if err := gob.NewDecoder(flate.NewReader(br)).Decode(compiled); err != nil {
+		return nil, fmt.Errorf("corrupted compiled program: %v", err)
+	}
+	if compiled.Program == nil {
+		compiled.Program = &Program{}
+	}
+
+	return compiled, nil
+}
@@ -0,0 +1,303 @@
+package astdiff
+
+import (
+	"blk/ast"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// kinds of declarations
+const (
+	KindFn     = "fn"
+	KindStruct = "struct"
+	KindEnum   = "enum"
+	KindConst  = "const"
+	KindVar    = "var"
+)
+
+// kinds of changes
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// a field of a struct, the type is the one of its default value
+type Field struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Default string `json:"default"`
+	Mutable bool   `json:"mutable"`
+}
+
+// a member of an enum with its value
+type Member struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+// a method of a struct, self isn't part of the params
+type Method struct {
+	Name   string   `json:"name"`
+	Params []string `json:"params"`
+}
+
+// a top level declaration of a program, the part of it the diff compares
+type Decl struct {
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"`
+	Row        int      `json:"row"`
+	Deprecated bool     `json:"deprecated,omitempty"`
+	Type       string   `json:"type,omitempty"` // consts and vars, the type of their value
+	Params     []string `json:"params,omitempty"`
+	Fields     []Field  `json:"fields,omitempty"`
+	Methods    []Method `json:"methods,omitempty"`
+	Members    []Member `json:"members,omitempty"`
+}
+
+// names starting with _ are private to the file
+func (d *Decl) Exported() bool { return !strings.HasPrefix(d.Name, "_") }
+
+// a change inside a declaration, an example of this: a param added to a function
+type Detail struct {
+	Change string `json:"change"` // added, removed or changed
+	What   string `json:"what"`   // params, field, method, member, kind, type, mutability or deprecation
+	Name   string `json:"name,omitempty"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// a declaration added, removed or changed between two versions of a program
+type Change struct {
+	Change  string   `json:"change"`
+	Name    string   `json:"name"`
+	Kind    string   `json:"kind"`
+	Old     *Decl    `json:"old,omitempty"`
+	New     *Decl    `json:"new,omitempty"`
+	Details []Detail `json:"details,omitempty"`
+}
+
+// the top level declarations of the program, in the order they got declared
+func Declarations(program *ast.Program) []*Decl {
+	var decls []*Decl
+	for _, stmt := range program.Statements {
+		decl, ok := stmt.(*ast.VarDeclaration)
+		if !ok {
+			continue
+		}
+		for idx, name := range decl.Name {
+			d := &Decl{Name: name.Value, Row: name.Token.Row, Kind: KindVar}
+			if !decl.Mutable {
+				d.Kind = KindConst
+			}
+			for _, annotation := range decl.Annotations {
+				d.Deprecated = d.Deprecated || annotation.Name.Value == "deprecated"
+			}
+			// the value describes the declaration only when it's the one of a single name
+			if idx == 0 && len(decl.Name) == 1 {
+				describe(d, decl.Value)
+			}
+			decls = append(decls, d)
+		}
+	}
+	return decls
+}
+
+func describe(d *Decl, value ast.Expression) {
+	switch value := value.(type) {
+	case *ast.FunctionExpression:
+		d.Kind = KindFn
+		d.Params = params(value)
+	case *ast.StructExpression:
+		d.Kind = KindStruct
+		for _, field := range value.Fields {
+			d.Fields = append(d.Fields, Field{
+				Name:    field.Name[0].Value,
+				Type:    TypeOf(field.Value),
+				Default: field.Value.String(),
+				Mutable: field.Mutable,
+			})
+		}
+		for _, method := range value.Methods {
+			d.Methods = append(d.Methods, Method{Name: method.Key.Value, Params: params(method.Value)})
+		}
+	case *ast.EnumExpression:
+		d.Kind = KindEnum
+		for _, field := range value.Body {
+			d.Members = append(d.Members, Member{Name: field.Name.Value, Value: field.Value})
+		}
+	default:
+		d.Type = TypeOf(value)
+	}
+}
+
+func params(fn *ast.FunctionExpression) []string {
+	names := make([]string, 0, len(fn.Args))
+	for _, arg := range fn.Args {
+		names = append(names, arg.Value)
+	}
+	return names
+}
+
+// the type of the value an expression evaluates to when it can be told from the expression
+// alone, the name of the struct for the instances and the struct references, expr otherwise
+func TypeOf(expr ast.Expression) string {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return "int"
+	case *ast.FloatLiteral:
+		return "float"
+	case *ast.StringLiteral:
+		return "string"
+	case *ast.CharLiteral:
+		return "char"
+	case *ast.BooleanLiteral:
+		return "bool"
+	case *ast.NulLiteral:
+		return "nul"
+	case *ast.ArrayLiteral, *ast.BytesLiteral:
+		return "array"
+	case *ast.MapLiteral:
+		return "map"
+	case *ast.FunctionExpression:
+		return KindFn
+	case *ast.StructExpression:
+		return KindStruct
+	case *ast.EnumExpression:
+		return KindEnum
+	case *ast.StructInstanceExpression:
+		return expr.Left.String()
+	case *ast.Identifier:
+		return expr.Value
+	}
+	return "expr"
+}
+
+// the declarations removed, added and changed from old to new, removed ones first then
+// the others in the order of new
+func Diff(old, new *ast.Program) []Change {
+	return DiffDecls(Declarations(old), Declarations(new))
+}
+
+func DiffDecls(old, new []*Decl) []Change {
+	changes := []Change{}
+	newByName := byName(new)
+	oldByName := byName(old)
+
+	for _, d := range old {
+		if _, ok := newByName[d.Name]; !ok {
+			changes = append(changes, Change{Change: Removed, Name: d.Name, Kind: d.Kind, Old: d})
+		}
+	}
+	for _, d := range new {
+		before, ok := oldByName[d.Name]
+		if !ok {
+			changes = append(changes, Change{Change: Added, Name: d.Name, Kind: d.Kind, New: d})
+			continue
+		}
+		if details := compare(before, d); len(details) > 0 {
+			changes = append(changes, Change{Change: Changed, Name: d.Name, Kind: d.Kind, Old: before, New: d, Details: details})
+		}
+	}
+	return changes
+}
+
+// the last declaration of a name wins, like it does when the program runs
+func byName(decls []*Decl) map[string]*Decl {
+	names := make(map[string]*Decl, len(decls))
+	for _, d := range decls {
+		names[d.Name] = d
+	}
+	return names
+}
+
+func compare(old, new *Decl) []Detail {
+	var details []Detail
+	if old.Kind != new.Kind {
+		// the rest can't be compared, a function turned into a struct shares nothing with it
+		return []Detail{{Change: Changed, What: "kind", Old: old.Kind, New: new.Kind}}
+	}
+	if old.Deprecated != new.Deprecated {
+		change := Added
+		if !new.Deprecated {
+			change = Removed
+		}
+		details = append(details, Detail{Change: change, What: "deprecation"})
+	}
+	if old.Type != new.Type {
+		details = append(details, Detail{Change: Changed, What: "type", Old: old.Type, New: new.Type})
+	}
+	if !slices.Equal(old.Params, new.Params) {
+		details = append(details, Detail{Change: Changed, What: "params", Old: signature(old.Params), New: signature(new.Params)})
+	}
+
+	details = append(details, diffNamed(old.Fields, new.Fields, "field", func(f Field) string { return f.Name }, compareFields)...)
+	details = append(details, diffNamed(old.Methods, new.Methods, "method", func(m Method) string { return m.Name }, func(old, new Method) []Detail {
+		if slices.Equal(old.Params, new.Params) {
+			return nil
+		}
+		return []Detail{{Change: Changed, What: "method", Name: new.Name, Old: signature(old.Params), New: signature(new.Params)}}
+	})...)
+	details = append(details, diffNamed(old.Members, new.Members, "member", func(m Member) string { return m.Name }, func(old, new Member) []Detail {
+		if old.Value == new.Value {
+			return nil
+		}
+		return []Detail{{Change: Changed, What: "member", Name: new.Name, Old: itoa(old.Value), New: itoa(new.Value)}}
+	})...)
+	return details
+}
+
+func compareFields(old, new Field) []Detail {
+	var details []Detail
+	if old.Type != new.Type {
+		details = append(details, Detail{Change: Changed, What: "field", Name: new.Name, Old: old.Type, New: new.Type})
+	}
+	if old.Mutable != new.Mutable {
+		details = append(details, Detail{Change: Changed, What: "mutability", Name: new.Name, Old: mutability(old.Mutable), New: mutability(new.Mutable)})
+	}
+	return details
+}
+
+// the items removed, added and changed between two lists of named items
+func diffNamed[T any](old, new []T, what string, name func(T) string, compare func(old, new T) []Detail) []Detail {
+	var details []Detail
+	before := make(map[string]T, len(old))
+	for _, item := range old {
+		before[name(item)] = item
+	}
+	after := make(map[string]bool, len(new))
+	for _, item := range new {
+		after[name(item)] = true
+	}
+
+	for _, item := range old {
+		if !after[name(item)] {
+			details = append(details, Detail{Change: Removed, What: what, Name: name(item)})
+		}
+	}
+	for _, item := range new {
+		previous, ok := before[name(item)]
+		if !ok {
+			details = append(details, Detail{Change: Added, What: what, Name: name(item)})
+			continue
+		}
+		details = append(details, compare(previous, item)...)
+	}
+	return details
+}
+
+func signature(params []string) string {
+	return "(" + strings.Join(params, ", ") + ")"
+}
+
+func mutability(mutable bool) string {
+	if mutable {
+		return "mutable"
+	}
+	return "const"
+}
+
+func itoa(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
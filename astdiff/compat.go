@@ -0,0 +1,103 @@
+package astdiff
+
+import "fmt"
+
+// a change of the exported declarations that breaks the programs importing the module
+type Break struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Row    int    `json:"row"` // in the old version for the removals, in the new one otherwise
+	Reason string `json:"reason"`
+	// the declaration isn't in the new version
+	Removed bool `json:"removed"`
+}
+
+// the breaking changes among the changes of the exported declarations, the private ones
+// (starting with _) can't be used by the importers so they can change freely
+func Breaking(changes []Change) []Break {
+	var breaks []Break
+	for _, change := range changes {
+		switch change.Change {
+		case Removed:
+			if change.Old.Exported() {
+				breaks = append(breaks, Break{Name: change.Name, Kind: change.Kind, Row: change.Old.Row, Reason: change.Kind + " removed", Removed: true})
+			}
+		case Changed:
+			if !change.New.Exported() {
+				continue
+			}
+			for _, detail := range change.Details {
+				if reason, ok := breakingDetail(change, detail); ok {
+					breaks = append(breaks, Break{Name: change.Name, Kind: change.Kind, Row: change.New.Row, Reason: reason})
+				}
+			}
+		}
+	}
+	return breaks
+}
+
+// whether the detail breaks the programs using the declaration, with why, the additions never
+// do, every struct field has a default so a new one isn't required from the instances
+func breakingDetail(change Change, detail Detail) (string, bool) {
+	switch detail.What {
+	case "kind":
+		// a const turned var can still be read the way it was
+		if detail.Old != KindConst || detail.New != KindVar {
+			return fmt.Sprintf("was a %s, is a %s", detail.Old, detail.New), true
+		}
+	case "type":
+		if narrowed(detail.Old, detail.New) {
+			return fmt.Sprintf("value type changed from %s to %s", detail.Old, detail.New), true
+		}
+	case "params":
+		// the calls pass the args by position, only their count matters
+		if len(change.Old.Params) != len(change.New.Params) {
+			return fmt.Sprintf("takes %d args instead of %d, %s became %s", len(change.New.Params), len(change.Old.Params), detail.Old, detail.New), true
+		}
+	case "field":
+		if detail.Change == Removed {
+			return fmt.Sprintf("field %s removed", detail.Name), true
+		}
+		if detail.Change == Changed && narrowed(detail.Old, detail.New) {
+			return fmt.Sprintf("field %s only takes %s values instead of %s ones", detail.Name, detail.New, detail.Old), true
+		}
+	case "mutability":
+		if detail.New == mutability(false) {
+			return fmt.Sprintf("field %s can't be assigned anymore", detail.Name), true
+		}
+	case "method":
+		if detail.Change == Removed {
+			return fmt.Sprintf("method %s removed", detail.Name), true
+		}
+		if detail.Change == Changed && len(methodParams(change.Old, detail.Name)) != len(methodParams(change.New, detail.Name)) {
+			return fmt.Sprintf("method %s takes %s instead of %s", detail.Name, detail.New, detail.Old), true
+		}
+	case "member":
+		if detail.Change == Removed {
+			return fmt.Sprintf("member %s removed", detail.Name), true
+		}
+		if detail.Change == Changed {
+			// the values get stored and compared as ints by the importers
+			return fmt.Sprintf("member %s changed value from %s to %s", detail.Name, detail.Old, detail.New), true
+		}
+	}
+	return "", false
+}
+
+// whether a field or a value of type old can't hold the values it used to anymore, nul
+// fields take any value and expr is a value the type of can't be told
+func narrowed(old, new string) bool {
+	if old == new || new == "nul" || new == "expr" || old == "expr" {
+		return false
+	}
+	return true
+}
+
+func methodParams(d *Decl, name string) []string {
+	for _, method := range d.Methods {
+		if method.Name == name {
+			return method.Params
+		}
+	}
+	return nil
+}
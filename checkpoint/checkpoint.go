@@ -0,0 +1,353 @@
+package checkpoint
+
+import (
+	"blk/object"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// the layout of the files, bumped when it changes, the files of another layout are refused
+const Version = 1
+
+// the state of a run at a top level statement, enough to continue the run from there: blk resume
+// runs the declarations before the statement again, which binds the functions, the structs, the
+// enums and the modules, then puts the globals back and evaluates from the statement on
+type Checkpoint struct {
+	Version  int
+	Program  string // absolute path of the program
+	Checksum string // of the source, its statements have to be the same ones
+	// index of the top level statement that made the checkpoint
+	Statement int
+	Globals   []Binding
+	// the globals holding functions, the declarations bind them again, a function made any
+	// other way can't be kept
+	Functions []string
+	// the arrays, the maps and the instances, the values refer to them by index so the values
+	// shared by many others and the cycles come back the same
+	Values []Value
+}
+
+type Binding struct {
+	Name    string
+	Mutable bool
+	Value   Value
+}
+
+// a scalar, or a ref to the Values of the checkpoint, which are arrays, maps and instances
+type Value struct {
+	Kind   string // int, float, string, char, bool, nul, ref, array, map, instance, result
+	Int    int64
+	Float  float64
+	String string // the text of the strings and the chars, the struct name of the instances
+	Bool   bool
+	Ref    int
+	Size   int // of the arrays, -1 for the dynamic ones
+	Items  []Value
+	// the keys of the maps, the field names of the instances, in the order of the Items
+	Keys []Value
+}
+
+// the kinds of values bound again by the declarations, they aren't saved
+var declared = []object.ObjectType{
+	object.FUNCTION_OBJ, object.BUILTIN_OBJ, object.STRUCT_OBJ, object.ENUM_OBJ,
+	object.BUILTIN_MODULE, object.USER_MODULE,
+}
+
+type encoder struct {
+	values []Value
+	refs   map[object.Object]int
+}
+
+// the state of the globals of the program, an error when a value can't be saved
+func New(program, checksum string, statement int, globals *object.Environment) (*Checkpoint, error) {
+	cp := &Checkpoint{Version: Version, Program: program, Checksum: checksum, Statement: statement}
+	enc := &encoder{refs: map[object.Object]int{}}
+
+	store := globals.GetStore()
+	names := make([]string, 0, len(store))
+	for name := range store {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		item := store[name]
+		value, _ := object.Cast(item)
+		if item.IsBuiltIn || value == nil {
+			continue
+		}
+		if slices.Contains(declared, value.Type()) {
+			if value.Type() == object.FUNCTION_OBJ {
+				cp.Functions = append(cp.Functions, name)
+			}
+			continue
+		}
+		encoded, err := enc.encode(value, name)
+		if err != nil {
+			return nil, err
+		}
+		cp.Globals = append(cp.Globals, Binding{Name: name, Mutable: item.IsMutable, Value: encoded})
+	}
+	cp.Values = enc.values
+	return cp, nil
+}
+
+func (enc *encoder) encode(obj object.Object, path string) (Value, error) {
+	obj, _ = object.Cast(obj)
+	switch v := obj.(type) {
+	case nil, *object.Nul:
+		return Value{Kind: "nul"}, nil
+	case *object.Integer:
+		return Value{Kind: "int", Int: v.Value}, nil
+	case *object.Float:
+		return Value{Kind: "float", Float: v.Value}, nil
+	case *object.String:
+		return Value{Kind: "string", String: v.Value}, nil
+	case *object.Char:
+		return Value{Kind: "char", String: string(v.Value)}, nil
+	case *object.Boolean:
+		return Value{Kind: "bool", Bool: v.Value}, nil
+	case *object.Array, *object.Map, *object.StructInstance:
+		if ref, ok := enc.refs[obj]; ok {
+			return Value{Kind: "ref", Ref: ref}, nil
+		}
+	default:
+		return Value{}, fmt.Errorf("can't save %s, a %s can't be kept, only the data can", path, strings.ToLower(string(obj.Type())))
+	}
+
+	// the ref is taken before the items are, a value holding itself refers to it
+	ref := len(enc.values)
+	enc.refs[obj] = ref
+	enc.values = append(enc.values, Value{})
+
+	var value Value
+	var err error
+	switch v := obj.(type) {
+	case *object.Array:
+		value = Value{Kind: "array", Size: v.Size}
+		for idx, elem := range v.Elements {
+			item, err := enc.encode(elem, fmt.Sprintf("%s[%d]", path, idx))
+			if err != nil {
+				return Value{}, err
+			}
+			value.Items = append(value.Items, item)
+		}
+	case *object.Map:
+		value, err = enc.encodeMap(v, path)
+	case *object.StructInstance:
+		value, err = enc.encodeInstance(v, path)
+	}
+	if err != nil {
+		return Value{}, err
+	}
+	enc.values[ref] = value
+	return Value{Kind: "ref", Ref: ref}, nil
+}
+
+func (enc *encoder) encodeMap(m *object.Map, path string) (Value, error) {
+	value := Value{Kind: "map"}
+	// sorted so the same map makes the same file
+	pairs := make([]object.HashPair, 0, len(m.Pairs))
+	for _, pair := range m.Pairs {
+		pairs = append(pairs, pair)
+	}
+	slices.SortFunc(pairs, func(a, b object.HashPair) int { return strings.Compare(a.Key.Inspect(), b.Key.Inspect()) })
+
+	for _, pair := range pairs {
+		key, err := enc.encode(pair.Key, path)
+		if err != nil {
+			return Value{}, err
+		}
+		item, err := enc.encode(pair.Value, fmt.Sprintf("%s[%s]", path, pair.Key.Inspect()))
+		if err != nil {
+			return Value{}, err
+		}
+		value.Keys = append(value.Keys, key)
+		value.Items = append(value.Items, item)
+	}
+	return value, nil
+}
+
+func (enc *encoder) encodeInstance(instance *object.StructInstance, path string) (Value, error) {
+	if instance.Def == nil || len(instance.Def.Name) == 0 {
+		return Value{}, fmt.Errorf("can't save %s, the instances of anonymous structs can't be kept", path)
+	}
+	value := Value{Kind: "instance", String: instance.Def.Name}
+	if instance.Def == object.ResultDef {
+		value.Kind = "result"
+	}
+
+	names := instance.Def.FieldNames()
+	for name := range instance.Fields {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		field, ok := instance.Fields[name]
+		if !ok {
+			continue
+		}
+		item, err := enc.encode(field, path+"."+name)
+		if err != nil {
+			return Value{}, err
+		}
+		value.Keys = append(value.Keys, Value{Kind: "string", String: name})
+		value.Items = append(value.Items, item)
+	}
+	return value, nil
+}
+
+// writes the checkpoint to a temporary file first, an interrupted write keeps the last one
+func (cp *Checkpoint) WriteFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(cp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func ReadFile(path string) (*Checkpoint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cp Checkpoint
+	if err := gob.NewDecoder(file).Decode(&cp); err != nil {
+		return nil, fmt.Errorf("%s isn't a checkpoint: %v", path, err)
+	}
+	if cp.Version != Version {
+		return nil, fmt.Errorf("%s is a checkpoint of another blk (layout %d, expected %d)", path, cp.Version, Version)
+	}
+	return &cp, nil
+}
+
+// binds the saved globals in env, the structs of the instances are looked up by name with
+// structs, once the declarations got evaluated again
+func (cp *Checkpoint) Restore(env *object.Environment, structs func(name string) (*object.Struct, bool)) error {
+	dec := &decoder{cp: cp, objects: make([]object.Object, len(cp.Values))}
+
+	// the objects are made empty first, so the refs between them can be set while filling them
+	for idx, value := range cp.Values {
+		switch value.Kind {
+		case "array":
+			dec.objects[idx] = &object.Array{Size: value.Size, Elements: make([]object.Object, 0, len(value.Items))}
+		case "map":
+			dec.objects[idx] = &object.Map{Pairs: make(object.PairsType, len(value.Items))}
+		case "result":
+			dec.objects[idx] = &object.StructInstance{Def: object.ResultDef, Fields: map[string]object.Object{}, Methods: object.ResultDef.Methods}
+		case "instance":
+			def, ok := structs(value.String)
+			if !ok {
+				return fmt.Errorf("the checkpoint holds instances of %s, the program doesn't declare it anymore", value.String)
+			}
+			dec.objects[idx] = &object.StructInstance{Def: def, Fields: map[string]object.Object{}, Methods: def.Methods}
+		default:
+			return fmt.Errorf("the checkpoint is corrupted, unknown value kind %s", value.Kind)
+		}
+	}
+	for idx, value := range cp.Values {
+		if err := dec.fill(dec.objects[idx], value); err != nil {
+			return err
+		}
+	}
+
+	for _, binding := range cp.Globals {
+		value, err := dec.decode(binding.Value)
+		if err != nil {
+			return err
+		}
+		env.OverrideDefine(binding.Name, object.ItemObject{Object: value, IsMutable: binding.Mutable})
+	}
+	for _, name := range cp.Functions {
+		if _, ok := env.Resolve(name); !ok {
+			return fmt.Errorf("%s held a function made while the program ran, only the declared functions can be kept", name)
+		}
+	}
+	return nil
+}
+
+type decoder struct {
+	cp      *Checkpoint
+	objects []object.Object
+}
+
+func (dec *decoder) decode(value Value) (object.Object, error) {
+	switch value.Kind {
+	case "nul":
+		return object.NUL, nil
+	case "int":
+		return &object.Integer{Value: value.Int}, nil
+	case "float":
+		return &object.Float{Value: value.Float}, nil
+	case "string":
+		return &object.String{Value: value.String}, nil
+	case "char":
+		if chars := []rune(value.String); len(chars) == 1 {
+			return &object.Char{Value: chars[0]}, nil
+		}
+	case "bool":
+		if value.Bool {
+			return object.TRUE, nil
+		}
+		return object.FALSE, nil
+	case "ref":
+		if value.Ref < 0 || value.Ref >= len(dec.objects) {
+			return nil, fmt.Errorf("the checkpoint is corrupted, ref %d out of %d values", value.Ref, len(dec.objects))
+		}
+		return dec.objects[value.Ref], nil
+	}
+	return nil, fmt.Errorf("the checkpoint is corrupted, unknown value kind %s", value.Kind)
+}
+
+func (dec *decoder) fill(obj object.Object, value Value) error {
+	if value.Kind != "array" && len(value.Keys) != len(value.Items) {
+		return fmt.Errorf("the checkpoint is corrupted, %d keys for %d items", len(value.Keys), len(value.Items))
+	}
+	items := make([]object.Object, 0, len(value.Items))
+	for _, item := range value.Items {
+		decoded, err := dec.decode(item)
+		if err != nil {
+			return err
+		}
+		items = append(items, decoded)
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		obj.Elements = append(obj.Elements, items...)
+	case *object.Map:
+		for idx, key := range value.Keys {
+			decoded, err := dec.decode(key)
+			if err != nil {
+				return err
+			}
+			hashable, ok := decoded.(object.Hashable)
+			if !ok {
+				return fmt.Errorf("the checkpoint is corrupted, a map key of kind %s", key.Kind)
+			}
+			obj.Pairs[hashable.HashKey()] = object.HashPair{Key: decoded, Value: items[idx]}
+		}
+	case *object.StructInstance:
+		for idx, key := range value.Keys {
+			// the fields of the results can't be assigned
+			obj.Fields[key.String] = object.ItemObject{Object: items[idx], IsMutable: obj.Def != object.ResultDef}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"blk/ast"
+	"blk/astdiff"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func AstDiff(args []string) {
+	flags := flag.NewFlagSet("astdiff", flag.ContinueOnError)
+
+	if err := parseFlags(flags, args); err != nil {
+		return
+	}
+
+	if flags.NArg() != 2 {
+		fmt.Println("ERROR: provide the old and the new version of the program, an example of this: blk astdiff old.blk new.blk")
+		return
+	}
+	oldPath, newPath := flags.Arg(0), flags.Arg(1)
+
+	oldProgram := readProgram(oldPath)
+	if oldProgram == nil {
+		os.Exit(2)
+	}
+	newProgram := readProgram(newPath)
+	if newProgram == nil {
+		os.Exit(2)
+	}
+
+	out, _ := json.MarshalIndent(map[string]any{
+		"old":     oldPath,
+		"new":     newPath,
+		"changes": astdiff.Diff(oldProgram, newProgram),
+	}, "", "  ")
+	fmt.Println(string(out))
+}
+
+// reads and parses a program, prints the errors and returns nil if any
+func readProgram(path string) *ast.Program {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return nil
+	}
+	return parseSource(path, content, false)
+}
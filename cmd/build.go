@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"blk/ast"
+	"blk/diagnostics"
+	"blk/internals"
+	"blk/interpreter"
+	"blk/object"
+	"blk/stdlib"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// an executable made by blk build is an interpreter with the bundle appended to it, followed
+// by the size of the bundle and bundleMagic, blk looks for them when it starts
+const bundleMagic = "BLKBUNDL"
+
+// the size of the bundle then the magic
+const bundleTrailer = 8 + len(bundleMagic)
+
+// the platforms blk build can make executables for
+var buildTargets = []string{"linux/amd64", "linux/arm64", "windows/amd64", "darwin/amd64", "darwin/arm64"}
+
+// what gets appended to the interpreter, the grants are the ones given to blk build since the
+// executable doesn't take blk flags
+type bundle struct {
+	AllowFS, AllowNet, AllowRun string
+	Program                     []byte // a compiled program, the layout of a .blkc
+}
+
+func Build(args []string) {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	fileTarget := flags.String("f", "", "program file path")
+	output := flags.String("o", "", "output file path")
+	target := flags.String("target", runtime.GOOS+"/"+runtime.GOARCH, "platform of the executable")
+	allowFS := flags.String("allow-fs", "", "paths the program can access")
+	allowNet := flags.String("allow-net", "", "hosts the program can reach")
+	allowRun := flags.String("allow-run", "", "programs the stdlib can start")
+
+	if err := parseFlags(flags, args); err != nil {
+		return
+	}
+
+	if len(*fileTarget) <= 0 {
+		fmt.Println("ERROR: provide the filepath flag -f to assign the path to it")
+		return
+	}
+	if filepath.Ext(*fileTarget) != ".blk" {
+		fmt.Println("ERROR: provide a blk program to build")
+		return
+	}
+	if !slices.Contains(buildTargets, *target) {
+		fmt.Printf("ERROR: unsupported target %s, pick one of %s\n", *target, strings.Join(buildTargets, ", "))
+		return
+	}
+	// checked now, the executable would only find out when it starts
+	if _, err := internals.NewPermissions(*allowFS, *allowNet, *allowRun); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return
+	}
+
+	if len(*output) <= 0 {
+		*output = strings.TrimSuffix(filepath.Base(*fileTarget), ".blk")
+		if strings.HasPrefix(*target, "windows/") {
+			*output += ".exe"
+		}
+	}
+
+	content, err := os.ReadFile(*fileTarget)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	program := parseSource(*fileTarget, content, false)
+	if program == nil {
+		return
+	}
+
+	// the stdlib is part of the interpreter, the other modules would be looked for next to
+	// the executable
+	for _, stmt := range program.Statements {
+		if imp, ok := stmt.(*ast.ImportStatement); ok && strings.Contains(imp.ModuleName.Value, "/") {
+			fmt.Printf("%s ERROR: %q can't be bundled, blk build only carries a single file\n",
+				diagnostics.Location(*fileTarget, imp.Token.Row, imp.Token.Col), imp.ModuleName.Value)
+			os.Exit(1)
+		}
+	}
+
+	var compiled bytes.Buffer
+	if err := ast.EncodeProgram(&compiled, &ast.CompiledProgram{Source: filepath.Base(*fileTarget), Program: program}); err != nil {
+		fmt.Printf("ERROR: failed to compile the program: %v\n", err)
+		return
+	}
+
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(bundle{AllowFS: *allowFS, AllowNet: *allowNet, AllowRun: *allowRun, Program: compiled.Bytes()}); err != nil {
+		fmt.Printf("ERROR: failed to bundle the program: %v\n", err)
+		return
+	}
+	payload.Write(binary.LittleEndian.AppendUint64(nil, uint64(payload.Len())))
+	payload.WriteString(bundleMagic)
+
+	executable, err := interpreterFor(*target)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*output, append(executable, payload.Bytes()...), 0o755); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("built %s for %s\n", *output, *target)
+}
+
+// the interpreter for the target, the running one for the host, the others are compiled from
+// the blk sources at BLK_SRC with the go toolchain
+func interpreterFor(target string) ([]byte, error) {
+	if target == runtime.GOOS+"/"+runtime.GOARCH {
+		path, err := os.Executable()
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// an executable made by blk build drops its own program
+		if size, ok := bundleSize(content); ok {
+			content = content[:len(content)-size]
+		}
+		return content, nil
+	}
+
+	src := os.Getenv("BLK_SRC")
+	if len(src) == 0 {
+		return nil, fmt.Errorf("building for %s compiles the interpreter from the blk sources, set BLK_SRC to the directory of the blk repository", target)
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		return nil, fmt.Errorf("building for %s needs the go toolchain in the PATH", target)
+	}
+
+	tmp, err := os.CreateTemp("", "blk-interpreter-*")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	goos, goarch, _ := strings.Cut(target, "/")
+	cmd := exec.Command("go", "build", "-trimpath", "-o", tmp.Name(), ".")
+	cmd.Dir = src
+	// plugins need cgo, which doesn't cross compile, the native modules stay with the host
+	cmd.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch, "CGO_ENABLED=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to compile the interpreter for %s: %s", target, strings.TrimSpace(stderr.String()))
+	}
+	return os.ReadFile(tmp.Name())
+}
+
+// the size of the bundle at the end of the executable with its trailer, if it has one
+func bundleSize(content []byte) (int, bool) {
+	if len(content) < bundleTrailer || string(content[len(content)-len(bundleMagic):]) != bundleMagic {
+		return 0, false
+	}
+	size := binary.LittleEndian.Uint64(content[len(content)-bundleTrailer:])
+	if size > uint64(len(content)-bundleTrailer) {
+		return 0, false
+	}
+	return int(size) + bundleTrailer, true
+}
+
+// the bundle appended to the running executable, nil for blk itself
+func loadBundle() (*bundle, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.Size() < int64(bundleTrailer) {
+		return nil, nil
+	}
+	trailer := make([]byte, bundleTrailer)
+	if _, err := file.ReadAt(trailer, info.Size()-int64(bundleTrailer)); err != nil || string(trailer[8:]) != bundleMagic {
+		return nil, nil
+	}
+
+	size := int64(binary.LittleEndian.Uint64(trailer))
+	if size > info.Size()-int64(bundleTrailer) {
+		return nil, fmt.Errorf("the bundled program of %s is truncated", filepath.Base(path))
+	}
+	payload := make([]byte, size)
+	if _, err := file.ReadAt(payload, info.Size()-int64(bundleTrailer)-size); err != nil {
+		return nil, err
+	}
+	var b bundle
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&b); err != nil {
+		return nil, fmt.Errorf("the bundled program of %s is corrupted: %v", filepath.Base(path), err)
+	}
+	return &b, nil
+}
+
+// runs the program of an executable made by blk build, the exit status is 1 when it fails
+func runBundle(b *bundle) {
+	compiled, err := ast.DecodeProgram(bytes.NewReader(b.Program))
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(2)
+	}
+	permissions, err := internals.NewPermissions(b.AllowFS, b.AllowNet, b.AllowRun)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(2)
+	}
+	stdlib.Permissions = permissions
+
+	env := object.NewEnvironment(nil)
+	i := interpreter.NewInterpreter(env, compiled.Source)
+	evaluated := i.Eval(compiled.Program)
+
+	// the source isn't bundled, the diagnostics only have their positions
+	renderer := diagnostics.NewRenderer()
+	for _, warning := range i.Warnings {
+		fmt.Fprintln(os.Stderr, renderError(renderer, warning))
+	}
+	if err, ok := evaluated.(*object.Error); ok {
+		if err.Row > 0 {
+			fmt.Println(renderer.Render(err.Diagnostic()))
+		} else {
+			fmt.Println(err.Inspect())
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// the interpreter, the payload then the trailer giving the size of the bundle
+func bundled(executable, payload string, size uint64) []byte {
+	content := []byte(executable + payload)
+	content = binary.LittleEndian.AppendUint64(content, size)
+	return append(content, bundleMagic...)
+}
+
+func TestBundleSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		expected int
+		ok       bool
+		// what's left once blk build drops the bundle of the running executable
+		interpreter string
+	}{
+		{"bundled", bundled("ELF...", "program", 7), 7 + bundleTrailer, true, "ELF..."},
+		{"empty bundle", bundled("ELF...", "", 0), bundleTrailer, true, "ELF..."},
+		{"the whole file", bundled("", "program", 7), 7 + bundleTrailer, true, ""},
+		{"plain interpreter", []byte("ELF..."), 0, false, ""},
+		{"shorter than a trailer", []byte(bundleMagic), 0, false, ""},
+		{"bigger than the file", bundled("ELF...", "program", 100), 0, false, ""},
+		{"other magic", append(binary.LittleEndian.AppendUint64([]byte("ELF..."), 0), "NOTABUND"...), 0, false, ""},
+	}
+	for _, tt := range tests {
+		size, ok := bundleSize(tt.content)
+		if ok != tt.ok || size != tt.expected {
+			t.Errorf("%s: expected=(%d, %t), got=(%d, %t)", tt.name, tt.expected, tt.ok, size, ok)
+			continue
+		}
+		if left := string(tt.content[:len(tt.content)-size]); ok && left != tt.interpreter {
+			t.Errorf("%s: expected the interpreter %q to be left, got=%q", tt.name, tt.interpreter, left)
+		}
+	}
+}
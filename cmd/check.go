@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"blk/diagnostics"
+	"blk/internals"
+	"blk/lexer"
+	"blk/parser"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// a file to check, the content is read ahead since staged files come from the git index
+type checkedFile struct {
+	path    string
+	content []byte
+	// where the file is on disk, the includes are relative to it
+	dir string
+}
+
+// a diagnostic of blk check --json, rows and cols start at 1, 0 when the error has no position
+type checkReport struct {
+	File     string   `json:"file"`
+	Row      int      `json:"row"`
+	Col      int      `json:"col"`
+	Severity string   `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Message  string   `json:"message"`
+	Notes    []string `json:"notes,omitempty"`
+}
+
+func Check(args []string) {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	fileTarget := flags.String("f", "", "program file path")
+	staged := flags.Bool("staged", false, "check the .blk files staged in git")
+	asJSON := flags.Bool("json", false, "print the diagnostics as json")
+	all := flags.Bool("all", false, "check every .blk file of the project")
+	jobs := flags.Int("jobs", runtime.NumCPU(), "files checked at once")
+	strict := flags.Bool("strict", false, "check the files in strict mode")
+
+	if err := parseFlags(flags, args); err != nil {
+		return
+	}
+
+	var files []checkedFile
+	if *staged {
+		var err error
+		if files, err = stagedFiles(); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			os.Exit(2)
+		}
+	} else if *all {
+		var err error
+		if files, err = projectFiles(); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			os.Exit(2)
+		}
+	} else {
+		paths := flags.Args()
+		if len(*fileTarget) > 0 {
+			paths = append([]string{*fileTarget}, paths...)
+		}
+		if len(paths) == 0 {
+			fmt.Println("ERROR: provide the files to check, with -f or as args, --staged for the files staged in git or --all for the whole project")
+			return
+		}
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Printf("ERROR: %v\n", err)
+				os.Exit(2)
+			}
+			files = append(files, checkedFile{path: filepath.ToSlash(path), content: content, dir: filepath.Dir(path)})
+		}
+	}
+
+	reports := []checkReport{}
+	failed := false
+	dropped := 0
+	failedFiles := 0
+	// the files get checked at once, the diagnostics are printed file by file in order
+	for idx, p := range checkSources(files, *jobs, *strict) {
+		file := files[idx]
+		renderer := diagnostics.NewRenderer()
+		renderer.AddSource(file.path, string(file.content))
+
+		dropped += p.Dropped
+		if len(p.Errors) > 0 {
+			failedFiles++
+			// a project holds many files, their diagnostics get a header
+			if *all && !*asJSON {
+				fmt.Println(diagnostics.Paint("1;36", fmt.Sprintf("%s (%d)", file.path, len(p.Errors)+p.Dropped)))
+			}
+		}
+		for _, err := range p.Errors {
+			report := checkReport{File: file.path, Severity: string(diagnostics.Error), Message: err.Error()}
+			if d, ok := err.(*diagnostics.Diagnostic); ok {
+				report.Row, report.Col = d.Primary.Row, d.Primary.Col
+				report.Severity, report.Message, report.Notes = string(d.Severity), d.Message, d.Notes
+				report.Code = string(d.Code)
+			}
+			failed = failed || report.Severity == string(diagnostics.Error)
+
+			if *asJSON {
+				reports = append(reports, report)
+			} else {
+				fmt.Println(renderError(renderer, err))
+			}
+		}
+		if summary := p.ErrorSummary(); len(summary) > 0 && !*asJSON {
+			fmt.Println(summary)
+		}
+	}
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(map[string]any{"files": len(files), "diagnostics": reports, "dropped": dropped}, "", "  ")
+		fmt.Println(string(out))
+	} else if *all {
+		fmt.Printf("checked %d files, %d with errors\n", len(files), failedFiles)
+	}
+	// the exit status is what git hooks look at
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// the parser that went through the file, with the diagnostics of the lexer and its own
+func checkSource(file checkedFile, strict bool) *parser.Parser {
+	l := lexer.NewLexer(file.path, string(file.content))
+	p := parser.NewParser(l.Tokenize(), file.path)
+	p.Dir = file.dir
+	p.Strict = strict
+	p.Parse()
+	return p
+}
+
+// checks the files with a pool of jobs workers, the parsers are in the order of the files
+func checkSources(files []checkedFile, jobs int, strict bool) []*parser.Parser {
+	parsers := make([]*parser.Parser, len(files))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range max(1, min(jobs, len(files))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				parsers[idx] = checkSource(files[idx], strict)
+			}
+		}()
+	}
+	for idx := range files {
+		indexes <- idx
+	}
+	close(indexes)
+	wg.Wait()
+
+	return parsers
+}
+
+// the .blk files under the root of the project, the directory of blk.toml or the current one
+// when there is none, sorted by path, the hidden directories are skipped
+func projectFiles() ([]checkedFile, error) {
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if manifest, err := internals.FindManifest(root); err == nil {
+		root = filepath.Dir(manifest)
+	}
+
+	var files []checkedFile
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".blk" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, checkedFile{path: filepath.ToSlash(rel), content: content, dir: filepath.Dir(path)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .blk files found in %s", root)
+	}
+	return files, nil
+}
+
+// the .blk files added, copied, modified or renamed in the git index, with their staged content,
+// the part of them left unstaged isn't what gets committed, so it isn't checked
+// the paths are relative to the root of the repository, like git prints them
+func stagedFiles() ([]checkedFile, error) {
+	toplevel, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root := strings.TrimSpace(string(toplevel))
+	out, err := git("diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []checkedFile
+	for path := range strings.SplitSeq(string(out), "\x00") {
+		if filepath.Ext(path) != ".blk" {
+			continue
+		}
+		content, err := git("show", ":"+path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, checkedFile{path: path, content: content, dir: filepath.Join(root, filepath.Dir(path))})
+	}
+	return files, nil
+}
+
+func git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// the first line says what went wrong, git follows it with its usage
+		if msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); len(msg) > 0 {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return out, nil
+}
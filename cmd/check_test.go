@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStagedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't in the PATH")
+	}
+	root := t.TempDir()
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	t.Chdir(root)
+	if _, err := git("init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		staged string // empty when the file isn't added to the index
+		// written after git add, the part left unstaged isn't checked
+		worktree string
+		expected bool
+	}{
+		{path: "main.blk", staged: "x := 1", worktree: "x := ", expected: true},
+		{path: "lib/util.blk", staged: "y := 2", expected: true},
+		{path: "with space.blk", staged: "z := 3", expected: true},
+		{path: "notes.txt", staged: "not blk"},
+		{path: "draft.blk", worktree: "w := 4"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(root, filepath.FromSlash(tt.path))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if len(tt.staged) > 0 {
+			if err := os.WriteFile(path, []byte(tt.staged), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := git("add", tt.path); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(tt.worktree) > 0 {
+			if err := os.WriteFile(path, []byte(tt.worktree), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// the work tree directory the command runs from doesn't change the paths
+	t.Chdir(filepath.Join(root, "lib"))
+	files, err := stagedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := make(map[string]checkedFile, len(files))
+	for _, file := range files {
+		byPath[file.path] = file
+	}
+	for _, tt := range tests {
+		file, ok := byPath[tt.path]
+		if ok != tt.expected {
+			t.Errorf("%s: expected=%t to be checked, got=%t", tt.path, tt.expected, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if string(file.content) != tt.staged {
+			t.Errorf("%s: expected the staged content %q, got=%q", tt.path, tt.staged, file.content)
+		}
+		if dir := filepath.Join(root, filepath.Dir(filepath.FromSlash(tt.path))); file.dir != dir {
+			t.Errorf("%s: expected the dir %s, got=%s", tt.path, dir, file.dir)
+		}
+	}
+	if len(files) != 3 {
+		t.Errorf("expected 3 staged files, got=%d", len(files))
+	}
+}
+
+func TestProjectFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string // under the temp dir, blk.toml marks the root of the project
+		cwd      string
+		expected []string
+	}{
+		{
+			name:     "no manifest",
+			files:    []string{"main.blk", "lib/util.blk", "lib/deep/more.blk", "README.md", "main.blkc"},
+			expected: []string{"lib/deep/more.blk", "lib/util.blk", "main.blk"},
+		},
+		{
+			name:     "hidden directories",
+			files:    []string{"main.blk", ".git/hooks/pre.blk", "lib/.cache/old.blk", ".hidden.blk"},
+			expected: []string{".hidden.blk", "main.blk"},
+		},
+		{
+			name:     "from a sub directory",
+			files:    []string{"blk.toml", "main.blk", "lib/util.blk"},
+			cwd:      "lib",
+			expected: []string{"lib/util.blk", "main.blk"},
+		},
+		{
+			name:  "no files",
+			files: []string{"README.md"},
+		},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		for _, name := range tt.files {
+			path := filepath.Join(root, filepath.FromSlash(name))
+			os.MkdirAll(filepath.Dir(path), 0755)
+			if err := os.WriteFile(path, []byte("x := 1"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		t.Chdir(filepath.Join(root, tt.cwd))
+
+		files, err := projectFiles()
+		if len(tt.expected) == 0 {
+			if err == nil || !strings.Contains(err.Error(), "no .blk files found") {
+				t.Errorf("%s: expected no files to be found, got=%v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		paths := make([]string, 0, len(files))
+		for _, file := range files {
+			paths = append(paths, file.path)
+		}
+		if !slices.Equal(paths, tt.expected) {
+			t.Errorf("%s: expected=%v, got=%v", tt.name, tt.expected, paths)
+		}
+	}
+}
+
+func TestCheckSourcesOrder(t *testing.T) {
+	var files []checkedFile
+	for idx := range 20 {
+		content := fmt.Sprintf("x%d := 1", idx)
+		// every third file has an error
+		if idx%3 == 0 {
+			content = fmt.Sprintf("x%d := ", idx)
+		}
+		files = append(files, checkedFile{path: fmt.Sprintf("f%d.blk", idx), content: []byte(content)})
+	}
+	for _, jobs := range []int{0, 1, 4, 64} {
+		for idx, p := range checkSources(files, jobs, false) {
+			if failed := len(p.Errors) > 0; failed != (idx%3 == 0) {
+				t.Errorf("jobs=%d, %s: expected errors=%t, got=%v", jobs, files[idx].path, idx%3 == 0, p.Errors)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"blk/astdiff"
+	"blk/diagnostics"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// a breaking change of a file of the module
+type compatReport struct {
+	File string `json:"file"`
+	astdiff.Break
+}
+
+func Compat(args []string) {
+	flags := flag.NewFlagSet("compat", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the breaking changes as json")
+
+	if err := parseFlags(flags, args); err != nil {
+		return
+	}
+
+	if flags.NArg() != 2 {
+		fmt.Println("ERROR: provide the old and the new version of the module, files or directories, an example of this: blk compat v1/ v2/")
+		return
+	}
+	oldRoot, newRoot := flags.Arg(0), flags.Arg(1)
+
+	files, err := moduleFiles(oldRoot)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(2)
+	}
+
+	reports := []compatReport{}
+	for _, rel := range files {
+		oldPath, newPath := filepath.Join(oldRoot, rel), filepath.Join(newRoot, rel)
+		// the rows of the removals are in the old file, the others in the new one
+		oldName, newName := filepath.ToSlash(rel), filepath.ToSlash(rel)
+		if rel == "." {
+			oldPath, newPath = oldRoot, newRoot
+			oldName, newName = filepath.ToSlash(oldRoot), filepath.ToSlash(newRoot)
+		}
+
+		oldProgram := readProgram(oldPath)
+		if oldProgram == nil {
+			os.Exit(2)
+		}
+		if _, err := os.Stat(newPath); os.IsNotExist(err) {
+			reports = append(reports, compatReport{File: oldName, Break: astdiff.Break{Reason: "file removed", Removed: true}})
+			continue
+		}
+		newProgram := readProgram(newPath)
+		if newProgram == nil {
+			os.Exit(2)
+		}
+
+		for _, b := range astdiff.Breaking(astdiff.Diff(oldProgram, newProgram)) {
+			report := compatReport{File: newName, Break: b}
+			if b.Removed {
+				report.File = oldName
+			}
+			reports = append(reports, report)
+		}
+	}
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(map[string]any{"old": oldRoot, "new": newRoot, "breaking": reports}, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		for _, report := range reports {
+			if len(report.Name) == 0 {
+				fmt.Printf("%s: %s\n", report.File, diagnostics.Paint("1;31", report.Reason))
+				continue
+			}
+			fmt.Printf("%s %s %s: %s\n", diagnostics.Location(report.File, report.Row, 1), report.Kind, diagnostics.Paint("1;36", report.Name), diagnostics.Paint("1;31", report.Reason))
+		}
+		if len(reports) == 0 {
+			fmt.Println("no breaking changes")
+		} else {
+			fmt.Printf("%d breaking changes, the module needs a new major version\n", len(reports))
+		}
+	}
+	if len(reports) > 0 {
+		os.Exit(1)
+	}
+}
+
+// the .blk files of a module relative to its root, . when the module is a single file
+func moduleFiles(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{"."}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".blk" {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	slices.Sort(files)
+	return files, err
+}
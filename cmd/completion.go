@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"blk/examples"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var shells = []string{"bash", "zsh", "fish", "powershell"}
+
+// prints the completion script of the shell, made from the commands and their flags so it
+// follows the cli as it grows
+func Completion(args []string) {
+	if len(args) != 1 || !slices.Contains(shells, args[0]) {
+		fmt.Printf("ERROR: provide the shell to complete for, one of %s\n", strings.Join(shells, ", "))
+		return
+	}
+
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion()
+	case "zsh":
+		script = zshCompletion()
+	case "fish":
+		script = fishCompletion()
+	case "powershell":
+		script = powershellCompletion()
+	}
+	fmt.Print(script)
+}
+
+type completedFlag struct {
+	name        string
+	description string
+}
+
+// the command names, sorted so the script is the same from one run to another
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// the flags of the command then the global ones, an entry can name many flags sharing the
+// description: --allow-fs, --allow-net, --allow-run
+func commandFlags(name string) []completedFlag {
+	var flags []completedFlag
+	for _, info := range append(slices.Clone(commands[name].Flags), globalFlags...) {
+		for _, flag := range strings.Split(info.Name, ", ") {
+			flags = append(flags, completedFlag{name: flag, description: info.Description})
+		}
+	}
+	return flags
+}
+
+// the words a command takes as args instead of files, nil for the commands taking programs
+func commandArgs(name string) []string {
+	switch name {
+	case "help":
+		return commandNames()
+	case "examples":
+		return append([]string{"list", "run"}, examples.Names()...)
+	case "completion":
+		return shells
+	case "repl", "version", "upgrade", "messages":
+		return []string{}
+	}
+	return nil
+}
+
+func flagNames(flags []completedFlag) string {
+	names := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		names = append(names, flag.name)
+	}
+	return strings.Join(names, " ")
+}
+
+// the description up to its first clause, the shells show them on one line
+func shortDescription(description string) string {
+	for _, sep := range []string{", ", " ("} {
+		if idx := strings.Index(description, sep); idx > 0 {
+			description = description[:idx]
+		}
+	}
+	return description
+}
+
+// quotes the text for the single quoted strings of bash, zsh and fish
+func singleQuoted(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+}
+
+func bashCompletion() string {
+	var b strings.Builder
+	b.WriteString("# bash completion for blk, load it with: source <(blk completion bash)\n")
+	b.WriteString("_blk() {\n")
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    if [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %s -- \"$cur\"))\n", singleQuoted(strings.Join(commandNames(), " ")))
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    local flags words\n")
+	b.WriteString("    case \"${COMP_WORDS[1]}\" in\n")
+	for _, name := range commandNames() {
+		fmt.Fprintf(&b, "        %s)\n", name)
+		fmt.Fprintf(&b, "            flags=%s\n", singleQuoted(flagNames(commandFlags(name))))
+		if args := commandArgs(name); args != nil {
+			fmt.Fprintf(&b, "            words=%s\n", singleQuoted(strings.Join(args, " ")))
+		}
+		b.WriteString("            ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("    if [[ \"$cur\" == -* ]]; then\n")
+	b.WriteString("        COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	b.WriteString("    elif [ -n \"${words+set}\" ]; then\n")
+	b.WriteString("        COMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	b.WriteString("    else\n")
+	b.WriteString("        COMPREPLY=($(compgen -f -X '!*.blk' -- \"$cur\") $(compgen -d -- \"$cur\"))\n")
+	b.WriteString("    fi\n")
+	b.WriteString("}\n")
+	b.WriteString("complete -o filenames -F _blk blk\n")
+	return b.String()
+}
+
+func zshCompletion() string {
+	var b strings.Builder
+	b.WriteString("#compdef blk\n")
+	b.WriteString("# zsh completion for blk, load it with: source <(blk completion zsh)\n")
+	b.WriteString("_blk() {\n")
+	// words is the command line in the completion functions of zsh, the args go in args
+	b.WriteString("    local -a commands flags args\n")
+	b.WriteString("    local takes_args=0\n")
+	b.WriteString("    commands=(\n")
+	for _, name := range commandNames() {
+		fmt.Fprintf(&b, "        %s\n", singleQuoted(name+":"+shortDescription(commands[name].Description)))
+	}
+	b.WriteString("    )\n")
+	b.WriteString("    if (( CURRENT == 2 )); then\n")
+	b.WriteString("        _describe 'command' commands\n")
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    case $words[2] in\n")
+	for _, name := range commandNames() {
+		fmt.Fprintf(&b, "        %s)\n", name)
+		b.WriteString("            flags=(")
+		for _, flag := range commandFlags(name) {
+			fmt.Fprintf(&b, " %s", singleQuoted(flag.name+":"+shortDescription(flag.description)))
+		}
+		b.WriteString(" )\n")
+		if args := commandArgs(name); args != nil {
+			fmt.Fprintf(&b, "            args=(%s)\n", strings.Join(args, " "))
+			b.WriteString("            takes_args=1\n")
+		}
+		b.WriteString("            ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("    if [[ $PREFIX == -* ]]; then\n")
+	b.WriteString("        _describe 'flag' flags\n")
+	b.WriteString("    elif (( takes_args )); then\n")
+	b.WriteString("        compadd -a args\n")
+	b.WriteString("    else\n")
+	b.WriteString("        _files -g '*.blk'\n")
+	b.WriteString("    fi\n")
+	b.WriteString("}\n")
+	b.WriteString("compdef _blk blk\n")
+	return b.String()
+}
+
+func fishCompletion() string {
+	var b strings.Builder
+	b.WriteString("# fish completion for blk, load it with: blk completion fish | source\n")
+	b.WriteString("complete -c blk -f\n")
+	for _, name := range commandNames() {
+		fmt.Fprintf(&b, "complete -c blk -n __fish_use_subcommand -a %s -d %s\n", name, singleQuoted(shortDescription(commands[name].Description)))
+	}
+	for _, name := range commandNames() {
+		condition := singleQuoted("__fish_seen_subcommand_from " + name)
+		for _, flag := range commandFlags(name) {
+			option := "-l " + strings.TrimPrefix(flag.name, "--")
+			if !strings.HasPrefix(flag.name, "--") {
+				option = "-s " + strings.TrimPrefix(flag.name, "-")
+			}
+			fmt.Fprintf(&b, "complete -c blk -n %s %s -d %s\n", condition, option, singleQuoted(shortDescription(flag.description)))
+		}
+		if args := commandArgs(name); args != nil {
+			if len(args) > 0 {
+				fmt.Fprintf(&b, "complete -c blk -n %s -a %s\n", condition, singleQuoted(strings.Join(args, " ")))
+			}
+		} else {
+			fmt.Fprintf(&b, "complete -c blk -n %s -a '(__fish_complete_suffix .blk)'\n", condition)
+		}
+	}
+	return b.String()
+}
+
+func powershellCompletion() string {
+	quoted := func(text string) string { return "'" + strings.ReplaceAll(text, "'", "''") + "'" }
+	list := func(words []string) string {
+		items := make([]string, 0, len(words))
+		for _, word := range words {
+			items = append(items, quoted(word))
+		}
+		return "@(" + strings.Join(items, ", ") + ")"
+	}
+
+	var b strings.Builder
+	b.WriteString("# powershell completion for blk, load it with: blk completion powershell | Out-String | Invoke-Expression\n")
+	b.WriteString("Register-ArgumentCompleter -Native -CommandName blk -ScriptBlock {\n")
+	b.WriteString("    param($wordToComplete, $commandAst, $cursorPosition)\n")
+	b.WriteString("    $flags = @{\n")
+	for _, name := range commandNames() {
+		var names []string
+		for _, flag := range commandFlags(name) {
+			names = append(names, flag.name)
+		}
+		fmt.Fprintf(&b, "        %s = %s\n", quoted(name), list(names))
+	}
+	b.WriteString("    }\n")
+	b.WriteString("    $words = @{\n")
+	for _, name := range commandNames() {
+		if args := commandArgs(name); args != nil {
+			fmt.Fprintf(&b, "        %s = %s\n", quoted(name), list(args))
+		}
+	}
+	b.WriteString("    }\n")
+	b.WriteString("    $elements = @($commandAst.CommandElements | ForEach-Object { $_.ToString() })\n")
+	b.WriteString("    if ($elements.Count -lt 2 -or ($elements.Count -eq 2 -and $wordToComplete -ne '')) {\n")
+	b.WriteString("        $candidates = $flags.Keys | Sort-Object\n")
+	b.WriteString("    } elseif ($wordToComplete -like '-*') {\n")
+	b.WriteString("        $candidates = $flags[$elements[1]]\n")
+	b.WriteString("    } elseif ($words.ContainsKey($elements[1])) {\n")
+	b.WriteString("        $candidates = $words[$elements[1]]\n")
+	b.WriteString("    } else {\n")
+	b.WriteString("        $candidates = Get-ChildItem -Path \"$wordToComplete*\" -ErrorAction SilentlyContinue |\n")
+	b.WriteString("            Where-Object { $_.PSIsContainer -or $_.Extension -eq '.blk' } |\n")
+	b.WriteString("            ForEach-Object { Resolve-Path -Relative $_.FullName }\n")
+	b.WriteString("    }\n")
+	b.WriteString("    $candidates | Where-Object { $_ -like \"$wordToComplete*\" } | ForEach-Object {\n")
+	b.WriteString("        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)\n")
+	b.WriteString("    }\n")
+	b.WriteString("}\n")
+	return b.String()
+}
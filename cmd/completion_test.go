@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestShortDescription(t *testing.T) {
+	tests := []struct {
+		description string
+		expected    string
+	}{
+		{"program file path", "program file path"},
+		{"output file path, defaults to the program name", "output file path"},
+		{"platform of the executable (linux/amd64, linux/arm64), the host by default", "platform of the executable"},
+		{", starts with a comma", ", starts with a comma"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if actual := shortDescription(tt.description); actual != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.description, tt.expected, actual)
+		}
+	}
+}
+
+func TestSingleQuoted(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"run", `'run'`},
+		{"", `''`},
+		{"the program's grants", `'the program'\''s grants'`},
+		{"$HOME `pwd`", "'$HOME `pwd`'"},
+	}
+	for _, tt := range tests {
+		if actual := singleQuoted(tt.text); actual != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.text, tt.expected, actual)
+		}
+	}
+}
+
+func TestCommandFlags(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		// an entry naming many flags gives one flag each, the global ones come last
+		{"build", []string{"-f", "-o", "--target", "--allow-fs", "--allow-net", "--allow-run", "--color", "--messages"}},
+		{"repl", []string{"--color", "--messages"}},
+	}
+	for _, tt := range tests {
+		var names []string
+		for _, flag := range commandFlags(tt.command) {
+			names = append(names, flag.name)
+		}
+		if !slices.Equal(names, tt.expected) {
+			t.Errorf("%s: expected=%v, got=%v", tt.command, tt.expected, names)
+		}
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	tests := []struct {
+		shell  string
+		script func() string
+		// the lines completing the build command and its --target flag
+		expected []string
+	}{
+		{"bash", bashCompletion, []string{"        build)\n", "--target --allow-fs"}},
+		{"zsh", zshCompletion, []string{"'build:Bundles a single file program with the interpreter into an executable that runs it'", "'--target:platform of the executable'"}},
+		{"fish", fishCompletion, []string{"-a build -d ", "-n '__fish_seen_subcommand_from build' -l target -d 'platform of the executable'"}},
+		{"powershell", powershellCompletion, []string{"'build' = @('-f', '-o', '--target'"}},
+	}
+	for _, tt := range tests {
+		script := tt.script()
+		for _, expected := range tt.expected {
+			if !strings.Contains(script, expected) {
+				t.Errorf("%s: expected the script to contain %q", tt.shell, expected)
+			}
+		}
+		// every command is completed
+		for _, name := range commandNames() {
+			if !strings.Contains(script, name) {
+				t.Errorf("%s: expected the script to complete %s", tt.shell, name)
+			}
+		}
+	}
+}
@@ -1,14 +1,28 @@
 package cmd
 
 import (
+	"blk/ast"
+	"blk/diagnostics"
+	"blk/explain"
+	"blk/heapsnap"
+	"blk/internals"
 	"blk/interpreter"
 	"blk/lexer"
+	"blk/object"
 	"blk/parser"
+	"blk/profiler"
 	"blk/repl"
+	"blk/stdlib"
+	"blk/tracelog"
+	"blk/watch"
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type (
@@ -33,13 +47,230 @@ func init() {
 		"run": {
 			Description: "Takes the filepath of program, and executes it",
 			Function:    Run,
+			Flags: []FlagInfo{
+				{
+					Name:        "-f",
+					Description: "program file path, either a .blk source or a .blkc compiled program",
+				},
+				{
+					Name:        "--enable",
+					Description: "comma separated list of experimental features to enable (match)",
+				},
+				{
+					Name:        "--allow-fs",
+					Description: "comma separated list of paths the program can access through the stdlib, * allows every path",
+				},
+				{
+					Name:        "--allow-net",
+					Description: "comma separated list of hosts the program can reach through the stdlib, * allows every host",
+				},
+				{
+					Name:        "--allow-run",
+					Description: "comma separated list of programs the stdlib can start (ffmpeg, ffprobe), * allows every program",
+				},
+				{
+					Name:        "--plugin",
+					Description: "comma separated list of go plugins (.so) exporting native modules the program can import",
+				},
+				{
+					Name:        "--profile",
+					Description: "writes the time spent per call stack to the given file, as folded stacks for flamegraph tools (speedscope, flamegraph.pl)",
+				},
+				{
+					Name:        "--trace-log",
+					Description: "appends a json line per evaluated statement to the given file (file, line, kind, elapsed), to see what a failed run did with blk replay",
+				},
+				{
+					Name:        "--watch",
+					Description: "comma separated list of variables to watch, every value they get (step, location, value) is listed once the program stops",
+				},
+				{
+					Name:        "--explain",
+					Description: "prints each step of the evaluation as a tree (the statements, the calls, the values of the expressions, the variables that change), to learn how blk runs a small program",
+				},
+				{
+					Name:        "--heap-snapshot",
+					Description: "writes the scopes and the values left once the program stops (types, sizes, references) to the given file, as json or as graphviz dot for a .dot path",
+				},
+				{
+					Name:        "--dev",
+					Description: "prints a quick fix after the runtime errors that have an obvious one (typos, missing imports, const assignments)",
+				},
+				{
+					Name:        "--strict",
+					Description: "strict mode, like the # blk:strict pragma: no int and float mixing, no concatenation of non strings, no shadowing, annotated exported functions",
+				},
+				{
+					Name:        "--verify",
+					Description: "refuses to run the program, or any file it imports, if its sha256 doesn't match the one recorded in blk.toml",
+				},
+			},
+		},
+		"checksum": {
+			Description: "Prints the checksum entry of a program, to record under [checksums] in blk.toml for run --verify",
+			Function:    Checksum,
+			Flags: []FlagInfo{
+				{
+					Name:        "-f",
+					Description: "program file path",
+				},
+			},
+		},
+		"check": {
+			Description: "Lexes and parses blk programs without running them, prints the errors found and exits with 1 if there are any",
+			Function:    Check,
+			Flags: []FlagInfo{
+				{
+					Name:        "-f",
+					Description: "program file path, more files can follow as args",
+				},
+				{
+					Name:        "--staged",
+					Description: "checks the staged content of the .blk files staged in git, for pre-commit hooks",
+				},
+				{
+					Name:        "--all",
+					Description: "checks every .blk file under the root of the project (the directory of blk.toml, the current one otherwise), the hidden directories are skipped",
+				},
+				{
+					Name:        "--jobs",
+					Description: "how many files get checked at once, the number of cpus by default",
+				},
+				{
+					Name:        "--strict",
+					Description: "checks the files in strict mode, like the # blk:strict pragma",
+				},
+				{
+					Name:        "--json",
+					Description: "prints the diagnostics as json (file, row, col, severity, message, notes)",
+				},
+			},
+		},
+		"compile": {
+			Description: "Parses a blk program and writes it as a compiled .blkc file, that run loads without lexing or parsing",
+			Function:    Compile,
 			Flags: []FlagInfo{
 				{
 					Name:        "-f",
 					Description: "program file path",
 				},
+				{
+					Name:        "-o",
+					Description: "output file path, defaults to the program path with the .blkc extension",
+				},
+			},
+		},
+		"build": {
+			Description: "Bundles a single file program with the interpreter into an executable that runs it, for sharing tools with people that don't have blk",
+			Function:    Build,
+			Flags: []FlagInfo{
+				{
+					Name:        "-f",
+					Description: "program file path, it can only import stdlib modules",
+				},
+				{
+					Name:        "-o",
+					Description: "output file path, defaults to the program name, with .exe for windows",
+				},
+				{
+					Name:        "--target",
+					Description: "platform of the executable (linux/amd64, linux/arm64, windows/amd64, darwin/amd64, darwin/arm64), the host by default, the others compile the interpreter from the blk sources at BLK_SRC with the go toolchain",
+				},
+				{
+					Name:        "--allow-fs, --allow-net, --allow-run",
+					Description: "the grants of the program, like for run, the executable doesn't take them",
+				},
+			},
+		},
+		"astdiff": {
+			Description: "Prints the declarations added, removed and changed between two versions of a program as json (function params, struct fields and methods, enum members), takes the old then the new file as args",
+			Function:    AstDiff,
+			Flags:       []FlagInfo{},
+		},
+		"compat": {
+			Description: "Lists the changes of a module's exported declarations that break the programs importing it (removed exports, changed arity, narrowed field types, changed enum values), takes the old then the new version as args, files or directories, exits with 1 if there are any",
+			Function:    Compat,
+			Flags: []FlagInfo{
+				{
+					Name:        "--json",
+					Description: "prints the breaking changes as json (file, name, kind, row, reason)",
+				},
+			},
+		},
+		"minify": {
+			Description: "Prints an equivalent program with the comments dropped, the blanks compacted and the locals of the functions renamed, for distributing scripts",
+			Function:    Minify,
+			Flags: []FlagInfo{
+				{
+					Name:        "-f",
+					Description: "program file path, can be given as an arg",
+				},
+				{
+					Name:        "-o",
+					Description: "file to write the minified program to, printed when not given",
+				},
+			},
+		},
+		"examples": {
+			Description: "Lists the example programs that come with blk (fibonacci, word count, http fetch, shapes), examples run <name> runs one",
+			Function:    Examples,
+			Flags: []FlagInfo{
+				{
+					Name:        "--allow-fs, --allow-net, --allow-run",
+					Description: "the grants of the example, like for run, http_fetch needs --allow-net example.com --allow-fs .",
+				},
+			},
+		},
+		"replay": {
+			Description: "Steps through a run recorded with run --trace-log, with the source of each statement and the variables the run ended with",
+			Function:    Replay,
+			Flags: []FlagInfo{
+				{
+					Name:        "-f",
+					Description: "trace log path, can be given as an arg",
+				},
+				{
+					Name:        "--run",
+					Description: "the run of the log to replay when it holds many, 1 for the oldest, the last one by default",
+				},
+				{
+					Name:        "--src",
+					Description: "directory to read the sources from, the directory of the recorded program by default",
+				},
+				{
+					Name:        "--all",
+					Description: "prints every step without waiting for enter",
+				},
+			},
+		},
+		"resume": {
+			Description: "Continues a run from the last checkpoint it made with runtime.checkpoint, the globals get their saved values and the program goes on from the statement that made it",
+			Function:    Resume,
+			Flags: []FlagInfo{
+				{
+					Name:        "-f",
+					Description: "checkpoint path, can be given as an arg",
+				},
+				{
+					Name:        "--enable",
+					Description: "comma separated list of experimental features to enable, like for run",
+				},
+				{
+					Name:        "--allow-fs, --allow-net, --allow-run",
+					Description: "the grants of the program, like for run, the checkpoint doesn't keep them",
+				},
 			},
 		},
+		"completion": {
+			Description: "Prints the tab completion script of a shell (bash, zsh, fish, powershell) for the commands, their flags and the .blk files, load it from the shell profile: source <(blk completion bash)",
+			Function:    Completion,
+			Flags:       []FlagInfo{},
+		},
+		"messages": {
+			Description: "Prints the catalog of the diagnostic messages as json, the templates by code, a copy with some of them reworded or translated is loaded with --messages",
+			Function:    Messages,
+			Flags:       []FlagInfo{},
+		},
 		"help": {
 			Description: "Prints the usage of all commands",
 			Function:    Help,
@@ -50,6 +281,30 @@ func init() {
 			Function:    Repl,
 			Flags:       []FlagInfo{},
 		},
+		"version": {
+			Description: "Prints the version, build info and supported modules of blk",
+			Function:    Version,
+			Flags: []FlagInfo{
+				{
+					Name:        "--json",
+					Description: "prints the build info as json",
+				},
+			},
+		},
+		"upgrade": {
+			Description: "Replaces blk with the latest release of its channel, the download is checked against the checksum and the signature of the release",
+			Function:    Upgrade,
+			Flags: []FlagInfo{
+				{
+					Name:        "--channel",
+					Description: "release channel to follow, stable (default) or nightly",
+				},
+				{
+					Name:        "--check",
+					Description: "only tells whether a release is available",
+				},
+			},
+		},
 	}
 }
 
@@ -61,21 +316,26 @@ func Repl(args []string) {
 func Help(args []string) {
 	if len(args) < 1 {
 		// show the whole help catalog
-		printResult := "\n\033[1;35mSupported Commands:\033[0m\n\n"
+		printResult := "\n" + diagnostics.Paint("1;35", "Supported Commands:") + "\n\n"
 
 		for name, cmd := range commands {
-			printResult += fmt.Sprintf("  \033[1;36m%v\033[0m\n", name)
-			printResult += fmt.Sprintf("    \033[1;37mDescription:\033[0m \033[0;37m%v\033[0m\n", cmd.Description)
+			printResult += fmt.Sprintf("  %v\n", diagnostics.Paint("1;36", name))
+			printResult += fmt.Sprintf("    %v %v\n", diagnostics.Paint("1;37", "Description:"), diagnostics.Paint("0;37", cmd.Description))
 
 			if len(cmd.Flags) > 0 {
-				printResult += "    \033[1;37mFlags:\033[0m\n"
+				printResult += "    " + diagnostics.Paint("1;37", "Flags:") + "\n"
 				for _, flag := range cmd.Flags {
-					printResult += fmt.Sprintf("      \033[1;33m%v\033[0m - \033[0;37m%v\033[0m\n", flag.Name, flag.Description)
+					printResult += fmt.Sprintf("      %v - %v\n", diagnostics.Paint("1;33", flag.Name), diagnostics.Paint("0;37", flag.Description))
 				}
 			}
 			printResult += "\n"
 		}
 
+		printResult += diagnostics.Paint("1;35", "Global Flags:") + "\n\n"
+		for _, flag := range globalFlags {
+			printResult += fmt.Sprintf("  %v - %v\n", diagnostics.Paint("1;33", flag.Name), diagnostics.Paint("0;37", flag.Description))
+		}
+
 		fmt.Println(printResult)
 	} else if len(args) == 1 {
 		// print the help of the specified commands
@@ -89,16 +349,16 @@ func Help(args []string) {
 
 		cmd := commands[cmdName]
 
-		printResult := fmt.Sprintf("\n\033[1;35mCommand:\033[0m \033[1;36m%v\033[0m\n", cmdName)
-		printResult += fmt.Sprintf("\033[1;37mDescription:\033[0m \033[0;37m%v\033[0m\n", cmd.Description)
+		printResult := fmt.Sprintf("\n%v %v\n", diagnostics.Paint("1;35", "Command:"), diagnostics.Paint("1;36", cmdName))
+		printResult += fmt.Sprintf("%v %v\n", diagnostics.Paint("1;37", "Description:"), diagnostics.Paint("0;37", cmd.Description))
 
 		if len(cmd.Flags) > 0 {
-			printResult += fmt.Sprintln("\033[1;37mFlags:\033[0m")
+			printResult += fmt.Sprintln(diagnostics.Paint("1;37", "Flags:"))
 			for _, flag := range cmd.Flags {
-				printResult += fmt.Sprintf("  \033[1;33m%v\033[0m - \033[0;37m%v\033[0m\n", flag.Name, flag.Description)
+				printResult += fmt.Sprintf("  %v - %v\n", diagnostics.Paint("1;33", flag.Name), diagnostics.Paint("0;37", flag.Description))
 			}
 		} else {
-			printResult += "\033[0;37m(No flags available)\033[0m\n"
+			printResult += diagnostics.Paint("0;37", "(No flags available)") + "\n"
 		}
 
 		fmt.Println(printResult)
@@ -106,90 +366,420 @@ func Help(args []string) {
 }
 
 func Run(args []string) {
-	fileTarget := ""
-	if len(args) < 2 {
-		if args[0] != "-f" {
-			fmt.Println("ERROR: provide the filepath flag -f to assign the path to it")
-			return
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	fileTarget := flags.String("f", "", "program file path")
+	enable := flags.String("enable", "", "comma separated list of experimental features to enable")
+	verify := flags.Bool("verify", false, "verify the program against the checksums of blk.toml")
+	allowFS := flags.String("allow-fs", "", "paths the program can access")
+	allowNet := flags.String("allow-net", "", "hosts the program can reach")
+	allowRun := flags.String("allow-run", "", "programs the stdlib can start")
+	profile := flags.String("profile", "", "file to write the folded stacks to")
+	traceLog := flags.String("trace-log", "", "file to append the evaluated statements to")
+	heapSnapshot := flags.String("heap-snapshot", "", "file to write the scopes graph to")
+	watched := flags.String("watch", "", "variables to list the values of")
+	explained := flags.Bool("explain", false, "print the steps of the evaluation")
+	plugins := flags.String("plugin", "", "go plugins exporting native modules")
+	dev := flags.Bool("dev", false, "suggest quick fixes for runtime errors")
+	strict := flags.Bool("strict", false, "strict mode, like the # blk:strict pragma")
+	seed := flags.Int64("seed", 0, "seed of the rand module, a random one when not given")
+
+	if err := parseFlags(flags, args); err != nil {
+		return
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			stdlib.SetSeed(*seed)
 		}
+	})
 
-		if len(args[1]) <= 0 {
-			fmt.Println("ERROR: provide the filepath to the blk program")
-			return
-		}
+	// open the file target in this case
+	if len(*fileTarget) <= 0 {
+		fmt.Println("ERROR: provide the filepath flag -f to assign the path to it")
+		return
 	}
 
-	fileTarget = args[1]
+	ext := filepath.Ext(*fileTarget)
+	if ext != ".blk" && ext != ".blkc" {
+		fmt.Println("ERROR: provide a blk program to compile")
+		return
+	}
 
-	// open the file target in this case
-	if len(fileTarget) <= 0 {
-		fmt.Println("ERROR: provide a valid filepath")
+	features, err := internals.ParseFeatureList(*enable)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
 		return
 	}
 
-	if filepath.Ext(fileTarget) != ".blk" {
-		fmt.Println("ERROR: provide a blk program to compile")
+	// nothing is granted unless asked for
+	permissions, err := internals.NewPermissions(*allowFS, *allowNet, *allowRun)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
 		return
 	}
+	stdlib.Permissions = permissions
 
-	osPath, _ := os.Getwd()
-	targetFile := filepath.Join(osPath, fileTarget)
+	for _, path := range splitList(*plugins) {
+		if err := stdlib.LoadPlugin(path); err != nil {
+			fmt.Printf("ERROR: failed to load the plugin %s: %v\n", path, err)
+			return
+		}
+	}
 
-	byteContent, err := os.ReadFile(targetFile)
+	osPath, _ := os.Getwd()
+	targetFile := filepath.Join(osPath, *fileTarget)
 
+	content, err := os.ReadFile(targetFile)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	content := string(byteContent)
+	var manifest *internals.Manifest
+	if *verify {
+		manifest, err = loadManifest(targetFile)
+		if err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return
+		}
+		if err := manifest.Verify(targetFile, content); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			return
+		}
+	}
 
-	l := lexer.NewLexer(targetFile, content)
-	tokens := l.Tokenize()
+	var program *ast.Program
+	if ext == ".blkc" {
+		compiled, err := ast.DecodeProgram(bytes.NewReader(content))
+		if err != nil {
+			fmt.Printf("ERROR: %s: %v\n", filepath.Base(targetFile), err)
+			return
+		}
+		program = compiled.Program
+		// positions and relative imports point to the source next to the compiled program
+		targetFile = filepath.Join(filepath.Dir(targetFile), compiled.Source)
+		// the errors quote the source when it's around, the compiled bytes aren't text
+		content = nil
+		if source, err := os.ReadFile(targetFile); err == nil {
+			content = source
+		}
+	} else {
+		program = parseSource(targetFile, content, *strict)
+		if program == nil {
+			return
+		}
+
+		jsonData, err := json.MarshalIndent(program, " ", " ")
+		if err != nil {
+			fmt.Printf("ERROR: failed to marshal AST to JSON: %v\n", err)
+		}
+
+		err = os.WriteFile(filepath.Join(osPath, "/internal_examples/main_ast.json"), jsonData, 0644)
+		if err != nil {
+			fmt.Printf("ERROR: failed to write AST to file: %v\n", err)
+			return
+		}
+	}
+	// fmt.Println(ast)
+	// errCollector := internals.NewErrorCollector(tokens)
+
+	env := object.NewEnvironment(nil)
+	i := interpreter.NewInterpreter(env, targetFile)
+	i.EnableFeatures(features)
+	if *strict {
+		i.EnableStrict()
+	}
+	if manifest != nil {
+		i.SetVerifier(manifest.Verify)
+	}
+
+	var prof *profiler.Profiler
+	var trace *tracelog.Log
+	var watcher *watch.Watcher
+	var explainer *explain.Explainer
+	if len(*profile) > 0 {
+		prof = profiler.New(filepath.Base(targetFile))
+	}
+	if len(*traceLog) > 0 {
+		trace, err = tracelog.Open(*traceLog)
+		if err != nil {
+			fmt.Printf("ERROR: failed to open the trace log: %v\n", err)
+			return
+		}
+		trace.Begin(targetFile, stdlib.Seed())
+		// deferred so the buffered lines get written even if the interpreter panics
+		defer func() {
+			trace.Snapshot(env)
+			if err := trace.Close(); err != nil {
+				fmt.Printf("ERROR: failed to write the trace log: %v\n", err)
+			}
+		}()
+	}
+	if names := splitList(*watched); len(names) > 0 {
+		watcher = watch.New(names)
+	}
+	if *explained {
+		explainer = explain.New(os.Stdout, explain.MaxSteps)
+	}
+	if prof != nil || trace != nil || watcher != nil || explainer != nil {
+		var hooks []*interpreter.Hooks
+		if prof != nil {
+			hooks = append(hooks, prof.Hooks())
+		}
+		if trace != nil {
+			hooks = append(hooks, trace.Hooks())
+		}
+		if watcher != nil {
+			hooks = append(hooks, watcher.Hooks())
+		}
+		if explainer != nil {
+			hooks = append(hooks, explainer.Hooks())
+		}
+		i.SetHooks(interpreter.MergeHooks(hooks...))
+	}
+
+	evaluated := i.Eval(program)
+
+	if len(*heapSnapshot) > 0 {
+		if err := heapsnap.Snapshot(env).WriteFile(*heapSnapshot); err != nil {
+			fmt.Printf("ERROR: failed to write the heap snapshot: %v\n", err)
+		}
+	}
+
+	if prof != nil {
+		prof.Stop()
+		if err := writeProfile(*profile, prof); err != nil {
+			fmt.Printf("ERROR: failed to write the profile: %v\n", err)
+		}
+	}
+
+	renderer := diagnostics.NewRenderer()
+	if content != nil {
+		renderer.AddSource(filepath.Base(targetFile), string(content))
+	}
+
+	for _, warning := range i.Warnings {
+		fmt.Fprintln(os.Stderr, renderError(renderer, warning))
+	}
+
+	if err, ok := evaluated.(*object.Error); ok && err.Row > 0 {
+		d := err.Diagnostic()
+		if *dev {
+			// the quick fix gets printed as a diff instead
+			d.Notes = nil
+		}
+		fmt.Println(renderer.Render(d))
+	} else if evaluated != nil {
+		fmt.Println(evaluated.Inspect())
+	}
+
+	if err, ok := evaluated.(*object.Error); ok && *dev && err.Fix != nil {
+		printQuickFix(err, targetFile)
+	}
+
+	if watcher != nil {
+		watcher.WriteHistory(os.Stdout)
+	}
+}
+
+// the items of a comma separated list, without the blanks around them
+func splitList(list string) []string {
+	var items []string
+	for item := range strings.SplitSeq(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// shows the fix of an error as a diff of the lines it changes
+func printQuickFix(err *object.Error, targetFile string) {
+	fmt.Printf("quick fix: %s\n", err.Fix.Title)
+
+	// the fixes of imported files are only described
+	if !err.Fix.Applicable() || err.File != filepath.Base(targetFile) {
+		return
+	}
+
+	content, readErr := os.ReadFile(targetFile)
+	if readErr != nil {
+		return
+	}
+	fixed, ok := err.Fix.Apply(string(content))
+	if !ok {
+		return
+	}
+
+	row := err.Fix.Row
+	before := strings.Split(string(content), "\n")[row-1]
+	added := strings.Count(err.Fix.Text, "\n")
+	after := strings.Split(fixed, "\n")[row-1 : row+added]
+
+	fmt.Printf("%4d - %s\n", row, before)
+	for idx, line := range after {
+		fmt.Printf("%4d + %s\n", row+idx, line)
+	}
+}
 
-	// fmt.Println(tokens)
+// lexes and parses a program, prints the errors and returns nil if any
+func parseSource(targetFile string, content []byte, strict bool) *ast.Program {
+	l := lexer.NewLexer(targetFile, string(content))
+	tokens := l.Tokenize()
 
-	filename, _ := os.Stat(targetFile)
-	p := parser.NewParser(tokens, filename.Name())
-	ast := p.Parse()
+	p := parser.NewParser(tokens, filepath.Base(targetFile))
+	p.Dir = filepath.Dir(targetFile)
+	p.Strict = strict
+	program := p.Parse()
 
 	if len(p.Errors) > 0 {
+		renderer := diagnostics.NewRenderer()
+		renderer.AddSource(filepath.Base(targetFile), string(content))
 		for _, err := range p.Errors {
-			fmt.Println(err)
+			fmt.Println(renderError(renderer, err))
+		}
+		if summary := p.ErrorSummary(); len(summary) > 0 {
+			fmt.Println(summary)
 		}
+		return nil
+	}
+
+	return program
+}
+
+// the error with an excerpt of the source when it's a diagnostic, its message otherwise
+func renderError(renderer *diagnostics.Renderer, err error) string {
+	if d, ok := err.(*diagnostics.Diagnostic); ok {
+		return renderer.Render(d)
+	}
+	return err.Error()
+}
+
+// loads the nearest blk.toml, starting from the directory of the program
+func loadManifest(targetFile string) (*internals.Manifest, error) {
+	path, err := internals.FindManifest(filepath.Dir(targetFile))
+	if err != nil {
+		return nil, err
+	}
+	return internals.LoadManifest(path)
+}
+
+func writeProfile(path string, prof *profiler.Profiler) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := prof.WriteFolded(writer); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func Checksum(args []string) {
+	flags := flag.NewFlagSet("checksum", flag.ContinueOnError)
+	fileTarget := flags.String("f", "", "program file path")
+
+	if err := parseFlags(flags, args); err != nil {
+		return
+	}
+
+	if len(*fileTarget) <= 0 {
+		fmt.Println("ERROR: provide the filepath flag -f to assign the path to it")
 		return
 	}
 
-	jsonData, err := json.MarshalIndent(ast, " ", " ")
+	content, err := os.ReadFile(*fileTarget)
 	if err != nil {
-		fmt.Printf("ERROR: failed to marshal AST to JSON: %v\n", err)
+		fmt.Println(err)
+		return
 	}
 
-	err = os.WriteFile(filepath.Join(osPath, "/internal_examples/main_ast.json"), jsonData, 0644)
+	// keyed relative to blk.toml when there is one, the path as given otherwise
+	key := filepath.ToSlash(*fileTarget)
+	if manifest, err := loadManifest(*fileTarget); err == nil {
+		if rel, err := manifest.Key(*fileTarget); err == nil {
+			key = rel
+		}
+	}
+
+	fmt.Printf("%q = %q\n", key, internals.Checksum(content))
+}
+
+func Compile(args []string) {
+	flags := flag.NewFlagSet("compile", flag.ContinueOnError)
+	fileTarget := flags.String("f", "", "program file path")
+	output := flags.String("o", "", "output file path")
+
+	if err := parseFlags(flags, args); err != nil {
+		return
+	}
+
+	if len(*fileTarget) <= 0 {
+		fmt.Println("ERROR: provide the filepath flag -f to assign the path to it")
+		return
+	}
+
+	if filepath.Ext(*fileTarget) != ".blk" {
+		fmt.Println("ERROR: provide a blk program to compile")
+		return
+	}
+
+	if len(*output) <= 0 {
+		*output = strings.TrimSuffix(*fileTarget, ".blk") + ".blkc"
+	}
+
+	content, err := os.ReadFile(*fileTarget)
 	if err != nil {
-		fmt.Printf("ERROR: failed to write AST to file: %v\n", err)
+		fmt.Println(err)
 		return
 	}
-	// fmt.Println(ast)
-	// errCollector := internals.NewErrorCollector(tokens)
 
-	i := interpreter.NewInterpreter(nil, targetFile)
-	evaluated := i.Eval(ast)
+	program := parseSource(*fileTarget, content, false)
+	if program == nil {
+		return
+	}
 
-	if evaluated != nil {
-		fmt.Println(evaluated.Inspect())
+	file, err := os.Create(*output)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return
 	}
+	defer file.Close()
 
+	writer := bufio.NewWriter(file)
+	compiled := &ast.CompiledProgram{Source: filepath.Base(*fileTarget), Program: program}
+	if err := ast.EncodeProgram(writer, compiled); err != nil {
+		fmt.Printf("ERROR: failed to write the compiled program: %v\n", err)
+		return
+	}
+	if err := writer.Flush(); err != nil {
+		fmt.Printf("ERROR: failed to write the compiled program: %v\n", err)
+	}
 }
 
 func Execute() {
+	// an executable made by blk build runs its program instead
+	if b, err := loadBundle(); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(2)
+	} else if b != nil {
+		runBundle(b)
+		return
+	}
+
 	if len(os.Args) < 2 {
 		fmt.Println("ERROR: at least provide command name to kick off the cli")
 		return
 	}
 
 	name := os.Args[1]
-	args := os.Args[2:]
+	args, err := applyGlobalOptions(os.Args[2:])
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return
+	}
 
 	if _, ok := commands[name]; !ok {
 		fmt.Printf("ERROR: unknown command %v, check help for manual.\n", name)
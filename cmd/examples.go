@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"blk/diagnostics"
+	"blk/examples"
+	"blk/internals"
+	"blk/interpreter"
+	"blk/object"
+	"blk/stdlib"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func Examples(args []string) {
+	if len(args) == 0 || args[0] == "list" {
+		for _, name := range examples.Names() {
+			fmt.Printf("  %s %s\n", diagnostics.Paint("1;36", fmt.Sprintf("%-12s", name)), examples.Description(name))
+		}
+		fmt.Println("\nrun one with: blk examples run <name>")
+		return
+	}
+	if args[0] != "run" {
+		fmt.Printf("ERROR: unknown examples command %s, expected list or run\n", args[0])
+		return
+	}
+
+	flags := flag.NewFlagSet("examples", flag.ContinueOnError)
+	allowFS := flags.String("allow-fs", "", "paths the program can access")
+	allowNet := flags.String("allow-net", "", "hosts the program can reach")
+	allowRun := flags.String("allow-run", "", "programs the stdlib can start")
+
+	if err := parseFlags(flags, argFirst(args[1:])); err != nil {
+		return
+	}
+	if flags.NArg() != 1 {
+		fmt.Println("ERROR: provide the name of the example to run, blk examples lists them")
+		return
+	}
+
+	name := flags.Arg(0)
+	source, ok := examples.Source(name)
+	if !ok {
+		fmt.Printf("ERROR: unknown example %s, pick one of %s\n", name, strings.Join(examples.Names(), ", "))
+		return
+	}
+
+	permissions, err := internals.NewPermissions(*allowFS, *allowNet, *allowRun)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return
+	}
+	stdlib.Permissions = permissions
+
+	file := name + ".blk"
+	program := parseSource(file, source, false)
+	if program == nil {
+		os.Exit(1)
+	}
+
+	i := interpreter.NewInterpreter(object.NewEnvironment(nil), file)
+	evaluated := i.Eval(program)
+
+	renderer := diagnostics.NewRenderer()
+	renderer.AddSource(file, string(source))
+	for _, warning := range i.Warnings {
+		fmt.Fprintln(os.Stderr, renderError(renderer, warning))
+	}
+	if err, ok := evaluated.(*object.Error); ok {
+		if err.Row > 0 {
+			fmt.Println(renderer.Render(err.Diagnostic()))
+		} else {
+			fmt.Println(err.Inspect())
+		}
+		os.Exit(1)
+	}
+}
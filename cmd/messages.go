@@ -0,0 +1,11 @@
+package cmd
+
+import (
+	"blk/diagnostics"
+	"fmt"
+)
+
+// prints the default catalog, the start of a catalog for --messages
+func Messages(args []string) {
+	fmt.Println(string(diagnostics.DefaultCatalog()))
+}
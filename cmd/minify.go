@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"blk/minify"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func Minify(args []string) {
+	flags := flag.NewFlagSet("minify", flag.ContinueOnError)
+	fileTarget := flags.String("f", "", "program file path")
+	output := flags.String("o", "", "output file path")
+
+	if err := parseFlags(flags, args); err != nil {
+		return
+	}
+
+	path := *fileTarget
+	if len(path) == 0 && flags.NArg() > 0 {
+		path = flags.Arg(0)
+	}
+	if len(path) == 0 {
+		fmt.Println("ERROR: provide the program to minify, with -f or as an arg")
+		return
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return
+	}
+
+	minified, err := minify.Minify(path, string(content))
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(*output) == 0 {
+		fmt.Print(minified)
+		return
+	}
+	if err := os.WriteFile(*output, []byte(minified), 0644); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s: %d bytes, %d before\n", *output, len(minified), len(content))
+}
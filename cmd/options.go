@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"blk/diagnostics"
+	"blk/internals"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// flags understood by every command
+var globalFlags = []FlagInfo{
+	{
+		Name:        "--color",
+		Description: "colors of the diagnostics: auto (the default, off when NO_COLOR is set or the output isn't a terminal), always or never",
+	},
+	{
+		Name:        "--messages",
+		Description: "json catalog of the diagnostic messages by code, to reword or translate them, blk messages prints the default one",
+	},
+}
+
+// how the global flags get applied, and the values they take for the errors
+var globalSetters = map[string]struct {
+	apply  func(value string) error
+	values string
+}{
+	"color":    {diagnostics.SetColorMode, "auto, always, never"},
+	"messages": {diagnostics.LoadCatalog, "the path of a json catalog"},
+}
+
+// the BLK_OPTIONS flags left once the global ones are applied, merged by parseFlags
+var envOptions []string
+
+// reads BLK_OPTIONS and applies the global flags, of BLK_OPTIONS then of the
+// command line, returns the command line args without them
+func applyGlobalOptions(args []string) ([]string, error) {
+	if err := internals.SetDebug(os.Getenv(internals.DebugEnv)); err != nil {
+		return nil, fmt.Errorf("%s: %v", internals.DebugEnv, err)
+	}
+
+	options, err := internals.SplitOptions(os.Getenv(internals.OptionsEnv))
+	if err != nil {
+		return nil, err
+	}
+
+	envOptions, err = extractGlobalFlags(options)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", internals.OptionsEnv, err)
+	}
+
+	return extractGlobalFlags(args)
+}
+
+func extractGlobalFlags(args []string) ([]string, error) {
+	rest := make([]string, 0, len(args))
+
+	for idx := 0; idx < len(args); idx++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[idx], "-"), "=")
+		setter, ok := globalSetters[name]
+		if !strings.HasPrefix(args[idx], "-") || !ok {
+			rest = append(rest, args[idx])
+			continue
+		}
+
+		if !hasValue {
+			if idx+1 == len(args) {
+				return nil, fmt.Errorf("flag --%s needs a value (%s)", name, setter.values)
+			}
+			idx++
+			value = args[idx]
+		}
+		if err := setter.apply(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return rest, nil
+}
+
+// parses the args of a command, on top of the defaults BLK_OPTIONS holds for it
+// BLK_OPTIONS is shared by every command, so the flags the command doesn't define are skipped
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	defaults := make([]string, 0, len(envOptions))
+
+	for idx := 0; idx < len(envOptions); idx++ {
+		option := envOptions[idx]
+		name, _, hasValue := strings.Cut(strings.TrimLeft(option, "-"), "=")
+
+		if !strings.HasPrefix(option, "-") {
+			// the value of a flag another command defines
+			continue
+		}
+		defined := flags.Lookup(name)
+		if defined == nil {
+			if !isKnownFlag(name) {
+				fmt.Fprintf(os.Stderr, "WARNING: %s: unknown option %s\n", internals.OptionsEnv, option)
+			}
+			continue
+		}
+
+		defaults = append(defaults, option)
+		// --name value form, bool flags never take a separate value
+		if boolFlag, ok := defined.Value.(interface{ IsBoolFlag() bool }); !hasValue && !(ok && boolFlag.IsBoolFlag()) && idx+1 < len(envOptions) {
+			idx++
+			defaults = append(defaults, envOptions[idx])
+		}
+	}
+
+	// flag sets keep the last value of a flag, so the command line wins
+	return flags.Parse(append(defaults, args...))
+}
+
+// whether any command defines the flag, BLK_OPTIONS can hold the flags of other commands
+func isKnownFlag(name string) bool {
+	for _, cmd := range commands {
+		for _, info := range cmd.Flags {
+			if strings.TrimLeft(info.Name, "-") == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// moves the arg the command starts with after its flags, the flag package stops at the first
+// arg otherwise: blk resume job.ckpt --allow-fs .
+func argFirst(args []string) []string {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return args
+	}
+	return append(slices.Clone(args[1:]), args[0])
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"blk/diagnostics"
+	"blk/tracelog"
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+func Replay(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fileTarget := flags.String("f", "", "trace log path")
+	runIdx := flags.Int("run", 0, "the run of the log to replay, 1 for the first one, the last one by default")
+	srcDir := flags.String("src", "", "directory of the sources, the one of the program by default")
+	all := flags.Bool("all", false, "print every step without waiting")
+
+	if err := parseFlags(flags, args); err != nil {
+		return
+	}
+
+	path := *fileTarget
+	if len(path) == 0 && flags.NArg() > 0 {
+		path = flags.Arg(0)
+	}
+	if len(path) == 0 {
+		fmt.Println("ERROR: provide the trace log to replay, with -f or as an arg")
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return
+	}
+	runs, err := tracelog.Read(file)
+	file.Close()
+	if err != nil {
+		fmt.Printf("ERROR: %s: %v\n", path, err)
+		return
+	}
+	if len(runs) == 0 {
+		fmt.Printf("ERROR: %s has no runs\n", path)
+		return
+	}
+	if *runIdx < 0 || *runIdx > len(runs) {
+		fmt.Printf("ERROR: %s has %d runs, got=%d\n", path, len(runs), *runIdx)
+		return
+	}
+	run := runs[len(runs)-1]
+	if *runIdx > 0 {
+		run = runs[*runIdx-1]
+	}
+
+	dir := *srcDir
+	if len(dir) == 0 && len(run.Program) > 0 {
+		dir = filepath.Dir(run.Program)
+	}
+	if len(run.Program) > 0 {
+		when := run.Time
+		if run.Seed != nil {
+			when += fmt.Sprintf(" --seed=%d", *run.Seed)
+		}
+		fmt.Println(diagnostics.Paint("1;35", "run of "+run.Program) + " " + diagnostics.Paint("0;37", when))
+	}
+
+	renderer := diagnostics.NewRenderer()
+	renderer.Context = 2
+	loaded := map[string]bool{}
+
+	// steps one statement at a time when a person is reading, piped output gets all of them
+	info, err := os.Stdin.Stat()
+	interactive := !*all && err == nil && info.Mode()&os.ModeCharDevice != 0
+	input := bufio.NewReader(os.Stdin)
+
+	for idx, step := range run.Steps {
+		// the log only has the base names, they're looked up in the program directory
+		if !loaded[step.File] {
+			loaded[step.File] = true
+			if content, err := os.ReadFile(filepath.Join(dir, step.File)); err == nil {
+				renderer.AddSource(step.File, string(content))
+			}
+		}
+
+		d := diagnostics.New(diagnostics.Note, diagnostics.Span{File: step.File, Row: step.Line, Col: step.Col}, "%s", step.Kind)
+		if len(step.Error) > 0 {
+			d = diagnostics.New(diagnostics.Error, d.Primary, "%s: %s", step.Kind, strings.TrimPrefix(step.Error, "ERROR: "))
+		}
+		start := time.Duration(step.StartUs) * time.Microsecond
+		elapsed := time.Duration(step.ElapsedUs) * time.Microsecond
+		fmt.Printf("%s %s\n", diagnostics.Paint("1;36", fmt.Sprintf("step %d/%d", idx+1, len(run.Steps))),
+			diagnostics.Paint("0;37", fmt.Sprintf("at %v, took %v", start, elapsed)))
+		fmt.Println(renderer.Render(d))
+
+		if interactive && idx < len(run.Steps)-1 {
+			fmt.Print(diagnostics.Paint("1;90", "[enter] next, c continue, q quit: "))
+			answer, err := input.ReadString('\n')
+			switch strings.TrimSpace(answer) {
+			case "c":
+				interactive = false
+			case "q":
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+		fmt.Println()
+	}
+
+	if run.Vars == nil {
+		fmt.Println(diagnostics.Paint("1;33", "the run stopped before its variables got recorded"))
+		return
+	}
+	fmt.Println(diagnostics.Paint("1;35", "variables:"))
+	names := make([]string, 0, len(run.Vars))
+	for name := range run.Vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Printf("  %s = %s\n", diagnostics.Paint("1;37", name), run.Vars[name])
+	}
+}
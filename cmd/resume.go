@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"blk/checkpoint"
+	"blk/diagnostics"
+	"blk/internals"
+	"blk/interpreter"
+	"blk/object"
+	"blk/stdlib"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func Resume(args []string) {
+	flags := flag.NewFlagSet("resume", flag.ContinueOnError)
+	fileTarget := flags.String("f", "", "checkpoint path")
+	enable := flags.String("enable", "", "comma separated list of experimental features to enable")
+	allowFS := flags.String("allow-fs", "", "paths the program can access")
+	allowNet := flags.String("allow-net", "", "hosts the program can reach")
+	allowRun := flags.String("allow-run", "", "programs the stdlib can start")
+
+	if err := parseFlags(flags, argFirst(args)); err != nil {
+		return
+	}
+
+	path := *fileTarget
+	if len(path) == 0 && flags.NArg() > 0 {
+		path = flags.Arg(0)
+	}
+	if len(path) == 0 {
+		fmt.Println("ERROR: provide the checkpoint to resume from, with -f or as an arg")
+		return
+	}
+
+	cp, err := checkpoint.ReadFile(path)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return
+	}
+
+	features, err := internals.ParseFeatureList(*enable)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return
+	}
+	// the grants aren't part of the checkpoint, they are given again
+	permissions, err := internals.NewPermissions(*allowFS, *allowNet, *allowRun)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return
+	}
+	stdlib.Permissions = permissions
+
+	content, err := os.ReadFile(cp.Program)
+	if err != nil {
+		fmt.Printf("ERROR: the program of the checkpoint: %v\n", err)
+		return
+	}
+	// the checkpoint points to a statement, the statements of another version wouldn't line up
+	if internals.Checksum(content) != cp.Checksum {
+		fmt.Printf("ERROR: %s changed since the checkpoint, run it again from the start\n", filepath.Base(cp.Program))
+		os.Exit(1)
+	}
+	program := parseSource(cp.Program, content, false)
+	if program == nil {
+		os.Exit(1)
+	}
+
+	env := object.NewEnvironment(nil)
+	i := interpreter.NewInterpreter(env, cp.Program)
+	i.EnableFeatures(features)
+	evaluated := i.Resume(program, cp)
+
+	renderer := diagnostics.NewRenderer()
+	renderer.AddSource(filepath.Base(cp.Program), string(content))
+	for _, warning := range i.Warnings {
+		fmt.Fprintln(os.Stderr, renderError(renderer, warning))
+	}
+
+	if err, ok := evaluated.(*object.Error); ok {
+		if err.Row > 0 {
+			fmt.Println(renderer.Render(err.Diagnostic()))
+		} else {
+			fmt.Println(err.Inspect())
+		}
+		os.Exit(1)
+	} else if evaluated != nil {
+		fmt.Println(evaluated.Inspect())
+	}
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"blk/internals"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+func Upgrade(args []string) {
+	if err := upgrade(args); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func upgrade(args []string) error {
+	flags := flag.NewFlagSet("upgrade", flag.ContinueOnError)
+	channel := flags.String("channel", "stable", "release channel, stable or nightly")
+	check := flags.Bool("check", false, "only tell whether a release is available")
+
+	if err := parseFlags(flags, args); err != nil {
+		return nil
+	}
+
+	baseURL := internals.ReleaseURL
+	if url := os.Getenv("BLK_RELEASE_URL"); len(url) > 0 {
+		baseURL = url
+	}
+
+	release, err := internals.FetchRelease(baseURL, *channel)
+	if err != nil {
+		return err
+	}
+	// only a newer release gets installed, an older index served again would downgrade blk to a
+	// signed release with the bugs fixed since, going back from nightly to stable waits for a
+	// stable release newer than the nightly one
+	order, err := internals.CompareVersions(release.Version, internals.Version)
+	if err != nil {
+		return fmt.Errorf("the %s release index has an %v", *channel, err)
+	}
+	if order == 0 {
+		fmt.Printf("blk %s is up to date on the %s channel\n", internals.Version, *channel)
+		return nil
+	}
+	if order < 0 {
+		return fmt.Errorf("refusing to install blk %s from the %s channel, it's older than this blk %s", release.Version, *channel, internals.Version)
+	}
+	if *check {
+		fmt.Printf("blk %s is available on the %s channel, this is blk %s\n", release.Version, *channel, internals.Version)
+		return nil
+	}
+
+	if len(internals.ReleaseKey) == 0 {
+		return fmt.Errorf("this build of blk has no release key to verify the downloads with, install the release by hand")
+	}
+	asset, err := release.Asset(runtime.GOOS + "/" + runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	binary, err := asset.Download()
+	if err != nil {
+		return fmt.Errorf("failed to download blk %s: %v", release.Version, err)
+	}
+	if err := asset.Verify(binary, internals.ReleaseKey); err != nil {
+		return fmt.Errorf("refusing to install blk %s, %v", release.Version, err)
+	}
+
+	if err := replaceExecutable(binary); err != nil {
+		return fmt.Errorf("failed to install blk %s: %v", release.Version, err)
+	}
+	fmt.Printf("upgraded blk %s to %s\n", internals.Version, release.Version)
+	return nil
+}
+
+// writes the binary next to the running executable then renames it over it, so a failure
+// halfway leaves the old one in place
+func replaceExecutable(binary []byte) error {
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".blk-upgrade-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+
+	// windows doesn't replace a running executable, it only renames it
+	if runtime.GOOS == "windows" {
+		old := path + ".old"
+		os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
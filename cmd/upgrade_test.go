@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"blk/internals"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"0.1.0", "0.1.0", 0},
+		{"0.2.0", "0.1.0", 1},
+		{"0.1.0", "0.1.1", -1},
+		{"1.0.0", "0.9.9", 1},
+		{"0.10.0", "0.9.0", 1},
+		{"v0.2.0", "0.2.0", 0},
+		{"0.2.0+linux", "0.2.0", 0},
+		// the pre releases come before their release
+		{"0.2.0-nightly.3", "0.2.0", -1},
+		{"0.2.0-nightly.3", "0.1.0", 1},
+		{"0.2.0-nightly.10", "0.2.0-nightly.9", 1},
+		{"0.2.0-nightly", "0.2.0-nightly.1", -1},
+		{"0.2.0-1", "0.2.0-alpha", -1},
+	}
+	for _, tt := range tests {
+		actual, err := internals.CompareVersions(tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("%s, %s: %v", tt.a, tt.b, err)
+		}
+		if actual != tt.expected {
+			t.Errorf("%s, %s: expected=%d, got=%d", tt.a, tt.b, tt.expected, actual)
+		}
+	}
+
+	for _, version := range []string{"0.2", "0.2.x", "0.2.0-", "0.2.0-a..b", "-1.0.0"} {
+		if _, err := internals.CompareVersions(version, internals.Version); err == nil {
+			t.Errorf("%s: expected an invalid version", version)
+		}
+	}
+}
+
+func TestUpgradeRefusesOlderReleases(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected string
+	}{
+		{"0.0.9", "refusing to install blk 0.0.9 from the stable channel, it's older than this blk " + internals.Version},
+		{internals.Version + "-nightly.1", "refusing to install blk " + internals.Version + "-nightly.1 from the stable channel"},
+		{"latest", "the stable release index has an invalid version latest"},
+		// up to date, nothing to install
+		{internals.Version, ""},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the version is refused before the download and the signature check
+			fmt.Fprintf(w, `{"version": %q, "assets": {"linux/amd64": {"url": "http://%s/blk", "checksum": "sha256:00", "signature": "00"}}}`, tt.version, r.Host)
+		}))
+		t.Setenv("BLK_RELEASE_URL", server.URL)
+
+		err := upgrade(nil)
+		server.Close()
+		if len(tt.expected) == 0 {
+			if err != nil {
+				t.Errorf("%s: expected no error, got=%v", tt.version, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected=%q, got=%v", tt.version, tt.expected, err)
+		}
+	}
+}
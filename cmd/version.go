@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"blk/internals"
+	"blk/stdlib"
+	"encoding/json"
+	"fmt"
+	"runtime"
+)
+
+type BuildInfo struct {
+	Version   string          `json:"version"`
+	Language  string          `json:"language"`
+	Commit    string          `json:"commit"`
+	GoVersion string          `json:"go_version"`
+	Platform  string          `json:"platform"`
+	Features  map[string]bool `json:"features"`
+	Modules   []string        `json:"modules"`
+}
+
+func buildInfo() BuildInfo {
+	features := make(map[string]bool, len(internals.ExperimentalFeatures))
+	for name := range internals.ExperimentalFeatures {
+		// experimental features are all disabled by default
+		features[name] = false
+	}
+
+	modules := stdlib.ModuleNames()
+
+	return BuildInfo{
+		Version:   internals.Version,
+		Language:  internals.LanguageVersion,
+		Commit:    internals.BuildCommit(),
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Features:  features,
+		Modules:   modules,
+	}
+}
+
+func Version(args []string) {
+	info := buildInfo()
+
+	if len(args) > 0 && args[0] == "--json" {
+		jsonData, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			fmt.Printf("ERROR: failed to marshal build info to JSON: %v\n", err)
+			return
+		}
+		fmt.Println(string(jsonData))
+		return
+	}
+
+	if len(args) > 0 {
+		fmt.Printf("ERROR: unknown flag %v, check help for manual.\n", args[0])
+		return
+	}
+
+	fmt.Printf("blk %s (commit %s, %s %s)\n", info.Version, info.Commit, info.GoVersion, info.Platform)
+	fmt.Printf("language: %s\n", info.Language)
+	fmt.Printf("modules: %v\n", info.Modules)
+}
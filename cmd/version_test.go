@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"blk/internals"
+	"encoding/json"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestBuildInfo(t *testing.T) {
+	info := buildInfo()
+
+	tests := []struct {
+		field    string
+		actual   string
+		expected string
+	}{
+		{"version", info.Version, internals.Version},
+		{"language", info.Language, internals.LanguageVersion},
+		{"go_version", info.GoVersion, runtime.Version()},
+		{"platform", info.Platform, runtime.GOOS + "/" + runtime.GOARCH},
+	}
+	for _, tt := range tests {
+		if tt.actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.field, tt.expected, tt.actual)
+		}
+	}
+	if len(info.Commit) == 0 {
+		t.Errorf("expected a commit, unknown when there is none")
+	}
+
+	// the modules are sorted so the output stays the same from one run to the next
+	if !slices.IsSorted(info.Modules) || !slices.Contains(info.Modules, "fmt") {
+		t.Errorf("expected the sorted builtin modules, got=%v", info.Modules)
+	}
+	for name := range internals.ExperimentalFeatures {
+		if enabled, ok := info.Features[name]; !ok || enabled {
+			t.Errorf("expected the feature %s to be listed as disabled", name)
+		}
+	}
+
+	// the names of blk version --json
+	content, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(content, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"version", "language", "commit", "go_version", "platform", "features", "modules"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("expected the json to have %s", name)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// the code of a message, the catalog holds the template it's written with, so a team can
+// reword the messages (a translation, simpler words for a class) without changing blk
+type Code string
+
+// the parser codes start at E0001, the interpreter ones at E0101
+const (
+	ExpectedToken    Code = "E0001"
+	DuplicateImport  Code = "E0002"
+	ImportNameTaken  Code = "E0003"
+	DuplicateMapKey  Code = "E0004"
+	IfWithoutElse    Code = "E0005"
+	IfBranchTypes    Code = "E0006"
+	ArrayElemTypes   Code = "E0007"
+	ConcatTypes      Code = "E0008"
+	NotFound         Code = "E0101"
+	ConstAssign      Code = "E0102"
+	WrongArgCount    Code = "E0103"
+	NotAFunction     Code = "E0104"
+	IndexOutOfBound  Code = "E0105"
+	MissingKey       Code = "E0106"
+	UnsupportedTypes Code = "E0107"
+	UnknownOperator  Code = "E0108"
+	AssignMismatch   Code = "E0109"
+	ConditionType    Code = "E0110"
+	ModuleNotFound   Code = "E0111"
+	CircularImport   Code = "E0112"
+	FunctionNotConst Code = "E0113"
+	UnwrapErr        Code = "E0114"
+)
+
+type message struct {
+	template string
+	// values like the ones the message gets written with, the template of a catalog has to
+	// take the same ones
+	sample []any
+}
+
+var defaultMessages = map[Code]message{
+	ExpectedToken:    {"expected one of (%v), received %v", []any{"IDENTIFIER", "INTEGER"}},
+	DuplicateImport:  {"duplicate import of %s, first imported at %d:%d", []any{`"math"`, 1, 8}},
+	ImportNameTaken:  {"%s is already bound to the import of %s at %d:%d", []any{"m", `"math"`, 1, 8}},
+	DuplicateMapKey:  {"duplicate key %s in map literal, first defined at %d:%d", []any{`"a"`, 1, 3}},
+	IfWithoutElse:    {"if expression used as a value requires an else branch", nil},
+	IfBranchTypes:    {"branches of the if expression evaluate to different types (%s, %s)", []any{"int", "string"}},
+	ArrayElemTypes:   {"array elements need to be of one type, got (%s, %s)", []any{"int", "string"}},
+	ConcatTypes:      {"can't concatenate %s with %s", []any{"[]int", "[]string"}},
+	NotFound:         {"identifier not found: %s", []any{"x"}},
+	ConstAssign:      {"%v can't be mutated, since it was defined as const", []any{"x"}},
+	WrongArgCount:    {"wrong number of arguments to %s%s. got=%s, want=%d", []any{"add(a, b)", "", "1", 2}},
+	NotAFunction:     {"not a function: %s", []any{"INTEGER"}},
+	IndexOutOfBound:  {"index out of bound, %d", []any{3}},
+	MissingKey:       {"index (%v) is not associated with any value", []any{`"a"`}},
+	UnsupportedTypes: {"binary operations not supported on types: %s %s %s", []any{"INTEGER", "+", "ARRAY"}},
+	UnknownOperator:  {"unknown operator: %s%s", []any{"-", "STRING"}},
+	AssignMismatch:   {"type mismatch: can't assign %s to %s", []any{"STRING", "INTEGER"}},
+	ConditionType:    {"evaluation of the condition needs to return a boolean not %s", []any{"1"}},
+	ModuleNotFound:   {"Module Not found %s", []any{`"mth"`}},
+	CircularImport:   {"circular dependency detected in module: %s, issue on %s import", []any{"a.blk", "b.blk"}},
+	FunctionNotConst: {"functions are required to be declared as consts", nil},
+	UnwrapErr:        {"unwrap of an err result: %s", []any{"not found"}},
+}
+
+// the templates in use, the defaults with the ones of the loaded catalog on top
+var templates = defaultTemplates()
+
+func defaultTemplates() map[Code]string {
+	defaults := make(map[Code]string, len(defaultMessages))
+	for code, msg := range defaultMessages {
+		defaults[code] = msg.template
+	}
+	return defaults
+}
+
+// the message of the code, written with its template
+func Message(code Code, a ...any) string {
+	template, ok := templates[code]
+	if !ok {
+		return fmt.Sprintf("unknown message %s", code)
+	}
+	return fmt.Sprintf(template, a...)
+}
+
+// a diagnostic written with the template of the code
+func Coded(severity Severity, span Span, code Code, a ...any) *Diagnostic {
+	d := New(severity, span, "%s", Message(code, a...))
+	d.Code = code
+	return d
+}
+
+// reads a catalog, a json object of templates by code, the codes it doesn't hold keep their
+// default template: {"E0101": "%s isn't declared"}
+// the templates take the values of the defaults in the same order, %[2]s picks another one
+func LoadCatalog(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var catalog map[Code]string
+	if err := json.Unmarshal(content, &catalog); err != nil {
+		return fmt.Errorf("%s isn't a catalog of messages: %v", path, err)
+	}
+
+	loaded := defaultTemplates()
+	for _, code := range slices.Sorted(maps.Keys(catalog)) {
+		msg, ok := defaultMessages[code]
+		if !ok {
+			return fmt.Errorf("%s: unknown message code %s", path, code)
+		}
+		// a template taking other values would print %!d(MISSING) in the middle of the message
+		if written := fmt.Sprintf(catalog[code], msg.sample...); strings.Contains(written, "%!") {
+			return fmt.Errorf("%s: the template of %s doesn't take the values of %q: %s", path, code, msg.template, written)
+		}
+		loaded[code] = catalog[code]
+	}
+	templates = loaded
+	return nil
+}
+
+// puts the default templates back
+func ResetCatalog() {
+	templates = defaultTemplates()
+}
+
+// the default catalog as json, to start a catalog from
+func DefaultCatalog() []byte {
+	out, _ := json.MarshalIndent(defaultTemplates(), "", "  ")
+	return out
+}
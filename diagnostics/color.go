@@ -0,0 +1,49 @@
+package diagnostics
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	ColorAuto   = "auto"   // colored when writing to a terminal and NO_COLOR isn't set
+	ColorAlways = "always" // colored even when redirected, NO_COLOR included
+	ColorNever  = "never"
+)
+
+var colorMode = ColorAuto
+
+// sets the color mode of the diagnostics, from --color
+func SetColorMode(mode string) error {
+	switch mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		colorMode = mode
+		return nil
+	default:
+		return fmt.Errorf("invalid color mode %s, expected one of (auto, always, never)", mode)
+	}
+}
+
+// whether the output gets ansi colors, follows https://no-color.org in auto mode
+func Colored() bool {
+	switch colorMode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if len(os.Getenv("NO_COLOR")) > 0 {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// wraps text in the ansi style, an example of this: Paint("1;90", "main.blk:3:1:")
+func Paint(style, text string) string {
+	if !Colored() {
+		return text
+	}
+	return "\033[" + style + "m" + text + "\033[0m"
+}
@@ -0,0 +1,81 @@
+package diagnostics
+
+import (
+	"blk/lexer"
+	"fmt"
+)
+
+type Severity string
+
+const (
+	Error   Severity = "ERROR"
+	Warning Severity = "WARNING"
+	Note    Severity = "NOTE"
+)
+
+// a range of a source line, rows and cols start at 1
+// a zero length span covers the word at Col
+type Span struct {
+	File   string
+	Row    int
+	Col    int
+	Length int
+	Label  string // printed next to the underline, can be empty
+}
+
+// what every phase reports, the lexer, the parser and the interpreter alike
+type Diagnostic struct {
+	Severity Severity
+	Message  string
+	// the code of the message in the catalog, empty for the messages that don't have one
+	Code    Code
+	Primary Span
+	Related []Span   // other places the message refers to, an example of this: where a name got declared
+	Notes   []string // printed under the excerpt, an example of this: "help: declare x with :="
+}
+
+func New(severity Severity, span Span, format string, a ...any) *Diagnostic {
+	return &Diagnostic{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, a...),
+		Primary:  span,
+	}
+}
+
+// the span of a token as it's written in the source, strings include their quotes
+func TokenSpan(file string, tok lexer.Token) Span {
+	length := len([]rune(tok.Text))
+	switch tok.Kind {
+	case lexer.TokenString, lexer.TokenChar:
+		length += 2
+	}
+	return Span{File: file, Row: tok.Row, Col: tok.Col, Length: length}
+}
+
+// adds a secondary labeled span
+func (d *Diagnostic) WithRelated(span Span, label string) *Diagnostic {
+	span.Label = label
+	d.Related = append(d.Related, span)
+	return d
+}
+
+// adds a note printed under the excerpt
+func (d *Diagnostic) WithNote(format string, a ...any) *Diagnostic {
+	d.Notes = append(d.Notes, fmt.Sprintf(format, a...))
+	return d
+}
+
+// the one line form of the diagnostic, without the excerpt
+func (d *Diagnostic) Error() string {
+	return Header(d.Primary.File, d.Primary.Row, d.Primary.Col, d.Severity, d.Message)
+}
+
+// the "file:row:col: SEVERITY: message" line all the diagnostics start with
+func Header(file string, row, col int, severity Severity, message string) string {
+	return fmt.Sprintf("%s %s: %s", Location(file, row, col), severity, message)
+}
+
+// the position prefix of the diagnostics, painted in gray
+func Location(file string, row, col int) string {
+	return Paint("1;90", fmt.Sprintf("%s:%d:%d:", file, row, col))
+}
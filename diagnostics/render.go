@@ -0,0 +1,224 @@
+package diagnostics
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// width used when COLUMNS doesn't hold the terminal width
+const DefaultWidth = 100
+
+// prints diagnostics with an excerpt of the sources they point to
+//
+//	main.blk:3:6: ERROR: identifier not found: lne
+//	  |
+//	3 | x := lne(arr)
+//	  |      ^^^ not declared in this scope
+type Renderer struct {
+	Width   int // messages and notes wrap past it, 0 never wraps
+	Context int // lines printed before and after the spans
+	sources map[string][]string
+}
+
+func NewRenderer() *Renderer {
+	return &Renderer{
+		Width:   TerminalWidth(),
+		sources: make(map[string][]string),
+	}
+}
+
+// the width shells export in COLUMNS, DefaultWidth otherwise
+func TerminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return DefaultWidth
+}
+
+// registers the content of a file, spans of files without a source only get their header
+func (r *Renderer) AddSource(file, content string) {
+	content = strings.TrimPrefix(content, "\uFEFF")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	r.sources[file] = strings.Split(content, "\n")
+}
+
+func (r *Renderer) Render(d *Diagnostic) string {
+	var out strings.Builder
+
+	prefix := fmt.Sprintf("%s:%d:%d: %s: ", d.Primary.File, d.Primary.Row, d.Primary.Col, d.Severity)
+	message := r.wrap(d.Message, len(prefix), 4)
+	out.WriteString(Location(d.Primary.File, d.Primary.Row, d.Primary.Col))
+	out.WriteString(" " + Paint(severityStyle(d.Severity), string(d.Severity)+":") + " ")
+	out.WriteString(strings.Join(message, "\n    "))
+	out.WriteString("\n")
+
+	// the spans of the primary file come first, the other files follow in order of appearance
+	files := []string{d.Primary.File}
+	byFile := map[string][]Span{d.Primary.File: {d.Primary}}
+	for _, span := range d.Related {
+		if _, ok := byFile[span.File]; !ok {
+			files = append(files, span.File)
+		}
+		byFile[span.File] = append(byFile[span.File], span)
+	}
+
+	gutter := 1
+	for _, spans := range byFile {
+		for _, span := range spans {
+			gutter = max(gutter, len(strconv.Itoa(span.Row+r.Context)))
+		}
+	}
+
+	for idx, file := range files {
+		lines, ok := r.sources[file]
+		if !ok {
+			continue
+		}
+		if idx > 0 {
+			first := byFile[file][0]
+			fmt.Fprintf(&out, "%s %s:%d:%d\n", Paint("1;90", strings.Repeat(" ", gutter)+"-->"), file, first.Row, first.Col)
+		}
+		r.excerpt(&out, lines, byFile[file], idx == 0, d.Severity, gutter)
+	}
+
+	for _, note := range d.Notes {
+		wrapped := r.wrap(note, gutter+3, gutter+3)
+		fmt.Fprintf(&out, "%s %s\n", Paint("1;90", strings.Repeat(" ", gutter)+" ="), strings.Join(wrapped, "\n"+strings.Repeat(" ", gutter+3)))
+	}
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// the lines of the spans followed by their underlines, the first span of a primary excerpt is the primary span
+func (r *Renderer) excerpt(out *strings.Builder, lines []string, spans []Span, primary bool, severity Severity, gutter int) {
+	rows := make([]int, 0)
+	for _, span := range spans {
+		if span.Row < 1 || span.Row > len(lines) {
+			continue
+		}
+		for row := max(1, span.Row-r.Context); row <= min(len(lines), span.Row+r.Context); row++ {
+			if !slices.Contains(rows, row) {
+				rows = append(rows, row)
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	slices.Sort(rows)
+
+	bar := Paint("1;90", strings.Repeat(" ", gutter)+" |")
+	out.WriteString(bar + "\n")
+
+	for idx, row := range rows {
+		if idx > 0 && rows[idx-1] != row-1 {
+			out.WriteString(Paint("1;90", strings.Repeat(" ", gutter)+"...") + "\n")
+		}
+		line := lines[row-1]
+		fmt.Fprintf(out, "%s %s\n", Paint("1;90", fmt.Sprintf("%*d |", gutter, row)), line)
+
+		// spans of one row are underlined left to right, each on its own line
+		onRow := make([]int, 0)
+		for spanIdx, span := range spans {
+			if span.Row == row {
+				onRow = append(onRow, spanIdx)
+			}
+		}
+		slices.SortStableFunc(onRow, func(a, b int) int { return spans[a].Col - spans[b].Col })
+
+		for _, spanIdx := range onRow {
+			span := spans[spanIdx]
+			marker, style := "-", "1;36"
+			if primary && spanIdx == 0 {
+				marker, style = "^", severityStyle(severity)
+			}
+			underline := Paint(style, strings.Repeat(marker, spanLength(line, span)))
+			if len(span.Label) > 0 {
+				underline += " " + Paint(style, span.Label)
+			}
+			fmt.Fprintf(out, "%s %s%s\n", bar, indentation(line, span.Col), underline)
+		}
+	}
+}
+
+// the blanks before col, tabs are kept so the underline lines up with the source
+func indentation(line string, col int) string {
+	var indent strings.Builder
+	for idx, char := range []rune(line) {
+		if idx >= col-1 {
+			break
+		}
+		if char == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	// spans right after the end of the line, an example of this: a missing closing brace
+	for idx := len([]rune(line)); idx < col-1; idx++ {
+		indent.WriteRune(' ')
+	}
+	return indent.String()
+}
+
+// the length of the span, zero length spans cover the word at their col
+func spanLength(line string, span Span) int {
+	if span.Length > 0 {
+		return span.Length
+	}
+	runes := []rune(line)
+	length := 0
+	for idx := span.Col - 1; idx >= 0 && idx < len(runes); idx++ {
+		if runes[idx] != '_' && !unicode.IsLetter(runes[idx]) && !unicode.IsDigit(runes[idx]) {
+			break
+		}
+		length++
+	}
+	return max(length, 1)
+}
+
+// splits text on spaces so the lines fit the width, the first one starts after
+// a prefix, the others after an indent, words wider than the width stay whole
+func (r *Renderer) wrap(text string, prefix, indent int) []string {
+	if r.Width <= 0 {
+		return []string{text}
+	}
+
+	lines := make([]string, 0)
+	for _, paragraph := range strings.Split(text, "\n") {
+		current, used := "", prefix
+		if len(lines) > 0 {
+			used = indent
+		}
+		for _, word := range strings.Fields(paragraph) {
+			wordLen := len([]rune(word))
+			if len(current) > 0 && used+1+wordLen > r.Width {
+				lines = append(lines, current)
+				current, used = "", indent
+			}
+			if len(current) > 0 {
+				current += " "
+				used++
+			}
+			current += word
+			used += wordLen
+		}
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+func severityStyle(severity Severity) string {
+	switch severity {
+	case Warning:
+		return "1;33"
+	case Note:
+		return "1;36"
+	default:
+		return "1;31"
+	}
+}
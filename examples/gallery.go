@@ -0,0 +1,45 @@
+package examples
+
+import (
+	"embed"
+	"path"
+	"slices"
+	"strings"
+)
+
+// the programs blk examples runs, they are part of the executable so they run from anywhere
+// a program is named after its file, the first comment of the file tells what it shows
+//
+//go:embed gallery/*.blk
+var gallery embed.FS
+
+// the names of the programs of the gallery, sorted
+func Names() []string {
+	entries, _ := gallery.ReadDir("gallery")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".blk"))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// the source of the program, false when the gallery doesn't have it
+func Source(name string) ([]byte, bool) {
+	content, err := gallery.ReadFile(path.Join("gallery", name+".blk"))
+	return content, err == nil
+}
+
+// the first comment of the program, the imports come before it
+func Description(name string) string {
+	content, ok := Source(name)
+	if !ok {
+		return ""
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if comment, ok := strings.CutPrefix(strings.TrimSpace(line), "#"); ok {
+			return strings.TrimSpace(comment)
+		}
+	}
+	return ""
+}
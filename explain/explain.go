@@ -0,0 +1,218 @@
+package explain
+
+import (
+	"blk/ast"
+	"blk/interpreter"
+	"blk/object"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// the lines printed before the explanation stops, past them the run goes on without it
+const MaxSteps = 1000
+
+// the values longer than this get cut, an explanation line fits a terminal
+const maxValueLength = 60
+
+// prints how a program gets evaluated as a tree, for blk run --explain: the statements as they
+// start, the calls, the values of the expressions once their parts are done and the variables
+// the statements change
+//
+//	2 | total := (add(1, 2) * 3)
+//	    call add(1, 2)
+//	      1 | (a + b)
+//	          a → 1
+//	          b → 2
+//	        (a + b) → 3
+//	    add returned 3
+//	  (add(1, 2) * 3) → 9
+//	  total := 9
+type Explainer struct {
+	out   io.Writer
+	limit int
+	steps int
+	depth int
+	// whether each node being evaluated indents its parts, the innermost last
+	indents []bool
+	// the targets of the assignments, the value they had before isn't shown
+	targets map[ast.Node]bool
+}
+
+func New(out io.Writer, limit int) *Explainer {
+	return &Explainer{out: out, limit: limit, targets: map[ast.Node]bool{}}
+}
+
+// hooks to install on the interpreter that runs the program
+func (e *Explainer) Hooks() *interpreter.Hooks {
+	return &interpreter.Hooks{
+		OnEnterNode: e.enter,
+		OnLeaveNode: e.leave,
+		OnCall:      e.call,
+		OnReturn:    e.ret,
+		OnAssign:    e.assign,
+	}
+}
+
+func (e *Explainer) enter(node ast.Node) {
+	indents := false
+	switch node := node.(type) {
+	case *ast.Program, *ast.BlockStatement:
+	case ast.Statement:
+		e.print("%d | %s", node.GetToken().Row, statementText(node))
+		indents = true
+		if assign, ok := node.(*ast.AssignStatement); ok {
+			for _, target := range assign.Left {
+				e.targets[target] = true
+			}
+		}
+	case ast.Expression:
+		indents = e.shown(node)
+	}
+	e.indents = append(e.indents, indents)
+	if indents {
+		e.depth++
+	}
+}
+
+func (e *Explainer) leave(node ast.Node, result object.Object) {
+	if len(e.indents) == 0 {
+		return
+	}
+	if e.indents[len(e.indents)-1] {
+		e.depth--
+	}
+	e.indents = e.indents[:len(e.indents)-1]
+
+	if _, ok := node.(ast.Statement); ok {
+		return
+	}
+	expr, ok := node.(ast.Expression)
+	if !ok || !e.shown(expr) || !worthShowing(result) {
+		return
+	}
+	delete(e.targets, node)
+	// a literal or an array of literals is already its value
+	if text := firstLine(expr.String()); strings.ReplaceAll(text, " ", "") != strings.ReplaceAll(short(result), " ", "") {
+		e.print("%s → %s", text, short(result))
+	}
+}
+
+func (e *Explainer) call(call interpreter.CallEvent) {
+	args := make([]string, 0, len(call.Args))
+	for _, arg := range call.Args {
+		args = append(args, short(arg))
+	}
+	e.print("call %s(%s)", call.Name, strings.Join(args, ", "))
+	e.depth++
+}
+
+func (e *Explainer) ret(call interpreter.CallEvent, result object.Object) {
+	e.depth--
+	if result == nil || result.Type() == object.ERROR_OBJ {
+		return
+	}
+	e.print("%s returned %s", call.Name, short(result))
+}
+
+func (e *Explainer) assign(assign interpreter.AssignEvent) {
+	// the declarations of the functions, the structs and the enums show in their statement
+	if !worthShowing(assign.Value) {
+		return
+	}
+	op := "="
+	if decl, ok := assign.Node.(*ast.VarDeclaration); ok {
+		op = "::"
+		if decl.Mutable {
+			op = ":="
+		}
+	}
+	e.print("%s %s %s", assign.Name, op, short(assign.Value))
+}
+
+// prints a line of the tree, until the limit
+func (e *Explainer) print(format string, a ...any) {
+	e.steps++
+	if e.steps > e.limit {
+		if e.steps == e.limit+1 {
+			fmt.Fprintf(e.out, "... the run goes on, only the first %d steps are explained\n", e.limit)
+		}
+		return
+	}
+	fmt.Fprintf(e.out, "%s%s\n", strings.Repeat("  ", max(e.depth, 0)), fmt.Sprintf(format, a...))
+}
+
+// the expressions whose value tells something, the calls are shown by their returns, the
+// blocks, the ifs and the matches by the statements they run
+func (e *Explainer) shown(expr ast.Expression) bool {
+	if e.targets[expr] {
+		return false
+	}
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.CharLiteral,
+		*ast.BooleanLiteral, *ast.NulLiteral, *ast.BytesLiteral,
+		*ast.FunctionExpression, *ast.StructExpression, *ast.EnumExpression,
+		*ast.CallExpression, *ast.BlockStatement, *ast.IfExpression, *ast.MatchExpression:
+		return false
+	case *ast.MemberShipExpression:
+		_, isCall := expr.Property.(*ast.CallExpression)
+		return !isCall
+	}
+	return true
+}
+
+// the values left out: the errors get reported once the run stops, the functions and the
+// modules only have their names to show
+var hidden = []object.ObjectType{
+	object.ERROR_OBJ, object.FUNCTION_OBJ, object.BUILTIN_OBJ, object.BUILTIN_MODULE, object.USER_MODULE,
+	object.STRUCT_OBJ, object.ENUM_OBJ,
+}
+
+func worthShowing(result object.Object) bool {
+	result, _ = object.Cast(result)
+	return result != nil && !slices.Contains(hidden, result.Type())
+}
+
+// the value on a single line, cut when it's long
+func short(value object.Object) string {
+	if value == nil {
+		return "nul"
+	}
+	value, _ = object.Cast(value)
+	text := strings.Join(strings.Fields(value.Inspect()), " ")
+	// quoted so "1" isn't taken for 1
+	if str, ok := value.(*object.String); ok {
+		text = strconv.Quote(str.Value)
+	}
+	if runes := []rune(text); len(runes) > maxValueLength {
+		text = string(runes[:maxValueLength-3]) + "..."
+	}
+	return text
+}
+
+// the statement close to how it's written, the declarations print as let and const otherwise
+func statementText(stmt ast.Statement) string {
+	decl, ok := stmt.(*ast.VarDeclaration)
+	if !ok || decl.Value == nil {
+		return firstLine(stmt.String())
+	}
+	names := make([]string, 0, len(decl.Name))
+	for _, name := range decl.Name {
+		names = append(names, name.Value)
+	}
+	op := "::"
+	if decl.Mutable {
+		op = ":="
+	}
+	return firstLine(strings.Join(names, ", ") + " " + op + " " + decl.Value.String())
+}
+
+func firstLine(text string) string {
+	line, _, cut := strings.Cut(text, "\n")
+	if cut {
+		return strings.TrimSpace(line) + " ..."
+	}
+	return line
+}
@@ -0,0 +1,266 @@
+package heapsnap
+
+import (
+	"blk/object"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"unsafe"
+)
+
+// values longer than this are cut in the nodes
+const maxValueLength = 64
+
+// a scope or a value reachable from the snapshot roots
+type Node struct {
+	ID   int    `json:"id"`
+	Kind string `json:"kind"` // scope, or the type of the value, an example of this: ARRAY
+	// approximate bytes held by the node itself, the nodes it refers to aren't counted
+	Size int `json:"size"`
+	// the value of the scalars, the struct name of the instances, the bindings count of the scopes
+	Value string `json:"value,omitempty"`
+}
+
+// a reference, labeled with the binding, the index, the key or the field it goes through
+type Edge struct {
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+	Label string `json:"label"`
+}
+
+// the count and the size of the nodes of a kind
+type Total struct {
+	Count int `json:"count"`
+	Size  int `json:"size"`
+}
+
+// the graph of the scopes and the values they hold, closures link to the scopes they captured
+type Graph struct {
+	Nodes  []Node           `json:"nodes"`
+	Edges  []Edge           `json:"edges"`
+	Totals map[string]Total `json:"totals"`
+
+	// the node of every scope and value already walked, by pointer
+	ids map[any]int
+}
+
+// walks the scope and its outer scopes, the first node is the scope, the builtins are left out
+func Snapshot(env *object.Environment) *Graph {
+	g := &Graph{Totals: map[string]Total{}, ids: map[any]int{}}
+	g.scope(env)
+	return g
+}
+
+func (g *Graph) add(key any, kind string, size int, value string) (int, bool) {
+	if id, ok := g.ids[key]; ok {
+		return id, false
+	}
+	id := len(g.Nodes)
+	g.ids[key] = id
+	g.Nodes = append(g.Nodes, Node{ID: id, Kind: kind, Size: size, Value: value})
+
+	total := g.Totals[kind]
+	total.Count++
+	total.Size += size
+	g.Totals[kind] = total
+	return id, true
+}
+
+func (g *Graph) link(from, to int, label string) {
+	g.Edges = append(g.Edges, Edge{From: from, To: to, Label: label})
+}
+
+func (g *Graph) scope(env *object.Environment) int {
+	store := env.GetStore()
+	size := int(unsafe.Sizeof(*env)) + len(store)*int(unsafe.Sizeof("")+unsafe.Sizeof(object.ItemObject{}))
+	id, added := g.add(env, "scope", size, fmt.Sprintf("%d bindings", len(store)))
+	if !added {
+		return id
+	}
+
+	names := make([]string, 0, len(store))
+	for name, item := range store {
+		if !item.IsBuiltIn && item.Object != nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		g.link(id, g.value(store[name].Object), name)
+	}
+	if outer := env.GetOuterScope(); outer != nil {
+		g.link(id, g.scope(outer), "outer")
+	}
+	return id
+}
+
+func (g *Graph) value(obj object.Object) int {
+	obj, _ = object.Cast(obj)
+	if obj == nil {
+		obj = object.NUL
+	}
+	var key any = obj
+	if reflect.TypeOf(obj).Kind() != reflect.Pointer {
+		// values that aren't pointers have no identity, every use of them is its own node
+		key = new(int)
+	}
+	id, added := g.add(key, string(obj.Type()), sizeOf(obj), describe(obj))
+	if !added {
+		return id
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		for idx, elem := range obj.Elements {
+			g.link(id, g.value(elem), fmt.Sprintf("[%d]", idx))
+		}
+	case *object.Map:
+		pairs := make([]object.HashPair, 0, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			pairs = append(pairs, pair)
+		}
+		slices.SortFunc(pairs, func(a, b object.HashPair) int { return cmp.Compare(a.Key.Inspect(), b.Key.Inspect()) })
+		for _, pair := range pairs {
+			g.link(id, g.value(pair.Value), "["+pair.Key.Inspect()+"]")
+		}
+	case *object.StructInstance:
+		g.fields(id, obj.Fields, obj.FieldNames())
+		if obj.Def != nil {
+			g.link(id, g.value(obj.Def), "struct")
+		}
+	case *object.Struct:
+		g.fields(id, obj.Fields, obj.FieldNames())
+		g.fields(id, obj.Methods, obj.MethodNames())
+	case *object.Function:
+		if obj.Env != nil {
+			g.link(id, g.scope(obj.Env), "env")
+		}
+	case *object.UserModule:
+		names := make([]string, 0, len(obj.Attrs))
+		for name := range obj.Attrs {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		g.fields(id, obj.Attrs, names)
+	}
+	return id
+}
+
+func (g *Graph) fields(id int, fields map[string]object.Object, names []string) {
+	for _, name := range names {
+		g.link(id, g.value(fields[name]), "."+name)
+	}
+}
+
+// the struct of the value plus what it holds outside of it, the strings bytes, the slots of
+// the arrays, the buckets of the maps
+func sizeOf(obj object.Object) int {
+	typ := reflect.TypeOf(obj)
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	size := int(typ.Size())
+	slot := int(unsafe.Sizeof(obj))
+	switch obj := obj.(type) {
+	case *object.String:
+		size += len(obj.Value)
+	case *object.Array:
+		size += cap(obj.Elements) * slot
+	case *object.Map:
+		size += len(obj.Pairs) * int(unsafe.Sizeof(object.HashKey{})+unsafe.Sizeof(object.HashPair{}))
+	case *object.StructInstance:
+		size += (len(obj.Fields) + len(obj.Methods)) * (int(unsafe.Sizeof("")) + slot)
+	case *object.Struct:
+		size += (len(obj.Fields) + len(obj.Methods)) * (int(unsafe.Sizeof("")) + slot)
+	}
+	return size
+}
+
+func describe(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.Integer, *object.Float, *object.Boolean, *object.String, *object.Char, *object.Range:
+		value := []rune(obj.Inspect())
+		if len(value) > maxValueLength {
+			value = append(value[:maxValueLength], []rune("...")...)
+		}
+		return string(value)
+	case *object.StructInstance:
+		if obj.Def != nil {
+			return obj.Def.Name
+		}
+	case *object.Struct:
+		return obj.Name
+	case *object.Array:
+		return fmt.Sprintf("%d elements", len(obj.Elements))
+	case *object.Map:
+		return fmt.Sprintf("%d pairs", len(obj.Pairs))
+	case *object.Function:
+		return fmt.Sprintf("fn(%d params)", len(obj.Parameters))
+	case *object.BuiltInModule:
+		return obj.Name
+	case *object.UserModule:
+		return obj.Name
+	}
+	return ""
+}
+
+func (g *Graph) WriteJSON(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g)
+}
+
+// writes the graph in the dot format of graphviz, an example of this: dot -Tsvg heap.dot -o heap.svg
+func (g *Graph) WriteDOT(out io.Writer) error {
+	var dot strings.Builder
+	dot.WriteString("digraph heap {\n\tnode [fontname=monospace];\n")
+	for _, node := range g.Nodes {
+		label := node.Kind
+		if len(node.Value) > 0 {
+			label += "\n" + node.Value
+		}
+		label += fmt.Sprintf("\n%d B", node.Size)
+		shape := "ellipse"
+		if node.Kind == "scope" {
+			shape = "box"
+		}
+		fmt.Fprintf(&dot, "\tn%d [label=\"%s\", shape=%s];\n", node.ID, escape(label), shape)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&dot, "\tn%d -> n%d [label=\"%s\"];\n", edge.From, edge.To, escape(edge.Label))
+	}
+	dot.WriteString("}\n")
+
+	_, err := io.WriteString(out, dot.String())
+	return err
+}
+
+// the dot strings escape quotes and backslashes, \n is a line break of the label
+func escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(text)
+}
+
+// writes the graph to the file at path, as dot when it ends with .dot, as json otherwise
+func (g *Graph) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if filepath.Ext(path) == ".dot" {
+		err = g.WriteDOT(file)
+	} else {
+		err = g.WriteJSON(file)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
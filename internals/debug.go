@@ -0,0 +1,92 @@
+package internals
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// the variable naming the areas to log, read by the cli when it starts
+const DebugEnv = "BLK_DEBUG"
+
+// the parts of blk that log what they decide when BLK_DEBUG names them, an example of this:
+// BLK_DEBUG=modules,parser blk run -f main.blk
+const (
+	DebugModules = "modules" // where an import resolves to, the cache hits, the plugins
+	DebugParser  = "parser"  // the statements read, where the parsing stops, the errors dropped
+	DebugEval    = "eval"    // the calls of the functions and the errors they raise
+)
+
+var DebugAreas = []string{DebugModules, DebugParser, DebugEval}
+
+// the level under debug, the logs of every step
+const LevelTrace = slog.LevelDebug - 4
+
+// where the logs go
+var DebugOutput io.Writer = os.Stderr
+
+var (
+	debugLoggers = map[string]*slog.Logger{}
+	noLogs       = slog.New(slog.DiscardHandler)
+)
+
+// the logger of the area, it drops everything unless the area got enabled by SetDebug
+func Debug(area string) *slog.Logger {
+	if logger, ok := debugLoggers[area]; ok {
+		return logger
+	}
+	return noLogs
+}
+
+// enables the areas of a comma separated list, all for every area, an area can set the lowest
+// level it logs after a colon, debug when it doesn't: modules,eval:trace
+func SetDebug(list string) error {
+	loggers := map[string]*slog.Logger{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+		area, levelName, _ := strings.Cut(entry, ":")
+		level, err := debugLevel(levelName)
+		if err != nil {
+			return err
+		}
+
+		areas := []string{area}
+		if area == "all" {
+			areas = DebugAreas
+		} else if !slices.Contains(DebugAreas, area) {
+			return fmt.Errorf("unknown debug area %s, expected all or one of (%s)", area, strings.Join(DebugAreas, ", "))
+		}
+		for _, area := range areas {
+			loggers[area] = slog.New(slog.NewTextHandler(DebugOutput, &slog.HandlerOptions{
+				Level:       level,
+				ReplaceAttr: traceLevelName,
+			})).With("area", area)
+		}
+	}
+	debugLoggers = loggers
+	return nil
+}
+
+func debugLevel(name string) (slog.Level, error) {
+	switch name {
+	case "", "debug":
+		return slog.LevelDebug, nil
+	case "trace":
+		return LevelTrace, nil
+	}
+	return 0, fmt.Errorf("unknown debug level %s, expected trace or debug", name)
+}
+
+// slog names the levels under debug DEBUG-4
+func traceLevelName(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Key == slog.LevelKey && attr.Value.Any() == LevelTrace {
+		attr.Value = slog.StringValue("TRACE")
+	}
+	return attr
+}
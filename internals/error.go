@@ -1,11 +1,11 @@
 package internals
 
 import (
+	"blk/diagnostics"
 	"blk/lexer"
 	"errors"
 	"fmt"
 	"slices"
-	"sort"
 	"strings"
 )
 
@@ -33,88 +33,40 @@ func (ec *ErrorCollector) Add(errMsg error) {
 }
 
 func (ec *ErrorCollector) Error(tok lexer.Token, msg string) error {
-	errMsg := fmt.Sprintf("\033[1;90m%s:%d:%d:\033[0m\n\n", "main.blk", tok.Row, tok.Col)
-
-	// Build row set map
+	// the source is rebuilt from the tokens, only the error row and its neighbours are shown
 	rowSet := make(map[int][]lexer.Token)
+	lastRow := 0
 	for _, t := range ec.Tokens {
 		rowSet[t.Row] = append(rowSet[t.Row], t)
+		lastRow = max(lastRow, t.Row)
 	}
 
-	// Collect sorted rows
-	rows := []int{}
-	for row := range rowSet {
-		rows = append(rows, row)
-	}
-	sort.Ints(rows)
-
-	// Find closest previous and next row
-	var prevRow, nextRow int
-	prevRow, nextRow = -1, -1
-	for _, row := range rows {
-		if row < tok.Row {
-			prevRow = row
-		} else if row > tok.Row && nextRow == -1 {
-			nextRow = row
+	lines := make([]string, lastRow)
+	for row, tokens := range rowSet {
+		if row < 1 {
+			continue
 		}
-	}
-
-	// Build rowMap with only prevRow, tok.Row, nextRow
-	rowMap := make(map[int][]lexer.Token)
-	if prevRow != -1 {
-		rowMap[prevRow] = rowSet[prevRow]
-	}
-	rowMap[tok.Row] = rowSet[tok.Row]
-	if nextRow != -1 {
-		rowMap[nextRow] = rowSet[nextRow]
-	}
-
-	// Format rows
-	formattedRows := []int{}
-	for row := range rowMap {
-		formattedRows = append(formattedRows, row)
-	}
-	sort.Ints(formattedRows)
-
-	for _, row := range formattedRows {
-		currentLine := rowMap[row]
 		lineContent := ""
-		lastCol := 0
-
-		for _, t := range currentLine {
+		lastCol := 1
+		for _, t := range tokens {
 			if t.Col > lastCol {
 				lineContent += strings.Repeat(" ", t.Col-lastCol)
 			}
+			text := t.Text
 			if t.Kind == lexer.TokenString {
-				t.Text = fmt.Sprintf(`"%s"`, t.Text)
+				text = fmt.Sprintf(`"%s"`, t.Text)
 			}
-			lineContent += t.Text
-			lastCol = t.Col + len(t.Text)
-		}
-
-		lineNumStr := fmt.Sprintf("%d", row)
-		errMsg += fmt.Sprintf("%s    %s\n", lineNumStr, lineContent)
-
-		if row == tok.Row {
-			spacesBeforeLineNum := len(lineNumStr)
-			spacesAfterLineNum := 4
-			spacesBeforeToken := tok.Col
-
-			totalSpaces := spacesBeforeLineNum + spacesAfterLineNum + spacesBeforeToken
-
-			errorIndicator := strings.Repeat(" ", totalSpaces)
-			errMsg += errorIndicator + "\033[1;31m"
-			repeat := len(tok.Text)
-			if repeat == 0 {
-				repeat = 1
-			}
-			errMsg += strings.Repeat("^", repeat)
-			errMsg += "\033[0m\n"
+			lineContent += text
+			lastCol = t.Col + len([]rune(text))
 		}
+		lines[row-1] = lineContent
 	}
 
-	errMsg += msg
-	return errors.New(errMsg)
+	renderer := diagnostics.NewRenderer()
+	renderer.Context = 1
+	renderer.AddSource("main.blk", strings.Join(lines, "\n"))
+
+	return errors.New(renderer.Render(diagnostics.New(diagnostics.Error, diagnostics.TokenSpan("main.blk", tok), "%s", msg)))
 }
 
 func (ec *ErrorCollector) GetErrors() []error {
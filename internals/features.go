@@ -0,0 +1,69 @@
+package internals
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Feature = string
+
+const (
+	FeatureMatch Feature = "match"
+)
+
+// experimental features that ship dark, they need to be enabled explicitly
+// either using the --enable=<feature,...> flag or the # blk:feature <feature> pragma
+var ExperimentalFeatures = map[Feature]string{
+	FeatureMatch: "match expressions",
+}
+
+type FeatureSet map[Feature]bool
+
+func NewFeatureSet() FeatureSet {
+	return make(FeatureSet)
+}
+
+// parses a comma separated list of features, an error is returned if one of them isn't known
+func ParseFeatureList(list string) (FeatureSet, error) {
+	features := NewFeatureSet()
+
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+
+		if err := features.Enable(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return features, nil
+}
+
+func (fs FeatureSet) Enable(name Feature) error {
+	if _, ok := ExperimentalFeatures[name]; !ok {
+		known := make([]string, 0, len(ExperimentalFeatures))
+		for feature := range ExperimentalFeatures {
+			known = append(known, feature)
+		}
+		slices.Sort(known)
+		return fmt.Errorf("unknown feature %s, supported features are %v", name, known)
+	}
+
+	fs[name] = true
+	return nil
+}
+
+func (fs FeatureSet) Enabled(name Feature) bool {
+	return fs[name]
+}
+
+func (fs FeatureSet) Copy() FeatureSet {
+	features := NewFeatureSet()
+	for name, enabled := range fs {
+		features[name] = enabled
+	}
+	return features
+}
@@ -0,0 +1,164 @@
+package internals
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const ManifestName = "blk.toml"
+
+// the project manifest, only the subset of toml blk reads is supported:
+// [section] headers, key = "value" pairs and # comments
+//
+//	[checksums]
+//	"scripts/deploy.blk" = "sha256:<hex digest>"
+type Manifest struct {
+	Path string // where the manifest was loaded from
+	// sha256 digests of the scripts allowed to run with --verify
+	// keys are paths relative to the manifest directory, using forward slashes
+	Checksums map[string]string
+}
+
+// looks for the manifest in dir, then in its parents
+func FindManifest(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	start := dir
+
+	for {
+		candidate := filepath.Join(dir, ManifestName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no %s found in %s or any of its parents", ManifestName, start)
+		}
+		dir = parent
+	}
+}
+
+func LoadManifest(path string) (*Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	manifest := &Manifest{Path: path, Checksums: make(map[string]string)}
+	section := ""
+	scanner := bufio.NewScanner(file)
+
+	for row := 1; scanner.Scan(); row++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("%s:%d: unclosed section header", ManifestName, row)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", ManifestName, row)
+		}
+
+		key, err := unquote(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", ManifestName, row, err)
+		}
+		value, err = unquote(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", ManifestName, row, err)
+		}
+
+		// other sections are left for later use
+		if section == "checksums" {
+			manifest.Checksums[filepath.ToSlash(filepath.Clean(key))] = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// strips the quotes of a string, and a trailing comment after it
+func unquote(text string) (string, error) {
+	if !strings.HasPrefix(text, `"`) {
+		// bare keys
+		text, _, _ = strings.Cut(text, "#")
+		return strings.TrimSpace(text), nil
+	}
+
+	end := strings.Index(text[1:], `"`)
+	if end == -1 {
+		return "", errors.New("unclosed string")
+	}
+	rest := strings.TrimSpace(text[end+2:])
+	if len(rest) > 0 && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %s after the string", rest)
+	}
+
+	return strconv.Unquote(text[:end+2])
+}
+
+func Checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// path of a file relative to the manifest directory, the way the checksums are keyed
+func (m *Manifest) Key(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(m.Path), abs)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+// makes sure the content of the file matches the digest recorded in the manifest
+func (m *Manifest) Verify(path string, content []byte) error {
+	key, err := m.Key(path)
+	if err != nil {
+		return err
+	}
+
+	expected, ok := m.Checksums[key]
+	if !ok {
+		return fmt.Errorf("refusing to run %s, it has no checksum recorded in %s", key, m.Path)
+	}
+
+	actual := Checksum(content)
+	if !strings.HasPrefix(expected, "sha256:") {
+		expected = "sha256:" + expected
+	}
+	if strings.ToLower(expected) != actual {
+		return fmt.Errorf("refusing to run %s, its checksum %s doesn't match the one recorded in %s", key, actual, m.Path)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package internals
+
+import (
+	"fmt"
+	"strings"
+)
+
+// holds the default flags of every blk invocation, the flags of the command line win over them
+// an example of this: BLK_OPTIONS="--color=never --allow-fs=/srv/data"
+const OptionsEnv = "BLK_OPTIONS"
+
+// splits the options on spaces, the way a shell would without the expansions:
+// single and double quotes group words, a backslash escapes the next character
+func SplitOptions(value string) ([]string, error) {
+	options := make([]string, 0)
+	var current strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(value)
+	for idx := 0; idx < len(runes); idx++ {
+		ch := runes[idx]
+
+		switch {
+		case ch == '\\' && quote != '\'':
+			if idx+1 == len(runes) {
+				return nil, fmt.Errorf("%s: trailing backslash", OptionsEnv)
+			}
+			idx++
+			current.WriteRune(runes[idx])
+			inWord = true
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			} else {
+				current.WriteRune(ch)
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+			inWord = true
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			if inWord {
+				options = append(options, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(ch)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("%s: unclosed %c quote", OptionsEnv, quote)
+	}
+	if inWord {
+		options = append(options, current.String())
+	}
+
+	return options, nil
+}
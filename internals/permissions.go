@@ -0,0 +1,151 @@
+package internals
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// capabilities granted to a program, deno style: blk run denies them unless granted
+// with --allow-fs=<paths>, --allow-net=<hosts> and --allow-run=<programs>, * grants everything
+type Permissions struct {
+	fs     []string // absolute roots the program can access
+	net    []string // hosts the program can reach
+	run    []string // programs the stdlib can start, an example of this: ffmpeg
+	allFS  bool
+	allNet bool
+	allRun bool
+}
+
+// used when blk is embedded or in the repl
+func AllowAll() *Permissions {
+	return &Permissions{allFS: true, allNet: true, allRun: true}
+}
+
+// takes the comma separated grants of the cli flags, an empty grant denies the capability
+func NewPermissions(fs, net, run string) (*Permissions, error) {
+	perms := &Permissions{}
+
+	for _, root := range splitGrant(fs) {
+		if root == "*" {
+			perms.allFS = true
+			continue
+		}
+		abs, err := resolvePath(root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --allow-fs path %s: %v", root, err)
+		}
+		perms.fs = append(perms.fs, abs)
+	}
+
+	for _, host := range splitGrant(net) {
+		if host == "*" {
+			perms.allNet = true
+			continue
+		}
+		perms.net = append(perms.net, strings.ToLower(host))
+	}
+
+	for _, program := range splitGrant(run) {
+		if program == "*" {
+			perms.allRun = true
+			continue
+		}
+		perms.run = append(perms.run, program)
+	}
+
+	return perms, nil
+}
+
+func splitGrant(grant string) []string {
+	items := make([]string, 0)
+	for item := range strings.SplitSeq(grant, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// absolute form of a path with the symlinks resolved, so a link can't point out of a granted root,
+// a path that doesn't exist yet gets the longest part of it that exists resolved then the rest added
+// back, the links that point to nothing yet are followed too since writing to them creates their target
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	existing, rest := abs, ""
+	for range maxLinks {
+		if resolved, err := filepath.EvalSymlinks(existing); err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if target, err := os.Readlink(existing); err == nil {
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(existing), target)
+			}
+			existing = target
+			continue
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return filepath.Join(existing, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	return "", fmt.Errorf("too many links in %s", path)
+}
+
+// the links followed before a path is given up on, a link can point to itself
+const maxLinks = 255
+
+func (p *Permissions) CheckFS(path string) error {
+	if p.allFS {
+		return nil
+	}
+
+	abs, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+
+	for _, root := range p.fs {
+		rel, err := filepath.Rel(root, abs)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("access to %s denied, grant it with --allow-fs=%s", path, path)
+}
+
+// host can hold a port, an example of this: api.example.com:8080
+func (p *Permissions) CheckNet(host string) error {
+	if p.allNet {
+		return nil
+	}
+
+	host = strings.ToLower(host)
+	if slices.Contains(p.net, host) {
+		return nil
+	}
+	// a grant without a port allows every port of the host
+	if name, _, ok := strings.Cut(host, ":"); ok && slices.Contains(p.net, name) {
+		return nil
+	}
+
+	return fmt.Errorf("network access to %s denied, grant it with --allow-net=%s", host, host)
+}
+
+// program is the name the stdlib looks up in PATH, an example of this: ffprobe
+func (p *Permissions) CheckRun(program string) error {
+	if p.allRun || slices.Contains(p.run, program) {
+		return nil
+	}
+
+	return fmt.Errorf("running %s denied, grant it with --allow-run=%s", program, program)
+}
@@ -0,0 +1,117 @@
+package internals
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ReleaseURL is where blk upgrade looks for the release channels, it can be set at build time
+// using go build -ldflags "-X blk/internals.ReleaseURL=<url>" or overridden with BLK_RELEASE_URL
+var ReleaseURL = "https://github.com/BelkacemYerfa/blk/releases/download"
+
+// ReleaseKey is the hex encoded ed25519 public key the releases are signed with, it's set at
+// build time using go build -ldflags "-X blk/internals.ReleaseKey=<hex>", a build without it
+// can't verify the releases so it doesn't upgrade itself
+var ReleaseKey = ""
+
+var ReleaseChannels = []string{"stable", "nightly"}
+
+// the index a channel publishes at <ReleaseURL>/<channel>.json
+type Release struct {
+	Version string `json:"version"`
+	// by platform, an example of this: linux/amd64
+	Assets map[string]ReleaseAsset `json:"assets"`
+	// the channel the index was fetched from
+	channel string
+}
+
+type ReleaseAsset struct {
+	URL      string `json:"url"`
+	Checksum string `json:"checksum"` // sha256:<hex digest> of the binary
+	// hex encoded ed25519 signature of SignedMessage
+	Signature string `json:"signature"`
+	// what the signature binds the checksum to, filled from the release the asset is taken from
+	version, channel, platform string
+}
+
+// the message a release asset signs, the checksum alone would let an older binary be published
+// under a newer version, another channel or another platform with its genuine signature
+func SignedMessage(version, channel, platform, checksum string) []byte {
+	return []byte(strings.Join([]string{"blk", version, channel, platform, strings.ToLower(checksum)}, "\n"))
+}
+
+var releaseClient = &http.Client{Timeout: 5 * time.Minute}
+
+// the latest release of the channel
+func FetchRelease(baseURL, channel string) (*Release, error) {
+	if !slices.Contains(ReleaseChannels, channel) {
+		return nil, fmt.Errorf("unknown channel %s, pick one of %s", channel, strings.Join(ReleaseChannels, ", "))
+	}
+	content, err := fetch(strings.TrimSuffix(baseURL, "/") + "/" + channel + ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	var release Release
+	if err := json.Unmarshal(content, &release); err != nil {
+		return nil, fmt.Errorf("the %s release index is malformed: %v", channel, err)
+	}
+	if len(release.Version) == 0 {
+		return nil, fmt.Errorf("the %s release index has no version", channel)
+	}
+	release.channel = channel
+	return &release, nil
+}
+
+// the binary of the release for the platform, os/arch
+func (r *Release) Asset(platform string) (*ReleaseAsset, error) {
+	asset, ok := r.Assets[platform]
+	if !ok {
+		return nil, fmt.Errorf("blk %s has no binary for %s", r.Version, platform)
+	}
+	asset.version, asset.channel, asset.platform = r.Version, r.channel, platform
+	return &asset, nil
+}
+
+func (a *ReleaseAsset) Download() ([]byte, error) {
+	return fetch(a.URL)
+}
+
+// checks the binary against the checksum of the asset, and the checksum against its signature,
+// the checksum alone only tells the download went fine, the signature that it's the release of
+// this version, channel and platform
+func (a *ReleaseAsset) Verify(binary []byte, key string) error {
+	if actual := Checksum(binary); actual != strings.ToLower(a.Checksum) {
+		return fmt.Errorf("the checksum of the download %s doesn't match the one of the release %s", actual, a.Checksum)
+	}
+
+	publicKey, err := hex.DecodeString(key)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("the release key isn't a hex encoded ed25519 public key")
+	}
+	signature, err := hex.DecodeString(a.Signature)
+	if err != nil || !ed25519.Verify(publicKey, SignedMessage(a.version, a.channel, a.platform, a.Checksum), signature) {
+		return fmt.Errorf("the signature of the release doesn't match the release key")
+	}
+	return nil
+}
+
+func fetch(url string) ([]byte, error) {
+	resp, err := releaseClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,148 @@
+package internals
+
+import (
+	"cmp"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Version is the semantic version of the blk interpreter
+const Version = "0.1.0"
+
+// the version of the language this interpreter speaks, the major and the minor of Version
+// a file names the version it's written for with the # blk 0.1 pragma, the syntax and the
+// semantics that change between versions check it, so the older files keep working
+const LanguageVersion = "0.1"
+
+// Commit is the git revision the binary was built from, it can be set at build time using
+// go build -ldflags "-X blk/internals.Commit=<sha>"
+var Commit = ""
+
+// returns the git revision of the current build, falls back to the vcs info
+// embedded by the go toolchain when it wasn't set at build time
+func BuildCommit() string {
+	if len(Commit) > 0 {
+		return Commit
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+
+	return "unknown"
+}
+
+// the major and the minor of a language version, 0.3 gives 0 and 3
+func ParseLanguageVersion(text string) ([2]int, error) {
+	major, minor, ok := strings.Cut(text, ".")
+	a, errA := strconv.Atoi(major)
+	b, errB := strconv.Atoi(minor)
+	if !ok || errA != nil || errB != nil || a < 0 || b < 0 {
+		return [2]int{}, fmt.Errorf("invalid version %s, expected a major and a minor like %s", text, LanguageVersion)
+	}
+	return [2]int{a, b}, nil
+}
+
+// -1 when the version a comes before b, 1 when it comes after, 0 when they are the same
+// the versions are valid ones
+func CompareLanguageVersions(a, b string) int {
+	va, _ := ParseLanguageVersion(a)
+	vb, _ := ParseLanguageVersion(b)
+	for idx := range va {
+		if va[idx] != vb[idx] {
+			if va[idx] < vb[idx] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// the precedence of two semantic versions, -1 when a comes before b, 1 when it comes after, 0 when
+// they are the same, a pre release comes before its release (0.2.0-nightly.3 < 0.2.0) and the build
+// metadata after a + is left out
+func CompareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for idx := range va.core {
+		if va.core[idx] != vb.core[idx] {
+			return cmp.Compare(va.core[idx], vb.core[idx]), nil
+		}
+	}
+	// the release comes after its pre releases
+	switch {
+	case len(va.pre) == 0 && len(vb.pre) == 0:
+		return 0, nil
+	case len(va.pre) == 0:
+		return 1, nil
+	case len(vb.pre) == 0:
+		return -1, nil
+	}
+	for idx := range min(len(va.pre), len(vb.pre)) {
+		if c := comparePreRelease(va.pre[idx], vb.pre[idx]); c != 0 {
+			return c, nil
+		}
+	}
+	return cmp.Compare(len(va.pre), len(vb.pre)), nil
+}
+
+type semanticVersion struct {
+	core [3]int
+	pre  []string
+}
+
+func parseVersion(text string) (semanticVersion, error) {
+	var version semanticVersion
+	text, _, _ = strings.Cut(strings.TrimPrefix(text, "v"), "+")
+	core, pre, hasPre := strings.Cut(text, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return version, fmt.Errorf("invalid version %s, expected a major, a minor and a patch like %s", text, Version)
+	}
+	for idx, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version, fmt.Errorf("invalid version %s, expected a major, a minor and a patch like %s", text, Version)
+		}
+		version.core[idx] = n
+	}
+	if hasPre {
+		version.pre = strings.Split(pre, ".")
+		if slices.Contains(version.pre, "") {
+			return version, fmt.Errorf("invalid version %s, the pre release has an empty part", text)
+		}
+	}
+	return version, nil
+}
+
+// the numeric parts compare as numbers and come before the others, compared as text
+func comparePreRelease(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -1,7 +1,9 @@
 package interpreter
 
 import (
+	"blk/diagnostics"
 	"blk/object"
+	"blk/stdlib"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,18 +12,31 @@ import (
 
 // this offers built in function so u don't need module imports to use them
 var builtInFunction = object.Module{
-	"len":    &object.BuiltinFn{Fn: size},
-	"copy":   &object.BuiltinFn{Fn: clone},
-	"int":    &object.BuiltinFn{Fn: toInt},
-	"float":  &object.BuiltinFn{Fn: toFloat},
-	"string": &object.BuiltinFn{Fn: toString},
-	"bool":   &object.BuiltinFn{Fn: toBool},
-	"char":   &object.BuiltinFn{Fn: toChar},
-	"typeOf": &object.BuiltinFn{Fn: typeOf},
+	"len":    &object.BuiltinFn{Fn: size, Pure: true},
+	"copy":   &object.BuiltinFn{Fn: clone, Pure: true},
+	"int":    &object.BuiltinFn{Fn: toInt, Pure: true},
+	"float":  &object.BuiltinFn{Fn: toFloat, Pure: true},
+	"string": &object.BuiltinFn{Fn: toString, Pure: true},
+	"bool":   &object.BuiltinFn{Fn: toBool, Pure: true},
+	"char":   &object.BuiltinFn{Fn: toChar, Pure: true},
+	"typeOf": &object.BuiltinFn{Fn: typeOf, Pure: true},
+	"to_map": &object.BuiltinFn{Fn: toMap, Pure: true},
 	"clear":  &object.BuiltinFn{Fn: clear},
 	"assert": &object.BuiltinFn{Fn: assert},
+	// errors as values, see object.ResultDef
+	"ok":        &object.BuiltinFn{Fn: okResult, Pure: true},
+	"err":       &object.BuiltinFn{Fn: errResult, Pure: true},
+	"is_ok":     &object.BuiltinFn{Fn: isOk, Pure: true},
+	"is_err":    &object.BuiltinFn{Fn: isErr, Pure: true},
+	"unwrap":    &object.BuiltinFn{Fn: unwrap},
+	"unwrap_or": &object.BuiltinFn{Fn: unwrapOr, Pure: true},
+	// the modules picked when the program runs, plugins for an example
+	"import_module": &object.BuiltinFn{Fn: importModule},
 }
 
+// set by the interpreter, the builtins don't have access to it
+var loadModule func(path string) (*object.UserModule, *object.Error)
+
 func size(args ...object.Object) object.Object {
 	if len(args) != 1 {
 		return newError(ERROR, "wrong number of arguments. got=%d, want=1",
@@ -245,6 +260,38 @@ func typeOf(args ...object.Object) object.Object {
 	}
 }
 
+// the fields of a struct instance as a map keyed by their names, the methods are left out,
+// the values are copies so changing the map leaves the instance as it was
+// usage:
+// -	to_map(Vec2{x: 1.0, y: 2.0}) => {x: 1.0, y: 2.0}
+func toMap(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError(ERROR, "wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	arg, _ := object.Cast(args[0])
+	instance, ok := arg.(*object.StructInstance)
+	if !ok {
+		return newError(ERROR, "to_map takes a struct instance, got %s", typeName(arg))
+	}
+
+	pairs := make(object.PairsType, len(instance.Fields))
+	var first object.Object
+	for _, name := range instance.FieldNames() {
+		key := &object.String{Value: name}
+		value, _ := object.Cast(instance.Fields[name])
+		// same rule as the map literals, the values share a type
+		if first == nil {
+			first = value
+		} else if !object.ObjectTypesCheck(first, value, true) {
+			return newError(ERROR, "the fields need to share a type to make a map, got %s and %s", typeName(first), typeName(value))
+		}
+		pairs[key.HashKey()] = object.HashPair{Key: key, Value: value.Copy()}
+	}
+	return &object.Map{Pairs: pairs}
+}
+
 func clear(args ...object.Object) object.Object {
 	if len(args) != 1 {
 		return newError(ERROR, "wrong number of arguments. got=%d, want=1",
@@ -297,4 +344,103 @@ func assert(args ...object.Object) object.Object {
 	return &object.Nul{}
 }
 
+func okResult(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError(ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	value, _ := object.Cast(args[0])
+	return object.Ok(value)
+}
+
+func errResult(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError(ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	msg, _ := object.Cast(args[0])
+	str, ok := msg.(*object.String)
+	if !ok {
+		return newError(ERROR, "the message of err needs to be a string, got %s", msg.Type())
+	}
+	return object.Err(str.Value)
+}
+
+// the result of the args of is_ok, is_err, unwrap and unwrap_or
+func resultArg(name string, args []object.Object, want int) (*object.StructInstance, *object.Error) {
+	if len(args) != want {
+		return nil, newError(ERROR, "wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+	result, ok := object.AsResult(args[0])
+	if !ok {
+		return nil, newError(ERROR, "%s takes a result made by ok or err, got %s", name, typeName(args[0]))
+	}
+	return result, nil
+}
+
+func isOk(args ...object.Object) object.Object {
+	result, err := resultArg("is_ok", args, 1)
+	if err != nil {
+		return err
+	}
+	return nativeBooleanObject(!object.IsErr(result))
+}
+
+func isErr(args ...object.Object) object.Object {
+	result, err := resultArg("is_err", args, 1)
+	if err != nil {
+		return err
+	}
+	return nativeBooleanObject(object.IsErr(result))
+}
+
+// the value of an ok result, unwrapping an err result is an error
+func unwrap(args ...object.Object) object.Object {
+	result, err := resultArg("unwrap", args, 1)
+	if err != nil {
+		return err
+	}
+	if object.IsErr(result) {
+		return newCodedError(diagnostics.UnwrapErr, object.ResultError(result))
+	}
+	return object.ResultValue(result)
+}
+
+func unwrapOr(args ...object.Object) object.Object {
+	result, err := resultArg("unwrap_or", args, 2)
+	if err != nil {
+		return err
+	}
+	if object.IsErr(result) {
+		value, _ := object.Cast(args[1])
+		return value
+	}
+	return object.ResultValue(result)
+}
+
+// import_module("path/to/mod.blk") gives ok(module), or err(msg) when the file is missing,
+// doesn't check or fails when it gets evaluated
+func importModule(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError(ERROR, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+	arg, _ := object.Cast(args[0])
+	path, ok := arg.(*object.String)
+	if !ok {
+		return newError(ERROR, "import_module takes the path of a module, got %s", typeName(arg))
+	}
+	// the path is picked while running, it's held to the fs grants like the files the stdlib reads
+	if err := stdlib.Permissions.CheckFS(modulePath(path.Value)); err != nil {
+		return object.Err(err.Error())
+	}
+
+	module, err := loadModule(path.Value)
+	if err != nil {
+		message := strings.TrimPrefix(err.Message, "ERROR: ")
+		if err.Row > 0 {
+			message = fmt.Sprintf("%s:%d:%d: %s", path.Value, err.Row, err.Col, message)
+		}
+		return object.Err(message)
+	}
+	return object.Ok(module)
+}
+
 var builtInConstants = map[string]*object.BuiltinConst{}
@@ -0,0 +1,157 @@
+package interpreter
+
+import (
+	"blk/ast"
+	"blk/object"
+	"time"
+)
+
+// a function call seen by the hooks
+type CallEvent struct {
+	Name     string   // the callee as written, an example of this: fmt.println, user.getName
+	Node     ast.Node // the call site
+	File     string   // file of the call site, empty in the repl
+	Function object.Object
+	Args     []object.Object
+}
+
+// a statement seen by the hooks, once it's done
+type StatementEvent struct {
+	Node    ast.Statement
+	File    string // file of the statement, empty in the repl
+	Start   time.Time
+	Elapsed time.Duration // includes the statements it ran, the body of a loop or of a called function
+	Result  object.Object // can be an error
+}
+
+// a variable, or a field or element of one, that got a value
+type AssignEvent struct {
+	Name  string   // the target as written, an example of this: total, user.name, nums[0]
+	Node  ast.Node // the declaration or the assignment
+	File  string   // file of the assignment, empty in the repl
+	Value object.Object
+}
+
+// optional callbacks for external tools (tracing, coverage, profilers)
+// every hook can be left nil, when none are installed the interpreter only pays a nil check
+type Hooks struct {
+	// called before a node gets evaluated
+	OnEnterNode func(node ast.Node)
+	// called once the node is evaluated, with the value it gave, nil for the statements that
+	// don't give one
+	OnLeaveNode func(node ast.Node, result object.Object)
+	// called before a user function or a builtin runs
+	OnCall func(call CallEvent)
+	// called once the call returns, result can be an error
+	OnReturn func(call CallEvent, result object.Object)
+	// called after every statement, nested ones included, the statements are timed only when it's set
+	OnStatement func(stmt StatementEvent)
+	// called after a declaration or an assignment succeeded, once per target
+	OnAssign func(assign AssignEvent)
+	// called once per runtime error, with the innermost node that raised it
+	OnError func(node ast.Node, err *object.Error)
+}
+
+// hooks calling the ones of every tool given, in order, so they can be installed together
+func MergeHooks(all ...*Hooks) *Hooks {
+	merged := &Hooks{}
+	for _, hooks := range all {
+		if hooks == nil {
+			continue
+		}
+		merged.OnEnterNode = chain(merged.OnEnterNode, hooks.OnEnterNode)
+		merged.OnLeaveNode = chain2(merged.OnLeaveNode, hooks.OnLeaveNode)
+		merged.OnCall = chain(merged.OnCall, hooks.OnCall)
+		merged.OnReturn = chain2(merged.OnReturn, hooks.OnReturn)
+		merged.OnStatement = chain(merged.OnStatement, hooks.OnStatement)
+		merged.OnAssign = chain(merged.OnAssign, hooks.OnAssign)
+		merged.OnError = chain2(merged.OnError, hooks.OnError)
+	}
+	return merged
+}
+
+// a hook calling first then second, nil stays nil so the interpreter can skip the work behind it
+func chain[E any](first, second func(E)) func(E) {
+	if first == nil {
+		return second
+	}
+	if second == nil {
+		return first
+	}
+	return func(event E) {
+		first(event)
+		second(event)
+	}
+}
+
+func chain2[A, B any](first, second func(A, B)) func(A, B) {
+	if first == nil {
+		return second
+	}
+	if second == nil {
+		return first
+	}
+	return func(a A, b B) {
+		first(a, b)
+		second(a, b)
+	}
+}
+
+// installs the hooks, imported modules share them, nil removes them
+func (i *Interpreter) SetHooks(hooks *Hooks) {
+	i.hooks = hooks
+}
+
+// the name of a call made on a module or an instance, an example of this: v.len, only the hooks
+// use it so it isn't built without them
+func (i *Interpreter) memberCallName(obj ast.Expression, call *ast.CallExpression) string {
+	if i.hooks == nil || (i.hooks.OnCall == nil && i.hooks.OnReturn == nil) {
+		return ""
+	}
+	return obj.String() + "." + call.Function.Value
+}
+
+// applies fn, reporting the call to the hooks if any
+func (i *Interpreter) applyCall(name string, call ast.Node, fn object.Object, args []object.Object) object.Object {
+	if i.hooks == nil || (i.hooks.OnCall == nil && i.hooks.OnReturn == nil) {
+		return i.applyFunction(fn, args)
+	}
+
+	event := CallEvent{Name: name, Node: call, File: i.fileName(), Function: fn, Args: args}
+	if i.hooks.OnCall != nil {
+		i.hooks.OnCall(event)
+	}
+
+	result := i.applyFunction(fn, args)
+
+	if i.hooks.OnReturn != nil {
+		i.hooks.OnReturn(event, result)
+	}
+
+	return result
+}
+
+// reports the targets of a declaration or an assignment to the hooks if any
+func (i *Interpreter) reportAssign(node ast.Node, targets []ast.Expression, values []object.Object) {
+	if i.hooks == nil || i.hooks.OnAssign == nil {
+		return
+	}
+	for idx, target := range targets {
+		if idx >= len(values) {
+			return
+		}
+		i.hooks.OnAssign(AssignEvent{Name: target.String(), Node: node, File: i.fileName(), Value: values[idx]})
+	}
+}
+
+// reports the names of a declaration with the values they got
+func (i *Interpreter) reportDeclaration(nd *ast.VarDeclaration) {
+	if i.hooks == nil || i.hooks.OnAssign == nil {
+		return
+	}
+	for _, name := range nd.Name {
+		if item, ok := i.env.ResolveSymbol(name.Symbol()); ok {
+			i.hooks.OnAssign(AssignEvent{Name: name.Value, Node: nd, File: i.fileName(), Value: item.Object})
+		}
+	}
+}
@@ -2,15 +2,20 @@ package interpreter
 
 import (
 	"blk/ast"
+	"blk/diagnostics"
+	"blk/internals"
 	"blk/lexer"
 	"blk/object"
 	"blk/parser"
 	"blk/stdlib"
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 )
 
 var (
@@ -19,10 +24,34 @@ var (
 )
 
 type Interpreter struct {
-	env           *object.Environment
+	env *object.Environment
+	// the modules imported by the file, by absolute path for the files and by name for the
+	// stdlib, aliases holds the key of the module each import name is bound to
 	cachedModules map[string]object.Object
+	aliases       map[string]string
 	loadingMods   map[string]bool // tracks modules being loaded
-	path          string
+	// the export views of the modules loaded during the run by absolute path, shared by every
+	// file importing them so a module is evaluated once
+	modules  map[string]*object.UserModule
+	path     string
+	features internals.FeatureSet // experimental features enabled for the current program
+	// non fatal diagnostics collected during the evaluation (deprecated usage, ...)
+	Warnings      []error
+	reportedWarns map[string]bool
+	// checks the content of imported files before they get evaluated, nil skips the check
+	verify func(path string, content []byte) error
+	hooks  *Hooks
+	// name of the struct being declared, its fields can refer to it
+	structName string
+	// strict mode, the implicit conversions and the shadowing become errors
+	strict bool
+	// the logs of BLK_DEBUG=eval, traceEval is set when they go down to every statement
+	debug     *slog.Logger
+	traceEval bool
+	// index of the top level statement being evaluated, where runtime.checkpoint resumes from
+	statement int
+	// the map literal passed as the options of a builtin, the only map its values can mix types
+	options *ast.MapLiteral
 }
 
 func NewInterpreter(env *object.Environment, path string) *Interpreter {
@@ -31,12 +60,119 @@ func NewInterpreter(env *object.Environment, path string) *Interpreter {
 	}
 	loadingMods := make(map[string]bool)
 	loadingMods[path] = true
-	return &Interpreter{
+	i := &Interpreter{
 		env:           env,
 		cachedModules: make(map[string]object.Object),
+		aliases:       make(map[string]string),
 		loadingMods:   loadingMods,
+		modules:       make(map[string]*object.UserModule),
 		path:          path,
+		features:      internals.NewFeatureSet(),
+		Warnings:      []error{},
+		reportedWarns: make(map[string]bool),
 	}
+	i.setDebug()
+	loadModule = i.loadUserModule
+	stdlib.CallFunction = func(fn object.Object, args ...object.Object) object.Object {
+		return i.applyFunction(fn, args)
+	}
+	// a program starts with the float format the previous one might have changed
+	object.FloatOutput = object.DefaultFloatFormat
+	stdlib.CurrentEnv = func() *object.Environment {
+		return i.env
+	}
+	stdlib.CurrentProgram = func() (string, *object.Environment, int) {
+		return path, env, i.statement
+	}
+	stdlib.SaveState = func() func() {
+		env, path, structName := i.env, i.path, i.structName
+		return func() {
+			i.env, i.path, i.structName = env, path, structName
+		}
+	}
+	return i
+}
+
+func (i *Interpreter) setDebug() {
+	i.debug = internals.Debug(internals.DebugEval)
+	i.traceEval = i.debug.Enabled(context.Background(), internals.LevelTrace)
+}
+
+// records a warning on the given token position, a warning is reported only once per position
+func (i *Interpreter) warn(tok lexer.Token, format string, a ...interface{}) {
+	key := fmt.Sprintf("%d:%d:%s", tok.Row, tok.Col, format)
+	if i.reportedWarns[key] {
+		return
+	}
+	i.reportedWarns[key] = true
+
+	i.Warnings = append(i.Warnings, diagnostics.New(diagnostics.Warning, i.span(tok), format, a...))
+}
+
+// warns when the statement calls a pure function and drops its result, upper(name) on its own
+// line doesn't change name, _ = ... drops a result on purpose
+func (i *Interpreter) checkDiscarded(stmt ast.Statement) {
+	expr, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return
+	}
+
+	var name string
+	var fn object.Object
+	switch nd := expr.Expression.(type) {
+	case *ast.CallExpression:
+		name, fn = nd.Function.Value, i.evalIdentifier(&nd.Function)
+	case *ast.MemberShipExpression:
+		owner, ok := nd.Object.(*ast.Identifier)
+		call, isCall := nd.Property.(*ast.CallExpression)
+		if !ok || !isCall {
+			return
+		}
+		module, _ := object.Cast(i.evalIdentifier(owner))
+		if module, ok := module.(*object.BuiltInModule); ok {
+			name, fn = owner.Value+"."+call.Function.Value, module.Attrs[call.Function.Value]
+		}
+	default:
+		return
+	}
+
+	fn, _ = object.Cast(fn)
+	if builtin, ok := fn.(*object.BuiltinFn); ok && builtin.Pure {
+		i.warn(expr.Token, "the result of %s is unused, it doesn't change its args, _ = ... drops it on purpose", name)
+	}
+}
+
+// warns if the used symbol was declared with the @deprecated annotation
+func (i *Interpreter) checkDeprecation(tok lexer.Token, name string, symbol object.Object) {
+	item, ok := symbol.(object.ItemObject)
+	if !ok || !item.IsDeprecated {
+		return
+	}
+
+	if len(item.DeprecationNote) > 0 {
+		i.warn(tok, "%s is deprecated: %s", name, item.DeprecationNote)
+	} else {
+		i.warn(tok, "%s is deprecated", name)
+	}
+}
+
+// every imported file needs to pass the verifier before it's evaluated
+func (i *Interpreter) SetVerifier(verify func(path string, content []byte) error) {
+	i.verify = verify
+}
+
+// enables the provided experimental features, mainly coming from the --enable flag
+func (i *Interpreter) EnableFeatures(features internals.FeatureSet) {
+	for name, enabled := range features {
+		if enabled {
+			i.features[name] = true
+		}
+	}
+}
+
+// turns on strict mode, like the # blk:strict pragma does
+func (i *Interpreter) EnableStrict() {
+	i.strict = true
 }
 
 func (i *Interpreter) enterScope() {
@@ -71,6 +207,18 @@ func newError(level LEVEL, format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(msg, a...)}
 }
 
+// an error written with the template of the code in the catalog of messages
+func newCodedError(code diagnostics.Code, a ...any) *object.Error {
+	err := newError(ERROR, "%s", diagnostics.Message(code, a...))
+	err.Code = code
+	return err
+}
+
+func isDiscard(node ast.Expression) bool {
+	ident, ok := node.(*ast.Identifier)
+	return ok && ident.Value == "_"
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
@@ -79,6 +227,69 @@ func isError(obj object.Object) bool {
 }
 
 func (i *Interpreter) Eval(node ast.Node) object.Object {
+	stdlib.EvalSteps.Add(1)
+
+	if i.hooks != nil && i.hooks.OnEnterNode != nil {
+		i.hooks.OnEnterNode(node)
+	}
+
+	stmt, timed := node.(ast.Statement)
+	timed = timed && i.hooks != nil && i.hooks.OnStatement != nil
+	var start time.Time
+	if timed {
+		start = time.Now()
+	}
+
+	result := i.evalNode(node)
+
+	// attach the position of the innermost node that raised the error
+	// so runtime errors can be mapped back to the original source
+	if err, ok := result.(*object.Error); ok && err.Row == 0 {
+		if _, isProgram := node.(*ast.Program); !isProgram && node != nil {
+			tok := node.GetToken()
+			if tok.Row > 0 {
+				err.File = i.fileName()
+				err.Row = tok.Row
+				err.Col = tok.Col
+				i.debug.Debug("error raised", "file", err.File, "row", err.Row, "col", err.Col, "error", err.Message)
+
+				if i.hooks != nil && i.hooks.OnError != nil && err.Propagated == nil {
+					i.hooks.OnError(node, err)
+				}
+			}
+		}
+	}
+
+	if i.traceEval && stmt != nil {
+		tok := stmt.GetToken()
+		i.debug.Log(context.Background(), internals.LevelTrace, "statement", "file", i.fileName(), "row", tok.Row, "col", tok.Col, "kind", reflect.TypeOf(stmt).Elem().Name())
+	}
+	if timed {
+		i.hooks.OnStatement(StatementEvent{
+			Node: stmt, File: i.fileName(), Start: start, Elapsed: time.Since(start), Result: result,
+		})
+	}
+	if i.hooks != nil && i.hooks.OnLeaveNode != nil {
+		i.hooks.OnLeaveNode(node, result)
+	}
+
+	return result
+}
+
+// returns the name of the file being evaluated, empty for the repl
+func (i *Interpreter) fileName() string {
+	if len(i.path) == 0 {
+		return ""
+	}
+	return filepath.Base(i.path)
+}
+
+// the span of a token of the file being evaluated
+func (i *Interpreter) span(tok lexer.Token) diagnostics.Span {
+	return diagnostics.TokenSpan(i.fileName(), tok)
+}
+
+func (i *Interpreter) evalNode(node ast.Node) object.Object {
 	switch nd := node.(type) {
 	case *ast.Program:
 		return i.evalProgram(nd.Statements)
@@ -86,17 +297,42 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 	case *ast.ImportStatement:
 		return i.evalModuleImport(nd)
 
+	case *ast.PragmaStatement:
+		return i.evalPragmaStatement(nd)
+
 	case *ast.StructExpression:
 		methods := make(map[string]object.Object, 0)
 		fields := make(map[string]object.Object, 0)
+		strct := &object.Struct{Name: i.structName}
+		i.structName = ""
+
+		// the struct is bound to its name while its fields are evaluated, so it can be
+		// a field type of its own, the fields get resolved against it when instances are made
+		fieldsEnv := i.env
+		if len(strct.Name) > 0 {
+			fieldsEnv = object.NewEnvironment(i.env)
+			fieldsEnv.Define(strct.Name, object.ItemObject{Object: strct})
+		}
 
 		// var declaration such as (x := 0, name :: "john")
 		for _, decl := range nd.Fields {
+			outer := i.env
+			i.env = fieldsEnv
 			val := i.Eval(decl.Value)
+			i.env = outer
 			if isError(val) {
 				return val
 			}
 
+			// a struct as default makes the field hold instances of it, nul until one is set
+			if fieldType, ok := castStruct(val); ok {
+				if strct.FieldTypes == nil {
+					strct.FieldTypes = make(map[string]*object.Struct)
+				}
+				strct.FieldTypes[decl.Name[0].Value] = fieldType
+				val = object.NUL
+			}
+
 			varDecl := object.ItemObject{
 				// this works as the way done when declaring stuff, where
 				Object:    object.UseCopyValueOrRef(val),
@@ -104,21 +340,29 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 			}
 
 			fields[decl.Name[0].Value] = varDecl
+			strct.FieldOrder = append(strct.FieldOrder, decl.Name[0].Value)
 		}
 
 		// methods built in into the struct
 		for _, method := range nd.Methods {
 			// here we pass teh value cause it is of type ast.FunctionExpression
 			evaluated := i.Eval(method.Value)
+			if fn, ok := evaluated.(*object.Function); ok {
+				fn.Name = method.Key.Value
+				if len(strct.Name) > 0 {
+					fn.Name = strct.Name + "." + fn.Name
+				}
+			}
 			methods[method.Key.Value] = object.ItemObject{
 				Object: evaluated,
 			}
+			strct.MethodOrder = append(strct.MethodOrder, method.Key.Value)
 		}
 
-		return &object.Struct{
-			Fields:  fields,
-			Methods: methods,
-		}
+		strct.Fields = fields
+		strct.Methods = methods
+		strct.ResolveMethods()
+		return strct
 
 	case *ast.StructInstanceExpression:
 		// deal with this one
@@ -127,6 +371,7 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 		if isError(val) {
 			return val
 		}
+		i.checkDeprecation(nd.Token, nd.Left.String(), val)
 		// val mostly is struct name
 		// checks the fields also compare
 		// for now the fields are mutable, no support for const :: in fields
@@ -139,6 +384,7 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 
 		structDefCopy := structDef.Copy().(*object.Struct)
 		copyOfStructDef := &object.StructInstance{
+			Def:     structDef,
 			Fields:  structDefCopy.Fields,
 			Methods: structDef.Methods,
 		}
@@ -157,7 +403,13 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 			}
 
 			fieldValue := i.Eval(field.Value)
+			if isError(fieldValue) {
+				return fieldValue
+			}
 
+			if !structDef.Accepts(field.Key.Value, fieldValue) {
+				return fieldTypeError(structDef, field.Key.Value, fieldValue)
+			}
 			if fieldDef.Type() != fieldValue.Type() && fieldDef.Type() != object.NUL_OBJ {
 				// type error
 				return newError(ERROR, "type mismatch on %s, definition type %s, got %s", field.Key.Value, fieldDef.Type(), fieldValue.Type())
@@ -245,6 +497,13 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 
 		return &object.Range{Elements: elements}
 
+	case *ast.BytesLiteral:
+		elements := make([]object.Object, len(nd.Value))
+		for idx, b := range nd.Value {
+			elements[idx] = &object.Integer{Value: int64(b)}
+		}
+		return &object.Array{Elements: elements, Size: -1}
+
 	case *ast.ArrayLiteral:
 		elements := i.evalArrayExpression(nd.Elements)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -275,7 +534,38 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 		return &object.Array{Size: size, Elements: elements}
 
 	case *ast.MapLiteral:
-		return i.evalMapExpression(nd.Pairs)
+		return i.evalMapExpression(nd.Pairs, nd == i.options)
+
+	case *ast.CastExpression:
+		return i.evalCastExpression(nd)
+
+	case *ast.PropagateExpression:
+		value := i.Eval(nd.Value)
+		if isError(value) {
+			return value
+		}
+		result, ok := object.AsResult(value)
+		if !ok {
+			return newError(ERROR, "! takes a result made by ok or err, got %s", typeName(value))
+		}
+		if object.IsErr(result) {
+			// goes up like an error until applyFunction turns it back into the result
+			err := newError(ERROR, "%s", object.ResultError(result))
+			err.Propagated = result
+			return err
+		}
+		return object.ResultValue(result)
+
+	case *ast.IsExpression:
+		value := i.Eval(nd.Value)
+		if isError(value) {
+			return value
+		}
+		ok, err := matchesType(nd.Type, value, i.env)
+		if err != nil {
+			return err
+		}
+		return nativeBooleanObject(ok)
 
 	case *ast.IndexExpression:
 		left := i.Eval(nd.Left)
@@ -334,12 +624,28 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 		return Break
 
 	case *ast.VarDeclaration:
+		switch nd.Value.(type) {
+		case *ast.StructExpression, *ast.EnumExpression:
+			if len(nd.Name) == 1 {
+				i.structName = nd.Name[0].Value
+			}
+		}
 		val := i.Eval(nd.Value)
 		if isError(val) {
 			return val
 		}
+		if fn, ok := val.(*object.Function); ok && len(nd.Name) == 1 && len(fn.Name) == 0 {
+			fn.Name = nd.Name[0].Value
+		}
 
-		return i.evalVarDeclaration(val, nd)
+		declared := i.evalVarDeclaration(val, nd)
+		if !isError(declared) {
+			i.reportDeclaration(nd)
+		}
+		return declared
+
+	case *ast.LetElseStatement:
+		return i.evalLetElseStatement(nd)
 
 	case *ast.Identifier:
 		return i.evalIdentifier(nd)
@@ -347,16 +653,18 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 	case *ast.FunctionExpression:
 		params := nd.Args
 		body := nd.Body
-		if len(nd.Self.Value) > 0 {
+		// compiled programs drop the empty self identifier
+		if nd.Self != nil && len(nd.Self.Value) > 0 {
 			params = append([]*ast.Identifier{nd.Self}, params...)
 		}
-		return &object.Function{Parameters: params, Env: i.env, Body: body}
+		return &object.Function{Parameters: params, Types: nd.Types, ReturnType: nd.ReturnType, Env: i.env, Body: body, File: i.path, Token: nd.Token}
 
 	case *ast.CallExpression:
 		function := i.Eval(&nd.Function)
 		if isError(function) {
 			return function
 		}
+		i.checkDeprecation(nd.Token, nd.Function.Value, function)
 		// function is always of type object.ItemObject
 		ableToCast := function.(object.ItemObject).IsBuiltIn
 		args := i.evalExpressions(nd.Args, !ableToCast)
@@ -364,7 +672,7 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 			// error out
 			return args[0]
 		}
-		return i.applyFunction(function, args)
+		return i.applyCall(nd.Function.Value, nd, function, args)
 
 	case *ast.ReturnStatement:
 		returnValues := i.evalReturnValues(nd.ReturnValues)
@@ -385,6 +693,8 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 
 	case *ast.IfExpression:
 		return i.evalIfExpression(nd)
+	case *ast.ConditionalExpression:
+		return i.evalConditionalExpression(nd)
 
 	case *ast.UnaryExpression:
 		right := i.Eval(nd.Right)
@@ -428,6 +738,11 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 		leftResults := make([]LeftRes, 0)
 
 		for _, left := range nd.Left {
+			// _ = ... drops the value
+			if isDiscard(left) {
+				leftResults = append(leftResults, LeftRes{node: left})
+				continue
+			}
 			evaluated := i.Eval(left)
 			if isError(evaluated) {
 				return evaluated
@@ -462,7 +777,11 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 		}
 
 		// otherwise we're cool, but still need to check the assignments
-		return i.evalAssignment(leftResults, rightResults)
+		assigned := i.evalAssignment(leftResults, rightResults)
+		if !isError(assigned) {
+			i.reportAssign(nd, nd.Left, rightResults)
+		}
+		return assigned
 
 	case *ast.MemberShipExpression:
 		// evaluate the owner
@@ -473,9 +792,20 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 
 		return i.evalMembershipExpression(obj, nd.Object, nd.Property)
 
-	case *ast.MatchExpression, *ast.EnumExpression:
-		// no support in the interpreter
-		return newError(WARNING, "no support currently for this feature")
+	case *ast.MatchExpression:
+		if !i.features.Enabled(internals.FeatureMatch) {
+			return newError(ERROR, "match expressions are experimental, enable them with --enable=%s or %sfeature %s", internals.FeatureMatch, lexer.PragmaPrefix, internals.FeatureMatch)
+		}
+		return i.evalMatchExpression(nd)
+
+	case *ast.EnumExpression:
+		enum := &object.Enum{Name: i.structName, Values: make(map[string]int64, len(nd.Body))}
+		i.structName = ""
+		for _, field := range nd.Body {
+			enum.Members = append(enum.Members, field.Name.Value)
+			enum.Values[field.Name.Value] = field.Value
+		}
+		return enum
 
 	default:
 		if reflect.TypeOf(node) != nil {
@@ -486,9 +816,18 @@ func (i *Interpreter) Eval(node ast.Node) object.Object {
 }
 
 func (i *Interpreter) evalProgram(stmts []ast.Statement) object.Object {
+	return i.evalProgramFrom(stmts, 0)
+}
+
+func (i *Interpreter) evalProgramFrom(stmts []ast.Statement, from int) object.Object {
 	var result object.Object
-	for _, statement := range stmts {
+	for idx := from; idx < len(stmts); idx++ {
+		statement := stmts[idx]
+		i.statement = idx
 		result = i.Eval(statement)
+		if idx < len(stmts)-1 && !isError(result) {
+			i.checkDiscarded(statement)
+		}
 		res, _ := object.Cast(result)
 		switch res := res.(type) {
 		case *object.ReturnValue:
@@ -501,6 +840,25 @@ func (i *Interpreter) evalProgram(stmts []ast.Statement) object.Object {
 	return result
 }
 
+func (i *Interpreter) evalPragmaStatement(nd *ast.PragmaStatement) object.Object {
+	switch nd.Name {
+	case "strict":
+		i.strict = true
+	case "feature":
+		for _, feature := range nd.Args {
+			if err := i.features.Enable(feature); err != nil {
+				return newError(ERROR, err.Error())
+			}
+		}
+	case "version":
+		// the parser checked it, a newer version doesn't get this far
+	default:
+		return newError(ERROR, "unknown pragma %s", nd.Name)
+	}
+
+	return nil
+}
+
 func (i *Interpreter) evalModuleImport(nd *ast.ImportStatement) object.Object {
 
 	isModuleAPath := strings.Contains(nd.ModuleName.Value, "/")
@@ -512,95 +870,180 @@ func (i *Interpreter) evalModuleImport(nd *ast.ImportStatement) object.Object {
 		moduleName = nd.Alias.Value
 	}
 
-	if module, ok := i.cachedModules[moduleName]; ok {
-		return module
+	// the same file spelled another way is the same module, "a/../u.blk" is "u.blk"
+	key := nd.ModuleName.Value
+	if isModuleAPath {
+		key = modulePath(key)
+	}
+
+	logs := internals.Debug(internals.DebugModules)
+	if bound, ok := i.aliases[moduleName]; ok {
+		// the name is taken by another module, the import would silently reuse it
+		if bound != key {
+			return importCollision(nd, moduleName, moduleSource(i.cachedModules[bound]))
+		}
+		logs.Debug("cache hit", "import", nd.ModuleName.Value, "name", moduleName)
+		return i.cachedModules[key]
+	}
+	if module, ok := i.cachedModules[key]; ok {
+		// imported again under another name, the module is bound to it without loading it again
+		logs.Debug("cache hit", "import", nd.ModuleName.Value, "name", moduleName)
+		return i.bindModule(nd, moduleName, key, module.(object.ItemObject))
 	}
 
 	if isModuleAPath {
+		module, err := i.loadUserModule(nd.ModuleName.Value)
+		if err != nil {
+			return err
+		}
+		return i.bindModule(nd, moduleName, key, object.ItemObject{Object: module, IsBuiltIn: true})
+	}
 
-		cwd, _ := os.Getwd()
-		cwd = filepath.Join(cwd, nd.ModuleName.Value)
+	module, ok := stdlib.Module(nd.ModuleName.Value)
+	if !ok && !isModuleAPath {
+		// the names without a / are the stdlib and the plugins, the files need a path
+		logs.Debug("not found", "import", nd.ModuleName.Value, "looked in", "the stdlib and the plugins")
+		return newCodedError(diagnostics.ModuleNotFound, nd.ModuleName)
+	}
+	logs.Debug("builtin module", "import", nd.ModuleName.Value, "name", moduleName)
 
-		// means that the module is builtin into the std
+	newModule := object.ItemObject{
+		Object: &object.BuiltInModule{
+			Name:  nd.ModuleName.Value,
+			Attrs: module,
+		},
+		IsBuiltIn: true,
+	}
 
-		// cycle detection
-		_, ok := i.loadingMods[cwd]
-		if ok {
-			moduleName, _ := os.Stat(i.path)
-			circularModule, _ := os.Stat(cwd)
-			return newError(ERROR, "circular dependency detected in module: %s, issue on %s import", moduleName.Name(), circularModule.Name())
-		}
+	return i.bindModule(nd, moduleName, key, newModule)
+}
 
-		i.loadingMods[cwd] = true
-		defer func() { i.loadingMods[cwd] = false }()
+// reads the module at path, relative to the working directory, and evaluates it in its own
+// interpreter, the names that don't start with _ are its exports, a module already loaded by
+// another file of the run isn't evaluated again
+func (i *Interpreter) loadUserModule(path string) (*object.UserModule, *object.Error) {
+	cwd := modulePath(path)
+	logs := internals.Debug(internals.DebugModules)
+	logs.Debug("resolved", "import", path, "path", cwd, "from", i.path)
+
+	if module, ok := i.modules[cwd]; ok {
+		logs.Debug("shared", "path", cwd, "from", i.path)
+		return module, nil
+	}
 
-		content, err := os.ReadFile(cwd)
-		if err != nil {
-			return newError(ERROR, err.Error())
-		}
-		l := lexer.NewLexer(cwd, string(content))
-		p := parser.NewParser(l.Tokenize(), cwd)
-		program := p.Parse()
+	// cycle detection, the module is still loading when it gets imported again
+	if i.loadingMods[cwd] {
+		logs.Debug("circular import", "path", cwd, "from", i.path)
+		moduleName, _ := os.Stat(i.path)
+		circularModule, _ := os.Stat(cwd)
+		return nil, newCodedError(diagnostics.CircularImport, moduleName.Name(), circularModule.Name())
+	}
 
-		tempEnv := object.NewEnvironment(nil)
+	i.loadingMods[cwd] = true
+	defer func() { i.loadingMods[cwd] = false }()
 
-		moduleInterpreter := &Interpreter{
-			env:           tempEnv,
-			cachedModules: make(map[string]object.Object),
-			loadingMods:   i.loadingMods,
-			path:          cwd,
+	content, err := os.ReadFile(cwd)
+	if err != nil {
+		logs.Debug("not readable", "path", cwd, "error", err)
+		return nil, newError(ERROR, err.Error())
+	}
+	if i.verify != nil {
+		if err := i.verify(cwd, content); err != nil {
+			return nil, newError(ERROR, "%v", err)
 		}
+	}
+	l := lexer.NewLexer(cwd, string(content))
+	p := parser.NewParser(l.Tokenize(), cwd)
+	program := p.Parse()
+	if len(p.Errors) > 0 {
+		logs.Debug("doesn't check", "path", cwd, "errors", len(p.Errors))
+		return nil, newError(ERROR, "%s:%s", path, plainDiagnostic(p.Errors[0]))
+	}
 
-		moduleEval := moduleInterpreter.Eval(program)
+	tempEnv := object.NewEnvironment(nil)
 
-		// check if the eval triggers any errors on imported module
-		if isError(moduleEval) {
-			return moduleEval
-		}
+	moduleInterpreter := &Interpreter{
+		env:           tempEnv,
+		cachedModules: make(map[string]object.Object),
+		aliases:       make(map[string]string),
+		loadingMods:   i.loadingMods,
+		modules:       i.modules,
+		path:          cwd,
+		features:      i.features.Copy(),
+		strict:        i.strict,
+		Warnings:      []error{},
+		reportedWarns: make(map[string]bool),
+		verify:        i.verify,
+		hooks:         i.hooks,
+		debug:         i.debug,
+		traceEval:     i.traceEval,
+	}
 
-		exports := make(map[string]object.Object)
-		for name, obj := range tempEnv.GetStore() {
-			// skip private imports
-			if strings.HasPrefix(name, "_") {
-				continue
-			}
-			// save the module as ItemObject type
-			exports[name] = obj
-		}
+	moduleEval := moduleInterpreter.Eval(program)
+	i.Warnings = append(i.Warnings, moduleInterpreter.Warnings...)
 
-		newModule := object.ItemObject{
-			Object: &object.UserModule{
-				Name:  nd.ModuleName.Value,
-				Attrs: exports,
-			},
-			IsBuiltIn: true,
-		}
+	// check if the eval triggers any errors on imported module
+	moduleEval, _ = object.Cast(moduleEval)
+	if err, ok := moduleEval.(*object.Error); ok {
+		return nil, err
+	}
 
-		i.cachedModules[moduleName] = newModule
-		i.env.Define(moduleName, newModule)
+	// the view is made once, the other importers get the same one
+	module := &object.UserModule{Name: path, Attrs: tempEnv.Exports()}
+	i.modules[cwd] = module
 
-		return nil
+	logs.Debug("loaded", "path", cwd, "exports", len(module.Attrs))
+	return module, nil
+}
+
+// the absolute path of the module file, the paths of the imports are relative to the working
+// directory
+func modulePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
 	}
+	return filepath.Clean(path)
+}
 
-	module, ok := stdlib.BuiltinModules[nd.ModuleName.Value]
-	if !ok && !isModuleAPath {
-		return newError(ERROR, "Module Not found %s", nd.ModuleName)
+// the row, the col and the message of a parser error, without the colors of the terminal
+func plainDiagnostic(err error) string {
+	if d, ok := err.(*diagnostics.Diagnostic); ok {
+		return fmt.Sprintf("%d:%d: %s", d.Primary.Row, d.Primary.Col, d.Message)
 	}
+	return err.Error()
+}
 
-	newModule := object.ItemObject{
-		Object: &object.BuiltInModule{
-			Name:  nd.ModuleName.Value,
-			Attrs: module,
-		},
-		IsBuiltIn: true,
+// caches the module under its key and defines it in the current env, unless the name is
+// already bound to something else
+func (i *Interpreter) bindModule(nd *ast.ImportStatement, name, key string, module object.ItemObject) object.Object {
+	if existing, ok := i.env.Define(name, module); ok {
+		if source := moduleSource(existing); len(source) > 0 {
+			return importCollision(nd, name, source)
+		}
+		return newError(ERROR, "can't import %s as %s, %s is already declared", nd.ModuleName, name, name)
 	}
+	i.cachedModules[key] = module
+	i.aliases[name] = key
+	return nil
+}
 
-	// cache it first
-	i.cachedModules[moduleName] = newModule
-	// define it in the current env
-	i.env.Define(moduleName, newModule)
+// the path or the name a module got imported with, empty for the other objects
+func moduleSource(obj object.Object) string {
+	obj, _ = object.Cast(obj)
+	switch module := obj.(type) {
+	case *object.UserModule:
+		return module.Name
+	case *object.BuiltInModule:
+		return module.Name
+	}
+	return ""
+}
 
-	return nil
+func importCollision(nd *ast.ImportStatement, name, source string) *object.Error {
+	if nd.Alias != nil {
+		return newError(ERROR, "can't import %s as %s, %s is already the import of %q", nd.ModuleName, name, name, source)
+	}
+	return newError(ERROR, "can't import %s, %s is already the import of %q, give it another name with as", nd.ModuleName, name, source)
 }
 
 func nativeBooleanObject(val bool) *object.Boolean {
@@ -611,11 +1054,19 @@ func nativeBooleanObject(val bool) *object.Boolean {
 }
 
 func (i *Interpreter) evalExpressions(exps []ast.Expression, ableToCast bool) []object.Object {
-	var result []object.Object
+	result := i.appendExpressions(nil, exps, ableToCast)
+	if len(result) > 0 && isError(result[len(result)-1]) {
+		return result[len(result)-1:]
+	}
+	return result
+}
+
+// evaluates exps after the values already in result, stops on the first error and leaves it last
+func (i *Interpreter) appendExpressions(result []object.Object, exps []ast.Expression, ableToCast bool) []object.Object {
 	for _, e := range exps {
 		evaluated := i.Eval(e)
 		if isError(evaluated) {
-			return []object.Object{evaluated}
+			return append(result, evaluated)
 		}
 		argEval := evaluated
 		switch ev := evaluated.(type) {
@@ -679,13 +1130,13 @@ func (i *Interpreter) evalReturnValues(exps []ast.Expression) []object.Object {
 	return result
 }
 
-// this is used for evaluating map pairs (key, value)
-func (i *Interpreter) evalMapExpression(prs map[ast.Expression]ast.Expression) object.Object {
+// this is used for evaluating map pairs (key, value), mixed lets the values be of different types
+func (i *Interpreter) evalMapExpression(prs []ast.MapPair, mixed bool) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair, len(prs))
+	keyTokens := make(map[object.HashKey]lexer.Token, len(prs))
 	var keyEl, valEl object.Object
-	idx := 0
-	for keyNode, valueNode := range prs {
-		key := i.Eval(keyNode)
+	for idx, pair := range prs {
+		key := i.Eval(pair.Key)
 		if isError(key) {
 			return key
 		}
@@ -701,20 +1152,29 @@ func (i *Interpreter) evalMapExpression(prs map[ast.Expression]ast.Expression) o
 			return newError(ERROR, "multitude of types, (%v,%v), key elements of a map should be of one type", keyEl.Type(), key.Type())
 		}
 
-		value := i.Eval(valueNode)
+		hashed := hashKey.HashKey()
+		// computed keys can collide, the literal ones are rejected by the parser
+		if _, ok := pairs[hashed]; ok {
+			err := newError(ERROR, "duplicate key %s in map literal", key.Inspect())
+			tok := pair.Key.GetToken()
+			err.File, err.Row, err.Col = i.fileName(), tok.Row, tok.Col
+			err.Related = append(err.Related, withLabel(i.span(keyTokens[hashed]), "first defined here"))
+			return err
+		}
+		keyTokens[hashed] = pair.Key.GetToken()
+
+		value := i.Eval(pair.Value)
 		if isError(value) {
 			return value
 		}
-		hashed := hashKey.HashKey()
 		value, _ = object.Cast(value)
 		if idx == 0 {
 			valEl = value
 		}
-		if !object.ObjectTypesCheck(valEl, value, true) {
+		if !mixed && !object.ObjectTypesCheck(valEl, value, true) {
 			return newError(ERROR, "multitude of types detected, value elements of a map should be of one type")
 		}
 		pairs[hashed] = object.HashPair{Key: key, Value: value}
-		idx++
 	}
 
 	return &object.Map{Pairs: pairs}
@@ -845,7 +1305,7 @@ func (i *Interpreter) evalArrayIndexExpression(array, index object.Object) objec
 	max := int64(len(arrayObject.Elements) - 1)
 
 	if idx < 0 || idx > max {
-		return newError(ERROR, "index out of bound, %d", idx)
+		return newCodedError(diagnostics.IndexOutOfBound, idx)
 	}
 
 	return arrayObject.Elements[idx]
@@ -861,7 +1321,7 @@ func (i *Interpreter) evalMapIndexExpression(hashMap, index object.Object) objec
 
 	pair, ok := mapObject.Pairs[key.HashKey()]
 	if !ok {
-		return newError(ERROR, "index (%v) is not associated with any value", index.Inspect())
+		return newCodedError(diagnostics.MissingKey, index.Inspect())
 	}
 
 	return pair.Value
@@ -875,7 +1335,7 @@ func (i *Interpreter) evalVarDeclaration(val object.Object, nd *ast.VarDeclarati
 
 	// functions need to be declared as consts
 	if (val.Type() == object.FUNCTION_OBJ) && nd.Mutable {
-		return newError(ERROR, "functions are required to be declared as consts")
+		return newCodedError(diagnostics.FunctionNotConst)
 	}
 
 	castedVal, _ := object.Cast(val)
@@ -911,6 +1371,21 @@ func (i *Interpreter) evalVarDeclaration(val object.Object, nd *ast.VarDeclarati
 		IsMutable: nd.Mutable,
 	}
 
+	for _, annotation := range nd.Annotations {
+		if annotation.Name.Value != "deprecated" {
+			continue
+		}
+
+		if castedVal.Type() != object.FUNCTION_OBJ && castedVal.Type() != object.STRUCT_OBJ {
+			return newError(ERROR, "deprecated annotation can only be applied on functions or structs, got %s", castedVal.Type())
+		}
+
+		newVal.IsDeprecated = true
+		if len(annotation.Args) > 0 {
+			newVal.DeprecationNote = annotation.Args[0].(*ast.StringLiteral).Value
+		}
+	}
+
 	// for multi value assignment from functions
 	if castedVal.Type() == object.RETURN_VALUE_OBJ {
 		returnValues := castedVal.(*object.ReturnValue).Values
@@ -921,34 +1396,79 @@ func (i *Interpreter) evalVarDeclaration(val object.Object, nd *ast.VarDeclarati
 
 		// this handles the declaration of multi values
 		for idx, ident := range nd.Name {
+			if err := i.checkShadowing(ident); err != nil {
+				return err
+			}
 			currentVarAssigned := object.ItemObject{
-				Object:    returnValues[idx].Copy(),
-				IsMutable: newVal.IsMutable,
+				Object:      returnValues[idx].Copy(),
+				IsMutable:   newVal.IsMutable,
+				Declaration: i.span(ident.Token),
 			}
 			// define it in the scope
-			_, firstDeclaration := i.env.Define(ident.Value, currentVarAssigned)
+			existing, alreadyDeclared := i.env.DefineSymbol(ident.Symbol(), currentVarAssigned)
 
-			if firstDeclaration {
-				return newError(WARNING, "name %s is already in use", ident.Value)
+			if alreadyDeclared {
+				return i.redeclarationError(ident, existing)
 			}
 		}
 	} else {
 		singleVar := nd.Name[0]
+		if err := i.checkShadowing(singleVar); err != nil {
+			return err
+		}
+		newVal.Declaration = i.span(singleVar.Token)
 		// define it in the scope
-		_, firstDeclaration := i.env.Define(singleVar.Value, newVal)
+		existing, alreadyDeclared := i.env.DefineSymbol(singleVar.Symbol(), newVal)
 
-		if firstDeclaration {
-			return newError(WARNING, "name %s is already in use", singleVar.Value)
+		if alreadyDeclared {
+			return i.redeclarationError(singleVar, existing)
 		}
 	}
 
 	return nil
 }
 
+// in strict mode a name can't be declared again in an inner scope, the outer one would be
+// hidden from the rest of the scope
+func (i *Interpreter) checkShadowing(ident *ast.Identifier) *object.Error {
+	if !i.strict || i.env.GetOuterScope() == nil {
+		return nil
+	}
+	existing, ok := i.env.GetOuterScope().ResolveSymbol(ident.Symbol())
+	if !ok {
+		return nil
+	}
+	return i.shadowingError(ident, existing)
+}
+
+func (i *Interpreter) shadowingError(ident *ast.Identifier, existing object.ItemObject) *object.Error {
+	err := newError(ERROR, "%s shadows a name of an outer scope, strict mode forbids it, give it another name", ident.Value)
+	err.File, err.Row, err.Col = i.fileName(), ident.Token.Row, ident.Token.Col
+	if existing.Declaration.Row > 0 {
+		err.Related = append(err.Related, withLabel(existing.Declaration, "declared here"))
+	}
+	return err
+}
+
+// points to both the name being declared again and its first declaration
+func (i *Interpreter) redeclarationError(ident *ast.Identifier, existing object.ItemObject) *object.Error {
+	err := newError(WARNING, "name %s is already in use", ident.Value)
+	err.File, err.Row, err.Col = i.fileName(), ident.Token.Row, ident.Token.Col
+	if existing.Declaration.Row > 0 {
+		err.Related = append(err.Related, withLabel(existing.Declaration, "first declared here"))
+	}
+	return err
+}
+
+func withLabel(span diagnostics.Span, label string) diagnostics.Span {
+	span.Label = label
+	return span
+}
+
 func (i *Interpreter) evalIdentifier(identifier *ast.Identifier) object.Object {
 
 	// do the check on the operation layer if the current treated value is mutable or not
-	if obj, ok := i.env.Resolve(identifier.Value); ok {
+	if obj, ok := i.env.ResolveSymbol(identifier.Symbol()); ok {
 		return obj
 	}
 
@@ -966,7 +1486,7 @@ func (i *Interpreter) evalIdentifier(identifier *ast.Identifier) object.Object {
 		}
 	}
 
-	return newError(ERROR, "identifier not found: %s", identifier.Value)
+	return withFix(newCodedError(diagnostics.NotFound, identifier.Value), i.identifierFix(identifier))
 }
 
 func (i *Interpreter) applyFunction(fn object.Object, args []object.Object) object.Object {
@@ -975,33 +1495,86 @@ func (i *Interpreter) applyFunction(fn object.Object, args []object.Object) obje
 	fn, _ = object.Cast(fn)
 	switch fn := fn.(type) {
 	case *object.Function:
-		argSize := len(args)
-		fnParamSize := len(fn.Parameters)
-
 		// check that the number of params is the same
-		if argSize != fnParamSize {
-			return newError(ERROR, "wrong number of arguments. got=%d, want=%d",
-				argSize, fnParamSize)
+		if len(args) != len(fn.Parameters) {
+			return arityError(fn, args)
+		}
+
+		if err := checkArgTypes(fn, args); err != nil {
+			return err
 		}
+		i.debug.Debug("call", "fn", fnName(fn), "args", len(args), "file", filepath.Base(fn.File))
 
 		extendedEnv := extendFunctionEnv(fn, args)
 		// save the current env
 		previousEnv := i.env
 		i.env = extendedEnv
+		// the body positions belong to the file where the function was declared
+		previousPath := i.path
+		i.path = fn.File
+		if i.strict {
+			for _, param := range fn.Parameters {
+				if existing, ok := fn.Env.ResolveSymbol(param.Symbol()); ok && param.Value != lexer.TokenSelf {
+					err := i.shadowingError(param, existing)
+					i.env, i.path = previousEnv, previousPath
+					return err
+				}
+			}
+		}
 		evaluated := i.Eval(fn.Body)
 		// restore the old env
 		i.env = previousEnv
-		return unwrapReturnValue(evaluated)
+		i.path = previousPath
+		result := unwrapReturnValue(evaluated)
+		if err, ok := result.(*object.Error); ok && err.Propagated != nil {
+			result = err.Propagated
+		}
+		if fn.ReturnType != nil && !isError(result) {
+			if err := checkReturnType(fn, result); err != nil {
+				return err
+			}
+		}
+		return result
 
 	case *object.BuiltinFn:
 		return fn.Fn(args...)
 
+	case *object.Foreign:
+		return fn.Call(args)
+
 	default:
-		return newError(ERROR, "not a function: %s", fn.Type())
+		return newCodedError(diagnostics.NotAFunction, fn.Type())
 	}
 
 }
 
+// tells the params the function was declared with and the types of the values it got
+func arityError(fn *object.Function, args []object.Object) *object.Error {
+	want := len(fn.Parameters)
+	if fn.IsMethod() {
+		// the instance isn't written in the call, so it isn't counted
+		want--
+		if len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	types := make([]string, len(args))
+	for idx, arg := range args {
+		arg, _ = object.Cast(arg)
+		types[idx] = string(arg.Type())
+	}
+	got := fmt.Sprintf("%d", len(args))
+	if len(types) > 0 {
+		got += " (" + strings.Join(types, ", ") + ")"
+	}
+
+	declared := ""
+	if len(fn.File) > 0 && fn.Token.Row > 0 {
+		declared = fmt.Sprintf(", declared at %s:%d:%d", filepath.Base(fn.File), fn.Token.Row, fn.Token.Col)
+	}
+	return newCodedError(diagnostics.WrongArgCount, fn.Signature(), declared, got, want)
+}
+
 func extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
@@ -1011,12 +1584,12 @@ func extendFunctionEnv(
 	for paramIdx, param := range fn.Parameters {
 		if param.Value == lexer.TokenSelf {
 			// 0 is the first context of the current struct
-			env.Define(param.Value, object.ItemObject{
+			env.DefineSymbol(param.Symbol(), object.ItemObject{
 				Object:    args[0],
 				IsMutable: true,
 			})
 		} else {
-			env.Define(param.Value, object.ItemObject{
+			env.DefineSymbol(param.Symbol(), object.ItemObject{
 				Object: args[paramIdx],
 				// this makes the params mutable
 				IsMutable: true,
@@ -1040,8 +1613,12 @@ func unwrapReturnValue(obj object.Object) object.Object {
 func (i *Interpreter) evalBlockStatement(block *ast.BlockStatement) object.Object {
 	var result object.Object
 
-	for _, statement := range block.Body {
+	for idx, statement := range block.Body {
 		result = i.Eval(statement)
+		// the last statement is the result of the block
+		if idx < len(block.Body)-1 && !isError(result) {
+			i.checkDiscarded(statement)
+		}
 		if result != nil {
 			rt := result.Type()
 			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.BREAK_OBJ || rt == object.NEXT_OBJ {
@@ -1061,10 +1638,14 @@ func (i *Interpreter) evalForStatement(nd *ast.ForStatement) object.Object {
 		return target
 	}
 
+	if stream, ok := target.(*object.Stream); ok {
+		return i.evalStreamLoop(nd, stream)
+	}
+
 	iterable, ok := target.(object.Iterable)
 
 	if !ok {
-		return newError(ERROR, "target needs to be either an array or a map, got %s", target.Type())
+		return newError(ERROR, "target needs to be an array, a map or a struct instance, got %s", target.Type())
 	}
 
 	items := iterable.Iter()
@@ -1072,27 +1653,28 @@ func (i *Interpreter) evalForStatement(nd *ast.ForStatement) object.Object {
 		return nil
 	}
 
-	i.enterScope()
-	defer i.exitScope()
-
 	for _, item := range items {
+		// every iteration binds the identifiers in a scope of its own, so the closures
+		// made in the body keep the values of their iteration
+		i.enterScope()
 		// bind identifiers
 		if len(nd.Identifiers) >= 1 && nd.Identifiers[0].Value != "_" {
 			if target.Type() == object.RANGE_OBJ {
-				i.env.OverrideDefine(nd.Identifiers[0].Value, object.ItemObject{Object: item.Value})
+				i.env.OverrideDefineSymbol(nd.Identifiers[0].Symbol(), object.ItemObject{Object: item.Value})
 			} else {
-				i.env.OverrideDefine(nd.Identifiers[0].Value, object.ItemObject{Object: item.Index})
+				i.env.OverrideDefineSymbol(nd.Identifiers[0].Symbol(), object.ItemObject{Object: item.Index})
 			}
 		}
 
 		if target.Type() != object.RANGE_OBJ {
 			if len(nd.Identifiers) >= 2 && nd.Identifiers[1].Value != "_" {
-				i.env.OverrideDefine(nd.Identifiers[1].Value, object.ItemObject{Object: item.Value})
+				i.env.OverrideDefineSymbol(nd.Identifiers[1].Symbol(), object.ItemObject{Object: item.Value})
 			}
 		}
 
 		// evaluate body
 		res := i.Eval(nd.Body)
+		i.exitScope()
 		if res != nil {
 			switch res.Type() {
 			case object.RETURN_VALUE_OBJ:
@@ -1110,6 +1692,39 @@ func (i *Interpreter) evalForStatement(nd *ast.ForStatement) object.Object {
 	return nil
 }
 
+// streams bind their values the same way ranges do, pulling one value per iteration
+func (i *Interpreter) evalStreamLoop(nd *ast.ForStatement, stream *object.Stream) object.Object {
+	for {
+		value, ok := stream.Next()
+		if !ok {
+			return nil
+		}
+		if isError(value) {
+			return value
+		}
+
+		i.enterScope()
+		if len(nd.Identifiers) >= 1 && nd.Identifiers[0].Value != "_" {
+			i.env.OverrideDefineSymbol(nd.Identifiers[0].Symbol(), object.ItemObject{Object: value})
+		}
+
+		res := i.Eval(nd.Body)
+		i.exitScope()
+		if res != nil {
+			switch res.Type() {
+			case object.RETURN_VALUE_OBJ:
+				return res
+			case object.NEXT_OBJ:
+				continue
+			case object.BREAK_OBJ:
+				return nil
+			case object.ERROR_OBJ:
+				return res
+			}
+		}
+	}
+}
+
 func (i *Interpreter) evalWhileStatement(nd *ast.WhileStatement) object.Object {
 	condition := i.Eval(nd.Condition)
 
@@ -1163,20 +1778,151 @@ func (i *Interpreter) evalIfExpression(nd *ast.IfExpression) object.Object {
 		// continue
 		if cdn.Value {
 			// eval the consequence
-			return i.Eval(nd.Consequence)
+			return ifValue(i.Eval(nd.Consequence))
 		}
 		// eval the alternative
-		return i.Eval(nd.Alternative)
+		return ifValue(i.Eval(nd.Alternative))
 	case *object.Nul:
 		// check of nul
-		return i.Eval(nd.Alternative)
+		return ifValue(i.Eval(nd.Alternative))
 	default:
 		// error out
 
-		return newError(ERROR, "evaluation of the condition needs to return a boolean not %s", cdn)
+		return newCodedError(diagnostics.ConditionType, cdn)
 	}
 }
 
+func (i *Interpreter) evalConditionalExpression(nd *ast.ConditionalExpression) object.Object {
+	condition := i.Eval(nd.Condition)
+
+	if isError(condition) {
+		return condition
+	}
+
+	condition, _ = object.Cast(condition)
+
+	switch cdn := condition.(type) {
+	case *object.Boolean:
+		if cdn.Value {
+			return ifValue(i.Eval(nd.Consequence))
+		}
+		return ifValue(i.Eval(nd.Alternative))
+	case *object.Nul:
+		return ifValue(i.Eval(nd.Alternative))
+	default:
+		return newCodedError(diagnostics.ConditionType, cdn)
+	}
+}
+
+// the value of an if expression is the final expression of the taken branch
+// if no branch was taken, or the branch doesn't end with an expression, the value is nul
+func ifValue(result object.Object) object.Object {
+	if result == nil {
+		return object.NUL
+	}
+	return result
+}
+
+// the value of a match is the value of the first arm that matches the key, nul if none do
+func (i *Interpreter) evalMatchExpression(nd *ast.MatchExpression) object.Object {
+	key := i.Eval(nd.MatchKey)
+
+	if isError(key) {
+		return key
+	}
+
+	key, _ = object.Cast(key)
+
+	for _, arm := range nd.Arms {
+		var matched bool
+		var err object.Object
+		if nd.OnType {
+			matched, err = i.matchTypePattern(key, arm.Pattern)
+		} else {
+			matched, err = i.matchPattern(key, arm.Pattern)
+		}
+		if err != nil {
+			return err
+		}
+		if matched {
+			return ifValue(i.Eval(arm.Body))
+		}
+	}
+
+	if nd.Default != nil {
+		return ifValue(i.Eval(nd.Default.Body))
+	}
+
+	return object.NUL
+}
+
+func (i *Interpreter) matchPattern(key object.Object, pattern ast.Expression) (bool, object.Object) {
+	switch pt := pattern.(type) {
+	case *ast.StringPattern:
+		str, ok := key.(*object.String)
+		return ok && matchStringPattern(pt, str.Value), nil
+	case *ast.Identifier:
+		// catch all
+		if pt.Value == "_" {
+			return true, nil
+		}
+	}
+
+	value := i.Eval(pattern)
+	if isError(value) {
+		return false, value
+	}
+
+	value, _ = object.Cast(value)
+	if value.Type() != key.Type() {
+		return false, nil
+	}
+
+	equal, ok := i.evalBinaryExpression(lexer.TokenEquals, key, value).(*object.Boolean)
+	return ok && equal.Value, nil
+}
+
+func matchStringPattern(pattern *ast.StringPattern, str string) bool {
+	switch pattern.Kind {
+	case ast.PatternPrefix:
+		return strings.HasPrefix(str, pattern.Literal)
+	case ast.PatternSuffix:
+		return strings.HasSuffix(str, pattern.Literal)
+	case ast.PatternContains:
+		return strings.Contains(str, pattern.Literal)
+	default:
+		return globMatch([]rune(pattern.Value), []rune(str))
+	}
+}
+
+// matches * and ? wildcards, on a mismatch backtracks to the last * and lets it eat one more character
+func globMatch(pattern, str []rune) bool {
+	p, s := 0, 0
+	star, mark := -1, 0
+
+	for s < len(str) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, s
+			p++
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == str[s]):
+			p++
+			s++
+		case star != -1:
+			mark++
+			p, s = star+1, mark
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
 func (i *Interpreter) evalUnaryExpression(op string, right object.Object) object.Object {
 	switch op {
 	case lexer.TokenExclamation:
@@ -1200,7 +1946,7 @@ func (i *Interpreter) evalUnaryExpression(op string, right object.Object) object
 			Value: ^val,
 		}
 	default:
-		return newError(ERROR, "unknown operator: %s%s", op, right.Type())
+		return newCodedError(diagnostics.UnknownOperator, op, right.Type())
 	}
 
 }
@@ -1238,7 +1984,7 @@ func (i *Interpreter) evalMinusPrefixOperatorExpression(right object.Object) obj
 		}
 	default:
 		// throw an error
-		return newError(ERROR, "unknown operator: -%s", right.Type())
+		return newCodedError(diagnostics.UnknownOperator, "-", right.Type())
 	}
 }
 
@@ -1252,20 +1998,22 @@ func (i *Interpreter) evalAssignment(left []LeftRes, right []object.Object) obje
 
 		rightVal := right[idx]
 		node := leftRes.node
+		if isDiscard(node) {
+			continue
+		}
 		leftObj, leftMutable := object.Cast(leftRes.Object)
 		rightObj, _ := object.Cast(rightVal)
 
 		// Check mutability first
 		if !leftMutable {
-			return newError(ERROR, "%v can't be mutated, since it was defined as const", leftObj.Inspect())
+			return withFix(newCodedError(diagnostics.ConstAssign, leftObj.Inspect()), constFix(node))
 		}
 
 		// Type compatibility check
 		// for nul value, u can assign it with what u want, then u need to respect the type rule that you're going to have
 		// a value can be nullified if it has a certain value attached to it whatever the value is
 		if leftObj.Type() != rightObj.Type() && leftObj.Type() != object.NUL_OBJ && rightObj.Type() != object.NUL_OBJ {
-			return newError(ERROR, "type mismatch: can't assign %s to %s",
-				rightObj.Type(), leftObj.Type())
+			return newCodedError(diagnostics.AssignMismatch, rightObj.Type(), leftObj.Type())
 		}
 
 		// Perform the assignment based on type
@@ -1273,7 +2021,7 @@ func (i *Interpreter) evalAssignment(left []LeftRes, right []object.Object) obje
 		case *object.Nul:
 			switch node := node.(type) {
 			case *ast.Identifier:
-				i.env.OverrideDefine(node.Value, object.ItemObject{
+				i.env.AssignSymbol(node.Symbol(), object.ItemObject{
 					Object:    object.UseCopyValueOrRef(rightObj),
 					IsMutable: leftMutable,
 				})
@@ -1366,18 +2114,41 @@ func (i *Interpreter) evalBinaryExpression(op string, left, right object.Object)
 	left, _ = object.Cast(left)
 	right, _ = object.Cast(right)
 
+	if i.strict {
+		if err := strictOperands(op, left, right); err != nil {
+			return err
+		}
+	}
+
+	// arrays support concatenation, repetition and equality
+	if left.Type() == object.ARRAY_OBJ {
+		return left.Binary(op, right)
+	}
+
 	// Check if either operand is a type that doesn't support binary operations
 	if left.Type() == object.ARRAY_OBJ || left.Type() == object.MAP_OBJ ||
 		right.Type() == object.ARRAY_OBJ || right.Type() == object.MAP_OBJ ||
 		left.Type() == object.STRUCT_OBJ || right.Type() == object.STRUCT_OBJ ||
 		left.Type() == object.FUNCTION_OBJ || right.Type() == object.FUNCTION_OBJ {
-		return newError(ERROR, "binary operations not supported on types: %s %s %s",
-			left.Type(), op, right.Type())
+		return newCodedError(diagnostics.UnsupportedTypes, left.Type(), op, right.Type())
 	}
 
 	return left.Binary(op, right)
 }
 
+// the implicit conversions strict mode refuses, an int mixed with a float and a value that
+// isn't a string concatenated to one
+func strictOperands(op string, left, right object.Object) *object.Error {
+	lt, rt := left.Type(), right.Type()
+	if (lt == object.INTEGER_OBJ && rt == object.FLOAT_OBJ) || (lt == object.FLOAT_OBJ && rt == object.INTEGER_OBJ) {
+		return newError(ERROR, "%s %s %s mixes an int and a float, strict mode doesn't convert them, write the int as a float, an example of this: 2.0", lt, op, rt)
+	}
+	if op == lexer.TokenPlus && lt == object.STRING_OBJ && rt != object.STRING_OBJ {
+		return newError(ERROR, "STRING + %s concatenates a value that isn't a string, strict mode doesn't convert it, use fmt.format", rt)
+	}
+	return nil
+}
+
 // this function is responsible for handling assign op for both struct, hashmaps, structs
 // Note: the assignment does a shallow copy, so modifying the value here will modify will affect the right struct instance
 // for deep copy, there is copy function in the builtin module of stdlib that allows u todo that
@@ -1403,19 +2174,50 @@ func (i *Interpreter) evalAssignmentExpression(leftNode ast.Expression, left, ri
 	}
 
 	typeCheck := object.ObjectTypesCheck(lft, lrt, true)
+	// dynamic arrays can grow or shrink, only their element type is kept
+	if arr, ok := lft.(*object.Array); ok && arr.Size == -1 {
+		typeCheck = sameElementType(arr, lrt)
+	}
 
 	if !typeCheck {
 		return newError(ERROR, errMsg)
 	}
 
 	// build a method into the env, and update it to left side
-	i.env.OverrideDefine(identifier.Value, object.ItemObject{
+	i.env.AssignSymbol(identifier.Symbol(), object.ItemObject{
 		Object:    lrt,
 		IsMutable: leftMutable,
 	})
 	return lrt
 }
 
+func castStruct(obj object.Object) (*object.Struct, bool) {
+	obj, _ = object.Cast(obj)
+	strct, ok := obj.(*object.Struct)
+	return strct, ok
+}
+
+// a value that isn't an instance of the struct the field got declared with
+func fieldTypeError(def *object.Struct, field string, value object.Object) *object.Error {
+	value, _ = object.Cast(value)
+	got := string(value.Type())
+	if instance, ok := value.(*object.StructInstance); ok && instance.Def != nil {
+		got = instance.Def.TypeName()
+	}
+	return newError(ERROR, "type mismatch on %s, definition type %s, got %s", field, def.FieldTypes[field].TypeName(), got)
+}
+
+func sameElementType(arr *object.Array, right object.Object) bool {
+	other, ok := right.(*object.Array)
+	if !ok {
+		return false
+	}
+	if len(arr.Elements) == 0 || len(other.Elements) == 0 {
+		return true
+	}
+	return object.ObjectTypesCheck(arr.Elements[0], other.Elements[0], true)
+}
+
 func (i *Interpreter) evalMembershipExpression(owner object.Object, obj, property ast.Expression) object.Object {
 	// switch on the object after cast
 
@@ -1429,15 +2231,21 @@ func (i *Interpreter) evalMembershipExpression(owner object.Object, obj, propert
 			// search for the corresponding property call and invoke
 			function, ok := owner.Attrs[ownerProperty.Function.Value]
 			if !ok {
-				return newError(ERROR, "function doesn't exist on the module %s", owner.Name)
+				return withFix(newError(ERROR, "function doesn't exist on the module %s", owner.Name), memberFix(&ownerProperty.Function, owner.Attrs))
+			}
+			// the options map of the builtin is written in the call, its values can mix types
+			outer := i.options
+			if fn, ok := function.(*object.BuiltinFn); ok && fn.Options && len(ownerProperty.Args) > 0 {
+				i.options, _ = ownerProperty.Args[len(ownerProperty.Args)-1].(*ast.MapLiteral)
 			}
 			// invokes the call expression
 			args := i.evalExpressions(ownerProperty.Args, false)
+			i.options = outer
 			if len(args) == 1 && isError(args[0]) {
 				// error out
 				return args[0]
 			}
-			return i.applyFunction(function, args)
+			return i.applyCall(i.memberCallName(obj, ownerProperty), ownerProperty, function, args)
 
 		case *ast.Identifier:
 			// a given constant in a module
@@ -1458,8 +2266,9 @@ func (i *Interpreter) evalMembershipExpression(owner object.Object, obj, propert
 			// search for the corresponding property call and invoke
 			function, ok := owner.Attrs[ownerProperty.Function.Value]
 			if !ok {
-				return newError(ERROR, "function doesn't exist on the module %s", owner.Name)
+				return withFix(newError(ERROR, "function doesn't exist on the module %s", owner.Name), memberFix(&ownerProperty.Function, owner.Attrs))
 			}
+			i.checkDeprecation(ownerProperty.Token, ownerProperty.Function.Value, function)
 			// invokes the call expression
 			// ableToCast := true
 			args := i.evalExpressions(ownerProperty.Args, true)
@@ -1467,7 +2276,7 @@ func (i *Interpreter) evalMembershipExpression(owner object.Object, obj, propert
 				// error out
 				return args[0]
 			}
-			return i.applyFunction(function, args)
+			return i.applyCall(i.memberCallName(obj, ownerProperty), ownerProperty, function, args)
 
 		case *ast.Identifier:
 			// a given constant in a module
@@ -1482,40 +2291,42 @@ func (i *Interpreter) evalMembershipExpression(owner object.Object, obj, propert
 			return newError(ERROR, "property needs to be of type call expression or identifier, for now")
 		}
 
+	case *object.Enum:
+		member, ok := property.(*ast.Identifier)
+		if !ok {
+			return newError(ERROR, "enums only have members, %s isn't one", property.String())
+		}
+		value, ok := owner.Values[member.Value]
+		if !ok {
+			return newError(ERROR, "%s isn't a member of the enum %s", member.Value, obj.String())
+		}
+		return &object.Integer{Value: value}
+
 	case *object.StructInstance:
 		switch ownerProperty := property.(type) {
 		case *ast.CallExpression:
 			// search for the corresponding property call and invoke
-			methodItem, ok := owner.Methods[ownerProperty.Function.Value]
+			// the *object.Function, or a builtin for the values the stdlib returns (term.progress, ...)
+			method, ok := owner.Method(ownerProperty.Function.Value)
 			if !ok {
 				return newError(ERROR, "method doesn't exist on the struct %v", ownerProperty.Function)
 			}
 
 			// responsible to detect if the current accessed method is private or not
 			// so private methods are only allowed within the struct scope methods
-			if strings.HasPrefix(ownerProperty.Function.Value, "_") {
-				if obj.GetToken().Text != lexer.TokenSelf {
-					return newError(ERROR, "%s is a private method, u can't use outside of the struct", ownerProperty.Function.Value)
-				}
+			if method.Private && obj.GetToken().Text != lexer.TokenSelf {
+				return newError(ERROR, "%s is a private method, u can't use outside of the struct", ownerProperty.Function.Value)
 			}
 
-			// methodItem is object.ItemObject wrapping the *object.Function
-			castFn, _ := object.Cast(methodItem)
-			fn := castFn.(*object.Function)
-
-			// Evaluate method args normally (do not include self yet)
-			ableToCast := methodItem.(object.ItemObject).IsBuiltIn
-			args := i.evalExpressions(ownerProperty.Args, !ableToCast)
-			if len(args) == 1 && isError(args[0]) {
-				return args[0]
+			// the instance is the first argument (self), the args are evaluated after it in the same slice
+			args := make([]object.Object, 1, len(ownerProperty.Args)+1)
+			args[0] = owner
+			args = i.appendExpressions(args, ownerProperty.Args, !method.IsBuiltIn)
+			if isError(args[len(args)-1]) {
+				return args[len(args)-1]
 			}
 
-			// PREPEND the instance as the first argument (self)
-			// Note: owner is already an object.Object (the struct instance)
-			args = append([]object.Object{(owner)}, args...)
-
-			// Now invoke the function using the normal applyFunction path.
-			return i.applyFunction(fn, args)
+			return i.applyCall(i.memberCallName(obj, ownerProperty), ownerProperty, method.Fn, args)
 
 		case *ast.Identifier:
 			// a given constant in a module
@@ -1634,6 +2445,9 @@ func (i *Interpreter) evalRecursiveAssignment(ownerObj, rightObj object.Object,
 		if !ok {
 			return newError(ERROR, "identifier doesn't exist on the struct %v", obj)
 		}
+		if instance, ok := castedOwner.(*object.StructInstance); ok && instance.Def != nil && !instance.Def.Accepts(property.Value, rightObj) {
+			return fieldTypeError(instance.Def, property.Value, rightObj)
+		}
 		_, mutable := object.Cast(identifier)
 		fields[property.Value] = object.ItemObject{
 			Object:    rightObj,
@@ -1673,7 +2487,7 @@ func (i *Interpreter) evalRecursiveAssignment(ownerObj, rightObj object.Object,
 			max := int64(len(lf.Elements) - 1)
 
 			if idx < 0 || idx > max {
-				return newError(ERROR, "index out of bound, %d", idx)
+				return newCodedError(diagnostics.IndexOutOfBound, idx)
 			}
 
 			lf.Elements[idx] = object.ItemObject{
@@ -1693,7 +2507,7 @@ func (i *Interpreter) evalRecursiveAssignment(ownerObj, rightObj object.Object,
 			pair, ok := lf.Pairs[key.HashKey()]
 
 			if !ok {
-				return newError(ERROR, "index (%v) is not associated with any value", index.Inspect())
+				return newCodedError(diagnostics.MissingKey, index.Inspect())
 			}
 
 			lf.Pairs[key.HashKey()] = object.HashPair{
@@ -0,0 +1,134 @@
+package interpreter
+
+import (
+	"blk/ast"
+	"blk/object"
+	"blk/stdlib"
+	"fmt"
+	"slices"
+)
+
+// a name that's not defined, either a module used without its import or a typo
+func (i *Interpreter) identifierFix(identifier *ast.Identifier) *object.QuickFix {
+	if slices.Contains(stdlib.ModuleNames(), identifier.Value) {
+		return &object.QuickFix{
+			Title: fmt.Sprintf("add import %q", identifier.Value),
+			Row:   1,
+			Col:   1,
+			Text:  fmt.Sprintf("import %q\n", identifier.Value),
+		}
+	}
+
+	names := make([]string, 0)
+	for env := i.env; env != nil; env = env.GetOuterScope() {
+		for name := range env.GetStore() {
+			names = append(names, name)
+		}
+	}
+	for name := range builtInFunction {
+		names = append(names, name)
+	}
+	for name := range builtInConstants {
+		names = append(names, name)
+	}
+
+	return renameFix(identifier, names)
+}
+
+// a member that's not defined on a module
+func memberFix(identifier *ast.Identifier, attrs map[string]object.Object) *object.QuickFix {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	return renameFix(identifier, names)
+}
+
+func renameFix(identifier *ast.Identifier, names []string) *object.QuickFix {
+	closest, ok := closestName(identifier.Value, names)
+	if !ok {
+		return nil
+	}
+
+	return &object.QuickFix{
+		Title:  fmt.Sprintf("replace %s with %s", identifier.Value, closest),
+		Row:    identifier.Token.Row,
+		Col:    identifier.Token.Col,
+		Length: len([]rune(identifier.Value)),
+		Text:   closest,
+	}
+}
+
+// the candidate the fewest edits away from name, short names only accept a single edit and the
+// edits are capped to a third of the shorter name, two edits turn print into int otherwise
+func closestName(name string, candidates []string) (string, bool) {
+	maxDistance := 2
+	if len(name) <= 4 {
+		maxDistance = 1
+	}
+
+	// sorted so ties always pick the same name
+	slices.Sort(candidates)
+
+	best, bestDistance := "", maxDistance+1
+	for _, candidate := range candidates {
+		distance := editDistance(name, candidate)
+		shorter := min(len([]rune(name)), len([]rune(candidate)))
+		if candidate == name || distance > min(maxDistance, max(1, shorter/3)) {
+			continue
+		}
+		// on ties, typos tend to drop letters rather than add them: printn => println, not print
+		if distance < bestDistance || (distance == bestDistance && len(candidate) > len(best)) {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	return best, len(best) > 0
+}
+
+// levenshtein distance, with a transposition counted as one edit
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// rows i-2, i-1 and i of the distance matrix
+	prevPrev := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prevPrev[j-2]+1)
+			}
+		}
+		prevPrev, prev, curr = prev, curr, prevPrev
+	}
+
+	return prev[len(rb)]
+}
+
+// a const that gets assigned, its declaration needs := instead of ::
+func constFix(node ast.Node) *object.QuickFix {
+	identifier, ok := node.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+	return &object.QuickFix{
+		Title: fmt.Sprintf("declare %s with := instead of :: to make it mutable", identifier.Value),
+	}
+}
+
+// attaches the fix to the error, nil fixes are ignored
+func withFix(err *object.Error, fix *object.QuickFix) *object.Error {
+	err.Fix = fix
+	return err
+}
@@ -0,0 +1,66 @@
+package interpreter
+
+import (
+	"blk/ast"
+	"blk/checkpoint"
+	"blk/object"
+)
+
+// continues a run from a checkpoint made by runtime.checkpoint, the declarations before the
+// statement of the checkpoint are evaluated again so the functions, the structs, the enums and
+// the modules are bound, then the globals get the values they had and the program goes on
+// from the statement
+func (i *Interpreter) Resume(program *ast.Program, cp *checkpoint.Checkpoint) object.Object {
+	if cp.Statement < 0 || cp.Statement >= len(program.Statements) {
+		return newError(ERROR, "the checkpoint is at the statement %d, the program has %d", cp.Statement+1, len(program.Statements))
+	}
+
+	for _, stmt := range program.Statements[:cp.Statement] {
+		if !isDeclaration(stmt) {
+			continue
+		}
+		if result := i.Eval(stmt); isError(result) {
+			return result
+		}
+	}
+	if err := cp.Restore(i.env, i.lookupStruct); err != nil {
+		return newError(ERROR, "%v", err)
+	}
+	return i.evalProgramFrom(program.Statements, cp.Statement)
+}
+
+// the statements that bind what a checkpoint doesn't hold: the imports, the pragmas and the
+// declarations of the functions, the structs and the enums
+func isDeclaration(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ImportStatement, *ast.PragmaStatement:
+		return true
+	case *ast.VarDeclaration:
+		switch stmt.Value.(type) {
+		case *ast.FunctionExpression, *ast.StructExpression, *ast.EnumExpression:
+			return true
+		}
+	}
+	return false
+}
+
+// the struct declared with the name, in the program first then in the modules it imports
+func (i *Interpreter) lookupStruct(name string) (*object.Struct, bool) {
+	if item, ok := i.env.Resolve(name); ok {
+		if def, ok := item.Object.(*object.Struct); ok {
+			return def, true
+		}
+	}
+	for _, item := range i.env.GetStore() {
+		value, _ := object.Cast(item)
+		module, ok := value.(*object.UserModule)
+		if !ok {
+			continue
+		}
+		attr, _ := object.Cast(module.Attrs[name])
+		if def, ok := attr.(*object.Struct); ok {
+			return def, true
+		}
+	}
+	return nil, false
+}
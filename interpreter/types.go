@@ -0,0 +1,206 @@
+package interpreter
+
+import (
+	"blk/ast"
+	"blk/object"
+	"math"
+	"strings"
+)
+
+// the annotations of the builtin types and the types of the values they take
+var builtinTypes = map[string][]object.ObjectType{
+	ast.IntType:    {object.INTEGER_OBJ},
+	ast.FloatType:  {object.FLOAT_OBJ},
+	ast.StringType: {object.STRING_OBJ},
+	ast.CharType:   {object.CHAR_OBJ},
+	ast.BoolType:   {object.BOOLEAN_OBJ},
+	ast.ArrayType:  {object.ARRAY_OBJ},
+	ast.MapType:    {object.MAP_OBJ},
+	ast.FnType:     {object.FUNCTION_OBJ, object.BUILTIN_OBJ},
+	ast.NulType:    {object.NUL_OBJ},
+	// a function without a result gives nul
+	ast.VoidType: {object.NUL_OBJ},
+}
+
+// the type of a value the way the annotations write it, an example of this: int, Vec2
+func typeName(value object.Object) string {
+	if value == nil {
+		return ast.NulType
+	}
+	value, _ = object.Cast(value)
+	if instance, ok := value.(*object.StructInstance); ok && instance.Def != nil && len(instance.Def.Name) > 0 {
+		return instance.Def.Name
+	}
+	for name, types := range builtinTypes {
+		if name != ast.VoidType && types[0] == value.Type() {
+			return name
+		}
+	}
+	if value.Type() == object.BUILTIN_OBJ {
+		return ast.FnType
+	}
+	return strings.ToLower(string(value.Type()))
+}
+
+// whether the value fits the annotation, the names of the structs and the enums are resolved
+// in env, the scope the function got declared in, an error when the name isn't a type
+func matchesType(typ *ast.Identifier, value object.Object, env *object.Environment) (bool, *object.Error) {
+	if typ.Value == ast.AnyType {
+		return true, nil
+	}
+	if value == nil {
+		value = object.NUL
+	}
+	value, _ = object.Cast(value)
+
+	if types, ok := builtinTypes[typ.Value]; ok {
+		for _, t := range types {
+			if value.Type() == t {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	item, ok := env.ResolveSymbol(typ.Symbol())
+	// a struct or an enum named Result takes the place of the builtin one
+	if !ok && typ.Value == ast.ResultType {
+		_, ok := object.AsResult(value)
+		return ok, nil
+	}
+	if !ok {
+		return false, newError(ERROR, "unknown type %s", typ.Value)
+	}
+	switch def := item.Object.(type) {
+	case *object.Struct:
+		instance, ok := value.(*object.StructInstance)
+		return ok && instance.Def == def, nil
+	case *object.Enum:
+		member, ok := value.(*object.Integer)
+		if !ok {
+			return false, nil
+		}
+		for _, v := range def.Values {
+			if v == member.Value {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, newError(ERROR, "%s isn't a type, it's a %s", typ.Value, typeName(item.Object))
+}
+
+// the args fitting the annotated params are the boundary between the untyped code and the
+// typed one, the values the parser couldn't check are checked there
+func checkArgTypes(fn *object.Function, args []object.Object) *object.Error {
+	offset := 0
+	if fn.IsMethod() {
+		offset = 1
+	}
+	for idx, typ := range fn.Types {
+		if typ == nil || idx+offset >= len(args) {
+			continue
+		}
+		arg := args[idx+offset]
+		ok, err := matchesType(typ, arg, fn.Env)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return newError(ERROR, "arg %s of %s needs to be %s, got %s", fn.Parameters[idx+offset].Value, fnName(fn), typ.Value, typeName(arg))
+		}
+	}
+	return nil
+}
+
+func checkReturnType(fn *object.Function, result object.Object) *object.Error {
+	ok, err := matchesType(fn.ReturnType, result, fn.Env)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newError(ERROR, "%s needs to return %s, got %s", fnName(fn), fn.ReturnType.Value, typeName(result))
+	}
+	return nil
+}
+
+func fnName(fn *object.Function) string {
+	if len(fn.Name) == 0 {
+		return "fn"
+	}
+	return fn.Name
+}
+
+// x as int, the ints and the floats convert to each other, a float only when it has no fraction,
+// the other values are returned as they are when they are of the type
+func (i *Interpreter) evalCastExpression(nd *ast.CastExpression) object.Object {
+	value := i.Eval(nd.Value)
+	if isError(value) {
+		return value
+	}
+	value, _ = object.Cast(value)
+
+	switch v := value.(type) {
+	case *object.Integer:
+		if nd.Type.Value == ast.FloatType {
+			return &object.Float{Value: float64(v.Value)}
+		}
+	case *object.Float:
+		if nd.Type.Value == ast.IntType {
+			if v.Value != math.Trunc(v.Value) || v.Value < math.MinInt64 || v.Value >= math.MaxInt64 {
+				return newError(ERROR, "%s doesn't fit an int without losing its fraction, use math.floor or math.round", v.Inspect())
+			}
+			return &object.Integer{Value: int64(v.Value)}
+		}
+	}
+
+	ok, err := matchesType(nd.Type, value, i.env)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newError(ERROR, "can't cast %s to %s", typeName(value), nd.Type.Value)
+	}
+	return value
+}
+
+// the arm of match type x, a type name or _
+func (i *Interpreter) matchTypePattern(key object.Object, pattern ast.Expression) (bool, object.Object) {
+	typ, ok := pattern.(*ast.Identifier)
+	if !ok {
+		return false, newError(ERROR, "the arms of match type are type names, got %s", pattern.String())
+	}
+	if typ.Value == "_" {
+		return true, nil
+	}
+	matched, err := matchesType(typ, key, i.env)
+	if err != nil {
+		return false, err
+	}
+	return matched, nil
+}
+
+// let Ok(x) = value else { ... }, the else branch exits, the parser makes sure of it
+func (i *Interpreter) evalLetElseStatement(nd *ast.LetElseStatement) object.Object {
+	value := i.Eval(nd.Value)
+	if isError(value) {
+		return value
+	}
+	result, ok := object.AsResult(value)
+	if !ok {
+		return newError(ERROR, "let %s(...) takes a result made by ok or err, got %s", nd.Variant.Value, typeName(value))
+	}
+
+	if (nd.Variant.Value == "Err") != object.IsErr(result) {
+		i.enterScope()
+		defer i.exitScope()
+		return i.evalBlockStatement(nd.Else)
+	}
+
+	var bound object.Object = &object.String{Value: object.ResultError(result)}
+	if nd.Variant.Value == "Ok" {
+		bound = object.ResultValue(result)
+	}
+	decl := &ast.VarDeclaration{Token: nd.Token, Mutable: true, Name: []*ast.Identifier{nd.Name}}
+	return i.evalVarDeclaration(bound, decl)
+}
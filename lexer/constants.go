@@ -2,6 +2,12 @@ package lexer
 
 type Operator = string
 
+// prefix of the comments that are treated as pragmas
+const PragmaPrefix = "# blk:"
+
+// prefix of the pragma naming the version of the language, followed by a digit: # blk 0.1
+const VersionPragmaPrefix = "# blk "
+
 var (
 	Keywords = map[string]TokenKind{
 		"let":    TokenLet,
@@ -11,6 +17,7 @@ var (
 		"enum":   TokenEnum,
 		"if":     TokenIf,
 		"else":   TokenElse,
+		"guard":  TokenGuard,
 		"use":    TokenUse,
 		"match":  TokenMatch,
 		"fn":     TokenFn,
@@ -19,6 +26,7 @@ var (
 		"while":  TokenWhile,
 		"import": TokenImport,
 		"as":     TokenAs,
+		"is":     TokenIs,
 		"return": TokenReturn,
 		"next":   TokenNext,
 		"break":  TokenBreak,
@@ -8,6 +8,11 @@ import (
 	"unicode/utf8"
 )
 
+// the mark some windows editors start the utf-8 files with
+const byteOrderMark = '\uFEFF'
+
+// the positions of the tokens count the chars, a tab is a single col like the other chars and
+// the excerpts of the diagnostics keep it so the underlines line up, \r\n is a single line break
 func NewLexer(filePath string, content string) *Lexer {
 	lexer := Lexer{
 		Content:  []rune(content),
@@ -16,6 +21,10 @@ func NewLexer(filePath string, content string) *Lexer {
 		Col:      1,
 		Cur:      0,
 	}
+	// skipped rather than dropped, the offsets of the tokens stay the ones of the content
+	if len(lexer.Content) > 0 && lexer.Content[0] == byteOrderMark {
+		lexer.Cur = 1
+	}
 	return &lexer
 }
 
@@ -28,10 +37,12 @@ func (l *Lexer) readChar() {
 
 	char := l.Content[l.Cur]
 
-	switch char {
-	case '\n':
+	switch {
+	case char == '\n':
 		l.Row++
 		l.Col = 1
+	case char == '\r' && l.Cur+1 < len(l.Content) && l.Content[l.Cur+1] == '\n':
+		// the \n that follows breaks the line
 	default:
 		l.Col++
 	}
@@ -44,12 +55,40 @@ type Token struct {
 	LiteralToken
 	Row int
 	Col int
+	// the interned text of the identifiers, left out of the compiled programs since the ids
+	// only hold within a process
+	symbol Symbol
+	// where the token starts and ends in the content, in runes, left out of the compiled
+	// programs like the symbol
+	start, end int
+}
+
+// the rune offsets of the text of the token in the lexed content, the end excluded, the
+// source of the token is Content[start:end], zero for the tokens of the compiled programs
+func (t Token) Offsets() (int, int) {
+	return t.start, t.end
+}
+
+// the symbol of the identifier, interned on the spot for the tokens that weren't lexed in
+// this process, 0 for the other kinds of tokens
+func (t Token) Symbol() Symbol {
+	if t.symbol == 0 && t.Kind == TokenIdentifier {
+		return Intern(t.Text)
+	}
+	return t.symbol
 }
 
 func (l *Lexer) NextToken() Token {
 	l.skipWhiteSpace()
 	l.skipComment()
 
+	start := l.Cur
+	token := l.readToken()
+	token.start, token.end = start, max(start, l.Cur)
+	return token
+}
+
+func (l *Lexer) readToken() Token {
 	token := Token{
 		Row: l.Row,
 		Col: l.Col,
@@ -240,10 +279,16 @@ func (l *Lexer) NextToken() Token {
 			Kind: TokenQuestion,
 			Text: "?",
 		}
+	case TokenAt:
+		l.readChar()
+		token.LiteralToken = LiteralToken{
+			Kind: TokenAt,
+			Text: "@",
+		}
 	case TokenExclamation:
 		l.readChar()
-		equalChar := string(l.Content[l.Cur])
-		if equalChar == TokenAssign {
+		// the ! of read()! can end the file
+		if l.Cur < len(l.Content) && string(l.Content[l.Cur]) == TokenAssign {
 			l.readChar()
 			token.LiteralToken = LiteralToken{
 				Kind: TokenNotEquals,
@@ -425,6 +470,9 @@ func (l *Lexer) NextToken() Token {
 				Text: "|",
 			}
 		}
+	case TokenComment:
+		// only pragmas reach this point, regular comments are skipped
+		return l.readPragma()
 	case TokenQuote:
 		return l.readString()
 	case TokenSingleQuote:
@@ -499,13 +547,15 @@ func (l *Lexer) readIdentifier() Token {
 		}, Row: row, Col: col}
 	}
 
+	symbol, text := intern(text)
 	return Token{
 		LiteralToken: LiteralToken{
 			Kind: TokenIdentifier,
-			Text: string(text),
+			Text: text,
 		},
-		Row: row,
-		Col: col,
+		Row:    row,
+		Col:    col,
+		symbol: symbol,
 	}
 }
 
@@ -547,7 +597,8 @@ func (l *Lexer) readString() Token {
 	start := l.Cur + 1 // skip the opening quote
 	row, col := l.Row, l.Col
 
-	l.Cur++
+	// through readChar so the cols of the next tokens account for the quote
+	l.readChar()
 
 	for l.Cur < len(l.Content) && l.Content[l.Cur] != '"' {
 		ch := l.Content[l.Cur]
@@ -589,7 +640,7 @@ func (l *Lexer) readRune() Token {
 	start := l.Cur + 1 // skip the opening quote
 	row, col := l.Row, l.Col
 
-	l.Cur++
+	l.readChar()
 
 	for l.Cur < len(l.Content) && l.Content[l.Cur] != '\'' {
 		ch := l.Content[l.Cur]
@@ -637,7 +688,7 @@ func (l *Lexer) readRawString() Token {
 	start := l.Cur + 1 // skip the opening quote
 	row, col := l.Row, l.Col
 
-	l.Cur++
+	l.readChar()
 
 	for l.Cur < len(l.Content) && l.Content[l.Cur] != '`' {
 		l.readChar()
@@ -651,8 +702,8 @@ func (l *Lexer) readRawString() Token {
 	end := l.Cur
 	l.readChar() // consume the closing backtick
 
-	// keep raw, no unescaping
-	text := string(l.Content[start:end])
+	// keep raw, no unescaping, the line breaks are the same on every os
+	text := strings.ReplaceAll(string(l.Content[start:end]), "\r\n", "\n")
 
 	return Token{
 		LiteralToken: LiteralToken{
@@ -716,8 +767,47 @@ func (l *Lexer) readNumber() Token {
 	}
 }
 
+func (l *Lexer) isPragma() bool {
+	end := min(l.Cur+len(PragmaPrefix), len(l.Content))
+	return string(l.Content[l.Cur:end]) == PragmaPrefix || l.isVersionPragma()
+}
+
+func (l *Lexer) isVersionPragma() bool {
+	end := l.Cur + len(VersionPragmaPrefix)
+	return end < len(l.Content) && string(l.Content[l.Cur:end]) == VersionPragmaPrefix && unicode.IsDigit(l.Content[end])
+}
+
+// reads a pragma comment, the text of the token is what comes after the # blk: prefix
+// an example of this: # blk:feature match, gives a token with text "feature match"
+// the version pragma reads like the version directive, # blk 0.1 gives "version 0.1"
+func (l *Lexer) readPragma() Token {
+	row, col := l.Row, l.Col
+
+	prefix, directive := PragmaPrefix, ""
+	if l.isVersionPragma() {
+		prefix, directive = VersionPragmaPrefix, "version "
+	}
+	for range len(prefix) {
+		l.readChar()
+	}
+
+	start := l.Cur
+	for l.Cur < len(l.Content) && l.Content[l.Cur] != '\n' {
+		l.readChar()
+	}
+
+	return Token{
+		LiteralToken: LiteralToken{
+			Kind: TokenPragma,
+			Text: directive + strings.TrimSpace(string(l.Content[start:l.Cur])),
+		},
+		Row: row,
+		Col: col,
+	}
+}
+
 func (l *Lexer) skipComment() {
-	for l.Cur < len(l.Content) && l.Content[l.Cur] == '#' {
+	for l.Cur < len(l.Content) && l.Content[l.Cur] == '#' && !l.isPragma() {
 		for l.Cur < len(l.Content) && l.Content[l.Cur] != '\n' {
 			l.readChar()
 		}
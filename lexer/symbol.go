@@ -0,0 +1,56 @@
+package lexer
+
+import "sync"
+
+// the id of an interned identifier, the environments are keyed by them so a lookup hashes
+// an int instead of the name, 0 is the id of no identifier
+type Symbol uint32
+
+// the table is shared by every lexer, an identifier has the same symbol in all the modules
+var symbols = &symbolTable{ids: map[string]Symbol{}, names: []string{""}}
+
+type symbolTable struct {
+	sync.RWMutex
+	ids   map[string]Symbol
+	names []string
+}
+
+func (t *symbolTable) name(sym Symbol) string {
+	t.RLock()
+	defer t.RUnlock()
+	if int(sym) >= len(t.names) {
+		return ""
+	}
+	return t.names[sym]
+}
+
+// the symbol of the name, added to the table the first time it's seen
+func Intern(name string) Symbol {
+	sym, _ := intern(name)
+	return sym
+}
+
+// the symbol and the copy of the name the table holds, the tokens of an identifier share it
+func intern(name string) (Symbol, string) {
+	symbols.RLock()
+	sym, ok := symbols.ids[name]
+	symbols.RUnlock()
+	if ok {
+		return sym, symbols.name(sym)
+	}
+
+	symbols.Lock()
+	defer symbols.Unlock()
+	if sym, ok := symbols.ids[name]; ok {
+		return sym, symbols.names[sym]
+	}
+	sym = Symbol(len(symbols.names))
+	symbols.ids[name] = sym
+	symbols.names = append(symbols.names, name)
+	return sym, name
+}
+
+// the name the symbol got interned from
+func (s Symbol) String() string {
+	return symbols.name(s)
+}
@@ -19,10 +19,12 @@ const (
 	TokenUse    TokenKind = "use"
 	TokenIf     TokenKind = "if"
 	TokenElse   TokenKind = "else"
+	TokenGuard  TokenKind = "guard"
 	TokenMatch  TokenKind = "match"
 	TokenReturn TokenKind = "return"
 	TokenImport TokenKind = "import"
 	TokenAs     TokenKind = "as"
+	TokenIs     TokenKind = "is"
 
 	// nul values
 	TokenNul TokenKind = "nul"
@@ -42,6 +44,7 @@ const (
 	TokenDot             TokenKind = "."
 	TokenRange           TokenKind = ".."
 	TokenQuestion        TokenKind = "?"
+	TokenAt              TokenKind = "@"
 
 	// Arithmetic Operators
 	TokenMinus          TokenKind = "-"
@@ -90,6 +93,8 @@ const (
 
 	// Comment
 	TokenComment TokenKind = "#"
+	// Pragma is a special comment (# blk:<name> <args>) holding directives for the interpreter
+	TokenPragma TokenKind = "pragma"
 
 	// Var Naming
 	TokenIdentifier TokenKind = "identifier"
@@ -0,0 +1,103 @@
+package minify
+
+import (
+	"blk/lexer"
+	"blk/parser"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// the source of a program doing the same thing with the comments dropped, the blanks compacted
+// and the locals of the functions renamed to short names
+// the parser tells the expressions apart by their rows, so the line breaks between tokens are
+// kept, the indentation, the blank lines and the spaces that don't separate anything aren't
+func Minify(path, source string) (string, error) {
+	content := []rune(source)
+	tokens := lexer.NewLexer(path, source).Tokenize()
+
+	p := parser.NewParser(tokens, path)
+	p.Parse()
+	if len(p.Errors) > 0 {
+		return "", p.Errors[0]
+	}
+
+	tokens = tokens[:len(tokens)-1] // the eof
+	renames := renameLocals(tokens)
+
+	var out strings.Builder
+	for idx, tok := range tokens {
+		start, end := tok.Offsets()
+		text := string(content[start:end])
+		if name, ok := renames[idx]; ok {
+			text = name
+		}
+
+		if idx > 0 {
+			_, prevEnd := tokens[idx-1].Offsets()
+			between := string(content[prevEnd:start])
+			switch {
+			case strings.Contains(between, "\n"):
+				out.WriteString("\n")
+			case len(between) > 0 && needsSpace(out.String(), text):
+				out.WriteString(" ")
+			}
+		}
+		out.WriteString(text)
+	}
+	out.WriteString("\n")
+
+	minified := out.String()
+	if err := verify(tokens, renames, minified); err != nil {
+		return "", err
+	}
+	return minified, nil
+}
+
+// whether the two texts would lex as other tokens once glued, a conservative guess,
+// verify is what makes sure of it
+func needsSpace(before, after string) bool {
+	last := []rune(before)
+	if len(last) == 0 {
+		return false
+	}
+	a, b := last[len(last)-1], []rune(after)[0]
+	switch {
+	case isWord(a) && isWord(b):
+		return true
+	case isOperator(a) && isOperator(b):
+		return true
+	case unicode.IsDigit(a) && b == '.':
+		return true
+	}
+	return false
+}
+
+func isWord(c rune) bool { return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' }
+
+func isOperator(c rune) bool { return strings.ContainsRune("+-*/%=<>!&|^~:.?@#", c) }
+
+// lexes the minified source again, it has to give the same tokens, the renamed ones aside,
+// with the same line breaks between them
+func verify(tokens []lexer.Token, renames map[int]string, minified string) error {
+	relexed := lexer.NewLexer("", minified).Tokenize()
+	relexed = relexed[:len(relexed)-1]
+	if len(relexed) != len(tokens) {
+		return fmt.Errorf("the minified program has %d tokens instead of %d", len(relexed), len(tokens))
+	}
+
+	for idx, tok := range tokens {
+		text := tok.Text
+		if name, ok := renames[idx]; ok {
+			text = name
+		}
+		got := relexed[idx]
+		if got.Kind != tok.Kind || got.Text != text {
+			return fmt.Errorf("%d:%d: the minified program has %s %q instead of %s %q", tok.Row, tok.Col, got.Kind, got.Text, tok.Kind, text)
+		}
+		if idx > 0 && (got.Row == relexed[idx-1].Row) != (tok.Row == tokens[idx-1].Row) {
+			return fmt.Errorf("%d:%d: the minified program breaks the line at another place", tok.Row, tok.Col)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package minify
+
+import (
+	"blk/lexer"
+	"strconv"
+)
+
+// a function, from its fn token to the closing brace of its body, the functions nested in
+// it are part of it
+type span struct{ start, end int }
+
+func (s span) contains(idx int) bool { return idx >= s.start && idx <= s.end }
+
+// the new names of the identifiers, by token index
+// the params and the variables a top level function declares get a name no other identifier
+// of the file has, every token holding one of them in the function is renamed, the nested
+// functions included, so the shadowing stays the same
+// a name is kept when the top level uses it too (globals, struct fields, modules), or when the
+// function uses it as a property (after a .) or as a key (before a :), those aren't variables
+func renameLocals(tokens []lexer.Token) map[int]string {
+	functions := topLevelFunctions(tokens)
+
+	used := make(map[string]bool)
+	topLevel := make(map[string]bool)
+	for idx, tok := range tokens {
+		if tok.Kind != lexer.TokenIdentifier {
+			continue
+		}
+		used[tok.Text] = true
+		if !inAny(functions, idx) {
+			topLevel[tok.Text] = true
+		}
+	}
+	// fn name(...) declares a global
+	for _, fn := range functions {
+		if tokens[fn.start+1].Kind == lexer.TokenIdentifier {
+			topLevel[tokens[fn.start+1].Text] = true
+		}
+	}
+
+	names := &nameGenerator{used: used}
+	renames := make(map[int]string)
+	for _, fn := range functions {
+		kept := make(map[string]bool)
+		for idx := fn.start; idx <= fn.end; idx++ {
+			if tokens[idx].Kind != lexer.TokenIdentifier {
+				continue
+			}
+			if tokens[idx-1].Kind == lexer.TokenDot || (idx+1 < len(tokens) && tokens[idx+1].Kind == lexer.TokenColon) {
+				kept[tokens[idx].Text] = true
+			}
+		}
+
+		locals := make(map[string]string)
+		for _, name := range declaredNames(tokens, fn) {
+			if _, ok := locals[name]; ok || kept[name] || topLevel[name] {
+				continue
+			}
+			locals[name] = names.next()
+		}
+
+		for idx := fn.start; idx <= fn.end; idx++ {
+			if name, ok := locals[tokens[idx].Text]; ok && tokens[idx].Kind == lexer.TokenIdentifier {
+				renames[idx] = name
+			}
+		}
+	}
+	return renames
+}
+
+func inAny(spans []span, idx int) bool {
+	for _, s := range spans {
+		if s.contains(idx) {
+			return true
+		}
+	}
+	return false
+}
+
+// the functions that aren't nested in another one
+func topLevelFunctions(tokens []lexer.Token) []span {
+	var functions []span
+	for idx := 0; idx < len(tokens); idx++ {
+		if tokens[idx].Kind != lexer.TokenFn {
+			continue
+		}
+		end, ok := functionEnd(tokens, idx)
+		if !ok {
+			continue
+		}
+		functions = append(functions, span{start: idx, end: end})
+		idx = end
+	}
+	return functions
+}
+
+// the index of the closing brace of the body of the function starting at fn
+func functionEnd(tokens []lexer.Token, fn int) (int, bool) {
+	idx := fn + 1
+	for idx < len(tokens) && tokens[idx].Kind != lexer.TokenBraceClose {
+		idx++
+	}
+	for idx < len(tokens) && tokens[idx].Kind != lexer.TokenCurlyBraceOpen {
+		idx++
+	}
+
+	depth := 0
+	for ; idx < len(tokens); idx++ {
+		switch tokens[idx].Kind {
+		case lexer.TokenCurlyBraceOpen:
+			depth++
+		case lexer.TokenCurlyBraceClose:
+			depth--
+			if depth == 0 {
+				return idx, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// the params of the function and of the ones nested in it, and the names they declare
+// with :=, ::, let, const and for
+func declaredNames(tokens []lexer.Token, fn span) []string {
+	var names []string
+	// the identifiers of a list, an example of this: a, b in a, b := 1, 2
+	list := func(idx, step int) {
+		for ; fn.contains(idx) && tokens[idx].Kind == lexer.TokenIdentifier; idx += 2 * step {
+			names = append(names, tokens[idx].Text)
+			if !fn.contains(idx+step) || tokens[idx+step].Kind != lexer.TokenComma {
+				return
+			}
+		}
+	}
+
+	for idx := fn.start; idx <= fn.end; idx++ {
+		switch tokens[idx].Kind {
+		case lexer.TokenFn:
+			// the name of a fn declaration is declared in the scope around it, like a const
+			start := idx + 1
+			if tokens[start].Kind == lexer.TokenIdentifier {
+				if idx > fn.start {
+					names = append(names, tokens[start].Text)
+				}
+				start++
+			}
+			if tokens[start].Kind != lexer.TokenBraceOpen {
+				continue
+			}
+			start++
+			if tokens[start].Kind == lexer.TokenSelf {
+				start += 2
+			}
+			list(start, 1)
+		case lexer.TokenWalrus, lexer.TokenBind:
+			list(idx-1, -1)
+		case lexer.TokenLet, lexer.TokenConst, lexer.TokenFor:
+			list(idx+1, 1)
+		}
+	}
+	return names
+}
+
+// short names that no identifier of the file has and that aren't keywords
+type nameGenerator struct {
+	used  map[string]bool
+	count int
+}
+
+func (g *nameGenerator) next() string {
+	for {
+		name := shortName(g.count)
+		g.count++
+		if _, keyword := lexer.Keywords[name]; !keyword && !g.used[name] {
+			g.used[name] = true
+			return name
+		}
+	}
+}
+
+// a, b, ..., z, a0, b0, ..., z0, a1, ...
+func shortName(n int) string {
+	name := string(rune('a' + n%26))
+	if n >= 26 {
+		name += strconv.Itoa(n/26 - 1)
+	}
+	return name
+}
@@ -0,0 +1,202 @@
+package object
+
+import (
+	"fmt"
+	"math"
+)
+
+// version of the native module abi below, it only changes when Value or the
+// native conversions break, new capabilities come as optional interfaces instead
+// a module built against an older abi keeps loading, a newer one is refused
+const ABIVersion = 1
+
+const FOREIGN_OBJ = "FOREIGN"
+
+// frozen view of a value owned by a native module (go plugin, embedder package)
+//
+// the signatures only use builtin go types on purpose: go refuses to load a plugin
+// that shares a package with the host unless both were built from the same sources,
+// so a native module implements Value without importing blk at all
+//
+// values crossing the boundary are converted with ToNative and FromNative:
+//
+//	nul => nil, bool => bool, int => int64, float => float64, string/char => string
+//	array => []any, map => map[string]any (keys are inspected), Value => itself
+type Value interface {
+	// type name shown to blk programs, an example of this: sqlite.Conn
+	Type() string
+	Inspect() string
+	// other is already converted to its native form
+	Equals(other any) bool
+	// lets the value be used as a map key
+	Hash() uint64
+	// args are already converted to their native form, values that aren't
+	// callable return an error
+	Call(args []any) (any, error)
+}
+
+// signature of the functions a native module exposes
+type NativeFunction = func(args []any) (any, error)
+
+// a native Value living inside the interpreter
+type Foreign struct {
+	EmptyObjImplementation
+	Value Value
+}
+
+func (f *Foreign) Type() ObjectType { return FOREIGN_OBJ }
+func (f *Foreign) Inspect() string  { return f.Value.Inspect() }
+
+// native values manage their own state, copies share it
+func (f *Foreign) Copy() Object { return f }
+
+func (f *Foreign) Equals(other Object) bool {
+	other, _ = Cast(other)
+	native, err := ToNative(other)
+	if err != nil {
+		return false
+	}
+	return f.Value.Equals(native)
+}
+
+func (f *Foreign) HashKey() HashKey {
+	return HashKey{Type: FOREIGN_OBJ, Value: float64(f.Value.Hash())}
+}
+
+func (f *Foreign) Call(args []Object) Object {
+	return callNative(f.Value.Type(), f.Value.Call, args)
+}
+
+// wraps a native function, name prefixes the errors it returns
+func NativeBuiltin(name string, fn NativeFunction) *BuiltinFn {
+	return &BuiltinFn{Fn: func(args ...Object) Object {
+		return callNative(name, fn, args)
+	}}
+}
+
+// converts the args, calls fn and converts its result back, errors become blk errors
+func callNative(name string, fn NativeFunction, args []Object) Object {
+	natives := make([]any, len(args))
+	for idx, arg := range args {
+		native, err := ToNative(arg)
+		if err != nil {
+			return &Error{Message: fmt.Sprintf("%s: arg %d: %v", name, idx+1, err)}
+		}
+		natives[idx] = native
+	}
+
+	result, err := fn(natives)
+	if err != nil {
+		return &Error{Message: fmt.Sprintf("%s: %v", name, err)}
+	}
+
+	obj, err := FromNative(result)
+	if err != nil {
+		return &Error{Message: fmt.Sprintf("%s: %v", name, err)}
+	}
+	return obj
+}
+
+// the native form of a blk value, see Value for the mapping
+func ToNative(obj Object) (any, error) {
+	obj, _ = Cast(obj)
+
+	switch obj := obj.(type) {
+	case *Nul:
+		return nil, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *Integer:
+		return obj.Value, nil
+	case *Float:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Char:
+		return string(obj.Value), nil
+	case *Foreign:
+		return obj.Value, nil
+	case *Array:
+		elements := make([]any, len(obj.Elements))
+		for idx, elem := range obj.Elements {
+			native, err := ToNative(elem)
+			if err != nil {
+				return nil, err
+			}
+			elements[idx] = native
+		}
+		return elements, nil
+	case *Map:
+		pairs := make(map[string]any, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			native, err := ToNative(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			key := pair.Key.Inspect()
+			if str, ok := pair.Key.(*String); ok {
+				key = str.Value
+			}
+			pairs[key] = native
+		}
+		return pairs, nil
+	default:
+		return nil, fmt.Errorf("values of type %s can't be passed to native modules", obj.Type())
+	}
+}
+
+// the blk form of a native value, see Value for the mapping
+func FromNative(value any) (Object, error) {
+	switch value := value.(type) {
+	case nil:
+		return NUL, nil
+	case bool:
+		return nativeBooleanObject(value), nil
+	case int:
+		return &Integer{Value: int64(value)}, nil
+	case int64:
+		return &Integer{Value: value}, nil
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("float %v has no blk representation", value)
+		}
+		return &Float{Value: value}, nil
+	case string:
+		return &String{Value: value}, nil
+	case Value:
+		return &Foreign{Value: value}, nil
+	case NativeFunction:
+		return NativeBuiltin("native function", value), nil
+	case []any:
+		elements := make([]Object, len(value))
+		for idx, elem := range value {
+			obj, err := FromNative(elem)
+			if err != nil {
+				return nil, err
+			}
+			if idx > 0 && obj.Type() != elements[0].Type() {
+				return nil, fmt.Errorf("array elements need to share a type, got=%s and %s", elements[0].Type(), obj.Type())
+			}
+			elements[idx] = obj
+		}
+		return &Array{Size: -1, Elements: elements}, nil
+	case map[string]any:
+		pairs := make(PairsType, len(value))
+		var valueType ObjectType
+		for key, elem := range value {
+			obj, err := FromNative(elem)
+			if err != nil {
+				return nil, err
+			}
+			if len(valueType) > 0 && obj.Type() != valueType {
+				return nil, fmt.Errorf("map values need to share a type, got=%s and %s", valueType, obj.Type())
+			}
+			valueType = obj.Type()
+			k := &String{Value: key}
+			pairs[k.HashKey()] = HashPair{Key: k, Value: obj}
+		}
+		return &Map{Pairs: pairs}, nil
+	default:
+		return nil, fmt.Errorf("native values of type %T have no blk representation", value)
+	}
+}
@@ -0,0 +1,121 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// values can refer to themselves through struct fields, an example of this: node.next = node
+// the walkers below keep track of the containers they're in to stop on the cycles
+
+// the containers already being inspected higher up print as ... instead of looping
+func inspect(obj Object, path map[Object]bool) string {
+	obj, _ = Cast(obj)
+
+	switch obj := obj.(type) {
+	case *Array:
+		if path[obj] {
+			return "[...]"
+		}
+		path[obj] = true
+		defer delete(path, obj)
+
+		var out bytes.Buffer
+		out.WriteString("[")
+		for idx, elem := range obj.Elements {
+			out.WriteString(inspect(elem, path))
+			if idx+1 <= len(obj.Elements)-1 {
+				out.WriteString(", ")
+			}
+		}
+		out.WriteString("]")
+		return out.String()
+
+	case *Map:
+		if path[obj] {
+			return "{...}"
+		}
+		path[obj] = true
+		defer delete(path, obj)
+
+		var out bytes.Buffer
+		pairs := []string{}
+		for _, pair := range obj.SortedPairs() {
+			pairs = append(pairs, fmt.Sprintf("%s: %s",
+				pair.Key.Inspect(), inspect(pair.Value, path)))
+		}
+		out.WriteString("{")
+		out.WriteString(strings.Join(pairs, ", "))
+		out.WriteString("}")
+		return out.String()
+
+	case *StructInstance:
+		if path[obj] {
+			return "struct {...}"
+		}
+		path[obj] = true
+		defer delete(path, obj)
+
+		var out bytes.Buffer
+		out.WriteString("struct {")
+		for _, name := range obj.FieldNames() {
+			out.WriteString(name + " := " + inspect(obj.Fields[name], path) + ", ")
+		}
+		for _, name := range obj.MethodNames() {
+			out.WriteString(name + " : " + obj.Methods[name].Inspect() + ", ")
+		}
+		out.WriteString("}")
+		return out.String()
+
+	default:
+		return obj.Inspect()
+	}
+}
+
+// deep copies obj, a container met twice is copied once so the copy keeps the cycles of the original
+func copyObject(obj Object, copies map[Object]Object) Object {
+	obj, _ = Cast(obj)
+	if copied, ok := copies[obj]; ok {
+		return copied
+	}
+
+	switch obj := obj.(type) {
+	case *Array:
+		arr := &Array{
+			Size:     obj.Size,
+			Elements: make([]Object, 0, len(obj.Elements)),
+		}
+		copies[obj] = arr
+		for _, v := range obj.Elements {
+			arr.Elements = append(arr.Elements, copyObject(v, copies))
+		}
+		return arr
+
+	case *Map:
+		m := &Map{Pairs: make(PairsType, len(obj.Pairs))}
+		copies[obj] = m
+		for k, v := range obj.Pairs {
+			m.Pairs[k] = HashPair{
+				Key:   v.Key.Copy(),
+				Value: copyObject(v.Value, copies),
+			}
+		}
+		return m
+
+	case *StructInstance:
+		strct := &StructInstance{
+			Def:     obj.Def,
+			Fields:  make(map[string]Object, len(obj.Fields)),
+			Methods: obj.Methods,
+		}
+		copies[obj] = strct
+		for k, v := range obj.Fields {
+			strct.Fields[k] = copyObject(v, copies)
+		}
+		return strct
+
+	default:
+		return obj.Copy()
+	}
+}
@@ -1,48 +1,129 @@
 package object
 
+import (
+	"blk/diagnostics"
+	"blk/lexer"
+	"strings"
+)
+
 type ItemObject struct {
 	Object
 	IsMutable bool
 	IsBuiltIn bool // this is useful for builtin function & default value into the language it self
+	// set when the symbol was declared with the @deprecated annotation
+	IsDeprecated    bool
+	DeprecationNote string
+	// where the symbol got declared, zero for the builtins and imported modules
+	Declaration diagnostics.Span
 }
 
 type Environment struct {
 	outer *Environment
-	store map[string]ItemObject
+	store map[lexer.Symbol]ItemObject
+	// the outermost scope, the one holding the globals, itself for the globals
+	root *Environment
+	// on the root only, the names some inner scope defined at some point, the other names
+	// can only be globals so their lookups skip the scopes in between
+	inner map[lexer.Symbol]bool
 }
 
 func NewEnvironment(outer *Environment) *Environment {
-	s := make(map[string]ItemObject)
-	return &Environment{
+	s := make(map[lexer.Symbol]ItemObject)
+	env := &Environment{
 		outer: outer,
 		store: s,
 	}
+	if outer == nil {
+		env.root = env
+		env.inner = make(map[lexer.Symbol]bool)
+	} else {
+		env.root = outer.root
+	}
+	return env
 }
 
+// the bindings of the scope by name, a copy so writing to it doesn't change the scope
 func (e *Environment) GetStore() map[string]ItemObject {
-	return e.store
+	store := make(map[string]ItemObject, len(e.store))
+	for sym, item := range e.store {
+		store[sym.String()] = item
+	}
+	return store
+}
+
+// the bindings of the scope a module exports, the names that don't start with _
+func (e *Environment) Exports() map[string]Object {
+	exports := make(map[string]Object)
+	for sym, item := range e.store {
+		if name := sym.String(); !strings.HasPrefix(name, "_") {
+			exports[name] = item
+		}
+	}
+	return exports
 }
 
 func (e *Environment) Resolve(name string) (ItemObject, bool) {
-	obj, ok := e.store[name]
-	if !ok && e.outer != nil {
-		obj, ok = e.outer.Resolve(name)
+	return e.ResolveSymbol(lexer.Intern(name))
+}
+
+func (e *Environment) ResolveSymbol(sym lexer.Symbol) (ItemObject, bool) {
+	obj, ok := e.store[sym]
+	if ok || e.outer == nil {
+		return obj, ok
+	}
+	if !e.root.inner[sym] {
+		obj, ok = e.root.store[sym]
+		return obj, ok
+	}
+	return e.outer.ResolveSymbol(sym)
+}
+
+func (e *Environment) bind(sym lexer.Symbol, val ItemObject) {
+	if e.root != e && !e.root.inner[sym] {
+		e.root.inner[sym] = true
 	}
-	return obj, ok
+	e.store[sym] = val
+}
+
+func (e *Environment) Define(name string, val ItemObject) (ItemObject, bool) {
+	return e.DefineSymbol(lexer.Intern(name), val)
 }
 
-func (e *Environment) Define(name string, val ItemObject) (Object, bool) {
-	if _, ok := e.store[name]; ok {
-		return val, true
+func (e *Environment) DefineSymbol(sym lexer.Symbol, val ItemObject) (ItemObject, bool) {
+	if existing, ok := e.store[sym]; ok {
+		// the symbol declared first, so redeclarations can point to it
+		return existing, true
 	}
 	// define if there no value already bound to it
-	e.store[name] = val
+	e.bind(sym, val)
 	// second return types is to indicate if the value is already there or first declare
 	return val, false
 }
 
 func (e *Environment) OverrideDefine(name string, val ItemObject) Object {
-	e.store[name] = val
+	return e.OverrideDefineSymbol(lexer.Intern(name), val)
+}
+
+func (e *Environment) OverrideDefineSymbol(sym lexer.Symbol, val ItemObject) Object {
+	e.bind(sym, val)
+	return val
+}
+
+// rebinds the name in the scope that declared it, the current one when none did, so
+// assignments in blocks and closures change the variable instead of shadowing it
+func (e *Environment) Assign(name string, val ItemObject) Object {
+	return e.AssignSymbol(lexer.Intern(name), val)
+}
+
+func (e *Environment) AssignSymbol(sym lexer.Symbol, val ItemObject) Object {
+	for env := e; env != nil; env = env.outer {
+		if existing, ok := env.store[sym]; ok {
+			val.Declaration = existing.Declaration
+			env.store[sym] = val
+			return val
+		}
+	}
+	e.bind(sym, val)
 	return val
 }
 
@@ -2,10 +2,14 @@ package object
 
 import (
 	"blk/ast"
+	"blk/diagnostics"
 	"blk/lexer"
 	"bytes"
+	"cmp"
 	"fmt"
 	"hash/fnv"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -28,14 +32,21 @@ const (
 	BREAK_OBJ           = "BREAK"
 	STRUCT_OBJ          = "STRUCT"
 	STRUCT_INSTANCE_OBJ = "STRUCT_INSTANCE"
+	ENUM_OBJ            = "ENUM"
 	BUILTIN_MODULE      = "BUILTIN_MODULE"
 	USER_MODULE         = "USER_MODULE"
 	BUILTIN_OBJ         = "BUILTIN"
+	STREAM_OBJ          = "STREAM"
 
 	// errors
 	ERROR_OBJ = "ERROR"
 )
 
+// kinds of errors that programs can tell apart from the generic ones
+const (
+	PermissionError = "PermissionError"
+)
+
 type HashKey struct {
 	Type  ObjectType
 	Value float64
@@ -317,8 +328,28 @@ type Float struct {
 	Value float64
 }
 
+// how the floats get printed
+type FloatFormat struct {
+	Precision  int  // digits after the point, -1 for as few as it takes to read the same float back
+	Scientific bool // 1.234560e+03 instead of 1234.560000
+}
+
+func (f FloatFormat) Format(value float64) string {
+	verb := byte('f')
+	if f.Scientific {
+		verb = 'e'
+	}
+	return strconv.FormatFloat(value, verb, f.Precision, 64)
+}
+
+// the format of Float.Inspect, fmt.set_float_format changes it for the whole program
+var FloatOutput = DefaultFloatFormat
+
+// 6 digits after the point, like %f
+var DefaultFloatFormat = FloatFormat{Precision: 6}
+
 func (b *Float) Type() ObjectType { return FLOAT_OBJ }
-func (b *Float) Inspect() string  { return fmt.Sprintf("%f", b.Value) }
+func (b *Float) Inspect() string  { return FloatOutput.Format(b.Value) }
 func (i *Float) Copy() Object {
 	return &Float{
 		Value: i.Value,
@@ -645,11 +676,44 @@ func (rv *ReturnValue) Inspect() string {
 type Function struct {
 	EmptyObjImplementation
 	Parameters []*ast.Identifier
+	// the type annotations of the params without self, nil when none is annotated
+	Types      []*ast.Identifier
+	ReturnType *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	File       string      // path of the file where the function was declared
+	Token      lexer.Token // the fn keyword of the declaration
+	// the name it got declared with, Struct.method for the methods, empty for the anonymous ones
+	Name string
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+
+// whether the first param is self, the instance is passed to it without being written in the call
+func (f *Function) IsMethod() bool {
+	return len(f.Parameters) > 0 && f.Parameters[0].Value == lexer.TokenSelf
+}
+
+// the name and the params as written in the call, self is left out, an example of this: add(a, b)
+func (f *Function) Signature() string {
+	params := f.Parameters
+	if f.IsMethod() {
+		params = params[1:]
+	}
+	names := make([]string, len(params))
+	for idx, param := range params {
+		names[idx] = param.Value
+	}
+	name := f.Name
+	if len(name) == 0 {
+		name = "fn"
+	}
+	return name + "(" + strings.Join(names, ", ") + ")"
+}
+
+// functions are passed around by reference, the copy shares the closure env
+func (f *Function) Copy() Object { return f }
+
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 	params := []string{}
@@ -708,18 +772,7 @@ type Array struct {
 }
 
 func (a *Array) Type() ObjectType { return ARRAY_OBJ }
-func (a *Array) Inspect() string {
-	var out bytes.Buffer
-	out.WriteString("[")
-	for idx, elem := range a.Elements {
-		out.WriteString(elem.Inspect())
-		if idx+1 <= len(a.Elements)-1 {
-			out.WriteString(", ")
-		}
-	}
-	out.WriteString("]")
-	return out.String()
-}
+func (a *Array) Inspect() string  { return inspect(a, map[Object]bool{}) }
 func (i *Array) Equals(v Object) bool {
 	bVal, ok := v.(*Array)
 	if !ok {
@@ -736,18 +789,72 @@ func (i *Array) Equals(v Object) bool {
 	}
 	return true
 }
-func (i *Array) Copy() Object {
-	elements := make([]Object, 0)
+func (i *Array) Binary(op lexer.TokenKind, r Object) Object {
+	switch op {
+	case lexer.TokenPlus:
+		right, ok := r.(*Array)
+		if !ok {
+			break
+		}
+		if len(i.Elements) > 0 && len(right.Elements) > 0 &&
+			!ObjectTypesCheck(i.Elements[0], right.Elements[0], true) {
+			return newError(ERROR, "can't concatenate arrays of different element types (%s, %s)",
+				i.Elements[0].Type(), right.Elements[0].Type())
+		}
+
+		elements := make([]Object, 0, len(i.Elements)+len(right.Elements))
+		for _, elem := range i.Elements {
+			elements = append(elements, elem.Copy())
+		}
+		for _, elem := range right.Elements {
+			elements = append(elements, elem.Copy())
+		}
+		return &Array{Size: concatSize(i, right, len(elements)), Elements: elements}
 
-	for _, v := range i.Elements {
-		elements = append(elements, v.Copy())
+	case lexer.TokenMultiply:
+		count, ok := r.(*Integer)
+		if !ok {
+			break
+		}
+		if count.Value < 0 {
+			return newError(ERROR, "can't repeat an array a negative number of times, got=%d", count.Value)
+		}
+
+		elements := make([]Object, 0, len(i.Elements)*int(count.Value))
+		for range count.Value {
+			// every repetition gets its own copy, [[0]] * 2 doesn't share the inner array
+			for _, elem := range i.Elements {
+				elements = append(elements, elem.Copy())
+			}
+		}
+		size := -1
+		if i.Size != -1 {
+			size = len(elements)
+		}
+		return &Array{Size: size, Elements: elements}
+
+	case lexer.TokenEquals:
+		if right, ok := r.(*Array); ok {
+			return nativeBooleanObject(i.Equals(right))
+		}
+	case lexer.TokenNotEquals:
+		if right, ok := r.(*Array); ok {
+			return nativeBooleanObject(!i.Equals(right))
+		}
 	}
 
-	return &Array{
-		Size:     i.Size,
-		Elements: elements,
+	return newError(ERROR, "Unsupported operation %s %s %s", i.Type(), op, r.Type())
+}
+
+// a concatenation is only fixed in size when both sides are
+func concatSize(a, b *Array, length int) int {
+	if a.Size == -1 || b.Size == -1 {
+		return -1
 	}
+	return length
 }
+
+func (i *Array) Copy() Object { return copyObject(i, map[Object]Object{}) }
 func (i *Array) Iter() []IterationItem {
 	elements := make([]IterationItem, 0)
 
@@ -785,32 +892,42 @@ type Map struct {
 }
 
 func (a *Map) Type() ObjectType { return MAP_OBJ }
-func (a *Map) Inspect() string {
-	var out bytes.Buffer
-	pairs := []string{}
-	for _, pair := range a.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s",
-			pair.Key.Inspect(), pair.Value.Inspect()))
-	}
-	out.WriteString("{")
-	out.WriteString(strings.Join(pairs, ", "))
-	out.WriteString("}")
-	return out.String()
-}
+func (a *Map) Inspect() string  { return inspect(a, map[Object]bool{}) }
 
-func (i *Map) Copy() Object {
-	pairs := make(PairsType, 0)
+func (i *Map) Copy() Object { return copyObject(i, map[Object]Object{}) }
 
-	for k, v := range i.Pairs {
-		pairs[k] = HashPair{
-			Key:   v.Key.Copy(),
-			Value: v.Value.Copy(),
-		}
+// the pairs ordered by key, the numbers by value, before the other keys ordered by type then text
+func (i *Map) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(i.Pairs))
+	for _, pair := range i.Pairs {
+		pairs = append(pairs, pair)
 	}
+	slices.SortFunc(pairs, func(a, b HashPair) int { return compareKeys(a.Key, b.Key) })
+	return pairs
+}
+
+func compareKeys(a, b Object) int {
+	aNum, aIsNum := numberValue(a)
+	bNum, bIsNum := numberValue(b)
+	switch {
+	case aIsNum && bIsNum:
+		return cmp.Or(cmp.Compare(aNum, bNum), cmp.Compare(a.Type(), b.Type()))
+	case aIsNum:
+		return -1
+	case bIsNum:
+		return 1
+	}
+	return cmp.Or(cmp.Compare(a.Type(), b.Type()), cmp.Compare(a.Inspect(), b.Inspect()))
+}
 
-	return &Map{
-		Pairs: pairs,
+func numberValue(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *Float:
+		return obj.Value, true
 	}
+	return 0, false
 }
 
 func (i *Map) Equals(v Object) bool {
@@ -852,22 +969,102 @@ func (i *Map) Iter() []IterationItem {
 
 type Error struct {
 	EmptyObjImplementation
+	Kind    string // empty for the generic errors, an example of this: PermissionError
 	Message string
+	// the code of the message in the catalog of diagnostics, empty when it doesn't have one
+	Code diagnostics.Code
+	// position of the node that raised the error, Row is 0 if unknown
+	File string
+	Row  int
+	Col  int
+	// set when the error has an obvious fix, nil otherwise
+	Fix *QuickFix
+	// other places the error refers to, an example of this: the first declaration of a name
+	Related []diagnostics.Span
+	// set by the ! operator, the err result the function returns instead of failing
+	Propagated *StructInstance
+}
+
+// an edit that fixes an error, shown by blk run --dev and offered by the repl
+type QuickFix struct {
+	Title string // an example of this: replace lenght with len
+	// the text to replace in the file of the error, Row is 0 when the fix
+	// is only a hint and can't be applied automatically
+	Row    int
+	Col    int
+	Length int // characters replaced, 0 inserts Text at Row:Col
+	Text   string
+}
+
+func (f *QuickFix) Applicable() bool { return f.Row > 0 }
+
+// returns the source with the fix applied, false if it doesn't fit the source anymore
+func (f *QuickFix) Apply(source string) (string, bool) {
+	if !f.Applicable() {
+		return source, false
+	}
+
+	lines := strings.Split(source, "\n")
+	if f.Row > len(lines) {
+		return source, false
+	}
+
+	line := []rune(lines[f.Row-1])
+	start := f.Col - 1
+	if start < 0 || start+f.Length > len(line) {
+		return source, false
+	}
+
+	lines[f.Row-1] = string(line[:start]) + f.Text + string(line[start+f.Length:])
+	return strings.Join(lines, "\n"), true
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return e.Message }
-func (e *Error) Copy() Object     { return e }
+func (e *Error) Inspect() string {
+	if e.Row == 0 {
+		return e.Message
+	}
+	return diagnostics.Location(e.File, e.Row, e.Col) + " " + e.Message
+}
+
+// the error as a diagnostic to render with the source it points to, the fix becomes a note
+func (e *Error) Diagnostic() *diagnostics.Diagnostic {
+	severity, message := diagnostics.Error, e.Message
+	if rest, ok := strings.CutPrefix(message, string(diagnostics.Warning)+": "); ok {
+		severity, message = diagnostics.Warning, rest
+	} else {
+		message = strings.TrimPrefix(message, string(diagnostics.Error)+": ")
+	}
+
+	d := &diagnostics.Diagnostic{
+		Severity: severity,
+		Message:  message,
+		Code:     e.Code,
+		Primary:  diagnostics.Span{File: e.File, Row: e.Row, Col: e.Col},
+		Related:  e.Related,
+	}
+	if e.Fix != nil {
+		d.WithNote("help: %s", e.Fix.Title)
+	}
+	return d
+}
+func (e *Error) Copy() Object { return e }
 
 type BuiltinFunction func(args ...Object) Object
 
 type BuiltinFn struct {
 	EmptyObjImplementation
 	Fn BuiltinFunction
+	// the function only computes its result, calling it without using the result does nothing
+	Pure bool
+	// the last arg is a map of options, written in the call its values can mix types,
+	// an example of this: fs.walk(root, {"ext": [".mp4"], "max_depth": 3})
+	Options bool
 }
 
 func (b *BuiltinFn) Type() ObjectType { return BUILTIN_OBJ }
 func (b *BuiltinFn) Inspect() string  { return "builtin function" }
+func (b *BuiltinFn) Copy() Object     { return b }
 
 type BuiltinConst struct {
 	EmptyObjImplementation
@@ -877,6 +1074,25 @@ type BuiltinConst struct {
 func (b *BuiltinConst) Type() ObjectType { return BUILTIN_OBJ }
 func (b *BuiltinConst) Inspect() string  { return b.Const.Inspect() }
 
+// lazily produced values, for loops pull them one at a time
+// so breaking out of the loop stops the producer as well
+type Stream struct {
+	EmptyObjImplementation
+	Name string // what the stream produces, an example of this: fs.walk
+	// returns the next value, false once the stream is exhausted
+	// an error value ends the stream as well
+	Next func() (Object, bool)
+}
+
+func (s *Stream) Type() ObjectType { return STREAM_OBJ }
+func (s *Stream) Inspect() string  { return "stream " + s.Name }
+
+// streams are consumed as they are read, copies share the same position
+func (s *Stream) Copy() Object { return s }
+func (s *Stream) Equals(other Object) bool {
+	return s == other
+}
+
 // this for module type which can be constants, functions (for now)
 type Module = map[string]Object
 
@@ -892,6 +1108,9 @@ func (b *BuiltInModule) Type() ObjectType { return BUILTIN_MODULE }
 // TODO: update this method later
 func (b *BuiltInModule) Inspect() string { return b.Name }
 
+// the modules are values since import_module, copying one shares it
+func (b *BuiltInModule) Copy() Object { return b }
+
 // user module, another file
 // TODO: structure to use for user modules
 type UserModule struct {
@@ -904,6 +1123,8 @@ func (b *UserModule) Type() ObjectType { return USER_MODULE }
 
 func (b *UserModule) Inspect() string { return b.Name }
 
+func (b *UserModule) Copy() Object { return b }
+
 type Next struct {
 	EmptyObjImplementation
 }
@@ -920,21 +1141,63 @@ func (b *Break) Inspect() string  { return "break" }
 
 type Struct struct {
 	EmptyObjImplementation
+	// name the struct got declared with, empty for anonymous structs
+	Name string
 	// Fields are both variable decl
 	Fields map[string]Object
 	// Methods are the builtin function that u can use from the struct
 	Methods map[string]Object
+	// fields declared with a struct as default, they hold instances of it and start as nul
+	// the struct can be the one being declared, an example of this: next := Node
+	FieldTypes map[string]*Struct
+	// the names of the fields and of the methods in the order they got declared
+	FieldOrder  []string
+	MethodOrder []string
+	// Methods resolved for the calls, built once by ResolveMethods
+	methodTable map[string]BoundMethod
+}
+
+// a method ready to be called, what a call needs without going through its ItemObject
+type BoundMethod struct {
+	Fn        Object
+	IsBuiltIn bool
+	Private   bool // starts with _, only callable on self
+}
+
+func bindMethod(name string, method Object) BoundMethod {
+	item, _ := method.(ItemObject)
+	fn, _ := Cast(method)
+	return BoundMethod{Fn: fn, IsBuiltIn: item.IsBuiltIn, Private: strings.HasPrefix(name, "_")}
+}
+
+// builds the method table, the interpreter calls it once the struct is evaluated, the methods
+// don't change after that
+func (b *Struct) ResolveMethods() {
+	b.methodTable = make(map[string]BoundMethod, len(b.Methods))
+	for name, method := range b.Methods {
+		b.methodTable[name] = bindMethod(name, method)
+	}
+}
+
+// the method of the struct, the table gets built on the first call for the structs that didn't
+// go through the interpreter
+func (b *Struct) Method(name string) (BoundMethod, bool) {
+	if b.methodTable == nil {
+		b.ResolveMethods()
+	}
+	method, ok := b.methodTable[name]
+	return method, ok
 }
 
 func (b *Struct) Type() ObjectType { return STRUCT_OBJ }
 func (b *Struct) Inspect() string {
 	var out bytes.Buffer
 	out.WriteString("struct {")
-	for name, field := range b.Fields {
-		out.WriteString(name + " := " + field.Inspect() + ", ")
+	for _, name := range b.FieldNames() {
+		out.WriteString(name + " := " + b.Fields[name].Inspect() + ", ")
 	}
-	for name, function := range b.Methods {
-		out.WriteString(name + " : " + function.Inspect() + ", ")
+	for _, name := range b.MethodNames() {
+		out.WriteString(name + " : " + b.Methods[name].Inspect() + ", ")
 	}
 	out.WriteString("}")
 	return out.String()
@@ -949,52 +1212,150 @@ func (i *Struct) Copy() Object {
 		strct.Fields[k] = v
 	}
 
+	strct.Name = i.Name
 	strct.Methods = i.Methods
+	strct.FieldTypes = i.FieldTypes
+	strct.FieldOrder = i.FieldOrder
+	strct.MethodOrder = i.MethodOrder
+	strct.methodTable = i.methodTable
 
 	return strct
 }
 
+// the names of the fields in the order they got declared
+func (b *Struct) FieldNames() []string { return orderedNames(b.Fields, b.FieldOrder) }
+
+// the names of the methods in the order they got declared
+func (b *Struct) MethodNames() []string { return orderedNames(b.Methods, b.MethodOrder) }
+
+// the names of order that are in the map, then the other names of the map sorted, for
+// the structs the stdlib builds out of go maps, they have no declaration order
+func orderedNames(fields map[string]Object, order []string) []string {
+	names := make([]string, 0, len(fields))
+	for _, name := range order {
+		if _, ok := fields[name]; ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == len(fields) {
+		return names
+	}
+
+	rest := make([]string, 0, len(fields)-len(names))
+	for name := range fields {
+		if !slices.Contains(order, name) {
+			rest = append(rest, name)
+		}
+	}
+	slices.Sort(rest)
+	return append(names, rest...)
+}
+
+// whether value can be stored in the field, fields declared with a struct only
+// accept nul and the instances of that struct
+func (b *Struct) Accepts(field string, value Object) bool {
+	fieldType, ok := b.FieldTypes[field]
+	if !ok {
+		return true
+	}
+	value, _ = Cast(value)
+	switch value := value.(type) {
+	case *Nul:
+		return true
+	case *StructInstance:
+		return value.Def == fieldType
+	default:
+		return false
+	}
+}
+
+// the name of the struct, for the error messages
+func (b *Struct) TypeName() string {
+	if len(b.Name) == 0 {
+		return string(STRUCT_OBJ)
+	}
+	return b.Name
+}
+
+type Enum struct {
+	EmptyObjImplementation
+	// name the enum got declared with, empty for anonymous enums
+	Name string
+	// the members in the order they got declared, with their values
+	Members []string
+	Values  map[string]int64
+}
+
+func (e *Enum) Type() ObjectType { return ENUM_OBJ }
+func (e *Enum) Inspect() string {
+	var out bytes.Buffer
+	out.WriteString("enum {")
+	for _, name := range e.Members {
+		out.WriteString(fmt.Sprintf("%s = %d, ", name, e.Values[name]))
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
 type StructInstance struct {
 	EmptyObjImplementation
+	// the struct the instance got created from, nil when unknown
+	Def *Struct
 	// Fields are both variable decl
 	Fields map[string]Object
 	// Methods are the builtin function that u can use from the struct
 	Methods map[string]Object
 }
 
-func (b *StructInstance) Type() ObjectType { return STRUCT_INSTANCE_OBJ }
-func (b *StructInstance) Inspect() string {
-	var out bytes.Buffer
-	out.WriteString("struct {")
-	for name, field := range b.Fields {
-		out.WriteString(name + " := " + field.Inspect() + ", ")
+// the method of the instance, looked up in the table of its struct, the instances the stdlib
+// builds without a struct go through their map
+func (b *StructInstance) Method(name string) (BoundMethod, bool) {
+	if b.Def != nil {
+		return b.Def.Method(name)
 	}
-	for name, function := range b.Methods {
-		out.WriteString(name + " : " + function.Inspect() + ", ")
+	method, ok := b.Methods[name]
+	if !ok {
+		return BoundMethod{}, false
 	}
-	out.WriteString("}")
-	return out.String()
-}
-func (i *StructInstance) HashKey() HashKey {
-	h := fnv.New64a()
-	h.Write([]byte(i.Inspect()))
-	return HashKey{Type: i.Type(), Value: float64(h.Sum64())}
+	return bindMethod(name, method), true
 }
 
-func (i *StructInstance) Copy() Object {
-	strct := &StructInstance{
-		Fields: make(map[string]Object),
+// the names of the fields in the order the struct declared them
+func (b *StructInstance) FieldNames() []string {
+	if b.Def == nil {
+		return orderedNames(b.Fields, nil)
 	}
+	return orderedNames(b.Fields, b.Def.FieldOrder)
+}
 
-	for k, v := range i.Fields {
-		strct.Fields[k] = v.Copy()
+// the fields with their names, in the order the struct declared them, methods are left out
+func (b *StructInstance) Iter() []IterationItem {
+	names := b.FieldNames()
+	items := make([]IterationItem, len(names))
+	for idx, name := range names {
+		items[idx] = IterationItem{Index: &String{Value: name}, Value: b.Fields[name]}
 	}
+	return items
+}
 
-	strct.Methods = i.Methods
+// the names of the methods in the order the struct declared them
+func (b *StructInstance) MethodNames() []string {
+	if b.Def == nil {
+		return orderedNames(b.Methods, nil)
+	}
+	return orderedNames(b.Methods, b.Def.MethodOrder)
+}
 
-	return strct
+func (b *StructInstance) Type() ObjectType { return STRUCT_INSTANCE_OBJ }
+func (b *StructInstance) Inspect() string  { return inspect(b, map[Object]bool{}) }
+func (i *StructInstance) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(i.Inspect()))
+	return HashKey{Type: i.Type(), Value: float64(h.Sum64())}
 }
 
+func (i *StructInstance) Copy() Object { return copyObject(i, map[Object]Object{}) }
+
 func (i *StructInstance) Binary(op lexer.TokenKind, right Object) Object {
 	switch r := right.(type) {
 	case *Nul:
@@ -1015,8 +1376,9 @@ func (i *StructInstance) Binary(op lexer.TokenKind, right Object) Object {
 func Cast(obj Object) (Object, bool) {
 	switch obj := obj.(type) {
 	case ItemObject:
-		o, mut := Cast(obj.Object)
-		return o, mut
+		// the binding decides the mutability, not the value it wraps
+		o, _ := Cast(obj.Object)
+		return o, obj.IsMutable
 	default:
 		return obj, true
 	}
@@ -1116,6 +1478,13 @@ func ObjectTypesCheck(a, b Object, checkLen bool) bool {
 		}
 
 		return true
+	case *Function, *BuiltinFn:
+		// the parameters aren't typed, any function fits, an example of this: {"on_progress": fn(pct) {...}}
+		switch b.(type) {
+		case *Function, *BuiltinFn:
+			return true
+		}
+		return false
 	default:
 		// fallback: not equal
 		return false
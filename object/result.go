@@ -0,0 +1,56 @@
+package object
+
+// the errors the program is expected to handle are values, a Result holds either the value of
+// an operation or the message of its failure, ok(v) and err(msg) build them, the stdlib
+// functions that can fail for reasons outside of the program return them
+var ResultDef = &Struct{
+	Name:       "Result",
+	Fields:     map[string]Object{"value": NUL, "error": NUL},
+	Methods:    map[string]Object{},
+	FieldOrder: []string{"value", "error"},
+}
+
+func Ok(value Object) *StructInstance {
+	return newResult(value, NUL)
+}
+
+func Err(message string) *StructInstance {
+	return newResult(NUL, &String{Value: message})
+}
+
+func newResult(value, err Object) *StructInstance {
+	return &StructInstance{
+		Def: ResultDef,
+		Fields: map[string]Object{
+			"value": ItemObject{Object: value},
+			"error": ItemObject{Object: err},
+		},
+		Methods: ResultDef.Methods,
+	}
+}
+
+// the result held by obj, false when it isn't a Result
+func AsResult(obj Object) (*StructInstance, bool) {
+	obj, _ = Cast(obj)
+	result, ok := obj.(*StructInstance)
+	return result, ok && result.Def == ResultDef
+}
+
+// whether the result holds the message of a failure
+func IsErr(result *StructInstance) bool {
+	err, _ := Cast(result.Fields["error"])
+	return err.Type() != NUL_OBJ
+}
+
+func ResultValue(result *StructInstance) Object {
+	value, _ := Cast(result.Fields["value"])
+	return value
+}
+
+func ResultError(result *StructInstance) string {
+	err, _ := Cast(result.Fields["error"])
+	if str, ok := err.(*String); ok {
+		return str.Value
+	}
+	return err.Inspect()
+}
@@ -2,12 +2,17 @@ package parser
 
 import (
 	"blk/ast"
+	"blk/diagnostics"
+	"blk/internals"
 	"blk/lexer"
-	"errors"
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -24,12 +29,17 @@ const (
 	BitShift    // << >>
 	SUM         // + -
 	PRODUCT     // * / %
+	CAST        // X as int, X is Vec2
 	PREFIX      // -X or !X or ~X
 	CALL        // myFunction(X)
 	INDEX       // arr[i]
 	STRUCT      // Vec2{}.distance()
 )
 
+// checks the files include_str and include_bytes read, set by the stdlib to its fs grants so
+// a program run with --allow-fs can't include files out of them, nil lets every file be read
+var CheckFS func(path string) error
+
 var precedences = map[lexer.TokenKind]int{
 	lexer.TokenCurlyBraceOpen:      ASSIGN,
 	lexer.TokenBind:                ASSIGN,
@@ -66,6 +76,8 @@ var precedences = map[lexer.TokenKind]int{
 	lexer.TokenAssignMultiply:      PRODUCT,
 	lexer.TokenModule:              PRODUCT,
 	lexer.TokenAssignModule:        PRODUCT,
+	lexer.TokenAs:                  CAST,
+	lexer.TokenIs:                  CAST,
 	lexer.TokenExclamation:         PREFIX,
 	lexer.TokenBitNot:              PREFIX,
 	lexer.TokenBraceOpen:           CALL,
@@ -78,10 +90,40 @@ type (
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// a syntax error makes the parser lose track of the statement, the errors after the first
+// ones are mostly about that, so only these many are kept for a statement
+const maxStatementErrors = 3
+
+// the errors kept for a file, the others are only counted
+const maxErrors = 20
+
 type Parser struct {
-	Tokens         []lexer.Token
-	FilePath       string
-	Errors         []error
+	Tokens   []lexer.Token
+	FilePath string
+	// the directory the paths of include_str and include_bytes are relative to, the one of
+	// FilePath when empty
+	Dir    string
+	Errors []error
+	// strict mode, set by the caller or by the # blk:strict pragma
+	Strict bool
+	// the version of the language the file is written for, set by the # blk 0.1 pragma,
+	// internals.LanguageVersion when the file doesn't name one, the syntax added after it
+	// checks it
+	Version    string
+	versionTok *lexer.Token
+	// the functions and the calls made outside of them, their annotations are checked once
+	// the whole file is read
+	functions []*ast.FunctionExpression
+	calls     []*ast.CallExpression
+	fnDepth   int
+	// the type names of the casts, the type tests and the arms of match type
+	typeNames []*ast.Identifier
+	// how many times each name gets bound (vars, params, loop vars) and the index expressions,
+	// for the indexes that are out of the arrays whatever the program does
+	bindings map[string]int
+	indexes  []*ast.IndexExpression
+	// the errors left out of Errors because of the limits, the duplicates aren't counted
+	Dropped        int
 	Pos            int
 	prefixParseFns map[lexer.TokenKind]prefixParseFn
 	infixParseFns  map[lexer.TokenKind]infixParseFn
@@ -97,6 +139,7 @@ func NewParser(tokens []lexer.Token, filepath string) *Parser {
 		infixParseFns:  make(map[lexer.TokenKind]infixParseFn),
 		Pos:            0,
 		internalFlags:  []string{},
+		Version:        internals.LanguageVersion,
 	}
 
 	// prefix/unary operators
@@ -143,6 +186,9 @@ func NewParser(tokens []lexer.Token, filepath string) *Parser {
 	p.registerInfix(lexer.TokenBracketOpen, p.parseIndexExpression)
 	p.registerInfix(lexer.TokenCurlyBraceOpen, p.parseCurlyBraceOpen)
 	p.registerInfix(lexer.TokenDot, p.parseMemberShipAccess)
+	p.registerInfix(lexer.TokenAs, p.parseTypeExpression)
+	p.registerInfix(lexer.TokenIs, p.parseTypeExpression)
+	p.registerInfix(lexer.TokenExclamation, p.parsePropagateExpression)
 
 	return &p
 }
@@ -182,17 +228,20 @@ func (p *Parser) currentToken() lexer.Token {
 func (p *Parser) expect(kinds []lexer.TokenKind) bool {
 	tok := p.nextToken()
 	if slices.Index(kinds, tok.Kind) == -1 {
-		p.Errors = append(p.Errors, p.error(tok, fmt.Sprintf("expected one of (%v), received %v", kinds, tok.Kind)))
+		p.Errors = append(p.Errors, p.codedError(tok, diagnostics.ExpectedToken, kinds, tok.Kind))
 		return false
 	}
 
 	return true
 }
 
-func (p *Parser) error(tok lexer.Token, msg ...interface{}) error {
-	errMsg := fmt.Sprintf("\033[1;90m%s:%d:%d:\033[0m ERROR: %s", p.FilePath, tok.Row, tok.Col, fmt.Sprint(msg...))
+func (p *Parser) error(tok lexer.Token, msg ...interface{}) *diagnostics.Diagnostic {
+	return diagnostics.New(diagnostics.Error, diagnostics.TokenSpan(p.FilePath, tok), "%s", fmt.Sprint(msg...))
+}
 
-	return errors.New(errMsg)
+// an error written with the template of the code in the catalog of messages
+func (p *Parser) codedError(tok lexer.Token, code diagnostics.Code, a ...any) *diagnostics.Diagnostic {
+	return diagnostics.Coded(diagnostics.Error, diagnostics.TokenSpan(p.FilePath, tok), code, a...)
 }
 
 func (p *Parser) registerPrefix(tokenType lexer.TokenKind, fn prefixParseFn) {
@@ -203,29 +252,207 @@ func (p *Parser) registerInfix(tokenType lexer.TokenKind, fn infixParseFn) {
 }
 
 func (p *Parser) Parse() *ast.Program {
+	imports := make(map[string]*ast.ImportStatement)
 	ast := ast.Program{
 		Statements: []ast.Statement{},
 	}
 
+	logs := internals.Debug(internals.DebugParser)
+	logs.Debug("parsing", "file", p.FilePath, "tokens", len(p.Tokens))
+	trace := logs.Enabled(context.Background(), internals.LevelTrace)
+
+	// a file written for a newer blk would only give errors about the syntax it doesn't know
+	if err := p.checkNewerVersion(); err != nil {
+		p.Errors = append(p.Errors, err)
+		logs.Debug("stopped at the version", "file", p.FilePath, "version", p.Version)
+		return nil
+	}
+
 	for p.currentToken().Kind != lexer.TokenEOF {
+		from := len(p.Errors)
+		tok := p.currentToken()
 		stmt, err := p.parseStatement()
 		if err != nil {
 			p.Errors = append(p.Errors, err)
+			p.limitErrors(from)
+			// the statements after it aren't read, their errors could come from this one
+			logs.Debug("stopped at a statement error", "file", p.FilePath, "row", tok.Row, "col", tok.Col, "error", err.Error())
 			return nil
 		} else {
 			ast.Statements = append(ast.Statements, stmt)
 		}
+		if trace {
+			logs.Log(context.Background(), internals.LevelTrace, "statement", "row", tok.Row, "col", tok.Col, "kind", fmt.Sprintf("%T", stmt), "errors", len(p.Errors)-from)
+		}
+		p.checkImport(stmt, imports)
+		p.limitErrors(from)
+	}
+
+	// the pragma can come after the functions, they're checked once the whole file is read
+	if p.Strict {
+		for _, stmt := range ast.Statements {
+			p.checkStrictAnnotations(stmt)
+		}
 	}
+	p.checkAnnotations(ast.Statements)
+	p.checkConstantIndexes(ast.Statements)
+	logs.Debug("parsed", "file", p.FilePath, "statements", len(ast.Statements), "errors", len(p.Errors), "dropped", p.Dropped)
 
 	return &ast
 }
 
+// the version pragma is read before the statements, an error when it names a version of the
+// language newer than the one of this blk
+func (p *Parser) checkNewerVersion() error {
+	for _, tok := range p.Tokens {
+		if tok.Kind != lexer.TokenPragma {
+			continue
+		}
+		fields := strings.Fields(tok.Text)
+		if len(fields) != 2 || fields[0] != "version" {
+			continue
+		}
+		if _, err := internals.ParseLanguageVersion(fields[1]); err != nil {
+			// reported with the pragma
+			return nil
+		}
+		if internals.CompareLanguageVersions(fields[1], internals.LanguageVersion) > 0 {
+			p.Version = fields[1]
+			return p.error(tok, fmt.Sprintf("%s is written for blk %s, this blk (%s) knows the language up to %s, upgrade blk to run it: blk upgrade",
+				p.FilePath, fields[1], internals.Version, internals.LanguageVersion))
+		}
+		return nil
+	}
+	return nil
+}
+
+// in strict mode the exported functions, the top level ones not starting with _, have their
+// args and their return value annotated, they're what other files see of the module
+func (p *Parser) checkStrictAnnotations(stmt ast.Statement) {
+	decl, ok := stmt.(*ast.VarDeclaration)
+	if !ok || len(decl.Name) != 1 || strings.HasPrefix(decl.Name[0].Value, "_") {
+		return
+	}
+	fn, ok := decl.Value.(*ast.FunctionExpression)
+	if !ok || fn.Annotated() {
+		return
+	}
+
+	missing := []string{}
+	for idx, arg := range fn.Args {
+		if idx >= len(fn.Types) || fn.Types[idx] == nil {
+			missing = append(missing, arg.Value)
+		}
+	}
+	if fn.ReturnType == nil {
+		missing = append(missing, "the return value")
+	}
+	p.Errors = append(p.Errors, p.error(decl.Name[0].Token, fmt.Sprintf("exported function %s needs type annotations in strict mode, missing for %s", decl.Name[0].Value, strings.Join(missing, ", "))).
+		WithNote("help: annotate every arg and the return value, an example of this: fn add(a: int, b: int): int { ... }, void when it returns nothing"))
+}
+
+// the name an import binds, the alias when there is one
+func importBinding(stmt *ast.ImportStatement) string {
+	if stmt.Alias != nil {
+		return stmt.Alias.Value
+	}
+	return stmt.ModuleName.Value
+}
+
+// reports the top level imports binding a name another import already bound, a module
+// can be imported again only under a distinct alias
+func (p *Parser) checkImport(node ast.Statement, imports map[string]*ast.ImportStatement) {
+	stmt, ok := node.(*ast.ImportStatement)
+	if !ok {
+		return
+	}
+	name := importBinding(stmt)
+	first, ok := imports[name]
+	if !ok {
+		imports[name] = stmt
+		return
+	}
+
+	firstTok := first.ModuleName.Token
+	if first.Alias != nil {
+		firstTok = first.Alias.Token
+	}
+	tok := stmt.ModuleName.Token
+	if stmt.Alias != nil {
+		tok = stmt.Alias.Token
+	}
+
+	var d *diagnostics.Diagnostic
+	if first.ModuleName.Value == stmt.ModuleName.Value {
+		d = p.codedError(tok, diagnostics.DuplicateImport, stmt.ModuleName, firstTok.Row, firstTok.Col).
+			WithRelated(diagnostics.TokenSpan(p.FilePath, firstTok), "first imported here").
+			WithNote("help: remove it, a module is imported again only under another alias")
+	} else {
+		d = p.codedError(tok, diagnostics.ImportNameTaken, name, first.ModuleName, firstTok.Row, firstTok.Col).
+			WithRelated(diagnostics.TokenSpan(p.FilePath, firstTok), "bound here").
+			WithNote("help: give one of them another name, an example of this: import %s as other", stmt.ModuleName)
+	}
+	p.Errors = append(p.Errors, d)
+}
+
+// drops the errors of a statement reported at the position of another error, and the ones
+// past maxStatementErrors and maxErrors
+func (p *Parser) limitErrors(from int) {
+	if len(p.Errors) == from {
+		return
+	}
+	seen := make(map[string]bool, len(p.Errors))
+	for _, err := range p.Errors[:from] {
+		seen[errorPosition(err)] = true
+	}
+
+	kept := p.Errors[:from]
+	count := 0
+	for _, err := range p.Errors[from:] {
+		position := errorPosition(err)
+		if seen[position] {
+			continue
+		}
+		seen[position] = true
+		if count == maxStatementErrors || len(kept) == maxErrors {
+			internals.Debug(internals.DebugParser).Debug("error dropped", "error", err.Error())
+			p.Dropped++
+			continue
+		}
+		count++
+		kept = append(kept, err)
+	}
+	p.Errors = kept
+}
+
+// the position of the diagnostics, the message of the other errors
+func errorPosition(err error) string {
+	if d, ok := err.(*diagnostics.Diagnostic); ok {
+		return fmt.Sprintf("%s:%d:%d", d.Primary.File, d.Primary.Row, d.Primary.Col)
+	}
+	return err.Error()
+}
+
+// the line printed after the errors when some got dropped, empty otherwise
+func (p *Parser) ErrorSummary() string {
+	switch p.Dropped {
+	case 0:
+		return ""
+	case 1:
+		return "and 1 more error"
+	}
+	return fmt.Sprintf("and %d more errors", p.Dropped)
+}
+
 // TODO: better error handling and targeting
 
 func (p *Parser) parseStatement() (ast.Statement, error) {
 	stmtToken := p.currentToken() // Consume stmt
 	switch stmtToken.Kind {
 	case lexer.TokenLet, lexer.TokenConst:
+		if next := p.lookToken(1); stmtToken.Kind == lexer.TokenLet && (next.Text == "Ok" || next.Text == "Err") && p.lookToken(2).Kind == lexer.TokenBraceOpen {
+			return p.parseLetElseStatement()
+		}
 		return p.parseVarDeclaration()
 	case lexer.TokenReturn:
 		return p.parseReturnStatement()
@@ -233,12 +460,23 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 		return p.parseImportStatement()
 	case lexer.TokenWhile:
 		return p.parseWhileStatement()
+	case lexer.TokenGuard:
+		return p.parseGuardStatement()
 	case lexer.TokenFor:
 		return p.parseForStatement()
 	case lexer.TokenNext:
 		return p.parseNextStatement()
 	case lexer.TokenBreak:
 		return p.parseBreakStatement()
+	case lexer.TokenPragma:
+		return p.parsePragmaStatement()
+	case lexer.TokenAt:
+		return p.parseAnnotatedStatement()
+	case lexer.TokenFn:
+		if p.lookToken(1).Kind == lexer.TokenIdentifier {
+			return p.parseFunctionDeclaration()
+		}
+		return p.parseExpressionStatement()
 	case lexer.TokenIdentifier, lexer.TokenSelf:
 		firstLook := p.lookToken(1)
 		// check after it if there is a colon and a {
@@ -322,6 +560,11 @@ func (p *Parser) parseVarDeclaration() (*ast.VarDeclaration, error) {
 	}
 
 	stmt.Value = p.parseExpression(LOWEST)
+
+	if _, err := p.valueType(stmt.Value); err != nil {
+		return nil, err
+	}
+
 	return stmt, nil
 }
 
@@ -512,11 +755,11 @@ func (p *Parser) parseEnumExpression() ast.Expression {
 
 	tok := p.currentToken()
 
-	if tok.Kind == lexer.TokenBracketClose {
+	if tok.Kind == lexer.TokenCurlyBraceClose {
 		p.nextToken()
 		return &ast.EnumExpression{
 			Token: expr.Token,
-			Body:  []*ast.Identifier{},
+			Body:  []*ast.EnumField{},
 		}
 	}
 
@@ -525,28 +768,60 @@ func (p *Parser) parseEnumExpression() ast.Expression {
 
 }
 
-func (p *Parser) parseEnumFields() []*ast.Identifier {
-	fields := make([]*ast.Identifier, 0)
-
-	field, ok := p.parseIdentifier().(*ast.Identifier)
-
-	if !ok {
-		p.Errors = append(p.Errors, p.error(p.lookToken(-1), "expected an identifier, got shit"))
-		return nil
-	}
-
-	fields = append(fields, field)
-
-	for p.currentToken().Kind == lexer.TokenComma {
-		p.nextToken()
-		field, ok := p.parseIdentifier().(*ast.Identifier)
-
+// the members of an enum, Name or Name = value, a member without a value gets the one of the
+// previous member plus one, the first one starts at 0
+func (p *Parser) parseEnumFields() []*ast.EnumField {
+	fields := make([]*ast.EnumField, 0)
+	names := make(map[string]lexer.Token)
+	values := make(map[int64]lexer.Token)
+	next := int64(0)
+
+	for {
+		nameTok := p.currentToken()
+		name, ok := p.parseIdentifier().(*ast.Identifier)
 		if !ok {
 			p.Errors = append(p.Errors, p.error(p.lookToken(-1), "expected an identifier, got shit"))
 			return nil
 		}
+		// every member gets its own node, so the values don't leak from one to the other
+		field := &ast.EnumField{Name: name, Value: next}
+
+		if p.currentToken().Kind == lexer.TokenAssign {
+			p.nextToken()
+			value, ok := p.parseEnumValue()
+			if !ok {
+				// the error is reported, the members that follow still get parsed
+				value = next
+			}
+			field.Value, field.Explicit = value, true
+		}
 
+		if first, ok := names[name.Value]; ok {
+			p.Errors = append(p.Errors, p.error(nameTok, fmt.Sprintf("duplicate member %s in enum, first defined at %d:%d",
+				name.Value, first.Row, first.Col)).WithRelated(diagnostics.TokenSpan(p.FilePath, first), "first defined here"))
+		} else if first, ok := values[field.Value]; ok {
+			d := p.error(nameTok, fmt.Sprintf("%s has the value %d, already taken by %s", name.Value, field.Value, first.Text)).
+				WithRelated(diagnostics.TokenSpan(p.FilePath, first), "value taken here")
+			if !field.Explicit {
+				d.WithNote("help: members without a value follow the previous one, give it an unused value")
+			}
+			p.Errors = append(p.Errors, d)
+		} else {
+			names[name.Value] = nameTok
+			values[field.Value] = nameTok
+		}
+		// the parsing goes on after a duplicate, so the errors that follow are real ones
 		fields = append(fields, field)
+		next = field.Value + 1
+
+		if p.currentToken().Kind != lexer.TokenComma {
+			break
+		}
+		p.nextToken()
+		// trailing comma
+		if p.currentToken().Kind == lexer.TokenCurlyBraceClose {
+			break
+		}
 	}
 
 	if !p.expect([]lexer.TokenKind{lexer.TokenCurlyBraceClose}) {
@@ -557,6 +832,23 @@ func (p *Parser) parseEnumFields() []*ast.Identifier {
 	return fields
 }
 
+// the explicit value of an enum member, an integer that can be negative
+func (p *Parser) parseEnumValue() (int64, bool) {
+	tok := p.currentToken()
+	sign := int64(1)
+	if tok.Kind == lexer.TokenMinus {
+		sign = -1
+		p.nextToken()
+	}
+
+	literal, ok := p.parseExpression(LOWEST).(*ast.IntegerLiteral)
+	if !ok {
+		p.Errors = append(p.Errors, p.error(tok, "the value of an enum member needs to be an integer literal"))
+		return 0, false
+	}
+	return sign * literal.Value, true
+}
+
 func (p *Parser) parseWhileStatement() (*ast.WhileStatement, error) {
 	stmt := &ast.WhileStatement{Token: p.currentToken()}
 	p.nextToken()
@@ -571,6 +863,107 @@ func (p *Parser) parseWhileStatement() (*ast.WhileStatement, error) {
 	return stmt, nil
 }
 
+// guard is sugar over if, an example of this: guard cond else { return } => if !cond { return }
+// the else branch needs to exit the current scope
+func (p *Parser) parseGuardStatement() (*ast.ExpressionStatement, error) {
+	tok := p.currentToken()
+	p.nextToken()
+
+	condition := p.parseExpression(ASSIGN)
+	if condition == nil {
+		return nil, p.error(tok, "expected a condition after guard")
+	}
+
+	if cur := p.nextToken(); cur.Kind != lexer.TokenElse {
+		return nil, p.error(cur, "expected else after the guard condition, got ", cur.Kind)
+	}
+
+	if cur := p.nextToken(); cur.Kind != lexer.TokenCurlyBraceOpen {
+		return nil, p.error(cur, "expected curly brace open ( { ) after guard else, got ", cur.Kind)
+	}
+
+	body, ok := p.parseBlockStatement().(*ast.BlockStatement)
+	if !ok {
+		return nil, p.error(tok, "expected a block for the guard else branch")
+	}
+
+	if !blockExits(body) {
+		return nil, p.error(tok, "the else branch of a guard needs to exit, end it with return, break or next")
+	}
+
+	return &ast.ExpressionStatement{
+		Token: tok,
+		Expression: &ast.IfExpression{
+			Token:       tok,
+			Condition:   &ast.UnaryExpression{Token: tok, Operator: "!", Right: condition},
+			Consequence: body,
+		},
+	}, nil
+}
+
+// whether the block ends with return, break or next
+func blockExits(body *ast.BlockStatement) bool {
+	if len(body.Body) == 0 {
+		return false
+	}
+	switch body.Body[len(body.Body)-1].(type) {
+	case *ast.ReturnStatement, *ast.BreakStatement, *ast.NextStatement:
+		return true
+	}
+	return false
+}
+
+// let Ok(data) = read(path) else { ... }
+func (p *Parser) parseLetElseStatement() (*ast.LetElseStatement, error) {
+	stmt := &ast.LetElseStatement{Token: p.nextToken()}
+	variant := p.nextToken()
+	stmt.Variant = &ast.Identifier{Token: variant, Value: variant.Text}
+	// the ( was checked to pick the statement
+	p.nextToken()
+
+	name := p.currentToken()
+	if name.Kind != lexer.TokenIdentifier {
+		return nil, p.error(name, fmt.Sprintf("expected the name to bind in %s(...), got %s", variant.Text, name.Text))
+	}
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: name, Value: name.Text}
+	p.bind(stmt.Name)
+
+	if cur := p.nextToken(); cur.Kind != lexer.TokenBraceClose {
+		return nil, p.error(cur, fmt.Sprintf("%s(...) binds a single name, expected ), got %s", variant.Text, cur.Text))
+	}
+	if cur := p.nextToken(); cur.Kind != lexer.TokenAssign {
+		return nil, p.error(cur, "expected assign (=), got ", cur.Kind)
+	}
+
+	stmt.Value = p.parseExpression(ASSIGN)
+	if stmt.Value == nil {
+		return nil, p.error(stmt.Token, fmt.Sprintf("expected a result to match against %s(%s)", variant.Text, name.Text))
+	}
+	if got := literalType(stmt.Value); len(got) > 0 {
+		return nil, p.error(stmt.Value.GetToken(), fmt.Sprintf("let %s(...) takes a result, got %s", variant.Text, got)).
+			WithNote("help: ok(value) and err(message) make results")
+	}
+
+	if cur := p.nextToken(); cur.Kind != lexer.TokenElse {
+		return nil, p.error(cur, fmt.Sprintf("expected else after let %s(...), the other results need to be handled, got %s", variant.Text, cur.Text))
+	}
+	if cur := p.nextToken(); cur.Kind != lexer.TokenCurlyBraceOpen {
+		return nil, p.error(cur, "expected curly brace open ( { ) after else, got ", cur.Kind)
+	}
+
+	body, ok := p.parseBlockStatement().(*ast.BlockStatement)
+	if !ok {
+		return nil, p.error(stmt.Token, "expected a block for the else branch")
+	}
+	if !blockExits(body) {
+		return nil, p.error(stmt.Token, fmt.Sprintf("the else branch of let %s(...) needs to exit, end it with return, break or next", variant.Text))
+	}
+	stmt.Else = body
+
+	return stmt, nil
+}
+
 func (p *Parser) parseForStatement() (*ast.ForStatement, error) {
 	stmt := &ast.ForStatement{Token: p.currentToken()}
 	p.nextToken()
@@ -594,6 +987,7 @@ func (p *Parser) parseForStatement() (*ast.ForStatement, error) {
 	} else {
 		p.Pos--
 	}
+	p.bind(stmt.Identifiers...)
 
 	tok = p.nextToken()
 	if tok.Kind != lexer.TokenIn {
@@ -648,6 +1042,126 @@ func (p *Parser) parseBreakStatement() (*ast.BreakStatement, error) {
 	return stmt, nil
 }
 
+// parses the directive held by a pragma comment
+// an example of this: # blk:feature match,spawn
+func (p *Parser) parsePragmaStatement() (*ast.PragmaStatement, error) {
+	stmt := &ast.PragmaStatement{Token: p.currentToken()}
+	// consume the pragma token
+	p.nextToken()
+
+	fields := strings.Fields(stmt.Token.Text)
+	if len(fields) == 0 {
+		return nil, p.error(stmt.Token, "expected a directive name after ", lexer.PragmaPrefix)
+	}
+
+	stmt.Name = fields[0]
+	stmt.Args = make([]string, 0)
+	for _, field := range fields[1:] {
+		for arg := range strings.SplitSeq(field, ",") {
+			if len(arg) > 0 {
+				stmt.Args = append(stmt.Args, arg)
+			}
+		}
+	}
+
+	switch stmt.Name {
+	case "strict":
+		if len(stmt.Args) > 0 {
+			return nil, p.error(stmt.Token, "strict pragma doesn't take args")
+		}
+		p.Strict = true
+	case "feature":
+		if len(stmt.Args) == 0 {
+			return nil, p.error(stmt.Token, "feature pragma expects at least one feature name")
+		}
+		for _, feature := range stmt.Args {
+			if _, ok := internals.ExperimentalFeatures[feature]; !ok {
+				return nil, p.error(stmt.Token, "unknown feature ", feature)
+			}
+		}
+	case "version":
+		if len(stmt.Args) != 1 {
+			return nil, p.error(stmt.Token, "version pragma expects a single version, an example of this: ", lexer.VersionPragmaPrefix, internals.LanguageVersion)
+		}
+		if p.versionTok != nil {
+			return nil, p.error(stmt.Token, fmt.Sprintf("the version is already set at %d:%d", p.versionTok.Row, p.versionTok.Col))
+		}
+		if _, err := internals.ParseLanguageVersion(stmt.Args[0]); err != nil {
+			return nil, p.error(stmt.Token, err.Error())
+		}
+		p.Version = stmt.Args[0]
+		p.versionTok = &stmt.Token
+	default:
+		return nil, p.error(stmt.Token, "unknown pragma ", stmt.Name)
+	}
+
+	return stmt, nil
+}
+
+// parses the annotations attached to a const declaration
+// an example of this:
+// @deprecated("use new_fn")
+// old_fn :: fn() {}
+func (p *Parser) parseAnnotatedStatement() (ast.Statement, error) {
+	annotations := make([]*ast.Annotation, 0)
+
+	for p.currentToken().Kind == lexer.TokenAt {
+		annotation, err := p.parseAnnotation()
+		if err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, annotation)
+	}
+
+	stmt, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+
+	decl, ok := stmt.(*ast.VarDeclaration)
+	if !ok || decl.Mutable {
+		return nil, p.error(annotations[0].Token, "annotations can only be applied on const declarations (::)")
+	}
+
+	decl.Annotations = annotations
+	return decl, nil
+}
+
+func (p *Parser) parseAnnotation() (*ast.Annotation, error) {
+	annotation := &ast.Annotation{Token: p.currentToken(), Args: []ast.Expression{}}
+	// consume the @ token
+	p.nextToken()
+
+	ident, ok := p.parseIdentifier().(*ast.Identifier)
+	if !ok {
+		return nil, p.error(annotation.Token, "expected an annotation name after @, got shit")
+	}
+	annotation.Name = ident
+
+	if p.currentToken().Kind == lexer.TokenBraceOpen {
+		annotation.Args = p.parseCallArguments()
+		if annotation.Args == nil {
+			return nil, p.error(annotation.Token, "expected valid arguments for the annotation ", ident.Value)
+		}
+	}
+
+	switch ident.Value {
+	case "deprecated":
+		if len(annotation.Args) > 1 {
+			return nil, p.error(annotation.Token, "deprecated annotation takes at most one argument, the deprecation message")
+		}
+		if len(annotation.Args) == 1 {
+			if _, ok := annotation.Args[0].(*ast.StringLiteral); !ok {
+				return nil, p.error(annotation.Token, "deprecation message needs to be a string literal")
+			}
+		}
+	default:
+		return nil, p.error(annotation.Token, "unknown annotation ", ident.Value)
+	}
+
+	return annotation, nil
+}
+
 func (p *Parser) parseAssignStatement() (*ast.AssignStatement, error) {
 	stmt := &ast.AssignStatement{Token: p.currentToken()}
 
@@ -710,12 +1224,13 @@ func (p *Parser) parseIdentifiers() []*ast.Identifier {
 
 		ident := p.parseIdentifier()
 		if ident == nil {
-			return identifiers
+			break
 		}
 
 		identifiers = append(identifiers, ident.(*ast.Identifier))
 	}
 
+	p.bind(identifiers...)
 	return identifiers
 }
 
@@ -858,7 +1373,7 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 		return nil
 	}
 
-	pairs := make(map[ast.Expression]ast.Expression, 0)
+	pairs := make([]ast.MapPair, 0)
 
 	tok := p.currentToken()
 
@@ -879,7 +1394,7 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 		return nil
 	}
 
-	pairs[key] = p.parseExpression(LOWEST)
+	pairs = append(pairs, ast.MapPair{Key: key, Value: p.parseExpression(LOWEST)})
 
 	for p.currentToken().Kind == lexer.TokenComma {
 		p.nextToken()
@@ -892,7 +1407,7 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 			return nil
 		}
 
-		pairs[key] = p.parseExpression(LOWEST)
+		pairs = append(pairs, ast.MapPair{Key: key, Value: p.parseExpression(LOWEST)})
 	}
 
 	if !p.expect([]lexer.TokenKind{lexer.TokenCurlyBraceClose}) {
@@ -900,12 +1415,56 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 		return nil
 	}
 
+	// the literal is still returned, a nil one would add an error of its own
+	if err := p.checkDuplicateKeys(pairs); err != nil {
+		p.Errors = append(p.Errors, err)
+	}
+
 	return &ast.MapLiteral{
 		Token: prev,
 		Pairs: pairs,
 	}
 }
 
+// literal keys written twice in a map literal, only the second one is reported
+func (p *Parser) checkDuplicateKeys(pairs []ast.MapPair) error {
+	seen := make(map[string]lexer.Token, len(pairs))
+
+	for _, pair := range pairs {
+		key, ok := literalKey(pair.Key)
+		if !ok {
+			// computed keys are checked at runtime
+			continue
+		}
+		tok := pair.Key.GetToken()
+		if first, ok := seen[key]; ok {
+			return p.codedError(tok, diagnostics.DuplicateMapKey, pair.Key.String(), first.Row, first.Col).
+				WithRelated(diagnostics.TokenSpan(p.FilePath, first), "first defined here")
+		}
+		seen[key] = tok
+	}
+
+	return nil
+}
+
+// identity of a literal key, values are compared so 1.0 and 1.00 are the same key
+func literalKey(expr ast.Expression) (string, bool) {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return fmt.Sprintf("int:%d", expr.Value), true
+	case *ast.FloatLiteral:
+		return fmt.Sprintf("float:%v", expr.Value), true
+	case *ast.StringLiteral:
+		return "string:" + expr.Value, true
+	case *ast.CharLiteral:
+		return fmt.Sprintf("char:%d", expr.Value), true
+	case *ast.BooleanLiteral:
+		return fmt.Sprintf("bool:%t", expr.Value), true
+	default:
+		return "", false
+	}
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
@@ -928,54 +1487,216 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 	// look ahead to the next token
 	if p.currentToken().Kind == lexer.TokenQuestion || p.currentToken().Kind == lexer.TokenUse {
-		// do something
-		p.nextToken() // consume the ?
-		exprStmt, err := p.parseExpressionStatement()
-		if err != nil {
-			return nil
+		return p.parseConditionalExpression(expr.Token, expr.Condition)
+	}
+
+	if !p.expect([]lexer.TokenKind{lexer.TokenCurlyBraceOpen}) {
+		p.Errors = append(p.Errors, p.error(p.currentToken(), "expected close curly brace ( } ), got shit"))
+		return nil
+	}
+	expr.Consequence = p.parseBlockStatement().(*ast.BlockStatement)
+	tok := p.nextToken()
+
+	// check if there is an else stmt
+	if tok.Kind == lexer.TokenElse {
+		tok = p.currentToken()
+		// support for else if
+		if tok.Kind == lexer.TokenIf {
+			expr.Alternative = p.parseIfExpression()
+		} else {
+			if !p.expect([]lexer.TokenKind{lexer.TokenCurlyBraceOpen}) {
+				return nil
+			}
+			expr.Alternative = p.parseBlockStatement()
 		}
-		expr.Consequence = &ast.BlockStatement{
-			Body: []ast.Statement{exprStmt},
+	} else {
+		p.Pos--
+	}
+
+	return expr
+}
+
+// parses the ternary form of the if, the condition is already consumed
+// both branches are parsed as full expressions, so nesting is right associative:
+// if a ? 1 : if b ? 2 : 3 => if a ? 1 : (if b ? 2 : 3)
+func (p *Parser) parseConditionalExpression(tok lexer.Token, condition ast.Expression) ast.Expression {
+	expr := &ast.ConditionalExpression{Token: tok, Condition: condition}
+	opener := p.nextToken()
+	separator := lexer.TokenColon
+	if opener.Kind == lexer.TokenUse {
+		separator = lexer.TokenElse
+	}
+
+	expr.Consequence = p.parseExpression(LOWEST)
+	if expr.Consequence == nil {
+		p.Errors = append(p.Errors, p.error(opener, "expected an expression after ", opener.Text, " in the ternary definition"))
+		return nil
+	}
+
+	cur := p.currentToken()
+	if cur.Kind != separator {
+		p.Errors = append(p.Errors, p.error(cur, "expected ", separator, " as following token for the ternary definition, got ", cur.Kind))
+		return nil
+	}
+	p.nextToken()
+
+	expr.Alternative = p.parseExpression(LOWEST)
+	if expr.Alternative == nil {
+		p.Errors = append(p.Errors, p.error(cur, "expected an expression after ", separator, " in the ternary definition"))
+		return nil
+	}
+
+	return expr
+}
+
+// validates an if expression used as a value, an example of this: x := if cond { 1 } else { 2 }
+// both branches are required, each one needs to end with an expression
+// and the literal values of the branches need to unify to one type
+// returns the unified type, empty if it can't be known before evaluation
+func (p *Parser) checkIfValue(expr *ast.IfExpression) (string, error) {
+	if expr.Alternative == nil {
+		return "", p.codedError(expr.Token, diagnostics.IfWithoutElse)
+	}
+
+	consequenceType, err := p.branchValueType(expr.Token, expr.Consequence)
+	if err != nil {
+		return "", err
+	}
+
+	var alternativeType string
+	if alternative, ok := expr.Alternative.(*ast.BlockStatement); ok {
+		alternativeType, err = p.branchValueType(expr.Token, alternative)
+	} else {
+		// else if
+		alternativeType, err = p.valueType(expr.Alternative)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return p.unifyBranchTypes(expr.Alternative.GetToken(), consequenceType, alternativeType)
+}
+
+// returns the type of the final expression of a branch, if it's a literal
+func (p *Parser) branchValueType(tok lexer.Token, branch *ast.BlockStatement) (string, error) {
+	if branch == nil || len(branch.Body) == 0 {
+		return "", p.error(tok, "branches of an if expression used as a value can't be empty")
+	}
+
+	last, ok := branch.Body[len(branch.Body)-1].(*ast.ExpressionStatement)
+	if !ok {
+		return "", p.error(branch.Body[len(branch.Body)-1].GetToken(), "branches of an if expression used as a value need to end with an expression")
+	}
+
+	return p.valueType(last.Expression)
+}
+
+// returns the type of an expression used as a value, if it's a literal
+// if expressions and ternaries are checked on the way
+func (p *Parser) valueType(expr ast.Expression) (string, error) {
+	switch value := expr.(type) {
+	case *ast.IntegerLiteral:
+		return lexer.TokenInt, nil
+	case *ast.FloatLiteral:
+		return lexer.TokenFloat, nil
+	case *ast.StringLiteral:
+		return lexer.TokenString, nil
+	case *ast.CharLiteral:
+		return lexer.TokenChar, nil
+	case *ast.BooleanLiteral:
+		return lexer.TokenBool, nil
+	case *ast.NulLiteral:
+		return lexer.TokenNul, nil
+	case *ast.IfExpression:
+		return p.checkIfValue(value)
+	case *ast.ConditionalExpression:
+		consequenceType, err := p.valueType(value.Consequence)
+		if err != nil {
+			return "", err
 		}
-		tok := p.nextToken()
-		if tok.Kind != lexer.TokenColon && tok.Kind != lexer.TokenElse {
-			p.Errors = append(p.Errors, p.error(p.currentToken(), "expected else or : as following token for the ternary definition, got ", tok.Kind))
-			return nil
+		alternativeType, err := p.valueType(value.Alternative)
+		if err != nil {
+			return "", err
 		}
-		// fill the alternative case
-		exprStmt, err = p.parseExpressionStatement()
+		return p.unifyBranchTypes(value.Alternative.GetToken(), consequenceType, alternativeType)
+	case *ast.ArrayLiteral:
+		return p.arrayType(value)
+	case *ast.BytesLiteral:
+		return "[]" + lexer.TokenInt, nil
+	case *ast.BinaryExpression:
+		return p.binaryType(value)
+	default:
+		// only known when evaluated
+		return "", nil
+	}
+}
+
+// type of an array literal written as []<element type>, an example of this: []int
+func (p *Parser) arrayType(arr *ast.ArrayLiteral) (string, error) {
+	elemType := ""
+	for _, elem := range arr.Elements {
+		current, err := p.valueType(elem)
 		if err != nil {
-			return nil
+			return "", err
 		}
-		expr.Alternative = &ast.BlockStatement{
-			Body: []ast.Statement{exprStmt},
+		if current == "" || current == lexer.TokenNul {
+			continue
 		}
-	} else {
-		if !p.expect([]lexer.TokenKind{lexer.TokenCurlyBraceOpen}) {
-			p.Errors = append(p.Errors, p.error(p.currentToken(), "expected close curly brace ( } ), got shit"))
-			return nil
+		if elemType != "" && current != elemType {
+			return "", p.codedError(elem.GetToken(), diagnostics.ArrayElemTypes, elemType, current)
 		}
-		expr.Consequence = p.parseBlockStatement().(*ast.BlockStatement)
-		tok := p.nextToken()
+		elemType = current
+	}
 
-		// check if there is an else stmt
-		if tok.Kind == lexer.TokenElse {
-			tok = p.currentToken()
-			// support for else if
-			if tok.Kind == lexer.TokenIf {
-				expr.Alternative = p.parseIfExpression()
-			} else {
-				if !p.expect([]lexer.TokenKind{lexer.TokenCurlyBraceOpen}) {
-					return nil
-				}
-				expr.Alternative = p.parseBlockStatement()
-			}
-		} else {
-			p.Pos--
+	if elemType == "" {
+		return "", nil
+	}
+	return "[]" + elemType, nil
+}
+
+// only the array operations are typed for now, the others are left to the runtime
+func (p *Parser) binaryType(expr *ast.BinaryExpression) (string, error) {
+	leftType, err := p.valueType(expr.Left)
+	if err != nil {
+		return "", err
+	}
+	rightType, err := p.valueType(expr.Right)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(leftType, "[]") {
+		return "", nil
+	}
+
+	switch expr.Operator {
+	case lexer.TokenPlus:
+		if rightType != "" && rightType != leftType {
+			return "", p.codedError(expr.Right.GetToken(), diagnostics.ConcatTypes, leftType, rightType)
+		}
+		return leftType, nil
+	case lexer.TokenMultiply:
+		if rightType != "" && rightType != lexer.TokenInt {
+			return "", p.error(expr.Right.GetToken(), fmt.Sprintf("arrays can only be repeated an int number of times, got %s", rightType))
 		}
+		return leftType, nil
+	case lexer.TokenEquals, lexer.TokenNotEquals:
+		return lexer.TokenBool, nil
 	}
 
-	return expr
+	return "", nil
+}
+
+func (p *Parser) unifyBranchTypes(tok lexer.Token, consequenceType, alternativeType string) (string, error) {
+	// nul unifies with any type, same as assignments
+	if consequenceType == alternativeType || alternativeType == "" || alternativeType == lexer.TokenNul {
+		return consequenceType, nil
+	}
+	if consequenceType == "" || consequenceType == lexer.TokenNul {
+		return alternativeType, nil
+	}
+
+	return "", p.codedError(tok, diagnostics.IfBranchTypes, consequenceType, alternativeType)
 }
 
 func (p *Parser) parseMatchExpression() ast.Expression {
@@ -983,6 +1704,12 @@ func (p *Parser) parseMatchExpression() ast.Expression {
 	// consume math keyword
 	p.nextToken()
 
+	// type is only a keyword there, match type { ... } matches a var named type
+	if tok := p.currentToken(); tok.Kind == lexer.TokenIdentifier && tok.Text == "type" && p.lookToken(1).Kind != lexer.TokenCurlyBraceOpen {
+		expr.OnType = true
+		p.nextToken()
+	}
+
 	expr.MatchKey = p.parseExpression(ASSIGN)
 
 	if !p.expect([]lexer.TokenKind{lexer.TokenCurlyBraceOpen}) {
@@ -999,7 +1726,7 @@ func (p *Parser) parseMatchExpression() ast.Expression {
 		return expr
 	}
 
-	pattern := p.parseExpression(LOWEST)
+	pattern := p.parseMatchPattern(expr.OnType)
 
 	tok = p.nextToken()
 
@@ -1026,7 +1753,7 @@ func (p *Parser) parseMatchExpression() ast.Expression {
 
 		patterCase := p.currentToken()
 
-		pattern := p.parseExpression(LOWEST)
+		pattern := p.parseMatchPattern(expr.OnType)
 		tok = p.nextToken()
 
 		if tok.Kind != lexer.TokenMatch {
@@ -1066,16 +1793,87 @@ func (p *Parser) parseMatchExpression() ast.Expression {
 	return expr
 }
 
+// parses the pattern of a match arm, string literals with wildcards are compiled into a string pattern
+func (p *Parser) parseMatchPattern(onType bool) ast.Expression {
+	if onType {
+		typ := p.parseTypeName("the arm of match type")
+		if typ == nil {
+			return nil
+		}
+		if typ.Value != "_" {
+			p.typeNames = append(p.typeNames, typ)
+		}
+		return typ
+	}
+
+	pattern := p.parseExpression(LOWEST)
+
+	str, ok := pattern.(*ast.StringLiteral)
+	if !ok || !strings.ContainsAny(str.Value, "*?") {
+		return pattern
+	}
+
+	expr := &ast.StringPattern{Token: str.Token, Value: str.Value, Kind: ast.PatternGlob}
+
+	// the common shapes are checked with a single strings call
+	if !strings.Contains(str.Value, "?") {
+		inner := strings.TrimSuffix(strings.TrimPrefix(str.Value, "*"), "*")
+		if !strings.Contains(inner, "*") {
+			leading := strings.HasPrefix(str.Value, "*")
+			trailing := len(str.Value) > 1 && strings.HasSuffix(str.Value, "*")
+			expr.Literal = inner
+			switch {
+			case leading && trailing:
+				expr.Kind = ast.PatternContains
+			case leading:
+				expr.Kind = ast.PatternSuffix
+			default:
+				expr.Kind = ast.PatternPrefix
+			}
+		}
+	}
+
+	return expr
+}
+
 func (p *Parser) parseFunctionExpression() ast.Expression {
 	expr := &ast.FunctionExpression{Token: p.currentToken()}
 	p.nextToken()
+	return p.parseFunctionParts(expr)
+}
+
+// fn add(a, b) { ... } declares the same const as add :: fn(a, b) { ... }
+func (p *Parser) parseFunctionDeclaration() (ast.Statement, error) {
+	fnToken := p.nextToken()
+	name, ok := p.parseIdentifier().(*ast.Identifier)
+	if !ok {
+		return nil, p.error(fnToken, "expected the name of the function after fn, got shit")
+	}
+
+	fn, ok := p.parseFunctionParts(&ast.FunctionExpression{Token: fnToken}).(*ast.FunctionExpression)
+	if !ok {
+		return nil, p.error(name.Token, "expected the params and the body of the function ", name.Value)
+	}
 
+	stmt := &ast.VarDeclaration{Token: lexer.Token{
+		LiteralToken: lexer.LiteralToken{
+			Text: "const",
+			Kind: lexer.TokenConst,
+		},
+		Col: fnToken.Col,
+		Row: fnToken.Row,
+	}, Name: []*ast.Identifier{name}, Value: fn}
+	return stmt, nil
+}
+
+// the params and the body, the fn keyword is already consumed
+func (p *Parser) parseFunctionParts(expr *ast.FunctionExpression) ast.Expression {
 	if !p.expect([]lexer.TokenKind{lexer.TokenBraceOpen}) {
 		p.Errors = append(p.Errors, p.error(p.currentToken(), "expected brace open '(' , got shit"))
 		return nil
 	}
 
-	self, args := p.parseArguments()
+	self, args, types := p.parseArguments()
 
 	if args == nil {
 		p.Errors = append(p.Errors, p.error(p.currentToken(), "expected arguments, got shit"))
@@ -1085,13 +1883,23 @@ func (p *Parser) parseFunctionExpression() ast.Expression {
 	// isn't required to exist
 	expr.Self = self
 	expr.Args = args
+	// the annotations are optional, a function without any keeps no types
+	if slices.ContainsFunc(types, func(t *ast.Identifier) bool { return t != nil }) {
+		expr.Types = types
+	}
+	expr.ReturnType = p.parseTypeAnnotation()
+	if expr.Types != nil || expr.ReturnType != nil {
+		p.functions = append(p.functions, expr)
+	}
 
 	if !p.expect([]lexer.TokenKind{lexer.TokenCurlyBraceOpen}) {
 		p.Errors = append(p.Errors, fmt.Errorf("expected curly brace open ( { ), got shit"))
 		return nil
 	}
 
+	p.fnDepth++
 	body := p.parseBlockStatement().(*ast.BlockStatement)
+	p.fnDepth--
 
 	if body == nil {
 		p.Errors = append(p.Errors, p.error(p.currentToken(), "expected valid body, got shit"))
@@ -1103,9 +1911,10 @@ func (p *Parser) parseFunctionExpression() ast.Expression {
 	return expr
 }
 
-func (p *Parser) parseArguments() (*ast.Identifier, []*ast.Identifier) {
+func (p *Parser) parseArguments() (*ast.Identifier, []*ast.Identifier, []*ast.Identifier) {
 	// return another identifier which is
 	args := make([]*ast.Identifier, 0)
+	types := make([]*ast.Identifier, 0)
 	self := &ast.Identifier{}
 
 	// self needs to be defined at first
@@ -1122,7 +1931,7 @@ func (p *Parser) parseArguments() (*ast.Identifier, []*ast.Identifier) {
 
 	if p.currentToken().Kind == lexer.TokenBraceClose {
 		p.nextToken()
-		return self, args
+		return self, args, types
 	}
 
 	ident := &ast.Identifier{
@@ -1132,6 +1941,7 @@ func (p *Parser) parseArguments() (*ast.Identifier, []*ast.Identifier) {
 
 	args = append(args, ident)
 	p.nextToken()
+	types = append(types, p.parseTypeAnnotation())
 
 	for p.currentToken().Kind == lexer.TokenComma {
 		p.nextToken()
@@ -1142,13 +1952,62 @@ func (p *Parser) parseArguments() (*ast.Identifier, []*ast.Identifier) {
 
 		args = append(args, ident)
 		p.nextToken()
+		types = append(types, p.parseTypeAnnotation())
 	}
 
 	if !p.expect([]lexer.TokenKind{lexer.TokenBraceClose}) {
-		return nil, nil
+		return nil, nil, nil
+	}
+
+	p.bind(args...)
+	return self, args, types
+}
+
+// the type after a :, nil when there is no : or when what follows isn't a type name
+// an example of this: the int of a: int
+func (p *Parser) parseTypeAnnotation() *ast.Identifier {
+	if p.currentToken().Kind != lexer.TokenColon {
+		return nil
+	}
+	p.nextToken()
+	return p.parseTypeName(":")
+}
+
+// the type name after the token named by after, fn and nul are keywords, they name types too
+func (p *Parser) parseTypeName(after string) *ast.Identifier {
+	tok := p.currentToken()
+	if tok.Kind != lexer.TokenIdentifier && tok.Kind != lexer.TokenFn && tok.Kind != lexer.TokenNul {
+		p.Errors = append(p.Errors, p.error(tok, fmt.Sprintf("expected a type name after %s, got %s", after, tok.Text)))
+		return nil
+	}
+	p.nextToken()
+	return &ast.Identifier{Token: tok, Value: tok.Text}
+}
+
+// read(path)!, the ! after a value, the one before it is the not operator
+func (p *Parser) parsePropagateExpression(left ast.Expression) ast.Expression {
+	tok := p.nextToken()
+	if p.fnDepth == 0 {
+		p.Errors = append(p.Errors, p.error(tok, "! returns the err results from the function it's in, it can't be used outside of one").
+			WithNote("help: use unwrap to stop the program on an err result"))
+		return nil
+	}
+	return &ast.PropagateExpression{Token: tok, Value: left}
+}
+
+// x as int and x is Vec2
+func (p *Parser) parseTypeExpression(left ast.Expression) ast.Expression {
+	tok := p.nextToken()
+	typ := p.parseTypeName(tok.Text)
+	if typ == nil {
+		return nil
 	}
+	p.typeNames = append(p.typeNames, typ)
 
-	return self, args
+	if tok.Kind == lexer.TokenAs {
+		return &ast.CastExpression{Token: tok, Value: left, Type: typ}
+	}
+	return &ast.IsExpression{Token: tok, Value: left, Type: typ}
 }
 
 func (p *Parser) parseBlockStatement() ast.Expression {
@@ -1157,6 +2016,7 @@ func (p *Parser) parseBlockStatement() ast.Expression {
 
 	for p.currentToken().Kind != lexer.TokenCurlyBraceClose && p.currentToken().Kind != lexer.TokenEOF {
 		// parse body expressions and statements
+		pos := p.Pos
 		stmt, err := p.parseStatement()
 
 		if err != nil {
@@ -1164,6 +2024,10 @@ func (p *Parser) parseBlockStatement() ast.Expression {
 		} else {
 			block.Body = append(block.Body, stmt)
 		}
+		// a statement failing on its first token would fail on it again, it gets skipped
+		if p.Pos == pos {
+			p.nextToken()
+		}
 	}
 
 	if !p.expect([]lexer.TokenKind{lexer.TokenCurlyBraceClose}) {
@@ -1185,9 +2049,67 @@ func (p *Parser) parseCallExpression(left ast.Expression) ast.Expression {
 
 	exp.Args = p.parseCallArguments()
 
+	if name := exp.Function.Value; name == "include_str" || name == "include_bytes" {
+		return p.parseInclude(&exp)
+	}
+	// the calls in the functions could reach a local of the same name
+	if p.fnDepth == 0 {
+		p.calls = append(p.calls, &exp)
+	}
+
 	return &exp
 }
 
+// include_str and include_bytes are replaced by the content of the file while parsing, so the
+// compiled programs carry it and don't need the file anymore
+func (p *Parser) parseInclude(call *ast.CallExpression) ast.Expression {
+	name := call.Function.Value
+	if len(call.Args) != 1 {
+		p.Errors = append(p.Errors, p.error(call.Token, fmt.Sprintf("%s takes the path of the file, an example of this: %s(\"template.html\")", name, name)))
+		return call
+	}
+	path, ok := call.Args[0].(*ast.StringLiteral)
+	if !ok {
+		p.Errors = append(p.Errors, p.error(call.Args[0].GetToken(), fmt.Sprintf("the path of %s needs to be a string literal", name)).
+			WithNote("help: the file is read while parsing, before any value is computed"))
+		return call
+	}
+
+	full := path.Value
+	if !filepath.IsAbs(full) {
+		dir := p.Dir
+		if len(dir) == 0 {
+			dir = filepath.Dir(p.FilePath)
+		}
+		full = filepath.Join(dir, full)
+	}
+	if CheckFS != nil {
+		if err := CheckFS(full); err != nil {
+			p.Errors = append(p.Errors, p.error(path.Token, fmt.Sprintf("can't include %q, %v", path.Value, err)))
+			return call
+		}
+	}
+	content, err := os.ReadFile(full)
+	if err != nil {
+		if pathErr, ok := err.(*os.PathError); ok {
+			err = pathErr.Err
+		}
+		p.Errors = append(p.Errors, p.error(path.Token, fmt.Sprintf("can't include %q, %v", path.Value, err)).
+			WithNote("help: the path is relative to the directory of the program"))
+		return call
+	}
+
+	if name == "include_bytes" {
+		return &ast.BytesLiteral{Token: call.Token, Value: content}
+	}
+	if !utf8.Valid(content) {
+		p.Errors = append(p.Errors, p.error(path.Token, fmt.Sprintf("%q isn't utf-8 text", path.Value)).
+			WithNote("help: include_bytes reads binary files"))
+		return call
+	}
+	return &ast.StringLiteral{Token: call.Token, Value: string(content)}
+}
+
 func (p *Parser) parseCallArguments() []ast.Expression {
 	args := make([]ast.Expression, 0)
 	if !p.expect([]lexer.TokenKind{lexer.TokenBraceOpen}) {
@@ -1238,6 +2160,7 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 		return nil
 	}
 
+	p.indexes = append(p.indexes, exp)
 	return exp
 }
 
@@ -1343,14 +2266,14 @@ func (p *Parser) parseAssignOperatorExpression() (*ast.AssignStatement, error) {
 	// consume the operator token
 	p.nextToken()
 	// parse the operator
-	expr.Right = []ast.Expression{
-		&ast.BinaryExpression{
-			Token:    p.currentToken(),
-			Operator: operator,
-			Left:     expr.Left[0],
-			Right:    p.parseExpression(LOWEST),
-		},
+	binary := &ast.BinaryExpression{
+		Token:    p.currentToken(),
+		Operator: operator,
+		Left:     expr.Left[0],
+		Right:    p.parseExpression(LOWEST),
 	}
+	p.checkDivisor(operator, binary.Right)
+	expr.Right = []ast.Expression{binary}
 
 	return expr, nil
 }
@@ -1400,11 +2323,10 @@ func (p *Parser) parseBindExpression() (ast.Statement, error) {
 
 	switch tok.Kind {
 	case lexer.TokenBind:
-		stmt.Token = lexer.Token{
-			LiteralToken: lexer.LiteralToken{
-				Text: "const",
-				Kind: lexer.TokenConst,
-			},
+		// the position stays the one of the names
+		stmt.Token.LiteralToken = lexer.LiteralToken{
+			Text: "const",
+			Kind: lexer.TokenConst,
 		}
 		stmt.Mutable = false
 		// fall through
@@ -1420,6 +2342,10 @@ func (p *Parser) parseBindExpression() (ast.Statement, error) {
 		return nil, p.error(tok, "expected an ast.Expression, got nil value")
 	}
 
+	if _, err := p.valueType(value); err != nil {
+		return nil, err
+	}
+
 	stmt.Value = value
 
 	return stmt, nil
@@ -1453,6 +2379,7 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	precedence := p.peekPrecedence()
 	p.nextToken()
 	right := p.parseExpression(precedence)
+	p.checkDivisor(tok.Text, right)
 
 	return &ast.BinaryExpression{
 		Token:    tok,
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"blk/ast"
+	"blk/lexer"
+	"fmt"
+	"unicode/utf8"
+)
+
+// x / 0 and x % 0, the divisor is known without running the program
+func (p *Parser) checkDivisor(operator string, right ast.Expression) {
+	if operator != lexer.TokenSlash && operator != lexer.TokenModule || !isZeroLiteral(right) {
+		return
+	}
+	p.Errors = append(p.Errors, p.error(right.GetToken(), fmt.Sprintf("%s by zero, the divisor is the literal %s", divisionName(operator), right.String())))
+}
+
+func divisionName(operator string) string {
+	if operator == lexer.TokenModule {
+		return "modulo"
+	}
+	return "division"
+}
+
+// an int 0, dividing a float by 0.0 gives +Inf or NaN at runtime instead of failing
+func isZeroLiteral(expr ast.Expression) bool {
+	switch lit := expr.(type) {
+	case *ast.IntegerLiteral:
+		return lit.Value == 0
+	case *ast.UnaryExpression:
+		return lit.Operator == lexer.TokenMinus && isZeroLiteral(lit.Right)
+	}
+	return false
+}
+
+// the value of an int literal index, -2 included
+func constantIndex(expr ast.Expression) (int64, bool) {
+	switch lit := expr.(type) {
+	case *ast.IntegerLiteral:
+		return lit.Value, true
+	case *ast.UnaryExpression:
+		if value, ok := constantIndex(lit.Right); ok && lit.Operator == lexer.TokenMinus {
+			return -value, true
+		}
+	}
+	return 0, false
+}
+
+// the literal indexes out of the arrays and the strings whose length is known: the literals and
+// the const arrays of the top level, a const array can't grow, the names bound again somewhere
+// else in the file are left to the runtime
+func (p *Parser) checkConstantIndexes(statements []ast.Statement) {
+	lengths := make(map[string]int)
+	for _, stmt := range statements {
+		decl, ok := stmt.(*ast.VarDeclaration)
+		if !ok || decl.Mutable || len(decl.Name) != 1 || p.bindings[decl.Name[0].Value] != 1 {
+			continue
+		}
+		if arr, ok := decl.Value.(*ast.ArrayLiteral); ok && arr.Size == nil {
+			lengths[decl.Name[0].Value] = len(arr.Elements)
+		}
+	}
+
+	for _, expr := range p.indexes {
+		if expr.Range || expr.Start == nil {
+			continue
+		}
+		idx, ok := constantIndex(expr.Start)
+		if !ok {
+			continue
+		}
+
+		var length int
+		unit := "elements"
+		switch left := expr.Left.(type) {
+		case *ast.ArrayLiteral:
+			if left.Size != nil {
+				continue
+			}
+			length = len(left.Elements)
+		case *ast.StringLiteral:
+			length = utf8.RuneCountInString(left.Value)
+			unit = "chars"
+		case *ast.Identifier:
+			if length, ok = lengths[left.Value]; !ok {
+				continue
+			}
+		default:
+			continue
+		}
+
+		if idx < 0 || idx >= int64(length) {
+			err := p.error(expr.Start.GetToken(), fmt.Sprintf("index %d is out of bounds, %s has %d %s", idx, expr.Left.String(), length, unit))
+			if length > 0 {
+				err = err.WithNote("help: the indexes go from 0 to %d", length-1)
+			}
+			p.Errors = append(p.Errors, err)
+		}
+	}
+}
+
+func (p *Parser) bind(names ...*ast.Identifier) {
+	if p.bindings == nil {
+		p.bindings = make(map[string]int)
+	}
+	for _, name := range names {
+		p.bindings[name.Value]++
+	}
+}
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"blk/ast"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// checks the type annotations of the file, the type names have to be builtin types or names
+// declared at the top level (structs, enums), and the literals passed to an annotated function
+// have to fit its args, the other values are only known when the program runs, the interpreter
+// checks them when they get in the function
+func (p *Parser) checkAnnotations(statements []ast.Statement) {
+	declared := make(map[string]int)
+	functions := make(map[string]*ast.FunctionExpression)
+	for _, stmt := range statements {
+		decl, ok := stmt.(*ast.VarDeclaration)
+		if !ok {
+			continue
+		}
+		for _, name := range decl.Name {
+			declared[name.Value]++
+		}
+		if fn, ok := decl.Value.(*ast.FunctionExpression); ok && !decl.Mutable && len(decl.Name) == 1 {
+			functions[decl.Name[0].Value] = fn
+		}
+	}
+
+	for _, fn := range p.functions {
+		for idx, typ := range fn.Types {
+			if typ == nil {
+				continue
+			}
+			if typ.Value == ast.VoidType {
+				p.Errors = append(p.Errors, p.error(typ.Token, fmt.Sprintf("void is only a return type, %s can't be void", fn.Args[idx].Value)).
+					WithNote("help: nul takes only nul, any takes every value"))
+				continue
+			}
+			p.checkTypeName(typ, declared)
+		}
+		if fn.ReturnType != nil && fn.ReturnType.Value != ast.VoidType {
+			p.checkTypeName(fn.ReturnType, declared)
+		}
+	}
+
+	for _, typ := range p.typeNames {
+		p.checkTypeName(typ, declared)
+	}
+
+	for _, call := range p.calls {
+		name := call.Function.Value
+		fn, ok := functions[name]
+		// a name declared again could be another function by the time of the call
+		if !ok || declared[name] > 1 || fn.Types == nil || len(call.Args) != len(fn.Args) {
+			continue
+		}
+		for idx, arg := range call.Args {
+			if fn.Types[idx] == nil {
+				continue
+			}
+			want, got := fn.Types[idx].Value, literalType(arg)
+			if len(got) > 0 && !literalFits(want, got) {
+				p.Errors = append(p.Errors, p.error(arg.GetToken(), fmt.Sprintf("arg %s of %s needs to be %s, got %s", fn.Args[idx].Value, name, want, got)))
+			}
+		}
+	}
+}
+
+func (p *Parser) checkTypeName(typ *ast.Identifier, declared map[string]int) {
+	if slices.Contains(ast.BuiltinTypes, typ.Value) || declared[typ.Value] > 0 {
+		return
+	}
+	p.Errors = append(p.Errors, p.error(typ.Token, fmt.Sprintf("unknown type %s", typ.Value)).
+		WithNote("help: the types are %s, void for the return values, or the name of a struct or an enum declared at the top level", strings.Join(ast.BuiltinTypes, ", ")))
+}
+
+// the type of a literal the way the annotations write it, empty for the other expressions
+func literalType(expr ast.Expression) string {
+	switch expr.(type) {
+	case *ast.IntegerLiteral:
+		return ast.IntType
+	case *ast.FloatLiteral:
+		return ast.FloatType
+	case *ast.StringLiteral:
+		return ast.StringType
+	case *ast.CharLiteral:
+		return ast.CharType
+	case *ast.BooleanLiteral:
+		return ast.BoolType
+	case *ast.NulLiteral:
+		return ast.NulType
+	case *ast.ArrayLiteral, *ast.BytesLiteral:
+		return ast.ArrayType
+	case *ast.MapLiteral:
+		return ast.MapType
+	case *ast.FunctionExpression:
+		return ast.FnType
+	}
+	return ""
+}
+
+// whether a literal of type got fits an arg annotated with want, a struct or an enum name
+// never takes a literal, except the ints of the enums which can't be told apart here
+func literalFits(want, got string) bool {
+	if want == ast.AnyType || want == got {
+		return true
+	}
+	return !slices.Contains(ast.BuiltinTypes, want) && got == ast.IntType
+}
@@ -0,0 +1,109 @@
+package profiler
+
+import (
+	"blk/interpreter"
+	"blk/object"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// frame of the blk call stack, labeled with the callee and its call site
+type frame struct {
+	label string
+	start time.Time
+	// time spent in the calls made from this frame
+	children time.Duration
+}
+
+// attributes the time spent running a program to its call stacks
+// instead of sampling on a timer, each call measures its own time, so the
+// weight of a stack is its self time in microseconds, which is what a sampler
+// at one sample per microsecond would report
+type Profiler struct {
+	stack   []*frame
+	samples map[string]time.Duration // folded stack => self time
+}
+
+// root is the label of the bottom frame, mostly the program file name
+func New(root string) *Profiler {
+	return &Profiler{
+		stack:   []*frame{{label: root, start: time.Now()}},
+		samples: make(map[string]time.Duration),
+	}
+}
+
+// hooks to install on the interpreter that runs the program
+func (p *Profiler) Hooks() *interpreter.Hooks {
+	return &interpreter.Hooks{
+		OnCall:   p.enter,
+		OnReturn: p.leave,
+	}
+}
+
+func (p *Profiler) enter(call interpreter.CallEvent) {
+	label := call.Name
+	if call.Node != nil {
+		tok := call.Node.GetToken()
+		label = fmt.Sprintf("%s (%s:%d)", call.Name, call.File, tok.Row)
+	}
+	p.stack = append(p.stack, &frame{label: label, start: time.Now()})
+}
+
+func (p *Profiler) leave(call interpreter.CallEvent, result object.Object) {
+	// the root frame is only closed by Stop
+	if len(p.stack) <= 1 {
+		return
+	}
+	p.pop()
+}
+
+func (p *Profiler) pop() {
+	top := p.stack[len(p.stack)-1]
+	total := time.Now().Sub(top.start)
+	p.samples[p.folded()] += total - top.children
+
+	p.stack = p.stack[:len(p.stack)-1]
+	if len(p.stack) > 0 {
+		p.stack[len(p.stack)-1].children += total
+	}
+}
+
+// semicolon separated labels of the current stack, from the root up
+func (p *Profiler) folded() string {
+	labels := make([]string, len(p.stack))
+	for idx, f := range p.stack {
+		// ; separates the frames in the folded format
+		labels[idx] = strings.ReplaceAll(f.label, ";", ",")
+	}
+	return strings.Join(labels, ";")
+}
+
+// closes the frames still open, the root one included
+func (p *Profiler) Stop() {
+	for len(p.stack) > 0 {
+		p.pop()
+	}
+}
+
+// writes the folded stacks (stack count per line) understood by
+// flamegraph.pl, speedscope and inferno, sorted to keep the output stable
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	stacks := make([]string, 0, len(p.samples))
+	for stack := range p.samples {
+		stacks = append(stacks, stack)
+	}
+	slices.Sort(stacks)
+
+	for _, stack := range stacks {
+		// stacks faster than a microsecond still show up
+		weight := max(p.samples[stack].Microseconds(), 1)
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, weight); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package repl
 
 import (
+	"blk/diagnostics"
 	"blk/interpreter"
 	"blk/lexer"
 	"blk/object"
@@ -27,20 +28,73 @@ func Start(in io.Reader, out io.Writer) {
 		if strings.Contains(line, "exit()") {
 			break
 		}
-		l := lexer.NewLexer("", line)
-		p := parser.NewParser(l.Tokenize(), "")
-		program := p.Parse()
-		if len(p.Errors) != 0 {
-			for _, err := range p.Errors {
-				fmt.Println(err)
+
+		evaluated := eval(env, line, out)
+
+		// offer the quick fix of the error, the fixed line runs right away
+		for {
+			err, ok := evaluated.(*object.Error)
+			if !ok || err.Fix == nil || !err.Fix.Applicable() {
+				break
+			}
+			fixed, ok := err.Fix.Apply(line)
+			if !ok {
+				break
+			}
+
+			fmt.Printf("quick fix: %s, apply it? [y/N] ", err.Fix.Title)
+			if !scanner.Scan() {
+				return
 			}
-			continue
+			if answer := strings.ToLower(strings.TrimSpace(scanner.Text())); answer != "y" && answer != "yes" {
+				break
+			}
+
+			line = fixed
+			fmt.Println(PROMPT + strings.ReplaceAll(line, "\n", "\n"+PROMPT))
+			evaluated = eval(env, line, out)
 		}
-		i := interpreter.NewInterpreter(env, "")
-		evaluated := i.Eval(program)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+	}
+}
+
+// runs a line in the session env, returns what it evaluated to
+func eval(env *object.Environment, line string, out io.Writer) object.Object {
+	renderer := diagnostics.NewRenderer()
+	renderer.AddSource("", line)
+
+	l := lexer.NewLexer("", line)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	if len(p.Errors) != 0 {
+		for _, err := range p.Errors {
+			fmt.Println(render(renderer, err))
 		}
+		if summary := p.ErrorSummary(); len(summary) > 0 {
+			fmt.Println(summary)
+		}
+		return nil
+	}
+	i := interpreter.NewInterpreter(env, "")
+	evaluated := i.Eval(program)
+	for _, warning := range i.Warnings {
+		fmt.Println(render(renderer, warning))
+	}
+	if err, ok := evaluated.(*object.Error); ok && err.Row > 0 {
+		// the fix is offered right after
+		d := err.Diagnostic()
+		d.Notes = nil
+		io.WriteString(out, renderer.Render(d))
+		io.WriteString(out, "\n")
+	} else if evaluated != nil {
+		io.WriteString(out, evaluated.Inspect())
+		io.WriteString(out, "\n")
+	}
+	return evaluated
+}
+
+func render(renderer *diagnostics.Renderer, err error) string {
+	if d, ok := err.(*diagnostics.Diagnostic); ok {
+		return renderer.Render(d)
 	}
+	return err.Error()
 }
@@ -0,0 +1,472 @@
+package stdlib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"blk/object"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func archiveModule() object.Module {
+	return object.Module{
+		"zip":      &object.BuiltinFn{Fn: archiveCreate("archive.zip", zipFormat)},
+		"unzip":    &object.BuiltinFn{Fn: archiveExtract("archive.unzip", zipFormat)},
+		"tar_gz":   &object.BuiltinFn{Fn: archiveCreate("archive.tar_gz", tarGzFormat)},
+		"untar_gz": &object.BuiltinFn{Fn: archiveExtract("archive.untar_gz", tarGzFormat)},
+		"list":     &object.BuiltinFn{Fn: archiveList},
+	}
+}
+
+type archiveFormat int
+
+const (
+	zipFormat archiveFormat = iota
+	tarGzFormat
+)
+
+// an entry of an archive, the name uses slashes, link is the target of a symlink
+type archiveEntry struct {
+	name    string
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+	link    string
+}
+
+// tells apart the formats with the first bytes of the file, the extension doesn't matter
+func detectArchive(src string) (archiveFormat, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	head := make([]byte, 4)
+	n, _ := io.ReadFull(file, head)
+	switch {
+	case bytes.HasPrefix(head[:n], []byte("PK\x03\x04")), bytes.HasPrefix(head[:n], []byte("PK\x05\x06")):
+		return zipFormat, nil
+	case bytes.HasPrefix(head[:n], []byte{0x1F, 0x8B}):
+		return tarGzFormat, nil
+	}
+	return 0, fmt.Errorf("%s isn't a zip or a tar.gz archive", src)
+}
+
+// calls visit with every entry of the archive and its content, in the order they're stored
+func readArchive(format archiveFormat, src string, visit func(entry archiveEntry, body io.Reader) error) error {
+	if format == zipFormat {
+		reader, err := zip.OpenReader(src)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		for _, file := range reader.File {
+			body, err := file.Open()
+			if err != nil {
+				return fmt.Errorf("%s: %v", file.Name, err)
+			}
+			entry := archiveEntry{name: file.Name, mode: file.Mode(), size: int64(file.UncompressedSize64), modTime: file.Modified}
+			// zip keeps the target of a symlink as its content
+			if entry.mode&fs.ModeSymlink != 0 {
+				target, err := io.ReadAll(body)
+				if err != nil {
+					body.Close()
+					return fmt.Errorf("%s: %v", file.Name, err)
+				}
+				entry.link = string(target)
+			}
+			err = visit(entry, body)
+			body.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entry := archiveEntry{name: header.Name, mode: header.FileInfo().Mode(), size: header.Size, modTime: header.ModTime, link: header.Linkname}
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeDir, tar.TypeSymlink:
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			return fmt.Errorf("%s: entries of type %q aren't supported, only files, directories and symlinks", header.Name, header.Typeflag)
+		}
+		if err := visit(entry, reader); err != nil {
+			return err
+		}
+	}
+}
+
+// writes the entries of an archive, files come with their content
+type archiveWriter interface {
+	add(entry archiveEntry, body io.Reader) error
+	Close() error
+}
+
+type zipWriter struct{ *zip.Writer }
+
+func (w zipWriter) add(entry archiveEntry, body io.Reader) error {
+	header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: entry.modTime}
+	header.SetMode(entry.mode)
+	if entry.mode.IsDir() {
+		header.Name += "/"
+		header.Method = zip.Store
+	}
+	writer, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	if entry.mode&fs.ModeSymlink != 0 {
+		body = strings.NewReader(entry.link)
+	}
+	if body != nil {
+		_, err = io.Copy(writer, body)
+	}
+	return err
+}
+
+type tarGzWriter struct {
+	gz  *gzip.Writer
+	tar *tar.Writer
+}
+
+func (w tarGzWriter) add(entry archiveEntry, body io.Reader) error {
+	header := &tar.Header{Name: entry.name, Mode: int64(entry.mode.Perm()), ModTime: entry.modTime, Typeflag: tar.TypeReg, Size: entry.size}
+	switch {
+	case entry.mode.IsDir():
+		header.Name += "/"
+		header.Typeflag, header.Size = tar.TypeDir, 0
+	case entry.mode&fs.ModeSymlink != 0:
+		header.Typeflag, header.Size, header.Linkname = tar.TypeSymlink, 0, entry.link
+	}
+	if err := w.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	if header.Typeflag == tar.TypeReg {
+		_, err := io.Copy(w.tar, body)
+		return err
+	}
+	return nil
+}
+
+func (w tarGzWriter) Close() error {
+	if err := w.tar.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+// adds a file or a directory and everything under it, named after its base name, the archive
+// being written, at the absolute path out, is left out when it's inside the directory
+func addPath(writer archiveWriter, root, out string) error {
+	root = filepath.Clean(root)
+	base := filepath.Base(root)
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if abs, _ := filepath.Abs(p); abs == out {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		entry := archiveEntry{name: path.Join(base, filepath.ToSlash(rel)), mode: info.Mode(), size: info.Size(), modTime: info.ModTime()}
+		// the directory . has no name of its own, its entries are at the root of the archive
+		if entry.name == "." {
+			return nil
+		}
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			if entry.link, err = os.Readlink(p); err != nil {
+				return err
+			}
+			return writer.add(entry, nil)
+		case info.IsDir():
+			return writer.add(entry, nil)
+		case !info.Mode().IsRegular():
+			return fmt.Errorf("%s isn't a regular file, a directory or a symlink", p)
+		}
+
+		file, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return writer.add(entry, file)
+	})
+}
+
+// the paths of the args, the first one being an array of them
+func archivePaths(arg object.Object) ([]string, *object.Error) {
+	arg, _ = object.Cast(arg)
+	arr, ok := arg.(*object.Array)
+	if !ok {
+		return nil, newError("paths need to be of type array, got=%v", arg.Type())
+	}
+	paths := make([]string, 0, len(arr.Elements))
+	for _, elem := range arr.Elements {
+		elem, _ = object.Cast(elem)
+		str, ok := elem.(*object.String)
+		if !ok {
+			return nil, newError("paths need to be strings, got=%v", elem.Type())
+		}
+		if err := requireFS(str.Value); err != nil {
+			return nil, err
+		}
+		paths = append(paths, str.Value)
+	}
+	return paths, nil
+}
+
+func archiveString(arg object.Object, name string) (string, *object.Error) {
+	arg, _ = object.Cast(arg)
+	str, ok := arg.(*object.String)
+	if !ok {
+		return "", newError("%s needs to be of type string, got=%v", name, arg.Type())
+	}
+	if err := requireFS(str.Value); err != nil {
+		return "", err
+	}
+	return str.Value, nil
+}
+
+// takes the files and directories to archive and the path of the archive, returns the path of the archive
+// the entries are named after the base name of the paths, the directories keep their tree under it,
+// an example of this: build/app/bin/run is app/bin/run in the archive of build/app
+// usage:
+// -	archive.zip(["build/app", "README.md"], "app.zip")
+// -	archive.tar_gz(["build/app"], "app.tar.gz")
+func archiveCreate(module string, format archiveFormat) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError("wrong number of arguments. got=%d, want=2",
+				len(args))
+		}
+
+		paths, err := archivePaths(args[0])
+		if err != nil {
+			return err
+		}
+		out, err := archiveString(args[1], "out")
+		if err != nil {
+			return err
+		}
+
+		file, createErr := os.Create(out)
+		if createErr != nil {
+			return newError("%s: %v", module, createErr)
+		}
+		var writer archiveWriter
+		if format == zipFormat {
+			writer = zipWriter{zip.NewWriter(file)}
+		} else {
+			gz := gzip.NewWriter(file)
+			writer = tarGzWriter{gz: gz, tar: tar.NewWriter(gz)}
+		}
+
+		fail := func(failure error) object.Object {
+			file.Close()
+			// a half written archive isn't left behind
+			os.Remove(out)
+			return newError("%s: %v", module, failure)
+		}
+		absOut, _ := filepath.Abs(out)
+		for _, p := range paths {
+			if err := addPath(writer, p, absOut); err != nil {
+				return fail(err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return fail(err)
+		}
+		if err := file.Close(); err != nil {
+			return fail(err)
+		}
+		return &object.String{Value: out}
+	}
+}
+
+// the path an entry is extracted to, the entries can't write outside of dest, by their name or by a symlink,
+// an entry going through a symlink under dest is refused, the ones of the archive since the links can be
+// chained to climb out (a -> . then a/b -> .. then b/escaped.txt) and the ones of an earlier extract alike
+func entryTarget(dest string, entry archiveEntry) (string, error) {
+	target := filepath.Join(dest, filepath.FromSlash(entry.name))
+	if !filepath.IsLocal(filepath.FromSlash(entry.name)) && filepath.Clean(target) != filepath.Clean(dest) {
+		return "", fmt.Errorf("entry %s is outside of the destination", entry.name)
+	}
+	for dir := filepath.Dir(target); dir != filepath.Clean(dest) && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if isSymlink(dir) {
+			return "", fmt.Errorf("entry %s goes through the symlink %s", entry.name, dir)
+		}
+	}
+	if entry.mode&fs.ModeSymlink == 0 {
+		// writing to the symlink would write to the file it links to
+		if isSymlink(target) {
+			return "", fmt.Errorf("entry %s overwrites the symlink %s", entry.name, target)
+		}
+		return target, nil
+	}
+
+	linked := filepath.Join(filepath.Dir(target), filepath.FromSlash(entry.link))
+	if rel, err := filepath.Rel(dest, linked); filepath.IsAbs(entry.link) || err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return "", fmt.Errorf("entry %s links to %s, outside of the destination", entry.name, entry.link)
+	}
+	// the link is checked as written, a/.. with a linking to . climbs out even if it reads as the
+	// directory of the link
+	parts := strings.Split(filepath.ToSlash(entry.link), "/")
+	dir := filepath.Dir(target)
+	for i, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		if isSymlink(dir) {
+			return "", fmt.Errorf("entry %s links through the symlink %s", entry.name, strings.Join(parts[:i+1], "/"))
+		}
+	}
+	return target, nil
+}
+
+// whether path is a symlink on disk, a missing path isn't one
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&fs.ModeSymlink != 0
+}
+
+// takes an archive and the directory to extract it in, created when it's missing, returns the paths of
+// the files extracted, the entries can't be written outside of the directory
+// usage:
+// -	archive.unzip("bundle.zip", "vendor")
+// -	archive.untar_gz("ffmpeg.tar.gz", "tools") => [tools/ffmpeg/bin/ffmpeg, ...]
+func archiveExtract(module string, format archiveFormat) object.BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return newError("wrong number of arguments. got=%d, want=2",
+				len(args))
+		}
+
+		src, err := archiveString(args[0], "src")
+		if err != nil {
+			return err
+		}
+		dest, err := archiveString(args[1], "dest")
+		if err != nil {
+			return err
+		}
+
+		extracted := []object.Object{}
+		readErr := readArchive(format, src, func(entry archiveEntry, body io.Reader) error {
+			target, err := entryTarget(dest, entry)
+			if err != nil {
+				return err
+			}
+			switch {
+			case entry.mode.IsDir():
+				return os.MkdirAll(target, 0755)
+			case entry.mode&fs.ModeSymlink != 0:
+				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+					return err
+				}
+				os.Remove(target)
+				if err := os.Symlink(filepath.FromSlash(entry.link), target); err != nil {
+					return err
+				}
+			default:
+				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+					return err
+				}
+				perm := entry.mode.Perm()
+				if perm == 0 {
+					perm = 0644
+				}
+				file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+				if err != nil {
+					return err
+				}
+				if _, err := io.Copy(file, body); err != nil {
+					file.Close()
+					return fmt.Errorf("%s: %v", entry.name, err)
+				}
+				if err := file.Close(); err != nil {
+					return err
+				}
+			}
+			extracted = append(extracted, &object.String{Value: target})
+			return nil
+		})
+		if readErr != nil {
+			return newError("%s: %v", module, readErr)
+		}
+		return &object.Array{Size: -1, Elements: extracted}
+	}
+}
+
+// takes a zip or a tar.gz archive, told apart by their content, returns its entries, each has the name,
+// the size and dir, whether it's a directory
+// usage:
+// -	for _, entry in archive.list("bundle.zip") { fmt.println(entry.name, entry.size) }
+func archiveList(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	src, err := archiveString(args[0], "src")
+	if err != nil {
+		return err
+	}
+	format, detectErr := detectArchive(src)
+	if detectErr != nil {
+		return newError("archive.list: %v", detectErr)
+	}
+
+	entries := []object.Object{}
+	readErr := readArchive(format, src, func(entry archiveEntry, _ io.Reader) error {
+		entries = append(entries, newRecord(map[string]object.Object{
+			"name": &object.String{Value: strings.TrimSuffix(entry.name, "/")},
+			"size": &object.Integer{Value: entry.size},
+			"dir":  nativeBool(entry.mode.IsDir()),
+		}))
+		return nil
+	})
+	if readErr != nil {
+		return newError("archive.list: %v", readErr)
+	}
+	return &object.Array{Size: -1, Elements: entries}
+}
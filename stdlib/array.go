@@ -6,19 +6,21 @@ import (
 	"sort"
 )
 
-var arrayModule = object.Module{
-	"equals":  &object.BuiltinFn{Fn: arrayEquals},
-	"index":   &object.BuiltinFn{Fn: arrayIndex},
-	"append":  &object.BuiltinFn{Fn: arrayAppend},
-	"reverse": &object.BuiltinFn{Fn: arrayReverse},
-	"sort":    &object.BuiltinFn{Fn: arraySort},
-	"min":     &object.BuiltinFn{Fn: arrayMin},
-	"max":     &object.BuiltinFn{Fn: arrayMax},
-	"replace": &object.BuiltinFn{Fn: arrayReplace},
-	"insert":  &object.BuiltinFn{Fn: arrayInsert},
-	"delete":  &object.BuiltinFn{Fn: arrayDelete},
-	"concat":  &object.BuiltinFn{Fn: arrayConcat},
-	// "contains": &object.BuiltinFn{Fn: arrayContains},
+func arrayModule() object.Module {
+	return object.Module{
+		"equals":  &object.BuiltinFn{Fn: arrayEquals, Pure: true},
+		"index":   &object.BuiltinFn{Fn: arrayIndex, Pure: true},
+		"append":  &object.BuiltinFn{Fn: arrayAppend},
+		"reverse": &object.BuiltinFn{Fn: arrayReverse},
+		"sort":    &object.BuiltinFn{Fn: arraySort},
+		"min":     &object.BuiltinFn{Fn: arrayMin, Pure: true},
+		"max":     &object.BuiltinFn{Fn: arrayMax, Pure: true},
+		"replace": &object.BuiltinFn{Fn: arrayReplace},
+		"insert":  &object.BuiltinFn{Fn: arrayInsert},
+		"delete":  &object.BuiltinFn{Fn: arrayDelete},
+		"concat":  &object.BuiltinFn{Fn: arrayConcat, Pure: true},
+		// "contains": &object.BuiltinFn{Fn: arrayContains},
+	}
 }
 
 // checks if 2 arrays are equals or not, and this by using a builtin method on the object interface
@@ -0,0 +1,391 @@
+package stdlib
+
+import (
+	"blk/object"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func diffModule() object.Module {
+	return object.Module{
+		"lines": &object.BuiltinFn{Fn: diffLines, Options: true},
+		"apply": &object.BuiltinFn{Fn: diffApply},
+	}
+}
+
+// the unchanged lines written around every change
+const diffContext = 3
+
+const noNewline = "\\ No newline at end of file"
+
+// a line of the edit script, op is ' ' for the lines kept, '-' for the removed ones and '+' for the added ones
+type edit struct {
+	op   byte
+	line string
+}
+
+// splits the text after every \n, a last line without one is kept as is
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// the shortest edit script turning a into b, with the algorithm of Myers, the same diff(1) uses
+// the furthest x reached on every diagonal k is kept per step d, only the diagonals the step can reach
+func shortestEdit(a, b []string) []edit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= n+m && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// walks back from the end, trace[d] holds where step d started from
+	edits := make([]edit, 0, n+m)
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d]
+		at := func(k int) int { return prev[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			edits = append(edits, edit{' ', a[x-1]})
+			x, y = x-1, y-1
+		}
+		if x == prevX {
+			edits = append(edits, edit{'+', b[y-1]})
+		} else {
+			edits = append(edits, edit{'-', a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		edits = append(edits, edit{' ', a[x-1]})
+		x, y = x-1, y-1
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// a range of a hunk header, diff(1) leaves out the length when it's 1, and starts an empty range at the line before it
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return strconv.Itoa(start + 1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, length)
+	}
+}
+
+// returns the unified diff turning a into b, with the names from and to in its header, empty when they're the same
+func UnifiedDiff(from, to, a, b string) string {
+	edits := shortestEdit(splitLines(a), splitLines(b))
+
+	// the lines of a and of b before the edit at i
+	aAt, bAt := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	for i, e := range edits {
+		aAt[i+1], bAt[i+1] = aAt[i], bAt[i]
+		if e.op != '+' {
+			aAt[i+1]++
+		}
+		if e.op != '-' {
+			bAt[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	for i, prevEnd := 0, 0; ; {
+		for i < len(edits) && edits[i].op == ' ' {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+
+		// the changes closer than twice the context share a hunk
+		last := i
+		for j := i + 1; j < len(edits); {
+			if edits[j].op != ' ' {
+				last, j = j, j+1
+				continue
+			}
+			run := j
+			for run < len(edits) && edits[run].op == ' ' {
+				run++
+			}
+			if run == len(edits) || run-j > 2*diffContext {
+				break
+			}
+			j = run
+		}
+		start, end := max(i-diffContext, prevEnd), min(last+1+diffContext, len(edits))
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", from, to)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aAt[start], aAt[end]-aAt[start]), hunkRange(bAt[start], bAt[end]-bAt[start]))
+		for _, e := range edits[start:end] {
+			out.WriteByte(e.op)
+			out.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				out.WriteString("\n" + noNewline + "\n")
+			}
+		}
+		i, prevEnd = end, end
+	}
+	return out.String()
+}
+
+// a hunk of a patch, the lines it expects to find and the lines replacing them
+type hunk struct {
+	header   string
+	oldStart int
+	old, new []string
+}
+
+// reads the start and the length of a range like 3,4 or 3
+func parseRange(raw string) (int, int, error) {
+	rawStart, rawLen, hasLen := strings.Cut(raw, ",")
+	start, err := strconv.Atoi(rawStart)
+	if err != nil {
+		return 0, 0, err
+	}
+	length := 1
+	if hasLen {
+		if length, err = strconv.Atoi(rawLen); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, length, nil
+}
+
+func parsePatch(patch string) ([]hunk, error) {
+	var hunks []hunk
+	lines := splitLines(patch)
+	for i := 0; i < len(lines); i++ {
+		header := strings.TrimRight(lines[i], "\r\n")
+		// the file headers and anything around the hunks are skipped, like patch(1) does
+		if !strings.HasPrefix(header, "@@ -") {
+			continue
+		}
+		fields := strings.Fields(header)
+		if len(fields) < 4 || !strings.HasPrefix(fields[2], "+") || fields[3] != "@@" {
+			return nil, fmt.Errorf("invalid hunk header %q", header)
+		}
+		oldStart, oldLen, err := parseRange(fields[1][1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid hunk header %q", header)
+		}
+		_, newLen, err := parseRange(fields[2][1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid hunk header %q", header)
+		}
+
+		h := hunk{header: header, oldStart: oldStart}
+		// the side the last line went to, for the no newline markers
+		var last *[]string
+		for i+1 < len(lines) && (len(h.old) < oldLen || len(h.new) < newLen || strings.HasPrefix(lines[i+1], "\\")) {
+			i++
+			line := lines[i]
+			if len(line) == 0 || line == "\n" {
+				// an editor trimmed the space of an empty context line
+				line = " \n"
+			}
+			switch line[0] {
+			case ' ':
+				h.old, h.new = append(h.old, line[1:]), append(h.new, line[1:])
+				last = nil
+			case '-':
+				h.old = append(h.old, line[1:])
+				last = &h.old
+			case '+':
+				h.new = append(h.new, line[1:])
+				last = &h.new
+			case '\\':
+				if len(h.old)+len(h.new) == 0 {
+					return nil, fmt.Errorf("%s: %s before the lines of the hunk", header, noNewline)
+				}
+				if last == nil {
+					h.old[len(h.old)-1] = strings.TrimSuffix(h.old[len(h.old)-1], "\n")
+					h.new[len(h.new)-1] = strings.TrimSuffix(h.new[len(h.new)-1], "\n")
+				} else {
+					(*last)[len(*last)-1] = strings.TrimSuffix((*last)[len(*last)-1], "\n")
+				}
+			default:
+				return nil, fmt.Errorf("%s: unexpected line %q", header, strings.TrimRight(line, "\n"))
+			}
+		}
+		if len(h.old) != oldLen || len(h.new) != newLen {
+			return nil, fmt.Errorf("%s: the hunk ends early", header)
+		}
+		hunks = append(hunks, h)
+	}
+	return hunks, nil
+}
+
+func linesMatch(lines []string, at int, want []string) bool {
+	if at+len(want) > len(lines) {
+		return false
+	}
+	for i, line := range want {
+		if lines[at+i] != line {
+			return false
+		}
+	}
+	return true
+}
+
+// applies a unified diff to the text, a hunk whose lines moved is looked for around where it
+// was, the closest place first, the patch fails when a hunk isn't anywhere after the previous one
+func ApplyPatch(text, patch string) (string, error) {
+	hunks, err := parsePatch(patch)
+	if err != nil {
+		return "", err
+	}
+
+	lines := splitLines(text)
+	var out strings.Builder
+	cursor := 0
+	for _, h := range hunks {
+		expected := h.oldStart - 1
+		if len(h.old) == 0 {
+			// the range of a hunk only adding lines starts at the line before them
+			expected = h.oldStart
+		}
+		expected = max(expected, cursor)
+
+		at := -1
+		for delta := 0; expected-delta >= cursor || expected+delta <= len(lines); delta++ {
+			if linesMatch(lines, expected+delta, h.old) {
+				at = expected + delta
+				break
+			}
+			if delta > 0 && expected-delta >= cursor && linesMatch(lines, expected-delta, h.old) {
+				at = expected - delta
+				break
+			}
+		}
+		if at < 0 {
+			return "", fmt.Errorf("%s: the lines of the hunk aren't in the text", h.header)
+		}
+
+		for _, line := range lines[cursor:at] {
+			out.WriteString(line)
+		}
+		for _, line := range h.new {
+			out.WriteString(line)
+		}
+		cursor = at + len(h.old)
+	}
+	for _, line := range lines[cursor:] {
+		out.WriteString(line)
+	}
+	return out.String(), nil
+}
+
+// takes the old text, the new one and the optional options, returns the unified diff between them,
+// with 3 lines of context around the changes, empty when the texts are the same
+// the options are from and to, the names in the header, a and b by default
+// usage:
+// -	diff.lines("a\nb\n", "a\nc\n") => --- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n
+// -	diff.lines(golden, got, {"from": "golden.txt", "to": "output"})
+func diffLines(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3",
+			len(args))
+	}
+
+	texts := make([]string, 2)
+	for i, name := range []string{"a", "b"} {
+		arg, _ := object.Cast(args[i])
+		str, ok := arg.(*object.String)
+		if !ok {
+			return newError("%s needs to be of type string, got=%v", name, arg.Type())
+		}
+		texts[i] = str.Value
+	}
+
+	from, to := "a", "b"
+	if len(args) == 3 {
+		opts, err := readOptions(args[2], map[string]optionKind{
+			"from": stringOption,
+			"to":   stringOption,
+		})
+		if err != nil {
+			return err
+		}
+		if opts.has("from") {
+			from = opts["from"].(*object.String).Value
+		}
+		if opts.has("to") {
+			to = opts["to"].(*object.String).Value
+		}
+	}
+
+	return &object.String{Value: UnifiedDiff(from, to, texts[0], texts[1])}
+}
+
+// takes a text and a unified diff, returns the text with the diff applied, like patch(1) does
+// the hunks can have moved since the diff was made, their lines need to be the same
+// usage:
+// -	patch := diff.lines(old, new)
+// -	diff.apply(old, patch) == new => true
+func diffApply(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	text, ok := args[0].(*object.String)
+	if !ok {
+		return newError("text needs to be of type string, got=%v", args[0].Type())
+	}
+	args[1], _ = object.Cast(args[1])
+	patch, ok := args[1].(*object.String)
+	if !ok {
+		return newError("patch needs to be of type string, got=%v", args[1].Type())
+	}
+
+	patched, err := ApplyPatch(text.Value, patch.Value)
+	if err != nil {
+		return newError("diff.apply: %v", err)
+	}
+	return &object.String{Value: patched}
+}
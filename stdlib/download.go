@@ -0,0 +1,147 @@
+package stdlib
+
+import (
+	"blk/object"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// downloads through yt-dlp, it needs to be installed and in PATH
+// blk run only starts it when granted, an example of this: --allow-run=yt-dlp --allow-net=www.youtube.com
+func downloadModule() object.Module {
+	return object.Module{
+		"audio": &object.BuiltinFn{Fn: downloadAudio, Options: true},
+		"video": &object.BuiltinFn{Fn: downloadVideo, Options: true},
+	}
+}
+
+// the progress lines yt-dlp writes with --newline, an example of this: [download]  42.3% of 3.20MiB at 1.2MiB/s
+// compiled on the first download
+var progressLine = sync.OnceValue(func() *regexp.Regexp {
+	return regexp.MustCompile(`^\[download\]\s+([0-9.]+)%`)
+})
+
+// takes a url and an optional map of options, returns a map with the url, the path of the
+// downloaded file and the error, empty unless the download failed
+// the options are format, the audio format (mp3 by default, wav, m4a, flac, opus), out, the output
+// path, it can hold yt-dlp templates, %(title)s.%(ext)s by default, and on_progress, a callback
+// getting the percentage downloaded, as a float
+// usage:
+// -	download.audio("https://www.youtube.com/watch?v=id")
+// -	result := download.audio(url, {"format": "wav", "out": "tracks/%(title)s.%(ext)s", "on_progress": fn(pct) { fmt.println(pct) }})
+// -	if result["error"] != "" { ... }
+func downloadAudio(args ...object.Object) object.Object {
+	return download("download.audio", args, "mp3", func(format string) []string {
+		return []string{"-x", "--audio-format", format}
+	})
+}
+
+// same as audio for videos, format is the container the streams get merged into, mp4 by default
+// usage:
+// -	download.video(url, {"format": "mkv", "out": "videos/%(title)s.%(ext)s"})
+func downloadVideo(args ...object.Object) object.Object {
+	return download("download.video", args, "mp4", func(format string) []string {
+		return []string{"-f", "bv*+ba/b", "--merge-output-format", format}
+	})
+}
+
+// the tool failures end up in the result, the wrong args, denied capabilities
+// and errors of the callback stop the program
+func download(module string, args []object.Object, defaultFormat string, formatArgs func(format string) []string) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	url, ok := args[0].(*object.String)
+	if !ok {
+		return newError("url needs to be of type string, got=%v", args[0].Type())
+	}
+	if err := requireNet(url.Value); err != nil {
+		return err
+	}
+
+	opts := options{}
+	if len(args) >= 2 {
+		var err *object.Error
+		opts, err = readOptions(args[1], map[string]optionKind{
+			"format":      stringOption,
+			"out":         stringOption,
+			"on_progress": functionOption,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	onProgress := opts["on_progress"]
+
+	format, out := defaultFormat, "%(title)s.%(ext)s"
+	if value, ok := opts["format"].(*object.String); ok {
+		format = value.Value
+	}
+	if value, ok := opts["out"].(*object.String); ok {
+		out = value.Value
+	}
+	if err := requireFS(filepath.Dir(out)); err != nil {
+		return err
+	}
+	if err := requireRun("yt-dlp"); err != nil {
+		return err
+	}
+
+	toolArgs := append(formatArgs(format),
+		"-o", out,
+		"--no-playlist", "--newline", "--progress",
+		// prints the final path, once the post processing is done
+		"--no-simulate", "--print", "after_move:filepath",
+		url.Value,
+	)
+
+	path := ""
+	var callbackErr *object.Error
+	err := streamTool(module, "yt-dlp", toolArgs, func(line string) *object.Error {
+		match := progressLine().FindStringSubmatch(line)
+		if match == nil {
+			if trimmed := strings.TrimSpace(line); len(trimmed) > 0 && !strings.HasPrefix(trimmed, "[") {
+				path = trimmed
+			}
+			return nil
+		}
+
+		pct, _ := strconv.ParseFloat(match[1], 64)
+		if onProgress == nil {
+			return nil
+		}
+		if result := CallFunction(onProgress, &object.Float{Value: pct}); isError(result) {
+			callbackErr = result.(*object.Error)
+			return callbackErr
+		}
+		return nil
+	})
+	if callbackErr != nil {
+		return callbackErr
+	}
+
+	result := map[string]string{"url": url.Value, "path": path, "error": ""}
+	if err != nil {
+		result["path"] = ""
+		result["error"] = err.Message
+	}
+	return stringMap(result)
+}
+
+func stringMap(values map[string]string) *object.Map {
+	pairs := make(object.PairsType, len(values))
+	for name, value := range values {
+		key := &object.String{Value: name}
+		pairs[key.HashKey()] = object.HashPair{
+			Key:   key,
+			Value: &object.String{Value: value},
+		}
+	}
+	return &object.Map{Pairs: pairs}
+}
@@ -5,20 +5,27 @@ import (
 	"fmt"
 )
 
-var fmtModule = object.Module{
-	"print":   &object.BuiltinFn{Fn: print},
-	"println": &object.BuiltinFn{Fn: println},
+func fmtModule() object.Module {
+	return object.Module{
+		"print":            &object.BuiltinFn{Fn: print},
+		"println":          &object.BuiltinFn{Fn: println},
+		"format":           &object.BuiltinFn{Fn: format},
+		"set_float_format": &object.BuiltinFn{Fn: setFloatFormat},
+	}
 }
 
+// the most digits a float can be printed with, a float64 holds 17 significant ones
+const maxPrecision = 100
+
 func print(args ...object.Object) object.Object {
 	printedArgs := prettifyArgs(args...)
-	fmt.Print(printedArgs...)
+	fmt.Fprint(Stdout, printedArgs...)
 	return nil
 }
 
 func println(args ...object.Object) object.Object {
 	printedArgs := prettifyArgs(args...)
-	fmt.Println(printedArgs...)
+	fmt.Fprintln(Stdout, printedArgs...)
 	return nil
 }
 
@@ -30,3 +37,75 @@ func prettifyArgs(args ...object.Object) []any {
 	}
 	return printedArgs
 }
+
+// reads a precision and the optional notation, fixed or scientific, into the format of the floats
+func readFloatFormat(args []object.Object) (object.FloatFormat, *object.Error) {
+	arg, _ := object.Cast(args[0])
+	precision, ok := arg.(*object.Integer)
+	if !ok {
+		return object.FloatFormat{}, newError("precision needs to be of type int, got=%v", arg.Type())
+	}
+	if precision.Value < -1 || precision.Value > maxPrecision {
+		return object.FloatFormat{}, newError("precision needs to be between -1 and %d, got=%d", maxPrecision, precision.Value)
+	}
+	floatFormat := object.FloatFormat{Precision: int(precision.Value)}
+
+	if len(args) == 2 {
+		arg, _ := object.Cast(args[1])
+		notation, ok := arg.(*object.String)
+		if !ok {
+			return object.FloatFormat{}, newError("notation needs to be of type string, got=%v", arg.Type())
+		}
+		switch notation.Value {
+		case "fixed":
+		case "scientific":
+			floatFormat.Scientific = true
+		default:
+			return object.FloatFormat{}, newError("invalid notation %s, expected one of (fixed, scientific)", notation.Value)
+		}
+	}
+	return floatFormat, nil
+}
+
+// takes a value, the digits after the point of its floats and the optional notation, fixed or scientific,
+// returns the value as printed with that format, the floats inside arrays, maps and structs included
+// a precision of -1 prints as few digits as it takes to read the same float back
+// usage:
+// -	fmt.format(3.14159, 2) => 3.14
+// -	fmt.format(1234.5, 3, "scientific") => 1.234e+03
+// -	fmt.format([0.1, 2.5], -1) => [0.1, 2.5]
+func format(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3",
+			len(args))
+	}
+
+	floatFormat, err := readFloatFormat(args[1:])
+	if err != nil {
+		return err
+	}
+
+	defer func(previous object.FloatFormat) { object.FloatOutput = previous }(object.FloatOutput)
+	object.FloatOutput = floatFormat
+	return &object.String{Value: args[0].Inspect()}
+}
+
+// takes the digits after the point and the optional notation, fixed or scientific, every float printed
+// afterwards uses them, the default is 6 digits in the fixed notation
+// usage:
+// -	fmt.set_float_format(2)
+// -	fmt.println(1 / 3.0) => 0.33
+// -	fmt.set_float_format(-1, "scientific")
+func setFloatFormat(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2",
+			len(args))
+	}
+
+	floatFormat, err := readFloatFormat(args)
+	if err != nil {
+		return err
+	}
+	object.FloatOutput = floatFormat
+	return object.NUL
+}
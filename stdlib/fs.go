@@ -0,0 +1,213 @@
+package stdlib
+
+import (
+	"blk/object"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func fsModule() object.Module {
+	return object.Module{
+		"walk":      &object.BuiltinFn{Fn: fsWalk, Options: true},
+		"walk_iter": &object.BuiltinFn{Fn: fsWalkIter, Options: true},
+		"read":      &object.BuiltinFn{Fn: fsRead},
+		"write":     &object.BuiltinFn{Fn: fsWrite},
+	}
+}
+
+type walkEntry struct {
+	path  string
+	depth int
+	isDir bool
+}
+
+// depth first traversal that reads a directory only when it's reached
+// entries of a directory are visited in lexical order, same as filepath.WalkDir
+type walker struct {
+	exts     []string // lower cased extensions to keep, all files if empty
+	maxDepth int      // 0 means no limit, 1 only keeps the direct children of root
+	pending  []walkEntry
+}
+
+func (w *walker) next() (object.Object, bool) {
+	for len(w.pending) > 0 {
+		entry := w.pending[len(w.pending)-1]
+		w.pending = w.pending[:len(w.pending)-1]
+
+		if !entry.isDir {
+			if w.keep(entry.path) {
+				return &object.String{Value: entry.path}, true
+			}
+			continue
+		}
+
+		if w.maxDepth > 0 && entry.depth >= w.maxDepth {
+			continue
+		}
+
+		children, err := os.ReadDir(entry.path)
+		if err != nil {
+			w.pending = nil
+			return newError("fs.walk: %v", err), true
+		}
+
+		// pushed in reverse, so the first child gets popped first
+		for idx := len(children) - 1; idx >= 0; idx-- {
+			w.pending = append(w.pending, walkEntry{
+				path:  filepath.Join(entry.path, children[idx].Name()),
+				depth: entry.depth + 1,
+				isDir: children[idx].IsDir(),
+			})
+		}
+	}
+
+	return nil, false
+}
+
+func (w *walker) keep(path string) bool {
+	if len(w.exts) == 0 {
+		return true
+	}
+	return slices.Contains(w.exts, strings.ToLower(filepath.Ext(path)))
+}
+
+// builds the walker from the args of walk, walk_iter: root, then an optional map of filters
+// -	ext: array of extensions to keep, an example of this: [".mp4", ".mkv"], compared case insensitively, [] keeps every file
+// -	max_depth: how deep to go under root, 1 only keeps the files directly in root, 0 has no limit
+func newWalker(args []object.Object) (*walker, *object.Error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, newError("wrong number of arguments. got=%d, want=1 or 2",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	root, ok := args[0].(*object.String)
+	if !ok {
+		return nil, newError("root needs to be of type string, got=%v", args[0].Type())
+	}
+
+	opts := options{}
+	if len(args) == 2 {
+		var err *object.Error
+		opts, err = readOptions(args[1], map[string]optionKind{
+			"ext":       stringsOption,
+			"max_depth": numberOption,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := requireFS(root.Value); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(root.Value)
+	if err != nil {
+		return nil, newError("fs.walk: %v", err)
+	}
+
+	w := &walker{
+		pending: []walkEntry{{path: root.Value, isDir: info.IsDir()}},
+	}
+
+	if exts, ok := opts["ext"].(*object.Array); ok {
+		for _, elem := range exts.Elements {
+			elem, _ = object.Cast(elem)
+			w.exts = append(w.exts, strings.ToLower(elem.(*object.String).Value))
+		}
+	}
+
+	if opts.has("max_depth") {
+		depth, ok := opts["max_depth"].(*object.Integer)
+		if !ok || depth.Value < 0 {
+			return nil, newError("max_depth needs to be a positive integer, got=%v", opts["max_depth"].Inspect())
+		}
+		w.maxDepth = int(depth.Value)
+	}
+
+	return w, nil
+}
+
+// takes a root path and an optional map of the ext, max_depth filters, returns an array of the file paths under root
+// usage:
+// -	fs.walk("./downloads")
+// -	fs.walk("./downloads", {"ext": [".mp4"], "max_depth": 3})
+func fsWalk(args ...object.Object) object.Object {
+	w, err := newWalker(args)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]object.Object, 0)
+	for {
+		path, ok := w.next()
+		if !ok {
+			break
+		}
+		if path.Type() == object.ERROR_OBJ {
+			return path
+		}
+		elements = append(elements, path)
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// same as walk, but returns a stream that reads the directories as the for loop goes
+// usage:
+// -	for path in fs.walk_iter("./downloads", {"ext": [".mp4"]}) { ... }
+func fsWalkIter(args ...object.Object) object.Object {
+	w, err := newWalker(args)
+	if err != nil {
+		return err
+	}
+
+	return &object.Stream{Name: "fs.walk", Next: w.next}
+}
+
+// reads the file at path, returns a result with its content
+// usage:
+// -	content := fs.read("notes.txt")!
+func fsRead(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("fs.read takes the path of the file, got %d args", len(args))
+	}
+	args[0], _ = object.Cast(args[0])
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return newError("path needs to be of type string, got=%v", args[0].Type())
+	}
+	if err := requireFS(path.Value); err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(path.Value)
+	return resultOf(&object.String{Value: string(content)}, err)
+}
+
+// writes content to the file at path, replacing it, returns a result with nul
+// usage:
+// -	fs.write("notes.txt", "hello")!
+func fsWrite(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("fs.write takes the path of the file and its content, got %d args", len(args))
+	}
+	args[0], _ = object.Cast(args[0])
+	args[1], _ = object.Cast(args[1])
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return newError("path needs to be of type string, got=%v", args[0].Type())
+	}
+	content, ok := args[1].(*object.String)
+	if !ok {
+		return newError("content needs to be of type string, got=%v", args[1].Type())
+	}
+	if err := requireFS(path.Value); err != nil {
+		return err
+	}
+
+	return resultOf(object.NUL, os.WriteFile(path.Value, []byte(content.Value), 0o644))
+}
@@ -116,11 +116,9 @@ func funcF64F64(fn func(float64) float64) object.BuiltinFunction {
 		}
 
 		args[0], _ = object.Cast(args[0])
-		args[1], _ = object.Cast(args[1])
 
-		// check that firstArg, secondArg is string
-		if args[0].Type() != object.FLOAT_OBJ || args[1].Type() != object.FLOAT_OBJ {
-			return newError("both args need to be of type float")
+		if args[0].Type() != object.FLOAT_OBJ {
+			return newError("arg needs to be of type float, got=%v", args[0].Type())
 		}
 
 		firstArg := args[0].(*object.Float)
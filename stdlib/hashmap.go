@@ -4,13 +4,15 @@ import (
 	"blk/object"
 )
 
-var hashmapModule = object.Module{
-	"keys":     &object.BuiltinFn{Fn: KEYS},
-	"values":   &object.BuiltinFn{Fn: VALUES},
-	"equals":   &object.BuiltinFn{Fn: EQUALS},
-	"insert":   &object.BuiltinFn{Fn: INSERT},
-	"getValue": &object.BuiltinFn{Fn: GET_VALUE},
-	"delete":   &object.BuiltinFn{Fn: DELETE},
+func hashmapModule() object.Module {
+	return object.Module{
+		"keys":     &object.BuiltinFn{Fn: KEYS, Pure: true},
+		"values":   &object.BuiltinFn{Fn: VALUES, Pure: true},
+		"equals":   &object.BuiltinFn{Fn: EQUALS, Pure: true},
+		"insert":   &object.BuiltinFn{Fn: INSERT},
+		"getValue": &object.BuiltinFn{Fn: GET_VALUE, Pure: true},
+		"delete":   &object.BuiltinFn{Fn: DELETE},
+	}
 }
 
 // takes a hashmap, returns an array containing all the keys in the hashmap
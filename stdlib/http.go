@@ -0,0 +1,164 @@
+package stdlib
+
+import (
+	"blk/object"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+func httpModule() object.Module {
+	return object.Module{
+		"download": &object.BuiltinFn{Fn: httpDownload, Options: true},
+	}
+}
+
+// the redirects are checked against the net grants as well
+var httpClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return Permissions.CheckNet(req.URL.Host)
+	},
+}
+
+// takes a url, the destination path and the optional options, returns the destination path
+// the body is written to the disk as it comes, to dest.part until it's complete, a download that got
+// cut resumes from the part file when the server takes ranges, it starts over when it doesn't
+// the options are on_progress, called with the percentage downloaded as a float, when the server sends the size
+// usage:
+// -	http.download("https://example.com/talk.mp4", "talk.mp4")
+// -	http.download(url, "talk.mp4", {"on_progress": fn(pct) { bar.update(int(pct)) }})
+func httpDownload(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	url, ok := args[0].(*object.String)
+	if !ok {
+		return newError("url needs to be of type string, got=%v", args[0].Type())
+	}
+	if err := requireNet(url.Value); err != nil {
+		return err
+	}
+	args[1], _ = object.Cast(args[1])
+	dest, ok := args[1].(*object.String)
+	if !ok {
+		return newError("dest needs to be of type string, got=%v", args[1].Type())
+	}
+	if err := requireFS(dest.Value); err != nil {
+		return err
+	}
+
+	var onProgress object.Object
+	if len(args) == 3 {
+		opts, err := readOptions(args[2], map[string]optionKind{
+			"on_progress": functionOption,
+		})
+		if err != nil {
+			return err
+		}
+		onProgress = opts["on_progress"]
+	}
+
+	// the part file can be a link of its own, out of the grants
+	partPath := dest.Value + ".part"
+	if err := requireFS(partPath); err != nil {
+		return err
+	}
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url.Value, nil)
+	if err != nil {
+		return newError("http.download: %v", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	var resp *http.Response
+	unlocked(func() { resp, err = httpClient.Do(req) })
+	if err != nil {
+		return newError("http.download: %v", err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		flags = os.O_WRONLY | os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// the part file holds the whole body already
+		if err := os.Rename(partPath, dest.Value); err != nil {
+			return newError("http.download: %v", err)
+		}
+		return &object.String{Value: dest.Value}
+	case resp.StatusCode == http.StatusOK:
+		// the server ignored the range
+		offset = 0
+	default:
+		return newError("http.download: GET %s: %s", url.Value, resp.Status)
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return newError("http.download: %v", err)
+	}
+
+	written, lastPercent := offset, int64(-1)
+	buf := make([]byte, 64*1024)
+	for {
+		var n int
+		var readErr error
+		unlocked(func() { n, readErr = resp.Body.Read(buf) })
+		if n > 0 {
+			if _, err := file.Write(buf[:n]); err != nil {
+				file.Close()
+				return newError("http.download: %v", err)
+			}
+			written += int64(n)
+
+			// a call per percent, not per chunk
+			if percent := written * 100 / max(total, 1); onProgress != nil && total > 0 && percent != lastPercent {
+				lastPercent = percent
+				result := CallFunction(onProgress, &object.Float{Value: float64(written) * 100 / float64(total)})
+				if isError(result) {
+					file.Close()
+					return result
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			file.Close()
+			// the part file stays, the next call resumes from it
+			return newError("http.download: %v, %d bytes downloaded so far", readErr, written)
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return newError("http.download: %v", err)
+	}
+	if total > 0 && written != total {
+		return newError("http.download: the body ended early, downloaded %d of %d bytes", written, total)
+	}
+	if err := os.Rename(partPath, dest.Value); err != nil {
+		return newError("http.download: %v", err)
+	}
+	return &object.String{Value: dest.Value}
+}
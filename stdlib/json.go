@@ -0,0 +1,518 @@
+package stdlib
+
+import (
+	"blk/object"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+func jsonModule() object.Module {
+	return object.Module{
+		"marshal":   &object.BuiltinFn{Fn: jsonMarshal},
+		"unmarshal": &object.BuiltinFn{Fn: jsonUnmarshal},
+	}
+}
+
+// takes a value and an optional indent, returns its json text
+// struct instances are written as objects keyed by their field names, methods are left out
+// usage:
+// -	json.marshal(user) => {"name":"lofi","age":22}
+// -	json.marshal(user, "  ")
+func jsonMarshal(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2",
+			len(args))
+	}
+
+	value, err := toJSONValue(args[0], map[object.Object]bool{})
+	if err != nil {
+		return newError("json: %v", err)
+	}
+
+	indent := ""
+	if len(args) == 2 {
+		args[1], _ = object.Cast(args[1])
+		str, ok := args[1].(*object.String)
+		if !ok {
+			return newError("indent needs to be of type string, got=%v", args[1].Type())
+		}
+		indent = str.Value
+	}
+
+	var text []byte
+	if len(indent) > 0 {
+		text, err = json.MarshalIndent(value, "", indent)
+	} else {
+		text, err = json.Marshal(value)
+	}
+	if err != nil {
+		return newError("json: %v", err)
+	}
+
+	return &object.String{Value: string(text)}
+}
+
+// the fields of a struct instance, written in the order the struct declared them,
+// encoding/json would sort the keys of a map
+type orderedFields struct {
+	names  []string
+	values map[string]any
+}
+
+func (f orderedFields) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for idx, name := range f.names {
+		if idx > 0 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		value, err := json.Marshal(f.values[name])
+		if err != nil {
+			return nil, err
+		}
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// path holds the containers obj is nested in, json has no way to write a cycle
+func toJSONValue(obj object.Object, path map[object.Object]bool) (any, error) {
+	obj, _ = object.Cast(obj)
+
+	switch obj.(type) {
+	case *object.Array, *object.Map, *object.StructInstance:
+		if path[obj] {
+			return nil, fmt.Errorf("the value refers to itself, json can't represent cycles")
+		}
+		path[obj] = true
+		defer delete(path, obj)
+	}
+
+	switch obj := obj.(type) {
+	case *object.Nul:
+		return nil, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.Float:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Char:
+		return string(obj.Value), nil
+	case *object.Array:
+		elements := make([]any, len(obj.Elements))
+		for idx, elem := range obj.Elements {
+			value, err := toJSONValue(elem, path)
+			if err != nil {
+				return nil, err
+			}
+			elements[idx] = value
+		}
+		return elements, nil
+	case *object.Map:
+		// encoding/json sorts the keys
+		pairs := make(map[string]any, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			value, err := toJSONValue(pair.Value, path)
+			if err != nil {
+				return nil, err
+			}
+			pairs[pair.Key.Inspect()] = value
+		}
+		return pairs, nil
+	case *object.StructInstance:
+		fields := orderedFields{names: obj.FieldNames(), values: make(map[string]any, len(obj.Fields))}
+		for _, name := range fields.names {
+			value, err := toJSONValue(obj.Fields[name], path)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", name, err)
+			}
+			fields.values[name] = value
+		}
+		return fields, nil
+	default:
+		return nil, fmt.Errorf("values of type %s can't be written as json", obj.Type())
+	}
+}
+
+// takes a json text and an optional struct, returns the decoded value
+// without a struct, objects become maps, so their values need to share a type
+// with one, every field of the struct has to be present with the type of its default value,
+// nul fields accept any value, fields declared with a struct take an object or null,
+// keys that aren't fields are ignored
+// usage:
+// -	json.unmarshal("[1, 2]") => [1, 2]
+// -	user := json.unmarshal(text, User)
+func jsonUnmarshal(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	text, ok := args[0].(*object.String)
+	if !ok {
+		return newError("arg needs to be of type string, got=%v", args[0].Type())
+	}
+
+	root, err := parseJSON(text.Value)
+	if err != nil {
+		return newError("%v", err)
+	}
+
+	if len(args) == 1 {
+		return root.toObject(object.NUL, "$")
+	}
+
+	args[1], _ = object.Cast(args[1])
+	def, ok := args[1].(*object.Struct)
+	if !ok {
+		return newError("second arg needs to be a struct, got=%v", args[1].Type())
+	}
+
+	return root.toStruct(def, "$")
+}
+
+// a decoded json value with the position it starts at
+type jsonNode struct {
+	text   string // the whole input, to turn offsets into rows and columns
+	offset int
+	// nil, bool, json.Number, string, []*jsonNode or *jsonObject
+	value any
+}
+
+type jsonObject struct {
+	keys   []string // in input order
+	values map[string]*jsonNode
+}
+
+func parseJSON(text string) (*jsonNode, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+
+	root, err := decodeJSONNode(dec, text)
+	if err != nil {
+		return nil, jsonSyntaxError(text, err)
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("json %s: unexpected data after the top level value", jsonPosition(text, nextTokenOffset(dec, text)))
+	}
+
+	return root, nil
+}
+
+func decodeJSONNode(dec *json.Decoder, text string) (*jsonNode, error) {
+	node := &jsonNode{text: text, offset: nextTokenOffset(dec, text)}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			elements := make([]*jsonNode, 0)
+			for dec.More() {
+				elem, err := decodeJSONNode(dec, text)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, elem)
+			}
+			node.value = elements
+		} else {
+			obj := &jsonObject{values: make(map[string]*jsonNode)}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key := keyTok.(string)
+				value, err := decodeJSONNode(dec, text)
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := obj.values[key]; !ok {
+					obj.keys = append(obj.keys, key)
+				}
+				obj.values[key] = value
+			}
+			node.value = obj
+		}
+		// closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	default:
+		node.value = tok
+	}
+
+	return node, nil
+}
+
+// the decoder reports the end of the previous token, which leaves the separators in between
+func nextTokenOffset(dec *json.Decoder, text string) int {
+	offset := int(dec.InputOffset())
+	for offset < len(text) && strings.ContainsRune(" \t\r\n,:", rune(text[offset])) {
+		offset++
+	}
+	return offset
+}
+
+func jsonSyntaxError(text string, err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("json %s: %v", jsonPosition(text, int(syntaxErr.Offset)), err)
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("json %s: unexpected end of input", jsonPosition(text, len(text)))
+	}
+	return fmt.Errorf("json: %v", err)
+}
+
+// row:col of an offset, both starting at 1
+func jsonPosition(text string, offset int) string {
+	offset = min(offset, len(text))
+	before := text[:offset]
+	row := strings.Count(before, "\n") + 1
+	col := offset - strings.LastIndex(before, "\n")
+	return fmt.Sprintf("%d:%d", row, col)
+}
+
+func (n *jsonNode) errorf(path, format string, a ...any) *object.Error {
+	return newError("json %s: %s: %s", jsonPosition(n.text, n.offset), path, fmt.Sprintf(format, a...))
+}
+
+func (n *jsonNode) kind() string {
+	switch n.value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []*jsonNode:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// decodes the node into a value shaped like def, nul accepts any shape
+func (n *jsonNode) toObject(def object.Object, path string) object.Object {
+	def, _ = object.Cast(def)
+
+	switch def := def.(type) {
+	case *object.Nul:
+		return n.toLooseObject(path)
+
+	case *object.Integer:
+		number, ok := n.value.(json.Number)
+		if !ok {
+			return n.errorf(path, "expected an int, got %s", n.kind())
+		}
+		value, err := number.Int64()
+		if err != nil {
+			return n.errorf(path, "expected an int, got %s", number)
+		}
+		return &object.Integer{Value: value}
+
+	case *object.Float:
+		number, ok := n.value.(json.Number)
+		if !ok {
+			return n.errorf(path, "expected a float, got %s", n.kind())
+		}
+		value, err := number.Float64()
+		if err != nil {
+			return n.errorf(path, "%v", err)
+		}
+		return &object.Float{Value: value}
+
+	case *object.String:
+		str, ok := n.value.(string)
+		if !ok {
+			return n.errorf(path, "expected a string, got %s", n.kind())
+		}
+		return &object.String{Value: str}
+
+	case *object.Char:
+		str, ok := n.value.(string)
+		if !ok || len([]rune(str)) != 1 {
+			return n.errorf(path, "expected a single character string")
+		}
+		return &object.Char{Value: []rune(str)[0]}
+
+	case *object.Boolean:
+		value, ok := n.value.(bool)
+		if !ok {
+			return n.errorf(path, "expected a bool, got %s", n.kind())
+		}
+		return nativeBool(value)
+
+	case *object.Array:
+		elements, ok := n.value.([]*jsonNode)
+		if !ok {
+			return n.errorf(path, "expected an array, got %s", n.kind())
+		}
+		elemDef := object.Object(object.NUL)
+		if len(def.Elements) > 0 {
+			elemDef = def.Elements[0]
+		}
+		return n.toArray(elements, elemDef, path)
+
+	case *object.Map:
+		obj, ok := n.value.(*jsonObject)
+		if !ok {
+			return n.errorf(path, "expected an object, got %s", n.kind())
+		}
+		valueDef := object.Object(object.NUL)
+		for _, pair := range def.Pairs {
+			valueDef = pair.Value
+			break
+		}
+		return n.toMap(obj, valueDef, path)
+
+	case *object.StructInstance:
+		if def.Def != nil {
+			return n.toStruct(def.Def, path)
+		}
+		return n.toStruct(&object.Struct{Fields: def.Fields, Methods: def.Methods}, path)
+
+	default:
+		return n.errorf(path, "fields of type %s can't be read from json", def.Type())
+	}
+}
+
+func (n *jsonNode) toLooseObject(path string) object.Object {
+	switch value := n.value.(type) {
+	case nil:
+		return object.NUL
+	case bool:
+		return nativeBool(value)
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return &object.Integer{Value: integer}
+		}
+		float, err := value.Float64()
+		if err != nil {
+			return n.errorf(path, "%v", err)
+		}
+		return &object.Float{Value: float}
+	case string:
+		return &object.String{Value: value}
+	case []*jsonNode:
+		return n.toArray(value, object.NUL, path)
+	default:
+		return n.toMap(value.(*jsonObject), object.NUL, path)
+	}
+}
+
+func (n *jsonNode) toArray(nodes []*jsonNode, elemDef object.Object, path string) object.Object {
+	elements := make([]object.Object, 0, len(nodes))
+
+	for idx, node := range nodes {
+		elemPath := fmt.Sprintf("%s[%d]", path, idx)
+		elem := node.toObject(elemDef, elemPath)
+		if _, ok := elem.(*object.Error); ok {
+			return elem
+		}
+		if len(elements) > 0 && !object.ObjectTypesCheck(elements[0], elem, false) {
+			return node.errorf(elemPath, "array elements need to be of one type, got %s and %s", elements[0].Type(), elem.Type())
+		}
+		elements = append(elements, elem)
+	}
+
+	return &object.Array{Size: -1, Elements: elements}
+}
+
+func (n *jsonNode) toMap(obj *jsonObject, valueDef object.Object, path string) object.Object {
+	pairs := make(object.PairsType, len(obj.keys))
+	var first object.Object
+
+	for _, key := range obj.keys {
+		node := obj.values[key]
+		valuePath := path + "." + key
+		value := node.toObject(valueDef, valuePath)
+		if _, ok := value.(*object.Error); ok {
+			return value
+		}
+		if first == nil {
+			first = value
+		} else if !object.ObjectTypesCheck(first, value, false) {
+			return node.errorf(valuePath, "map values need to be of one type, got %s and %s, decode it into a struct instead", first.Type(), value.Type())
+		}
+		k := &object.String{Value: key}
+		pairs[k.HashKey()] = object.HashPair{Key: k, Value: value}
+	}
+
+	return &object.Map{Pairs: pairs}
+}
+
+func (n *jsonNode) toStruct(strct *object.Struct, path string) object.Object {
+	obj, ok := n.value.(*jsonObject)
+	if !ok {
+		return n.errorf(path, "expected an object, got %s", n.kind())
+	}
+
+	fields := strct.Fields
+	instance := &object.StructInstance{
+		Def:     strct,
+		Fields:  make(map[string]object.Object, len(fields)),
+		Methods: strct.Methods,
+	}
+
+	// in input order, so the first error reported is the first one in the text
+	for _, key := range obj.keys {
+		def, ok := fields[key]
+		if !ok {
+			continue
+		}
+
+		var value object.Object
+		if fieldType, ok := strct.FieldTypes[key]; ok {
+			// fields of a struct type, recursive ones included, decode as deep as the text goes
+			value = object.NUL
+			if obj.values[key].value != nil {
+				value = obj.values[key].toStruct(fieldType, path+"."+key)
+			}
+		} else {
+			value = obj.values[key].toObject(def, path+"."+key)
+		}
+		if _, ok := value.(*object.Error); ok {
+			return value
+		}
+		// same as struct instance expressions, the fields stay mutable
+		instance.Fields[key] = object.ItemObject{Object: value, IsMutable: true}
+	}
+
+	missing := make([]string, 0)
+	for name := range fields {
+		if _, ok := instance.Fields[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		if len(missing) == 1 {
+			return n.errorf(path, "missing required field %s", missing[0])
+		}
+		return n.errorf(path, "missing required fields %s", strings.Join(missing, ", "))
+	}
+
+	return instance
+}
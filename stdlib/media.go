@@ -0,0 +1,291 @@
+package stdlib
+
+import (
+	"blk/object"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// wraps ffmpeg and ffprobe, they need to be installed and in PATH
+// blk run only starts them when granted, an example of this: --allow-run=ffmpeg,ffprobe
+func mediaModule() object.Module {
+	return object.Module{
+		"probe":     &object.BuiltinFn{Fn: mediaProbe},
+		"trim":      &object.BuiltinFn{Fn: mediaTrim, Options: true},
+		"concat":    &object.BuiltinFn{Fn: mediaConcat},
+		"thumbnail": &object.BuiltinFn{Fn: mediaThumbnail, Options: true},
+	}
+}
+
+// the string args of the media functions are paths, checked against the fs grants
+func mediaPath(arg object.Object, name string) (string, *object.Error) {
+	arg, _ = object.Cast(arg)
+	str, ok := arg.(*object.String)
+	if !ok {
+		return "", newError("%s needs to be of type string, got=%v", name, arg.Type())
+	}
+	if err := requireFS(str.Value); err != nil {
+		return "", err
+	}
+	return str.Value, nil
+}
+
+// formats seconds the way ffmpeg takes them, an example of this: 1.5
+func formatSeconds(seconds float64) string {
+	return strings.TrimSuffix(strings.TrimRight(strconv.FormatFloat(seconds, 'f', 3, 64), "0"), ".")
+}
+
+// what ffprobe -print_format json writes, the numbers of the format section are strings
+type probeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		Size       string `json:"size"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+	Streams []struct {
+		Index      int64  `json:"index"`
+		CodecType  string `json:"codec_type"`
+		CodecName  string `json:"codec_name"`
+		Duration   string `json:"duration"`
+		Width      int64  `json:"width"`
+		Height     int64  `json:"height"`
+		SampleRate string `json:"sample_rate"`
+		Channels   int64  `json:"channels"`
+	} `json:"streams"`
+}
+
+// takes a media file path, returns a struct with its format, duration (seconds), size (bytes),
+// bit_rate and streams, each stream has an index, kind (video, audio, subtitle), codec, duration,
+// width, height, sample_rate and channels, the fields a stream doesn't have are 0
+// usage:
+// -	info := media.probe("clip.mp4")
+// -	info.streams[0].codec => h264
+func mediaProbe(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	input, err := mediaPath(args[0], "input")
+	if err != nil {
+		return err
+	}
+
+	out, err := runTool("media.probe", "ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", input)
+	if err != nil {
+		return err
+	}
+
+	var probe probeOutput
+	if err := json.Unmarshal([]byte(out), &probe); err != nil {
+		return newError("media.probe: unexpected ffprobe output: %v", err)
+	}
+
+	streams := make([]object.Object, 0, len(probe.Streams))
+	for _, stream := range probe.Streams {
+		streams = append(streams, newRecord(map[string]object.Object{
+			"index":       &object.Integer{Value: stream.Index},
+			"kind":        &object.String{Value: stream.CodecType},
+			"codec":       &object.String{Value: stream.CodecName},
+			"duration":    &object.Float{Value: parseFloat(stream.Duration)},
+			"width":       &object.Integer{Value: stream.Width},
+			"height":      &object.Integer{Value: stream.Height},
+			"sample_rate": &object.Integer{Value: parseInt(stream.SampleRate)},
+			"channels":    &object.Integer{Value: stream.Channels},
+		}))
+	}
+
+	return newRecord(map[string]object.Object{
+		"path":     &object.String{Value: input},
+		"format":   &object.String{Value: probe.Format.FormatName},
+		"duration": &object.Float{Value: parseFloat(probe.Format.Duration)},
+		"size":     &object.Integer{Value: parseInt(probe.Format.Size)},
+		"bit_rate": &object.Integer{Value: parseInt(probe.Format.BitRate)},
+		"streams":  &object.Array{Size: -1, Elements: streams},
+	})
+}
+
+// a struct instance without a struct, for the values the stdlib builds
+func newRecord(fields map[string]object.Object) *object.StructInstance {
+	instance := &object.StructInstance{
+		Fields:  make(map[string]object.Object, len(fields)),
+		Methods: map[string]object.Object{},
+	}
+	for name, value := range fields {
+		instance.Fields[name] = object.ItemObject{Object: value, IsMutable: true}
+	}
+	return instance
+}
+
+// ffprobe leaves out or writes N/A for the values it doesn't know
+func parseFloat(value string) float64 {
+	f, _ := strconv.ParseFloat(value, 64)
+	return f
+}
+
+func parseInt(value string) int64 {
+	i, _ := strconv.ParseInt(value, 10, 64)
+	return i
+}
+
+// takes an input path, an output path and the options, returns the output path
+// the options are in seconds: start, then either end or duration, they default to the start
+// and the end of the input, the streams are copied, so the cuts land on the closest keyframes
+// usage:
+// -	media.trim("talk.mp4", "intro.mp4", {"start": 0, "end": 30})
+// -	media.trim("talk.mp4", "clip.mp4", {"start": 62.5, "duration": 10.0})
+func mediaTrim(args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3",
+			len(args))
+	}
+
+	input, err := mediaPath(args[0], "input")
+	if err != nil {
+		return err
+	}
+	output, err := mediaPath(args[1], "output")
+	if err != nil {
+		return err
+	}
+	opts, err := readOptions(args[2], map[string]optionKind{
+		"start":    numberOption,
+		"end":      numberOption,
+		"duration": numberOption,
+	})
+	if err != nil {
+		return err
+	}
+	if opts.has("end") && opts.has("duration") {
+		return newError("media.trim: options end and duration can't be used together")
+	}
+
+	ffmpegArgs := []string{"-y", "-v", "error", "-i", input}
+	if start, ok := opts.number("start"); ok {
+		ffmpegArgs = append(ffmpegArgs, "-ss", formatSeconds(start))
+	}
+	if end, ok := opts.number("end"); ok {
+		ffmpegArgs = append(ffmpegArgs, "-to", formatSeconds(end))
+	}
+	if duration, ok := opts.number("duration"); ok {
+		ffmpegArgs = append(ffmpegArgs, "-t", formatSeconds(duration))
+	}
+	ffmpegArgs = append(ffmpegArgs, "-c", "copy", output)
+
+	if _, err := runTool("media.trim", "ffmpeg", ffmpegArgs...); err != nil {
+		return err
+	}
+	return &object.String{Value: output}
+}
+
+// takes an array of input paths and an output path, returns the output path
+// the inputs are joined one after the other, they need to share their codecs
+// usage:
+// -	media.concat(["part1.mp4", "part2.mp4"], "full.mp4")
+func mediaConcat(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	inputs, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("inputs need to be of type array, got=%v", args[0].Type())
+	}
+	if len(inputs.Elements) == 0 {
+		return newError("media.concat: inputs can't be empty")
+	}
+	output, err := mediaPath(args[1], "output")
+	if err != nil {
+		return err
+	}
+
+	// the concat demuxer reads the inputs from a list file
+	var list strings.Builder
+	for _, elem := range inputs.Elements {
+		input, err := mediaPath(elem, "inputs elements")
+		if err != nil {
+			return err
+		}
+		abs, absErr := filepath.Abs(input)
+		if absErr != nil {
+			return newError("media.concat: %v", absErr)
+		}
+		fmt.Fprintf(&list, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+	}
+
+	listFile, createErr := os.CreateTemp("", "blk-concat-*.txt")
+	if createErr != nil {
+		return newError("media.concat: %v", createErr)
+	}
+	defer os.Remove(listFile.Name())
+	if _, writeErr := listFile.WriteString(list.String()); writeErr != nil {
+		listFile.Close()
+		return newError("media.concat: %v", writeErr)
+	}
+	listFile.Close()
+
+	if _, err := runTool("media.concat", "ffmpeg", "-y", "-v", "error", "-f", "concat", "-safe", "0", "-i", listFile.Name(), "-c", "copy", output); err != nil {
+		return err
+	}
+	return &object.String{Value: output}
+}
+
+// takes an input path, an output image path and the optional options, returns the output path
+// the options are at, the time of the frame in seconds (0 by default), width and height,
+// when only one of them is set the other one keeps the aspect ratio
+// usage:
+// -	media.thumbnail("clip.mp4", "cover.jpg")
+// -	media.thumbnail("clip.mp4", "cover.jpg", {"at": 12, "width": 320})
+func mediaThumbnail(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3",
+			len(args))
+	}
+
+	input, err := mediaPath(args[0], "input")
+	if err != nil {
+		return err
+	}
+	output, err := mediaPath(args[1], "output")
+	if err != nil {
+		return err
+	}
+
+	opts := options{}
+	if len(args) == 3 {
+		opts, err = readOptions(args[2], map[string]optionKind{
+			"at":     numberOption,
+			"width":  numberOption,
+			"height": numberOption,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	at, _ := opts.number("at")
+	ffmpegArgs := []string{"-y", "-v", "error", "-ss", formatSeconds(at), "-i", input, "-frames:v", "1"}
+	if opts.has("width") || opts.has("height") {
+		width, height := -1.0, -1.0
+		if w, ok := opts.number("width"); ok {
+			width = w
+		}
+		if h, ok := opts.number("height"); ok {
+			height = h
+		}
+		ffmpegArgs = append(ffmpegArgs, "-vf", fmt.Sprintf("scale=%d:%d", int(width), int(height)))
+	}
+	ffmpegArgs = append(ffmpegArgs, output)
+
+	if _, err := runTool("media.thumbnail", "ffmpeg", ffmpegArgs...); err != nil {
+		return err
+	}
+	return &object.String{Value: output}
+}
@@ -0,0 +1,177 @@
+package stdlib
+
+import (
+	"blk/object"
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func mimeModule() object.Module {
+	return object.Module{
+		"by_ext": &object.BuiltinFn{Fn: mimeByExt},
+		"sniff":  &object.BuiltinFn{Fn: mimeSniff},
+	}
+}
+
+// what the types of files nobody recognized are
+const unknownMime = "application/octet-stream"
+
+// the types of the media and the files next to them, the same on every os,
+// the other extensions are looked up in the mime tables of the system
+var mimeTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mkv":  "video/x-matroska",
+	".webm": "video/webm",
+	".mov":  "video/quicktime",
+	".avi":  "video/x-msvideo",
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".ogg":  "audio/ogg",
+	".opus": "audio/opus",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+	".ico":  "image/x-icon",
+	".svg":  "image/svg+xml",
+	".srt":  "application/x-subrip",
+	".vtt":  "text/vtt",
+	".json": "application/json",
+	".txt":  "text/plain",
+	".html": "text/html",
+	".pdf":  "application/pdf",
+	".zip":  "application/zip",
+	".gz":   "application/gzip",
+	".tar":  "application/x-tar",
+}
+
+// drops the parameters, an example of this: text/plain; charset=utf-8 => text/plain
+func mediaType(value string) string {
+	value, _, _ = strings.Cut(value, ";")
+	return strings.TrimSpace(value)
+}
+
+// takes an extension, with or without the dot, or a path, returns the mime type,
+// application/octet-stream when the extension is unknown
+// usage:
+// -	mime.by_ext(".mp4") => video/mp4
+// -	mime.by_ext("covers/front.JPG") => image/jpeg
+func mimeByExt(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("ext needs to be of type string, got=%v", args[0].Type())
+	}
+
+	ext := filepath.Ext(str.Value)
+	if len(ext) == 0 {
+		ext = "." + str.Value
+	}
+	ext = strings.ToLower(ext)
+
+	if value, ok := mimeTypes[ext]; ok {
+		return &object.String{Value: value}
+	}
+	if value := mime.TypeByExtension(ext); len(value) > 0 {
+		return &object.String{Value: mediaType(value)}
+	}
+	return &object.String{Value: unknownMime}
+}
+
+// the signatures net/http doesn't know, or reads as another type
+func sniffMedia(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return "audio/flac"
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		// the brand tells apart the containers based on the iso format
+		switch brand := string(head[8:12]); {
+		case strings.HasPrefix(brand, "M4A"):
+			return "audio/mp4"
+		case brand == "qt  ":
+			return "video/quicktime"
+		case brand == "avif" || brand == "avis":
+			return "image/avif"
+		case brand == "heic" || brand == "heix" || brand == "mif1":
+			return "image/heic"
+		default:
+			return "video/mp4"
+		}
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}) && bytes.Contains(head[:min(len(head), 64)], []byte("matroska")):
+		return "video/x-matroska"
+	case len(head) >= 2 && head[0] == 0xFF && slices.Contains([]byte{0xFA, 0xF2, 0xE2}, head[1]&0xFE):
+		// an mp3 frame without an id3 tag before it, the sync bits then a layer 3 header
+		return "audio/mpeg"
+	case bytes.HasPrefix(head, []byte("OggS")) && bytes.Contains(head, []byte("OpusHead")):
+		return "audio/opus"
+	}
+	return ""
+}
+
+// takes a path, or the first bytes of a file as an array of ints, returns the mime type the content
+// starts with, application/octet-stream when it's unknown, the extension doesn't matter
+// it knows the common image, video and audio formats, the text ones and the archives
+// usage:
+// -	mime.sniff("download.bin") => audio/mpeg
+// -	mime.sniff([137, 80, 78, 71, 13, 10, 26, 10]) => image/png
+func mimeSniff(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	// the sniffing algorithm of net/http doesn't read further
+	head := make([]byte, 0, 512)
+	args[0], _ = object.Cast(args[0])
+	switch arg := args[0].(type) {
+	case *object.String:
+		if err := requireFS(arg.Value); err != nil {
+			return err
+		}
+		file, err := os.Open(arg.Value)
+		if err != nil {
+			return newError("mime.sniff: %v", err)
+		}
+		defer file.Close()
+		n, err := io.ReadFull(file, head[:cap(head)])
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return newError("mime.sniff: %v", err)
+		}
+		head = head[:n]
+
+	case *object.Array:
+		for _, elem := range arg.Elements[:min(len(arg.Elements), cap(head))] {
+			elem, _ = object.Cast(elem)
+			b, ok := elem.(*object.Integer)
+			if !ok || b.Value < 0 || b.Value > 255 {
+				return newError("mime.sniff: bytes need to be ints between 0 and 255, got=%v", elem.Inspect())
+			}
+			head = append(head, byte(b.Value))
+		}
+
+	default:
+		return newError("arg needs to be a path or an array of bytes, got=%v", args[0].Type())
+	}
+
+	if value := sniffMedia(head); len(value) > 0 {
+		return &object.String{Value: value}
+	}
+	return &object.String{Value: mediaType(http.DetectContentType(head))}
+}
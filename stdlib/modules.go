@@ -1,20 +1,140 @@
 package stdlib
 
 import (
+	"blk/internals"
 	"blk/object"
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
 )
 
 func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR_OBJ
+}
+
+// the failures a program is expected to handle, a missing file or a host that doesn't answer,
+// are returned as results so the program checks them with is_err or passes them on with !,
+// the misuses of a function, the wrong args or a denied permission, stay errors
+func resultOf(value object.Object, err error) object.Object {
+	if err != nil {
+		return object.Err(err.Error())
+	}
+	return object.Ok(value)
+}
+
+// the conditions compare booleans with object.TRUE and object.FALSE, the builtins return those
+func nativeBool(value bool) *object.Boolean {
+	if value {
+		return object.TRUE
+	}
+	return object.FALSE
+}
+
+// where the programs write, fmt and term go through it, embedders can point it somewhere else
+var Stdout io.Writer = os.Stdout
+
+// calls a blk function from a builtin, an example of this: the progress callback of download.audio
+// the interpreter sets it up when it gets created
+var CallFunction func(fn object.Object, args ...object.Object) object.Object
+
+// where the prompts read the answers from
+var Stdin io.Reader = os.Stdin
+
+// keeps the state of the evaluation (the current env, file, ...), restore puts it back,
+// the workers of pipeline.run share the interpreter, they switch their state when they take turns
+var SaveState func() (restore func())
+
+// the scope the program is evaluating in, the interpreter sets it up when it gets created
+var CurrentEnv func() *object.Environment
+
+// the program being run: the path of its source, its globals and the index of the top level
+// statement being evaluated, the interpreter sets it up when it gets created
+var CurrentProgram func() (path string, globals *object.Environment, statement int)
+
+// makes the members of a module, it runs the first time a program imports the module so the
+// modules a program doesn't use cost nothing
+type ModuleFactory func() object.Module
+
 // every module added to the std lib needs to be defined here with a name
-var BuiltinModules = map[string]object.Module{
-	"fmt":     fmtModule,
-	"math":    mathModule,
-	"types":   typeModule,
-	"array":   arrayModule,
-	"hashmap": hashmapModule,
-	"strings": stringModule,
+var BuiltinModules = map[string]ModuleFactory{
+	"fmt":      fmtModule,
+	"math":     mathModule,
+	"types":    typeModule,
+	"array":    arrayModule,
+	"hashmap":  hashmapModule,
+	"strings":  stringModule,
+	"runtime":  runtimeModule,
+	"path":     pathModule,
+	"fs":       fsModule,
+	"json":     jsonModule,
+	"media":    mediaModule,
+	"download": downloadModule,
+	"pipeline": pipelineModule,
+	"term":     termModule,
+	"prompt":   promptModule,
+	"store":    storeModule,
+	"http":     httpModule,
+	"rate":     rateModule,
+	"url":      urlModule,
+	"mime":     mimeModule,
+	"schedule": scheduleModule,
+	"validate": validateModule,
+	"diff":     diffModule,
+	"archive":  archiveModule,
+	"rand":     randModule,
+}
+
+// the modules made so far, every import of a module shares its members, the mutex guards
+// BuiltinModules as well since the plugins register theirs while programs can import
+var (
+	loadedModules   = make(map[string]object.Module)
+	loadedModulesMu sync.Mutex
+)
+
+// adds a module to the ones programs can import by name, a name can't be taken twice
+func RegisterModule(name string, factory ModuleFactory) error {
+	loadedModulesMu.Lock()
+	defer loadedModulesMu.Unlock()
+	if _, ok := BuiltinModules[name]; ok {
+		return fmt.Errorf("a module named %s already exists", name)
+	}
+	BuiltinModules[name] = factory
+	delete(loadedModules, name)
+	return nil
+}
+
+// the members of the builtin module, made on the first call
+func Module(name string) (object.Module, bool) {
+	loadedModulesMu.Lock()
+	defer loadedModulesMu.Unlock()
+	factory, ok := BuiltinModules[name]
+	if !ok {
+		return nil, false
+	}
+
+	module, ok := loadedModules[name]
+	if !ok {
+		module = factory()
+		loadedModules[name] = module
+		internals.Debug(internals.DebugModules).Debug("builtin module made", "name", name, "members", len(module))
+	}
+	return module, true
+}
+
+// the names of the modules programs can import, sorted
+func ModuleNames() []string {
+	loadedModulesMu.Lock()
+	defer loadedModulesMu.Unlock()
+	names := make([]string, 0, len(BuiltinModules))
+	for name := range BuiltinModules {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
 }
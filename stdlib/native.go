@@ -0,0 +1,68 @@
+package stdlib
+
+import (
+	"blk/internals"
+	"blk/object"
+	"fmt"
+	"plugin"
+)
+
+// symbol a go plugin exports to be loaded as a native module, its type is
+//
+//	func() (name string, abiVersion int, members map[string]any)
+//
+// members follow the object.Value conversions, functions use the
+// object.NativeFunction signature, an example of this:
+//
+//	func BlkModule() (string, int, map[string]any) {
+//		return "hello", 1, map[string]any{
+//			"greet": func(args []any) (any, error) { return "hello " + args[0].(string), nil },
+//		}
+//	}
+const NativeModuleSymbol = "BlkModule"
+
+// adds a native module to the ones programs can import by name
+// modules built against a newer abi than this interpreter are refused
+func RegisterNative(name string, abiVersion int, members map[string]any) error {
+	if abiVersion < 1 || abiVersion > object.ABIVersion {
+		return fmt.Errorf("native module %s targets abi version %d, this interpreter supports 1 to %d",
+			name, abiVersion, object.ABIVersion)
+	}
+	module := make(object.Module, len(members))
+	for member, value := range members {
+		if fn, ok := value.(object.NativeFunction); ok {
+			module[member] = object.NativeBuiltin(name+"."+member, fn)
+			continue
+		}
+		obj, err := object.FromNative(value)
+		if err != nil {
+			return fmt.Errorf("native module %s, member %s: %v", name, member, err)
+		}
+		module[member] = obj
+	}
+
+	return RegisterModule(name, func() object.Module { return module })
+}
+
+// opens a go plugin (.so) and registers the native module it exports
+func LoadPlugin(path string) error {
+	plug, err := plugin.Open(path)
+	if err != nil {
+		return err
+	}
+
+	symbol, err := plug.Lookup(NativeModuleSymbol)
+	if err != nil {
+		return err
+	}
+
+	entry, ok := symbol.(func() (string, int, map[string]any))
+	if !ok {
+		return fmt.Errorf("%s: %s has type %T, want func() (string, int, map[string]any)",
+			path, NativeModuleSymbol, symbol)
+	}
+
+	name, abiVersion, members := entry()
+	internals.Debug(internals.DebugModules).Debug("plugin", "path", path, "module", name, "abi", abiVersion)
+	return RegisterNative(name, abiVersion, members)
+}
@@ -0,0 +1,114 @@
+package stdlib
+
+import (
+	"blk/object"
+	"slices"
+	"strings"
+)
+
+// kinds of values an option accepts, a number option takes an int or a float
+type optionKind int
+
+const (
+	numberOption optionKind = iota
+	stringOption
+	boolOption
+	functionOption
+	stringsOption
+)
+
+func (k optionKind) String() string {
+	switch k {
+	case numberOption:
+		return "a number"
+	case stringOption:
+		return "a string"
+	case functionOption:
+		return "a function"
+	case stringsOption:
+		return "an array of strings"
+	default:
+		return "a bool"
+	}
+}
+
+// the options a function got, keyed by name, the values are cast already
+type options map[string]object.Object
+
+// reads an options map, an example of this: {"start": 1.5, "end": 4}
+// every key needs to be in the schema and hold a value of its kind
+func readOptions(obj object.Object, schema map[string]optionKind) (options, *object.Error) {
+	obj, _ = object.Cast(obj)
+	m, ok := obj.(*object.Map)
+	if !ok {
+		return nil, newError("options need to be of type map, got=%v", obj.Type())
+	}
+
+	opts := make(options, len(m.Pairs))
+	for _, pair := range m.Pairs {
+		key, ok := pair.Key.(*object.String)
+		if !ok {
+			return nil, newError("option names need to be of type string, got=%v", pair.Key.Type())
+		}
+
+		kind, ok := schema[key.Value]
+		if !ok {
+			names := make([]string, 0, len(schema))
+			for name := range schema {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			return nil, newError("unknown option %s, expected one of (%s)", key.Value, strings.Join(names, ", "))
+		}
+
+		value, _ := object.Cast(pair.Value)
+		if !kind.accepts(value) {
+			return nil, newError("option %s needs to be %s, got=%v", key.Value, kind, value.Type())
+		}
+		opts[key.Value] = value
+	}
+
+	return opts, nil
+}
+
+func (k optionKind) accepts(value object.Object) bool {
+	switch value := value.(type) {
+	case *object.Integer, *object.Float:
+		return k == numberOption
+	case *object.String:
+		return k == stringOption
+	case *object.Boolean:
+		return k == boolOption
+	case *object.Function, *object.BuiltinFn:
+		return k == functionOption
+	case *object.Array:
+		if k != stringsOption {
+			return false
+		}
+		for _, elem := range value.Elements {
+			if elem, _ := object.Cast(elem); elem.Type() != object.STRING_OBJ {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// the value of a number option, ok is false when it isn't set
+func (o options) number(name string) (float64, bool) {
+	switch value := o[name].(type) {
+	case *object.Integer:
+		return float64(value.Value), true
+	case *object.Float:
+		return value.Value, true
+	default:
+		return 0, false
+	}
+}
+
+func (o options) has(name string) bool {
+	_, ok := o[name]
+	return ok
+}
@@ -0,0 +1,127 @@
+package stdlib
+
+import (
+	"blk/object"
+	"path/filepath"
+	"strings"
+)
+
+func pathModule() object.Module {
+	return object.Module{
+		"sep":  &object.String{Value: string(filepath.Separator)},
+		"join": &object.BuiltinFn{Fn: pathJoin, Pure: true},
+		"base": &object.BuiltinFn{Fn: funcSS(filepath.Base), Pure: true},
+		"dir":  &object.BuiltinFn{Fn: funcSS(filepath.Dir), Pure: true},
+		"ext":  &object.BuiltinFn{Fn: funcSS(filepath.Ext), Pure: true},
+		"abs":  &object.BuiltinFn{Fn: pathAbs, Pure: true},
+		"rel":  &object.BuiltinFn{Fn: pathRel, Pure: true},
+		"glob": &object.BuiltinFn{Fn: pathGlob},
+	}
+}
+
+// takes any number of path elements, returns them joined with the os separator
+// usage:
+// -	path.join("videos", "2024", "clip.mp4") => videos/2024/clip.mp4 (videos\2024\clip.mp4 on windows)
+func pathJoin(args ...object.Object) object.Object {
+	elements := make([]string, 0, len(args))
+
+	for idx, arg := range args {
+		arg, _ = object.Cast(arg)
+		str, ok := arg.(*object.String)
+		if !ok {
+			return newError("arg %d needs to be of type string, got=%v", idx+1, arg.Type())
+		}
+		elements = append(elements, str.Value)
+	}
+
+	return &object.String{Value: filepath.Join(elements...)}
+}
+
+// takes a path, returns its absolute form, relative paths are resolved from the working directory
+// usage:
+// -	path.abs("./clip.mp4")
+func pathAbs(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	target, ok := args[0].(*object.String)
+	if !ok {
+		return newError("arg needs to be of type string, got=%v", args[0].Type())
+	}
+
+	abs, err := filepath.Abs(target.Value)
+	if err != nil {
+		return newError("%v", err)
+	}
+
+	return &object.String{Value: abs}
+}
+
+// takes a base path and a target path, returns the target relative to the base
+// usage:
+// -	path.rel("/home/user", "/home/user/videos/clip.mp4") => videos/clip.mp4
+func pathRel(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	args[1], _ = object.Cast(args[1])
+
+	if args[0].Type() != object.STRING_OBJ || args[1].Type() != object.STRING_OBJ {
+		return newError("both args need to be of type string")
+	}
+
+	rel, err := filepath.Rel(args[0].(*object.String).Value, args[1].(*object.String).Value)
+	if err != nil {
+		return newError("%v", err)
+	}
+
+	return &object.String{Value: rel}
+}
+
+// takes a glob pattern, returns an array of the paths matching it, sorted
+// usage:
+// -	path.glob("videos/*.mp4")
+func pathGlob(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	pattern, ok := args[0].(*object.String)
+	if !ok {
+		return newError("arg needs to be of type string, got=%v", args[0].Type())
+	}
+
+	if err := requireFS(globRoot(pattern.Value)); err != nil {
+		return err
+	}
+
+	matches, err := filepath.Glob(pattern.Value)
+	if err != nil {
+		return newError("invalid glob pattern %q: %v", pattern.Value, err)
+	}
+
+	elements := make([]object.Object, 0, len(matches))
+	for _, match := range matches {
+		elements = append(elements, &object.String{Value: match})
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// the directory a glob pattern reads from, the part before the first wildcard
+// an example of this: videos/2024/*.mp4 => videos/2024
+func globRoot(pattern string) string {
+	idx := strings.IndexAny(pattern, "*?[")
+	if idx == -1 {
+		return filepath.Dir(pattern)
+	}
+	return filepath.Dir(pattern[:idx] + "x")
+}
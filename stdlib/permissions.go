@@ -0,0 +1,50 @@
+package stdlib
+
+import (
+	"blk/internals"
+	"blk/object"
+	"blk/parser"
+	"net/url"
+)
+
+// capabilities granted to the running program, every stdlib function touching
+// the file system, the network or starting a program goes through the checks below
+// everything is allowed unless the cli restricts it
+var Permissions = internals.AllowAll()
+
+// the files included while parsing go through the same grants
+func init() {
+	parser.CheckFS = func(path string) error { return Permissions.CheckFS(path) }
+}
+
+func permissionError(err error) *object.Error {
+	return &object.Error{Kind: object.PermissionError, Message: object.PermissionError + ": " + err.Error()}
+}
+
+// returns an error if the program isn't allowed to access path
+func requireFS(path string) *object.Error {
+	if err := Permissions.CheckFS(path); err != nil {
+		return permissionError(err)
+	}
+	return nil
+}
+
+// returns an error if the program isn't allowed to start the tool
+func requireRun(program string) *object.Error {
+	if err := Permissions.CheckRun(program); err != nil {
+		return permissionError(err)
+	}
+	return nil
+}
+
+// returns an error if the program isn't allowed to reach the host of the url
+func requireNet(rawURL string) *object.Error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || len(parsed.Host) == 0 {
+		return newError("invalid url %s", rawURL)
+	}
+	if err := Permissions.CheckNet(parsed.Host); err != nil {
+		return permissionError(err)
+	}
+	return nil
+}
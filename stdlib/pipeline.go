@@ -0,0 +1,169 @@
+package stdlib
+
+import (
+	"blk/object"
+	"sync"
+)
+
+func pipelineModule() object.Module {
+	return object.Module{
+		"run": &object.BuiltinFn{Fn: pipelineRun, Options: true},
+	}
+}
+
+// the interpreter evaluates one worker at a time, the workers take turns holding evalLock
+// and let go of it while they wait on something outside of blk (a tool, ...), that's
+// where the work of media and download scripts goes, so it's what runs in parallel
+var (
+	evalLock sync.Mutex
+	// pipelines in progress, above 0 the goroutine evaluating holds evalLock
+	pipelines int
+)
+
+// runs wait without holding evalLock, the interpreter state of the caller is put back
+// once it gets the lock again, the other workers evaluate in between
+func unlocked(wait func()) {
+	if pipelines == 0 {
+		wait()
+		return
+	}
+
+	restore := SaveState()
+	evalLock.Unlock()
+	wait()
+	evalLock.Lock()
+	restore()
+}
+
+// what a worker got for one item
+type pipelineResult struct {
+	value object.Object
+	err   *object.Error
+}
+
+// takes an array of items, a worker function called with each item and an optional map of options,
+// returns an array with a result per item, in the order of the items, each result has the item,
+// the value the worker returned, ok and the error
+// the options are concurrency, 4 by default, and on_error, "stop" by default, the first error stops
+// the program once the running workers are done, with "continue" the errors end up in the results,
+// the value is nul and ok is false
+// usage:
+// -	results := pipeline.run(urls, fn(url) { return download.audio(url) }, {"concurrency": 4, "on_error": "continue"})
+// -	results[0].ok => true
+func pipelineRun(args ...object.Object) object.Object {
+	if len(args) < 2 || len(args) > 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	items, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("items need to be of type array, got=%v", args[0].Type())
+	}
+	worker, _ := object.Cast(args[1])
+	if worker.Type() != object.FUNCTION_OBJ && worker.Type() != object.BUILTIN_OBJ {
+		return newError("worker needs to be a function, got=%v", worker.Type())
+	}
+
+	opts := options{}
+	if len(args) == 3 {
+		var err *object.Error
+		opts, err = readOptions(args[2], map[string]optionKind{
+			"concurrency": numberOption,
+			"on_error":    stringOption,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	concurrency := int64(4)
+	if opts.has("concurrency") {
+		value, ok := opts["concurrency"].(*object.Integer)
+		if !ok {
+			return newError("concurrency needs to be of type int, got=%v", opts["concurrency"].Type())
+		}
+		if value.Value < 1 {
+			return newError("pipeline.run: concurrency needs to be at least 1, got=%d", value.Value)
+		}
+		concurrency = value.Value
+	}
+
+	keepGoing := false
+	if value, ok := opts["on_error"].(*object.String); ok {
+		switch value.Value {
+		case "stop":
+		case "continue":
+			keepGoing = true
+		default:
+			return newError("pipeline.run: unknown on_error %s, expected one of (continue, stop)", value.Value)
+		}
+	}
+
+	if pipelines == 0 {
+		evalLock.Lock()
+		defer evalLock.Unlock()
+	}
+	pipelines++
+	defer func() { pipelines-- }()
+
+	results := make([]pipelineResult, len(items.Elements))
+	jobs := make(chan int)
+	// the first error in stop mode, no item starts once it's set
+	var stopErr *object.Error
+
+	var wg sync.WaitGroup
+	for range min(concurrency, int64(len(items.Elements))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				evalLock.Lock()
+				if stopErr == nil {
+					result := CallFunction(worker, items.Elements[idx])
+					if err, ok := result.(*object.Error); ok {
+						results[idx].err = err
+						if !keepGoing {
+							stopErr = err
+						}
+					} else {
+						results[idx].value = result
+					}
+				}
+				evalLock.Unlock()
+			}
+		}()
+	}
+
+	unlocked(func() {
+		for idx := range items.Elements {
+			jobs <- idx
+		}
+		close(jobs)
+		wg.Wait()
+	})
+
+	if stopErr != nil {
+		return stopErr
+	}
+
+	elements := make([]object.Object, 0, len(results))
+	for idx, result := range results {
+		record := map[string]object.Object{
+			"item":  items.Elements[idx],
+			"value": result.value,
+			"ok":    object.TRUE,
+			"error": &object.String{Value: ""},
+		}
+		if result.err != nil {
+			record["value"] = object.NUL
+			record["ok"] = object.FALSE
+			record["error"] = &object.String{Value: result.err.Diagnostic().Message}
+		} else if result.value == nil {
+			record["value"] = object.NUL
+		}
+		elements = append(elements, newRecord(record))
+	}
+	return &object.Array{Size: -1, Elements: elements}
+}
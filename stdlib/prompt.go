@@ -0,0 +1,247 @@
+package stdlib
+
+import (
+	"blk/diagnostics"
+	"blk/object"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// questions for interactive scripts, they read the answers from Stdin a line at a time
+// and ask again until the answer fits, a closed input is an error
+func promptModule() object.Module {
+	return object.Module{
+		"confirm":  &object.BuiltinFn{Fn: promptConfirm},
+		"select":   &object.BuiltinFn{Fn: promptSelect},
+		"text":     &object.BuiltinFn{Fn: promptText},
+		"password": &object.BuiltinFn{Fn: promptPassword},
+	}
+}
+
+// the reader keeps what it buffered past the line, it's shared by the prompts as long as Stdin stays the same
+var (
+	input       *bufio.Reader
+	inputSource io.Reader
+)
+
+func readAnswer(module string) (string, *object.Error) {
+	if input == nil || inputSource != Stdin {
+		input, inputSource = bufio.NewReader(Stdin), Stdin
+	}
+
+	line, err := input.ReadString('\n')
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		if err == io.EOF {
+			return "", newError("%s: no answer, the input is closed", module)
+		}
+		return "", newError("%s: %v", module, err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// an example of this: ? overwrite the file? [y/N]
+func ask(question, hint string) {
+	line := diagnostics.Paint("1;32", "?") + " " + diagnostics.Paint("1", question)
+	if len(hint) > 0 {
+		line += " " + diagnostics.Paint("2", hint)
+	}
+	termWrite(line + " ")
+}
+
+func promptString(arg object.Object, name string) (string, *object.Error) {
+	arg, _ = object.Cast(arg)
+	str, ok := arg.(*object.String)
+	if !ok {
+		return "", newError("%s needs to be of type string, got=%v", name, arg.Type())
+	}
+	return str.Value, nil
+}
+
+// takes a question and the optional default answer (false by default), returns whether the answer is yes
+// usage:
+// -	if prompt.confirm("overwrite the file?") { ... }
+// -	prompt.confirm("keep going?", true)
+func promptConfirm(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2",
+			len(args))
+	}
+
+	question, err := promptString(args[0], "message")
+	if err != nil {
+		return err
+	}
+	fallback := false
+	if len(args) == 2 {
+		args[1], _ = object.Cast(args[1])
+		value, ok := args[1].(*object.Boolean)
+		if !ok {
+			return newError("default needs to be of type bool, got=%v", args[1].Type())
+		}
+		fallback = value.Value
+	}
+
+	hint := "[y/N]"
+	if fallback {
+		hint = "[Y/n]"
+	}
+	for {
+		ask(question, hint)
+		answer, err := readAnswer("prompt.confirm")
+		if err != nil {
+			return err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "":
+			return nativeBool(fallback)
+		case "y", "yes":
+			return object.TRUE
+		case "n", "no":
+			return object.FALSE
+		}
+		termWrite("answer with y or n\n")
+	}
+}
+
+// takes a question and an array of options, returns the option picked, by its number or its text
+// usage:
+// -	format := prompt.select("format?", ["mp3", "wav", "flac"])
+func promptSelect(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	question, err := promptString(args[0], "message")
+	if err != nil {
+		return err
+	}
+	args[1], _ = object.Cast(args[1])
+	arr, ok := args[1].(*object.Array)
+	if !ok {
+		return newError("options need to be of type array, got=%v", args[1].Type())
+	}
+	if len(arr.Elements) == 0 {
+		return newError("prompt.select: options can't be empty")
+	}
+	choices := make([]string, 0, len(arr.Elements))
+	for _, elem := range arr.Elements {
+		choice, err := promptString(elem, "options elements")
+		if err != nil {
+			return err
+		}
+		choices = append(choices, choice)
+	}
+
+	var list strings.Builder
+	for idx, choice := range choices {
+		fmt.Fprintf(&list, "  %s %s\n", diagnostics.Paint("36", strconv.Itoa(idx+1)+")"), choice)
+	}
+
+	ask(question, "")
+	termWrite("\n" + list.String())
+	for {
+		termWrite(diagnostics.Paint("2", ">") + " ")
+		answer, err := readAnswer("prompt.select")
+		if err != nil {
+			return err
+		}
+
+		answer = strings.TrimSpace(answer)
+		if idx, convErr := strconv.Atoi(answer); convErr == nil && idx >= 1 && idx <= len(choices) {
+			return &object.String{Value: choices[idx-1]}
+		}
+		for _, choice := range choices {
+			if strings.EqualFold(choice, answer) {
+				return &object.String{Value: choice}
+			}
+		}
+		termWrite(fmt.Sprintf("pick a number between 1 and %d\n", len(choices)))
+	}
+}
+
+// takes a question and an optional default, returns the answer, the default when it's empty
+// usage:
+// -	name := prompt.text("project name?", "blk-app")
+func promptText(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2",
+			len(args))
+	}
+
+	question, err := promptString(args[0], "message")
+	if err != nil {
+		return err
+	}
+	fallback, hint := "", ""
+	if len(args) == 2 {
+		if fallback, err = promptString(args[1], "default"); err != nil {
+			return err
+		}
+		hint = "(" + fallback + ")"
+	}
+
+	ask(question, hint)
+	answer, err := readAnswer("prompt.text")
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(answer)) == 0 {
+		answer = fallback
+	}
+	return &object.String{Value: answer}
+}
+
+// takes a question, returns the answer, a terminal doesn't show what gets typed
+// usage:
+// -	token := prompt.password("api token?")
+func promptPassword(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	question, err := promptString(args[0], "message")
+	if err != nil {
+		return err
+	}
+
+	ask(question, "")
+	if restore := hideInput(); restore != nil {
+		defer func() {
+			restore()
+			// the enter key wasn't echoed either
+			termWrite("\n")
+		}()
+	}
+	answer, err := readAnswer("prompt.password")
+	if err != nil {
+		return err
+	}
+	return &object.String{Value: answer}
+}
+
+// turns off the echo of the terminal Stdin is, returns what turns it back on,
+// nil when Stdin isn't a terminal or stty isn't around
+func hideInput() func() {
+	file, ok := Stdin.(*os.File)
+	if !ok || !isTerminal(file) {
+		return nil
+	}
+
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = file
+		return cmd.Run()
+	}
+	if stty("-echo") != nil {
+		return nil
+	}
+	return func() { stty("echo") }
+}
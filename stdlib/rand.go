@@ -0,0 +1,133 @@
+package stdlib
+
+import (
+	"blk/object"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// every random value of a run comes from a single generator, the run can be replayed by
+// giving blk run the seed it had with --seed, runtime.seed() gives it
+var (
+	seed      int64
+	generator *rand.Rand
+	randMu    sync.Mutex
+)
+
+func init() {
+	SetSeed(time.Now().UnixNano())
+}
+
+// starts the generator over from the seed, the values drawn after it are the same every run
+func SetSeed(value int64) {
+	randMu.Lock()
+	defer randMu.Unlock()
+	seed = value
+	generator = rand.New(rand.NewPCG(uint64(value), 0))
+}
+
+// the seed the generator started from
+func Seed() int64 {
+	randMu.Lock()
+	defer randMu.Unlock()
+	return seed
+}
+
+func randModule() object.Module {
+	return object.Module{
+		"int":     &object.BuiltinFn{Fn: randInt},
+		"float":   &object.BuiltinFn{Fn: randFloat},
+		"choice":  &object.BuiltinFn{Fn: randChoice},
+		"shuffle": &object.BuiltinFn{Fn: randShuffle},
+	}
+}
+
+// takes the bounds, returns an int in [min, max)
+// usage:
+// -	dice := rand.int(1, 7)
+func randInt(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+	low, _ := object.Cast(args[0])
+	high, _ := object.Cast(args[1])
+	lowInt, ok := low.(*object.Integer)
+	highInt, ok2 := high.(*object.Integer)
+	if !ok || !ok2 {
+		return newError("the bounds of rand.int need to be of type int, got %v and %v", low.Type(), high.Type())
+	}
+	if lowInt.Value >= highInt.Value {
+		return newError("rand.int needs min < max, got %d and %d", lowInt.Value, highInt.Value)
+	}
+
+	randMu.Lock()
+	defer randMu.Unlock()
+	return &object.Integer{Value: lowInt.Value + generator.Int64N(highInt.Value-lowInt.Value)}
+}
+
+// returns a float in [0, 1)
+// usage:
+// -	if rand.float() < 0.1 { ... }
+func randFloat(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0",
+			len(args))
+	}
+
+	randMu.Lock()
+	defer randMu.Unlock()
+	return &object.Float{Value: generator.Float64()}
+}
+
+// takes an array, returns one of its elements
+// usage:
+// -	color := rand.choice(["red", "green", "blue"])
+func randChoice(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+	arg, _ := object.Cast(args[0])
+	array, ok := arg.(*object.Array)
+	if !ok {
+		return newError("argument needs to be of type array, got %v", arg.Type())
+	}
+	if len(array.Elements) == 0 {
+		return newError("rand.choice needs an array with elements, got an empty one")
+	}
+
+	randMu.Lock()
+	defer randMu.Unlock()
+	return array.Elements[generator.IntN(len(array.Elements))]
+}
+
+// takes an array, shuffles its elements in place
+// usage:
+// -	rand.shuffle(cards)
+func randShuffle(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+	arg, isMutable := object.Cast(args[0])
+
+	if arg.Type() != object.ARRAY_OBJ {
+		return newError("argument needs to be of type array, got %v", arg.Type())
+	}
+
+	if !isMutable {
+		return newError("can't mutate %v, probably defined as a const", args[0].Inspect())
+	}
+
+	array := arg.(*object.Array)
+
+	randMu.Lock()
+	defer randMu.Unlock()
+	generator.Shuffle(len(array.Elements), func(i, j int) {
+		array.Elements[i], array.Elements[j] = array.Elements[j], array.Elements[i]
+	})
+
+	return array
+}
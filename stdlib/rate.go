@@ -0,0 +1,143 @@
+package stdlib
+
+import (
+	"blk/object"
+	"time"
+)
+
+func rateModule() object.Module {
+	return object.Module{
+		"new":      &object.BuiltinFn{Fn: rateNew},
+		"debounce": &object.BuiltinFn{Fn: rateDebounce},
+		"throttle": &object.BuiltinFn{Fn: rateThrottle},
+	}
+}
+
+// lets at most limit calls through in any window of the period, the times
+// of the calls still in the window are kept, the oldest first
+type limiter struct {
+	limit  int
+	period time.Duration
+	calls  []time.Time
+}
+
+func (l *limiter) allow(now time.Time) bool {
+	for len(l.calls) > 0 && now.Sub(l.calls[0]) >= l.period {
+		l.calls = l.calls[1:]
+	}
+	if len(l.calls) < l.limit {
+		l.calls = append(l.calls, now)
+		return true
+	}
+	return false
+}
+
+// takes the number of calls and the period they're allowed in, a duration like 1s, 500ms or 1m,
+// returns a limiter, wait blocks until a call is allowed, try returns whether it is without waiting
+// usage:
+// -	limiter := rate.new(5, "1s")
+// -	limiter.wait()
+// -	if limiter.try() { ... }
+func rateNew(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	limit, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("limit needs to be of type int, got=%v", args[0].Type())
+	}
+	if limit.Value < 1 {
+		return newError("rate.new: limit needs to be at least 1, got=%d", limit.Value)
+	}
+	args[1], _ = object.Cast(args[1])
+	per, ok := args[1].(*object.String)
+	if !ok {
+		return newError("period needs to be of type string, got=%v", args[1].Type())
+	}
+	period, err := time.ParseDuration(per.Value)
+	if err != nil || period <= 0 {
+		return newError("rate.new: invalid period %s, expected a duration like 1s, 500ms or 1m", per.Value)
+	}
+
+	l := &limiter{limit: int(limit.Value), period: period}
+	return newHandle(map[string]object.BuiltinFunction{
+		"wait": func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=0",
+					len(args)-1)
+			}
+			// the workers of a pipeline sharing the limiter can take the call in the meantime
+			for !l.allow(time.Now()) {
+				// read while holding the lock, the other workers change the calls once it's given up
+				sleep := l.period - time.Since(l.calls[0])
+				unlocked(func() { time.Sleep(sleep) })
+			}
+			return nil
+		},
+		"try": func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=0",
+					len(args)-1)
+			}
+			return nativeBool(l.allow(time.Now()))
+		},
+	})
+}
+
+// the functions wrapped by debounce and throttle, they're called right away or skipped, never later,
+// a skipped call returns nul
+func rateWrapper(module string, args []object.Object, skip func(now time.Time, delay time.Duration) bool) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	fn, _ := object.Cast(args[0])
+	if fn.Type() != object.FUNCTION_OBJ && fn.Type() != object.BUILTIN_OBJ {
+		return newError("fn needs to be a function, got=%v", fn.Type())
+	}
+	args[1], _ = object.Cast(args[1])
+	ms, ok := args[1].(*object.Integer)
+	if !ok || ms.Value < 0 {
+		return newError("%s: ms needs to be a positive int, got=%v", module, args[1].Inspect())
+	}
+	delay := time.Duration(ms.Value) * time.Millisecond
+
+	return &object.BuiltinFn{Fn: func(args ...object.Object) object.Object {
+		if skip(time.Now(), delay) {
+			return object.NUL
+		}
+		return CallFunction(fn, args...)
+	}}
+}
+
+// takes a function and a delay in ms, returns a function calling it on the first call of a burst,
+// the leading edge, the calls coming less than the delay after the previous one are skipped, so
+// every call restarts the wait, even the skipped ones
+// usage:
+// -	search :: rate.debounce(fn(query) { lookup(query) }, 300)
+func rateDebounce(args ...object.Object) object.Object {
+	var last time.Time
+	return rateWrapper("rate.debounce", args, func(now time.Time, delay time.Duration) bool {
+		skip := !last.IsZero() && now.Sub(last) < delay
+		last = now
+		return skip
+	})
+}
+
+// takes a function and a delay in ms, returns a function calling it at most once per delay
+// usage:
+// -	report :: rate.throttle(fn(pct) { fmt.println(pct) }, 1000)
+func rateThrottle(args ...object.Object) object.Object {
+	var last time.Time
+	return rateWrapper("rate.throttle", args, func(now time.Time, delay time.Duration) bool {
+		if !last.IsZero() && now.Sub(last) < delay {
+			return true
+		}
+		last = now
+		return false
+	})
+}
@@ -0,0 +1,172 @@
+package stdlib
+
+import (
+	"blk/checkpoint"
+	"blk/heapsnap"
+	"blk/internals"
+	"blk/object"
+	"os"
+	"runtime"
+	"sync/atomic"
+)
+
+// EvalSteps counts the nodes evaluated by the interpreter so far,
+// it gets incremented by the interpreter on every Eval call
+var EvalSteps atomic.Int64
+
+func runtimeModule() object.Module {
+	return object.Module{
+		"version":        &object.String{Value: internals.Version},
+		"mem_usage":      &object.BuiltinFn{Fn: runtimeMemUsage},
+		"num_goroutines": &object.BuiltinFn{Fn: runtimeNumGoroutines},
+		"gc":             &object.BuiltinFn{Fn: runtimeGC},
+		"eval_steps":     &object.BuiltinFn{Fn: runtimeEvalSteps},
+		"heap_snapshot":  &object.BuiltinFn{Fn: runtimeHeapSnapshot},
+		"seed":           &object.BuiltinFn{Fn: runtimeSeed},
+		"checkpoint":     &object.BuiltinFn{Fn: runtimeCheckpoint},
+	}
+}
+
+// returns a map describing the current memory usage of the interpreter (in bytes)
+// keys are: alloc, total_alloc, sys, heap_objects, num_gc
+// usage:
+// -	usage := runtime.mem_usage()
+func runtimeMemUsage(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0",
+			len(args))
+	}
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	usage := map[string]int64{
+		"alloc":        int64(stats.Alloc),
+		"total_alloc":  int64(stats.TotalAlloc),
+		"sys":          int64(stats.Sys),
+		"heap_objects": int64(stats.HeapObjects),
+		"num_gc":       int64(stats.NumGC),
+	}
+
+	pairs := make(object.PairsType, len(usage))
+	for name, value := range usage {
+		key := &object.String{Value: name}
+		pairs[key.HashKey()] = object.HashPair{
+			Key:   key,
+			Value: &object.Integer{Value: value},
+		}
+	}
+
+	return &object.Map{Pairs: pairs}
+}
+
+// returns the number of goroutines that currently exist
+// usage:
+// -	count := runtime.num_goroutines()
+func runtimeNumGoroutines(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0",
+			len(args))
+	}
+
+	return &object.Integer{Value: int64(runtime.NumGoroutine())}
+}
+
+// runs a garbage collection, blocking the caller until it's done
+// usage:
+// -	runtime.gc()
+func runtimeGC(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0",
+			len(args))
+	}
+
+	runtime.GC()
+	return object.NUL
+}
+
+// returns the number of nodes evaluated by the interpreter since it started
+// usage:
+// -	steps := runtime.eval_steps()
+func runtimeEvalSteps(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0",
+			len(args))
+	}
+
+	return &object.Integer{Value: EvalSteps.Load()}
+}
+
+// returns the seed of the rand module, blk run --seed with it draws the same values again
+// usage:
+// -	fmt.println("seed", runtime.seed())
+func runtimeSeed(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0",
+			len(args))
+	}
+
+	return &object.Integer{Value: Seed()}
+}
+
+// takes a file path, writes the graph of the scopes visible from the call and of the values they hold,
+// with their types, approximate sizes and references, as json, or as graphviz dot when the path ends with .dot
+// usage:
+// -	runtime.heap_snapshot("heap.json")
+// -	runtime.heap_snapshot("heap.dot")
+func runtimeHeapSnapshot(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return newError("path needs to be of type string, got=%v", args[0].Type())
+	}
+	if err := requireFS(path.Value); err != nil {
+		return err
+	}
+
+	if err := heapsnap.Snapshot(CurrentEnv()).WriteFile(path.Value); err != nil {
+		return newError("runtime.heap_snapshot: %v", err)
+	}
+	return object.NUL
+}
+
+// takes a file path, saves the globals of the program (the ints, the floats, the strings, the
+// chars, the bools, the arrays, the maps and the instances) with the top level statement being
+// evaluated, blk resume continues the run from that statement when it gets interrupted
+// the functions, the structs, the enums and the modules aren't saved, resuming declares them again
+// usage:
+// -	runtime.checkpoint("job.ckpt")
+func runtimeCheckpoint(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return newError("path needs to be of type string, got=%v", args[0].Type())
+	}
+	if err := requireFS(path.Value); err != nil {
+		return err
+	}
+
+	program, globals, statement := CurrentProgram()
+	source, err := os.ReadFile(program)
+	if err != nil {
+		return newError("runtime.checkpoint: the source of the program is needed to resume it: %v", err)
+	}
+	cp, err := checkpoint.New(program, internals.Checksum(source), statement, globals)
+	if err != nil {
+		return newError("runtime.checkpoint: %v", err)
+	}
+	if err := cp.WriteFile(path.Value); err != nil {
+		return newError("runtime.checkpoint: %v", err)
+	}
+	return object.NUL
+}
@@ -0,0 +1,309 @@
+package stdlib
+
+import (
+	"blk/object"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// jobs registered by every and cron only run once the script calls run_forever,
+// which runs them one at a time, as they come due
+func scheduleModule() object.Module {
+	return object.Module{
+		"every":       &object.BuiltinFn{Fn: scheduleEvery},
+		"cron":        &object.BuiltinFn{Fn: scheduleCron},
+		"run_forever": &object.BuiltinFn{Fn: scheduleRunForever},
+	}
+}
+
+// the jobs waiting for run_forever, the cancelled ones are dropped on its next turn
+var jobs []*job
+
+type job struct {
+	fn  object.Object
+	due time.Time
+	// returns when the job runs again, after the run that was due
+	next      func(due time.Time) time.Time
+	cancelled bool
+}
+
+const scheduleTimeLayout = "2006-01-02 15:04:05"
+
+func scheduleJob(fn object.Object, next func(due time.Time) time.Time) object.Object {
+	j := &job{fn: fn, due: next(time.Now()), next: next}
+	jobs = append(jobs, j)
+
+	return newHandle(map[string]object.BuiltinFunction{
+		"cancel": func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=0",
+					len(args)-1)
+			}
+			j.cancelled = true
+			return nil
+		},
+		"next_run": func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=0",
+					len(args)-1)
+			}
+			return &object.String{Value: j.due.Format(scheduleTimeLayout)}
+		},
+	})
+}
+
+func scheduleArgs(module string, args []object.Object) (string, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return "", nil, newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return "", nil, newError("%s: the first arg needs to be of type string, got=%v", module, args[0].Type())
+	}
+	fn, _ := object.Cast(args[1])
+	if fn.Type() != object.FUNCTION_OBJ && fn.Type() != object.BUILTIN_OBJ {
+		return "", nil, newError("fn needs to be a function, got=%v", fn.Type())
+	}
+	return str.Value, fn, nil
+}
+
+// takes an interval, a duration like 30s, 5m or 1h, and a function, returns the job, it first runs
+// one interval after the call, a run taking longer than the interval skips the runs it overlapped
+// the job has cancel() and next_run(), the local time of its next run
+// usage:
+// -	job := schedule.every("5m", fn() { store.open("state.json").set("alive", true) })
+// -	job.next_run() => 2026-10-14 18:05:00
+func scheduleEvery(args ...object.Object) object.Object {
+	every, fn, err := scheduleArgs("schedule.every", args)
+	if err != nil {
+		return err
+	}
+	interval, parseErr := time.ParseDuration(every)
+	if parseErr != nil || interval <= 0 {
+		return newError("schedule.every: invalid interval %s, expected a duration like 30s, 5m or 1h", every)
+	}
+
+	return scheduleJob(fn, func(due time.Time) time.Time {
+		next, now := due.Add(interval), time.Now()
+		for !next.After(now) {
+			next = next.Add(interval)
+		}
+		return next
+	})
+}
+
+// takes a cron expression and a function, returns the job, like the one of every
+// the expression has the 5 fields of crontab, minute, hour, day of the month, month and
+// day of the week (0 is sunday), with lists, ranges and steps, or one of @hourly, @daily,
+// @weekly, @monthly and @yearly, the times are local
+// usage:
+// -	schedule.cron("0 3 * * *", cleanup) => every day at 3am
+// -	schedule.cron("*/15 9-17 * * 1-5", sync) => every 15 minutes of the work hours
+func scheduleCron(args ...object.Object) object.Object {
+	expr, fn, err := scheduleArgs("schedule.cron", args)
+	if err != nil {
+		return err
+	}
+	spec, parseErr := parseCron(expr)
+	if parseErr != nil {
+		return newError("schedule.cron: invalid expression %q: %v", expr, parseErr)
+	}
+
+	if spec.after(time.Now()).IsZero() {
+		return newError("schedule.cron: %q never matches", expr)
+	}
+
+	return scheduleJob(fn, func(due time.Time) time.Time {
+		// the runs missed while a job was running are skipped
+		if now := time.Now(); now.After(due) {
+			due = now
+		}
+		return spec.after(due)
+	})
+}
+
+// runs the jobs as they come due until none is left, or the process is interrupted, the job
+// running then gets to finish, the first error a job returns stops it as well and is returned
+// usage:
+// -	schedule.every("1h", backup)
+// -	schedule.run_forever()
+func scheduleRunForever(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0",
+			len(args))
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+	// the jobs don't outlive the loop, a later run_forever starts from the jobs registered after it
+	defer func() { jobs = nil }()
+
+	for {
+		var first *job
+		pending := jobs[:0]
+		for _, j := range jobs {
+			if j.cancelled {
+				continue
+			}
+			pending = append(pending, j)
+			if first == nil || j.due.Before(first.due) {
+				first = j
+			}
+		}
+		jobs = pending
+		if first == nil {
+			return nil
+		}
+
+		interrupted := false
+		unlocked(func() {
+			timer := time.NewTimer(time.Until(first.due))
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-interrupts:
+				interrupted = true
+			}
+		})
+		if interrupted {
+			return nil
+		}
+
+		result := CallFunction(first.fn)
+		if isError(result) {
+			return result
+		}
+		first.due = first.next(first.due)
+	}
+}
+
+// the values every field of a cron expression allows
+type cronSpec struct {
+	minutes, hours, days, months, weekdays map[int]bool
+	// when both the days and the weekdays are restricted, a time matching either runs, like crontab does
+	anyDay, anyWeekday bool
+}
+
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+func parseCron(expr string) (*cronSpec, error) {
+	if macro, ok := cronMacros[strings.TrimSpace(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	bounds := []struct {
+		name     string
+		min, max int
+	}{{"minute", 0, 59}, {"hour", 0, 23}, {"day", 1, 31}, {"month", 1, 12}, {"weekday", 0, 7}}
+	sets := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i].min, bounds[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s", bounds[i].name, err)
+		}
+		sets[i] = set
+	}
+	// 7 is sunday as well
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+
+	return &cronSpec{
+		minutes: sets[0], hours: sets[1], days: sets[2], months: sets[3], weekdays: sets[4],
+		anyDay: strings.HasPrefix(fields[2], "*"), anyWeekday: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parses a field like *, 5, 1-5, */15 or 0,30, and a list of them
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		rng, rawStep, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(rawStep)
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("has an invalid step %s", rawStep)
+			}
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			rawLo, rawHi, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(rawLo); err != nil {
+				return nil, fmt.Errorf("has an invalid value %s", rawLo)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(rawHi); err != nil {
+					return nil, fmt.Errorf("has an invalid value %s", rawHi)
+				}
+			} else if hasStep {
+				// 5/15 goes from 5 to the end
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%s is out of range, expected values from %d to %d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func (c *cronSpec) matchesDay(t time.Time) bool {
+	day, weekday := c.days[t.Day()], c.weekdays[int(t.Weekday())]
+	switch {
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// returns the first time matching the spec, after t, the fields that don't match skip
+// the whole month, day or hour, the zero time when nothing matches in 5 years (like the 30th of february)
+func (c *cronSpec) after(t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location())
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !c.months[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.hours[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minutes[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
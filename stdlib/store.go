@@ -0,0 +1,227 @@
+package stdlib
+
+import (
+	"blk/object"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// persistent maps for the state scripts keep between runs, an example of this: the tracks already downloaded
+// a store is a json object in a file, it's written again on every change
+func storeModule() object.Module {
+	return object.Module{
+		"open": &object.BuiltinFn{Fn: storeOpen},
+	}
+}
+
+type store struct {
+	path string
+	// the json text of every value, get decodes it in the shape of its default
+	values map[string]json.RawMessage
+}
+
+// takes a path, returns the store kept in it, a missing file is an empty store, created on the first change
+// the store has get(key, default), set(key, value), delete(key), has(key) and keys()
+// get returns the default when the key isn't set, nul when there's none, the value is decoded in the
+// shape of the default like json.unmarshal does, an example of this: db.get("user", User{})
+// usage:
+// -	db := store.open("state.json")
+// -	db.set("done", ["intro.mp3"])
+// -	done := db.get("done", [""])
+func storeOpen(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	path, ok := args[0].(*object.String)
+	if !ok {
+		return newError("path needs to be of type string, got=%v", args[0].Type())
+	}
+	if err := requireFS(path.Value); err != nil {
+		return err
+	}
+
+	s := &store{path: path.Value, values: map[string]json.RawMessage{}}
+	content, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return newError("store.open: %v", err)
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &s.values); err != nil {
+			return newError("store.open: %s isn't a store, expected a json object: %v", s.path, err)
+		}
+	}
+
+	handle := newHandle(map[string]object.BuiltinFunction{
+		"get":    s.get,
+		"set":    s.set,
+		"delete": s.delete,
+		"has":    s.has,
+		"keys":   s.keys,
+	})
+	handle.Fields["path"] = object.ItemObject{Object: &object.String{Value: s.path}}
+	return handle
+}
+
+// the args of the methods start with the store itself, the key comes after it
+func storeKey(args []object.Object, module string) (string, *object.Error) {
+	args[1], _ = object.Cast(args[1])
+	key, ok := args[1].(*object.String)
+	if !ok {
+		return "", newError("%s: key needs to be of type string, got=%v", module, args[1].Type())
+	}
+	return key.Value, nil
+}
+
+func (s *store) get(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2",
+			len(args)-1)
+	}
+	key, err := storeKey(args, "store.get")
+	if err != nil {
+		return err
+	}
+
+	fallback := object.Object(object.NUL)
+	if len(args) == 3 {
+		fallback, _ = object.Cast(args[2])
+	}
+	raw, ok := s.values[key]
+	if !ok {
+		return fallback
+	}
+
+	node, parseErr := parseJSON(string(raw))
+	if parseErr != nil {
+		return newError("store.get: %v", parseErr)
+	}
+	return node.toObject(fallback, "$."+key)
+}
+
+func (s *store) set(args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2",
+			len(args)-1)
+	}
+	key, err := storeKey(args, "store.set")
+	if err != nil {
+		return err
+	}
+
+	value, jsonErr := toJSONValue(args[2], map[object.Object]bool{})
+	if jsonErr != nil {
+		return newError("store.set: %v", jsonErr)
+	}
+	raw, jsonErr := json.Marshal(value)
+	if jsonErr != nil {
+		return newError("store.set: %v", jsonErr)
+	}
+
+	previous, existed := s.values[key]
+	s.values[key] = raw
+	if err := s.save("store.set"); err != nil {
+		// the store keeps matching the file
+		if existed {
+			s.values[key] = previous
+		} else {
+			delete(s.values, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// returns whether the key was set
+func (s *store) delete(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args)-1)
+	}
+	key, err := storeKey(args, "store.delete")
+	if err != nil {
+		return err
+	}
+
+	previous, existed := s.values[key]
+	if !existed {
+		return object.FALSE
+	}
+	delete(s.values, key)
+	if err := s.save("store.delete"); err != nil {
+		s.values[key] = previous
+		return err
+	}
+	return object.TRUE
+}
+
+func (s *store) has(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args)-1)
+	}
+	key, err := storeKey(args, "store.has")
+	if err != nil {
+		return err
+	}
+	_, ok := s.values[key]
+	return nativeBool(ok)
+}
+
+// returns the keys, sorted
+func (s *store) keys(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=0",
+			len(args)-1)
+	}
+
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	elements := make([]object.Object, 0, len(names))
+	for _, name := range names {
+		elements = append(elements, &object.String{Value: name})
+	}
+	return &object.Array{Size: -1, Elements: elements}
+}
+
+// writes a temporary file next to the store and renames it over the store, a crash
+// in between leaves the previous version in place, never half of the new one
+func (s *store) save(module string) *object.Error {
+	content, err := json.MarshalIndent(s.values, "", "  ")
+	if err != nil {
+		return newError("%s: %v", module, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+"-*")
+	if err != nil {
+		return newError("%s: %v", module, err)
+	}
+	defer os.Remove(tmp.Name())
+	// temporary files are private, the store gets the mode of a regular file
+	tmp.Chmod(0644)
+
+	if _, err := tmp.Write(append(content, '\n')); err != nil {
+		tmp.Close()
+		return newError("%s: %v", module, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return newError("%s: %v", module, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return newError("%s: %v", module, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return newError("%s: %v", module, err)
+	}
+	return nil
+}
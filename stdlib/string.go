@@ -5,28 +5,30 @@ import (
 	"strings"
 )
 
-var stringModule = object.Module{
-	"join":         &object.BuiltinFn{Fn: stringJoin},
-	"split":        &object.BuiltinFn{Fn: stringSplit},
-	"hasSuffix":    &object.BuiltinFn{Fn: funcSSB(strings.HasSuffix)},
-	"hasPrefix":    &object.BuiltinFn{Fn: funcSSB(strings.HasPrefix)},
-	"contains":     &object.BuiltinFn{Fn: funcSSB(strings.Contains)},
-	"containsAny":  &object.BuiltinFn{Fn: funcSSB(strings.ContainsAny)},
-	"equalFold":    &object.BuiltinFn{Fn: funcSSB(strings.EqualFold)},
-	"toUpperCase":  &object.BuiltinFn{Fn: funcSS(strings.ToUpper)},
-	"toLowerCase":  &object.BuiltinFn{Fn: funcSS(strings.ToLower)},
-	"trim":         &object.BuiltinFn{Fn: func2SS(strings.Trim)},
-	"trimLeft":     &object.BuiltinFn{Fn: func2SS(strings.TrimLeft)},
-	"trimRight":    &object.BuiltinFn{Fn: func2SS(strings.TrimRight)},
-	"trimPrefix":   &object.BuiltinFn{Fn: func2SS(strings.TrimPrefix)},
-	"trimSuffix":   &object.BuiltinFn{Fn: func2SS(strings.TrimSuffix)},
-	"trimSpace":    &object.BuiltinFn{Fn: funcSS(strings.TrimSpace)},
-	"index":        &object.BuiltinFn{Fn: funcSSI(strings.Index)},
-	"indexAny":     &object.BuiltinFn{Fn: funcSSI(strings.IndexAny)},
-	"lastIndex":    &object.BuiltinFn{Fn: funcSSI(strings.LastIndex)},
-	"lastIndexAny": &object.BuiltinFn{Fn: funcSSI(strings.LastIndexAny)},
-	"compare":      &object.BuiltinFn{Fn: funcSSI(strings.Compare)},
-	"count":        &object.BuiltinFn{Fn: funcSSI(strings.Count)},
+func stringModule() object.Module {
+	return object.Module{
+		"join":         &object.BuiltinFn{Fn: stringJoin, Pure: true},
+		"split":        &object.BuiltinFn{Fn: stringSplit, Pure: true},
+		"hasSuffix":    &object.BuiltinFn{Fn: funcSSB(strings.HasSuffix), Pure: true},
+		"hasPrefix":    &object.BuiltinFn{Fn: funcSSB(strings.HasPrefix), Pure: true},
+		"contains":     &object.BuiltinFn{Fn: funcSSB(strings.Contains), Pure: true},
+		"containsAny":  &object.BuiltinFn{Fn: funcSSB(strings.ContainsAny), Pure: true},
+		"equalFold":    &object.BuiltinFn{Fn: funcSSB(strings.EqualFold), Pure: true},
+		"toUpperCase":  &object.BuiltinFn{Fn: funcSS(strings.ToUpper), Pure: true},
+		"toLowerCase":  &object.BuiltinFn{Fn: funcSS(strings.ToLower), Pure: true},
+		"trim":         &object.BuiltinFn{Fn: func2SS(strings.Trim), Pure: true},
+		"trimLeft":     &object.BuiltinFn{Fn: func2SS(strings.TrimLeft), Pure: true},
+		"trimRight":    &object.BuiltinFn{Fn: func2SS(strings.TrimRight), Pure: true},
+		"trimPrefix":   &object.BuiltinFn{Fn: func2SS(strings.TrimPrefix), Pure: true},
+		"trimSuffix":   &object.BuiltinFn{Fn: func2SS(strings.TrimSuffix), Pure: true},
+		"trimSpace":    &object.BuiltinFn{Fn: funcSS(strings.TrimSpace), Pure: true},
+		"index":        &object.BuiltinFn{Fn: funcSSI(strings.Index), Pure: true},
+		"indexAny":     &object.BuiltinFn{Fn: funcSSI(strings.IndexAny), Pure: true},
+		"lastIndex":    &object.BuiltinFn{Fn: funcSSI(strings.LastIndex), Pure: true},
+		"lastIndexAny": &object.BuiltinFn{Fn: funcSSI(strings.LastIndexAny), Pure: true},
+		"compare":      &object.BuiltinFn{Fn: funcSSI(strings.Compare), Pure: true},
+		"count":        &object.BuiltinFn{Fn: funcSSI(strings.Count), Pure: true},
+	}
 }
 
 // Join concatenates the elements of its first argument to create a single string. The separator string sep is placed between elements in the resulting string.
@@ -0,0 +1,274 @@
+package stdlib
+
+import (
+	"blk/diagnostics"
+	"blk/object"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// feedback for long running scripts, it writes to Stdout and only redraws lines on terminals,
+// redirected to a file the progress bars and spinners write plain lines instead
+func termModule() object.Module {
+	return object.Module{
+		"progress": &object.BuiltinFn{Fn: termProgress},
+		"spinner":  &object.BuiltinFn{Fn: termSpinner},
+		"columns":  &object.BuiltinFn{Fn: termColumns},
+		"is_tty":   &object.BuiltinFn{Fn: termIsTTY},
+		"bold":     termStyle("1"),
+		"dim":      termStyle("2"),
+		"red":      termStyle("31"),
+		"green":    termStyle("32"),
+		"yellow":   termStyle("33"),
+		"blue":     termStyle("34"),
+		"magenta":  termStyle("35"),
+		"cyan":     termStyle("36"),
+	}
+}
+
+// the spinners draw from their own goroutine
+var termLock sync.Mutex
+
+func termWrite(text string) {
+	termLock.Lock()
+	defer termLock.Unlock()
+	io.WriteString(Stdout, text)
+}
+
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// returns whether the output is a terminal, false once it's redirected
+// usage:
+// -	if term.is_tty() { ... }
+func termIsTTY(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0",
+			len(args))
+	}
+	return nativeBool(isTerminal(Stdout))
+}
+
+// returns the width of the terminal, from COLUMNS, 100 when it isn't set
+// usage:
+// -	line := "-" * term.columns()
+func termColumns(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0",
+			len(args))
+	}
+	return &object.Integer{Value: int64(diagnostics.TerminalWidth())}
+}
+
+// takes a text, returns it in the ansi style, colors follow --color and NO_COLOR like the errors do
+// usage:
+// -	fmt.println(term.green("done"), term.dim("in 3s"))
+func termStyle(style string) *object.BuiltinFn {
+	return &object.BuiltinFn{Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%d, want=1",
+				len(args))
+		}
+		args[0], _ = object.Cast(args[0])
+		text, ok := args[0].(*object.String)
+		if !ok {
+			return newError("text needs to be of type string, got=%v", args[0].Type())
+		}
+		return &object.String{Value: diagnostics.Paint(style, text.Value)}
+	}}
+}
+
+// a record with the given methods, they get the record first like the methods of a struct
+func newHandle(methods map[string]object.BuiltinFunction) *object.StructInstance {
+	handle := newRecord(nil)
+	for name, method := range methods {
+		handle.Methods[name] = object.ItemObject{Object: &object.BuiltinFn{Fn: method}, IsBuiltIn: true}
+	}
+	return handle
+}
+
+type progressBar struct {
+	label    string
+	total    int64
+	current  int64
+	tty      bool
+	printed  int64 // the last tenth written when redirected
+	finished bool
+}
+
+// takes the total and an optional label, returns a progress bar to update with the count done so far,
+// finish completes it and ends the line
+// usage:
+// -	bar := term.progress(len(tracks), "tracks")
+// -	bar.update(3)
+// -	bar.finish()
+func termProgress(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	total, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("total needs to be of type int, got=%v", args[0].Type())
+	}
+	if total.Value < 1 {
+		return newError("term.progress: total needs to be at least 1, got=%d", total.Value)
+	}
+
+	bar := &progressBar{total: total.Value, tty: isTerminal(Stdout), printed: -1}
+	if len(args) == 2 {
+		args[1], _ = object.Cast(args[1])
+		label, ok := args[1].(*object.String)
+		if !ok {
+			return newError("label needs to be of type string, got=%v", args[1].Type())
+		}
+		bar.label = label.Value
+	}
+
+	return newHandle(map[string]object.BuiltinFunction{
+		"update": func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args)-1)
+			}
+			args[1], _ = object.Cast(args[1])
+			current, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError("done needs to be of type int, got=%v", args[1].Type())
+			}
+			if bar.finished {
+				return newError("term.progress: the progress bar is finished already")
+			}
+			bar.update(current.Value)
+			return nil
+		},
+		"finish": func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=0",
+					len(args)-1)
+			}
+			if !bar.finished {
+				bar.update(bar.total)
+				bar.finished = true
+				if bar.tty {
+					termWrite("\n")
+				}
+			}
+			return nil
+		},
+	})
+}
+
+func (b *progressBar) update(current int64) {
+	b.current = max(0, min(current, b.total))
+	percent := b.current * 100 / b.total
+
+	status := fmt.Sprintf("%3d%% %d/%d", percent, b.current, b.total)
+	if len(b.label) > 0 {
+		status = b.label + " " + status
+	}
+
+	if !b.tty {
+		// a line per tenth, logs don't need every step
+		if tenth := percent / 10; tenth != b.printed {
+			b.printed = tenth
+			termWrite(status + "\n")
+		}
+		return
+	}
+
+	width := min(40, diagnostics.TerminalWidth()-len(status)-3)
+	filled := 0
+	if width > 0 {
+		filled = int(b.current * int64(width) / b.total)
+	}
+	termWrite("\r[" + strings.Repeat("=", filled) + strings.Repeat(" ", max(0, width-filled)) + "] " + status)
+}
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// takes a message, returns a spinner that turns next to it until stop gets called,
+// stop takes an optional message to write in place of the spinner
+// usage:
+// -	s := term.spinner("downloading")
+// -	s.stop("downloaded")
+func termSpinner(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	message, ok := args[0].(*object.String)
+	if !ok {
+		return newError("message needs to be of type string, got=%v", args[0].Type())
+	}
+
+	tty := isTerminal(Stdout)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	if tty {
+		go func() {
+			defer close(done)
+			ticker := time.NewTicker(100 * time.Millisecond)
+			defer ticker.Stop()
+			for frame := 0; ; frame++ {
+				termWrite("\r" + spinnerFrames[frame%len(spinnerFrames)] + " " + message.Value)
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	} else {
+		close(done)
+		termWrite(message.Value + "\n")
+	}
+
+	stopped := false
+	return newHandle(map[string]object.BuiltinFunction{
+		"stop": func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=0 or 1",
+					len(args)-1)
+			}
+			final := ""
+			if len(args) == 2 {
+				args[1], _ = object.Cast(args[1])
+				text, ok := args[1].(*object.String)
+				if !ok {
+					return newError("message needs to be of type string, got=%v", args[1].Type())
+				}
+				final = text.Value
+			}
+			if stopped {
+				return nil
+			}
+			stopped = true
+
+			close(stop)
+			<-done
+			if tty {
+				// clears the spinner line
+				termWrite("\r\033[K")
+			}
+			if len(final) > 0 {
+				termWrite(final + "\n")
+			}
+			return nil
+		},
+	})
+}
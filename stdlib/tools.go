@@ -0,0 +1,75 @@
+package stdlib
+
+import (
+	"blk/object"
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strings"
+)
+
+// starts the tool and waits for it, returns what it wrote to stdout
+func runTool(module, tool string, args ...string) (string, *object.Error) {
+	var out strings.Builder
+	err := streamTool(module, tool, args, func(line string) *object.Error {
+		out.WriteString(line + "\n")
+		return nil
+	})
+	return out.String(), err
+}
+
+// starts the tool and hands the lines it writes to stdout to onLine as they come
+// an error returned by onLine stops the tool, failures keep the last line of stderr,
+// where the tools write the reason of the failure
+func streamTool(module, tool string, args []string, onLine func(line string) *object.Error) *object.Error {
+	if err := requireRun(tool); err != nil {
+		return err
+	}
+
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return newError("%s: %s isn't installed or isn't in PATH", module, tool)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return newError("%s: %v", module, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return newError("%s: %s failed: %v", module, tool, err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	// ffprobe writes its whole json output at once
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	// the workers of a pipeline evaluate while this one waits on the tool
+	for {
+		scanned := false
+		unlocked(func() { scanned = scanner.Scan() })
+		if !scanned {
+			break
+		}
+		if lineErr := onLine(scanner.Text()); lineErr != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return lineErr
+		}
+	}
+
+	unlocked(func() { err = cmd.Wait() })
+	if err != nil {
+		reason := strings.TrimSpace(stderr.String())
+		if idx := strings.LastIndex(reason, "\n"); idx != -1 {
+			reason = reason[idx+1:]
+		}
+		if len(reason) == 0 {
+			reason = err.Error()
+		}
+		return newError("%s: %s failed: %s", module, tool, reason)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package stdlib
+
+import (
+	"blk/object"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+func urlModule() object.Module {
+	return object.Module{
+		"parse":  &object.BuiltinFn{Fn: urlParse},
+		"build":  &object.BuiltinFn{Fn: urlBuild},
+		"encode": &object.BuiltinFn{Fn: funcSS(url.QueryEscape)},
+		"decode": &object.BuiltinFn{Fn: urlDecode},
+		"join":   &object.BuiltinFn{Fn: urlJoin},
+	}
+}
+
+// the fields of a parsed url, build takes the same ones
+var urlFields = []string{"scheme", "user", "host", "port", "path", "query", "fragment"}
+
+func urlString(arg object.Object, name string) (string, *object.Error) {
+	arg, _ = object.Cast(arg)
+	str, ok := arg.(*object.String)
+	if !ok {
+		return "", newError("%s needs to be of type string, got=%v", name, arg.Type())
+	}
+	return str.Value, nil
+}
+
+// takes a url, returns a struct with its scheme, user, host, port, path, query and fragment,
+// the query is a map of strings, a key given more than once keeps its first value,
+// the parts the url doesn't have are empty
+// usage:
+// -	u := url.parse("https://api.example.com:8080/v1/tracks?page=2#top")
+// -	u.query["page"] => 2
+func urlParse(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	raw, err := urlString(args[0], "url")
+	if err != nil {
+		return err
+	}
+	parsed, parseErr := url.Parse(raw)
+	if parseErr != nil {
+		return newError("url.parse: %v", parseErr)
+	}
+
+	query := map[string]string{}
+	for key, values := range parsed.Query() {
+		query[key] = values[0]
+	}
+
+	return newRecord(map[string]object.Object{
+		"scheme":   &object.String{Value: parsed.Scheme},
+		"user":     &object.String{Value: parsed.User.Username()},
+		"host":     &object.String{Value: parsed.Hostname()},
+		"port":     &object.String{Value: parsed.Port()},
+		"path":     &object.String{Value: parsed.Path},
+		"query":    stringMap(query),
+		"fragment": &object.String{Value: parsed.Fragment},
+	})
+}
+
+// takes a struct with the fields of url.parse, the missing ones are left out of the url, returns the url
+// the query is a map of strings, written with its keys sorted
+// usage:
+// -	u := url.parse("https://example.com/search")
+// -	hashmap.insert(u.query, "q", "lofi beats")
+// -	url.build(u) => https://example.com/search?q=lofi+beats
+func urlBuild(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	args[0], _ = object.Cast(args[0])
+	instance, ok := args[0].(*object.StructInstance)
+	if !ok {
+		return newError("parts need to be a struct with the fields of url.parse, got=%v", args[0].Type())
+	}
+
+	parts := map[string]string{}
+	u := &url.URL{}
+	for _, name := range instance.FieldNames() {
+		field := instance.Fields[name]
+		if !slices.Contains(urlFields, name) {
+			return newError("url.build: unknown field %s, expected one of (%s)", name, strings.Join(urlFields, ", "))
+		}
+		if name == "query" {
+			field, _ = object.Cast(field)
+			query, ok := field.(*object.Map)
+			if !ok {
+				return newError("url.build: query needs to be of type map, got=%v", field.Type())
+			}
+			values := url.Values{}
+			for _, pair := range query.Pairs {
+				value, _ := object.Cast(pair.Value)
+				if pair.Key.Type() != object.STRING_OBJ || value.Type() != object.STRING_OBJ {
+					return newError("url.build: query needs to map strings to strings, got=%v to %v", pair.Key.Type(), value.Type())
+				}
+				values.Set(pair.Key.(*object.String).Value, value.(*object.String).Value)
+			}
+			u.RawQuery = values.Encode()
+			continue
+		}
+
+		value, err := urlString(field, name)
+		if err != nil {
+			return err
+		}
+		parts[name] = value
+	}
+
+	u.Scheme, u.Path, u.Fragment = parts["scheme"], parts["path"], parts["fragment"]
+	u.Host = parts["host"]
+	if len(parts["port"]) > 0 {
+		u.Host += ":" + parts["port"]
+	}
+	if len(parts["user"]) > 0 {
+		u.User = url.User(parts["user"])
+	}
+	// a relative path needs a slash after the host
+	if len(u.Host) > 0 && len(u.Path) > 0 && !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+
+	return &object.String{Value: u.String()}
+}
+
+// takes a percent encoded text, returns it decoded, + is a space
+// usage:
+// -	url.decode("lofi+beats%21") => lofi beats!
+func urlDecode(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1",
+			len(args))
+	}
+
+	text, err := urlString(args[0], "text")
+	if err != nil {
+		return err
+	}
+	decoded, decodeErr := url.QueryUnescape(text)
+	if decodeErr != nil {
+		return newError("url.decode: %v", decodeErr)
+	}
+	return &object.String{Value: decoded}
+}
+
+// takes a base url and a reference, returns the reference resolved against the base like a browser does
+// usage:
+// -	url.join("https://example.com/v1/tracks", "../users") => https://example.com/users
+// -	url.join("https://example.com/v1/", "tracks?page=2") => https://example.com/v1/tracks?page=2
+func urlJoin(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	rawBase, err := urlString(args[0], "base")
+	if err != nil {
+		return err
+	}
+	rawRef, err := urlString(args[1], "ref")
+	if err != nil {
+		return err
+	}
+
+	base, parseErr := url.Parse(rawBase)
+	if parseErr != nil {
+		return newError("url.join: %v", parseErr)
+	}
+	ref, parseErr := url.Parse(rawRef)
+	if parseErr != nil {
+		return newError("url.join: %v", parseErr)
+	}
+	return &object.String{Value: base.ResolveReference(ref).String()}
+}
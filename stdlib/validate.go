@@ -0,0 +1,294 @@
+package stdlib
+
+import (
+	"blk/object"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+func validateModule() object.Module {
+	return object.Module{
+		"check": &object.BuiltinFn{Fn: validateCheck},
+	}
+}
+
+// the rules of a schema key, an example of this: "string,min=1,max=64"
+type fieldRules struct {
+	kind     string
+	optional bool
+	min, max *float64
+	oneOf    []string
+	pattern  *regexp.Regexp
+}
+
+var validateKinds = []string{"any", "string", "int", "float", "number", "bool", "array", "map"}
+
+func parseRules(key, spec string) (*fieldRules, error) {
+	parts := strings.Split(spec, ",")
+	rules := &fieldRules{kind: strings.TrimSpace(parts[0])}
+	if !slices.Contains(validateKinds, rules.kind) {
+		return nil, fmt.Errorf("%s: unknown type %s, expected one of (%s)", key, rules.kind, strings.Join(validateKinds, ", "))
+	}
+
+	for i := 1; i < len(parts); i++ {
+		name, value, _ := strings.Cut(strings.TrimSpace(parts[i]), "=")
+		switch name {
+		case "optional":
+			rules.optional = true
+		case "min", "max":
+			bound, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s needs a number, got %q", key, name, value)
+			}
+			if name == "min" {
+				rules.min = &bound
+			} else {
+				rules.max = &bound
+			}
+		case "oneof":
+			rules.oneOf = strings.Split(value, "|")
+		case "pattern":
+			// the pattern takes the rest of the rules, commas included
+			value = strings.Join(append([]string{value}, parts[i+1:]...), ",")
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid pattern: %v", key, err)
+			}
+			rules.pattern = re
+			i = len(parts)
+		default:
+			return nil, fmt.Errorf("%s: unknown rule %s, expected optional, min, max, oneof or pattern", key, name)
+		}
+	}
+	return rules, nil
+}
+
+// the name of the type of a value in the violations
+func kindOf(value object.Object) string {
+	switch value.(type) {
+	case *object.Integer:
+		return "int"
+	case *object.Float:
+		return "float"
+	case *object.String:
+		return "string"
+	case *object.Boolean:
+		return "bool"
+	case *object.Array:
+		return "array"
+	case *object.Map, *object.StructInstance:
+		return "map"
+	case *object.Nul:
+		return "nul"
+	default:
+		return strings.ToLower(string(value.Type()))
+	}
+}
+
+type violation struct {
+	path, rule, message string
+}
+
+// checks a value against its rules, the value is nil when it's missing
+func (r *fieldRules) check(path string, value object.Object) []violation {
+	if value == nil || value.Type() == object.NUL_OBJ {
+		if r.optional {
+			return nil
+		}
+		return []violation{{path, "required", "is required"}}
+	}
+
+	kind := kindOf(value)
+	switch {
+	case r.kind == "any", r.kind == kind:
+	case r.kind == "number" && (kind == "int" || kind == "float"):
+	default:
+		return []violation{{path, "type", fmt.Sprintf("expected %s, got %s", r.kind, kind)}}
+	}
+
+	// the bounds are on the value for numbers, on the length for the rest
+	var measure float64
+	what := "length"
+	switch value := value.(type) {
+	case *object.Integer:
+		measure, what = float64(value.Value), "value"
+	case *object.Float:
+		measure, what = value.Value, "value"
+	case *object.String:
+		measure = float64(utf8.RuneCountInString(value.Value))
+	case *object.Array:
+		measure = float64(len(value.Elements))
+	case *object.Map:
+		measure = float64(len(value.Pairs))
+	case *object.StructInstance:
+		measure = float64(len(value.Fields))
+	}
+
+	var violations []violation
+	if r.min != nil && measure < *r.min {
+		violations = append(violations, violation{path, "min", fmt.Sprintf("%s %v is below the min of %v", what, measure, *r.min)})
+	}
+	if r.max != nil && measure > *r.max {
+		violations = append(violations, violation{path, "max", fmt.Sprintf("%s %v is above the max of %v", what, measure, *r.max)})
+	}
+
+	text := value.Inspect()
+	if len(r.oneOf) > 0 && !slices.Contains(r.oneOf, text) {
+		violations = append(violations, violation{path, "oneof", fmt.Sprintf("%s isn't one of (%s)", text, strings.Join(r.oneOf, ", "))})
+	}
+	if r.pattern != nil && !r.pattern.MatchString(text) {
+		violations = append(violations, violation{path, "pattern", fmt.Sprintf("%s doesn't match %s", text, r.pattern)})
+	}
+	return violations
+}
+
+// returns the field of a map or a struct instance, nil when it's missing or the value holds no fields
+func fieldOf(value object.Object, name string) object.Object {
+	switch value := value.(type) {
+	case *object.Map:
+		pair, ok := value.Pairs[(&object.String{Value: name}).HashKey()]
+		if !ok {
+			return nil
+		}
+		field, _ := object.Cast(pair.Value)
+		return field
+	case *object.StructInstance:
+		field, ok := value.Fields[name]
+		if !ok {
+			return nil
+		}
+		field, _ = object.Cast(field)
+		return field
+	}
+	return nil
+}
+
+// a value a schema key leads to, with its path
+type located struct {
+	path  string
+	value object.Object
+}
+
+// follows a schema key like user.tags[].name from the value, [] goes through every element of an array
+// a missing parent ends the key at it when the schema has rules for the parent, they report it, the key
+// is missing itself otherwise
+func locate(root object.Object, key string, declared map[string]bool) []located {
+	segments := strings.Split(key, ".")
+	current := []located{{path: "$", value: root}}
+	prefix := ""
+	for i, segment := range segments {
+		name, each := strings.CutSuffix(segment, "[]")
+		if len(prefix) > 0 {
+			prefix += "."
+		}
+		prefix += name
+
+		next := make([]located, 0, len(current))
+		for _, loc := range current {
+			next = append(next, located{path: loc.path + "." + name, value: fieldOf(loc.value, name)})
+		}
+		current = next
+
+		if each {
+			next = nil
+			for _, loc := range current {
+				arr, ok := loc.value.(*object.Array)
+				if !ok {
+					if !declared[prefix] {
+						next = append(next, located{path: loc.path + "[]"})
+					}
+					continue
+				}
+				for i, elem := range arr.Elements {
+					elem, _ = object.Cast(elem)
+					next = append(next, located{path: fmt.Sprintf("%s[%d]", loc.path, i), value: elem})
+				}
+			}
+			current = next
+			prefix += "[]"
+		}
+
+		if i == len(segments)-1 {
+			break
+		}
+		// the next segment reads a field of each value
+		next = nil
+		for _, loc := range current {
+			switch loc.value.(type) {
+			case *object.Map, *object.StructInstance:
+				next = append(next, loc)
+			default:
+				if !declared[prefix] {
+					next = append(next, located{path: loc.path})
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// takes a value, a map or a struct instance, and a schema, a map from the keys of the value to their rules,
+// returns the violations, an empty array when the value is valid, each has the path, the rule and a message
+// a rule starts with the type, any, string, int, float, number, bool, array or map, optional lets the
+// key be missing or nul, min and max bound numbers or the length of the rest, oneof takes the values
+// split by |, and pattern a regexp, written last, the keys go into nested values with dots, and through
+// the elements of arrays with [], an example of this: "tracks[].title"
+// usage:
+// -	issues := validate.check(user, {"name": "string,min=1", "age": "int,min=0", "tags[]": "string,optional"})
+// -	issues[0].path => $.age
+// -	issues[0].message => value -3 is below the min of 0
+func validateCheck(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	value, _ := object.Cast(args[0])
+	if value.Type() != object.MAP_OBJ && value.Type() != object.STRUCT_INSTANCE_OBJ {
+		return newError("value needs to be a map or a struct instance, got=%v", value.Type())
+	}
+	args[1], _ = object.Cast(args[1])
+	schema, ok := args[1].(*object.Map)
+	if !ok {
+		return newError("schema needs to be of type map, got=%v", args[1].Type())
+	}
+
+	specs := map[string]string{}
+	declared := map[string]bool{}
+	keys := make([]string, 0, len(schema.Pairs))
+	for _, pair := range schema.Pairs {
+		spec, _ := object.Cast(pair.Value)
+		if pair.Key.Type() != object.STRING_OBJ || spec.Type() != object.STRING_OBJ {
+			return newError("validate.check: schema needs to map strings to strings, got=%v to %v", pair.Key.Type(), spec.Type())
+		}
+		key := pair.Key.(*object.String).Value
+		specs[key] = spec.(*object.String).Value
+		declared[key] = true
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	elements := []object.Object{}
+	for _, key := range keys {
+		rules, err := parseRules(key, specs[key])
+		if err != nil {
+			return newError("validate.check: %v", err)
+		}
+
+		for _, loc := range locate(value, key, declared) {
+			for _, v := range rules.check(loc.path, loc.value) {
+				elements = append(elements, newRecord(map[string]object.Object{
+					"path":    &object.String{Value: v.path},
+					"rule":    &object.String{Value: v.rule},
+					"message": &object.String{Value: v.message},
+				}))
+			}
+		}
+	}
+	return &object.Array{Size: -1, Elements: elements}
+}
@@ -1,10 +1,19 @@
 package evaluator_tests
 
 import (
+	"blk/ast"
+	"blk/diagnostics"
+	"blk/internals"
 	"blk/interpreter"
 	"blk/lexer"
 	"blk/object"
 	"blk/parser"
+	"blk/stdlib"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -81,6 +90,57 @@ res
 `,
 			expected: &object.String{Value: "See ya"},
 		},
+		{
+			input: `
+res := if false { 1 } else if true { nul } else { 3 }
+res
+`,
+			expected: object.NUL,
+		},
+		{
+			input: `
+check :: fn(cond) {
+	if cond { 1 }
+}
+check(false)
+`,
+			expected: object.NUL,
+		},
+		{
+			input: `
+a := 5
+res :: if a > 10 ? "big" : if a > 3 ? "medium" : "small"
+res
+`,
+			expected: &object.String{Value: "medium"},
+		},
+		{
+			input: `
+res :: 1 + if true ? if false ? 10 : 20 : 30
+res
+`,
+			expected: &object.Integer{Value: 21},
+		},
+		{
+			input: `
+check :: fn(age) {
+	guard age >= 18 else { return "minor" }
+	"adult"
+}
+check(12)
+`,
+			expected: &object.String{Value: "minor"},
+		},
+		{
+			input: `
+check :: fn(age) {
+	guard age >= 18 else { return "minor" }
+	"adult"
+}
+check(30)
+`,
+			expected: &object.String{Value: "adult"},
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.NewLexer("", tt.input)
@@ -124,6 +184,47 @@ string(r)
 `,
 			expected: &object.String{Value: "6"},
 		},
+		{
+			input:    `[1, 2] + [3]`,
+			expected: &object.Array{Elements: []object.Object{&object.Integer{Value: 1}, &object.Integer{Value: 2}, &object.Integer{Value: 3}}},
+		},
+		{
+			input:    `[0] * 3`,
+			expected: &object.Array{Elements: []object.Object{&object.Integer{Value: 0}, &object.Integer{Value: 0}, &object.Integer{Value: 0}}},
+		},
+		{
+			input: `
+grid := [[0]] * 2
+grid[0][0] = 1
+grid[1][0]
+`,
+			expected: &object.Integer{Value: 0},
+		},
+		{
+			input: `
+res := []
+res = res + [1]
+res += [2]
+len(res)
+`,
+			expected: &object.Integer{Value: 2},
+		},
+		{
+			input:    `[1, 2] == [1, 2]`,
+			expected: object.TRUE,
+		},
+		{
+			input:    `[1, 2] != [1, 3]`,
+			expected: object.TRUE,
+		},
+		{
+			input: `
+a := [1]
+b := [2.0]
+a + b
+`,
+			expected: &object.Error{Message: "ERROR: can't concatenate arrays of different element types (INTEGER, FLOAT)", Row: 4, Col: 3},
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.NewLexer("", tt.input)
@@ -139,3 +240,1094 @@ string(r)
 		}
 	}
 }
+
+func TestConstAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"limit :: 10\nlimit = 20", "10 can't be mutated, since it was defined as const"},
+		{"a :: [1]\na[0] = 2", "1 can't be mutated, since it was defined as const"},
+		{"f :: fn() {\nn :: 1\nn = 2\n}\nf()", "1 can't be mutated, since it was defined as const"},
+		{"x := 1\nx = 2\nx", "2"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestDeprecationWarnings(t *testing.T) {
+	diagnostics.SetColorMode(diagnostics.ColorNever)
+	t.Cleanup(func() { diagnostics.SetColorMode(diagnostics.ColorAuto) })
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			input: `
+@deprecated("use add")
+sum :: fn(a, b) { a + b }
+add :: fn(a, b) { a + b }
+for i in 0..3 { sum(i, 1) }
+add(1, 2)
+`,
+			expected: []string{"5:17: WARNING: sum is deprecated: use add"},
+		},
+		{
+			input: `
+@deprecated
+Point :: struct { x := 0 }
+p := Point{ x: 1 }
+`,
+			expected: []string{"4:6: WARNING: Point is deprecated"},
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		evaluator := interpreter.NewInterpreter(nil, "")
+		evaluator.Eval(program)
+		if len(evaluator.Warnings) != len(tt.expected) {
+			t.Fatalf("expected %d warnings, got=%v", len(tt.expected), evaluator.Warnings)
+		}
+		for idx, warning := range evaluator.Warnings {
+			if !strings.HasSuffix(warning.Error(), tt.expected[idx]) {
+				t.Errorf("expected=%q, got=%q", tt.expected[idx], warning.Error())
+			}
+		}
+	}
+}
+
+func TestUnusedResultWarnings(t *testing.T) {
+	diagnostics.SetColorMode(diagnostics.ColorNever)
+	t.Cleanup(func() { diagnostics.SetColorMode(diagnostics.ColorAuto) })
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			input: `
+import "strings"
+name := "bob"
+strings.toUpperCase(name)
+len(name)
+name
+`,
+			expected: []string{
+				"4:1: WARNING: the result of strings.toUpperCase is unused, it doesn't change its args, _ = ... drops it on purpose",
+				"5:1: WARNING: the result of len is unused, it doesn't change its args, _ = ... drops it on purpose",
+			},
+		},
+		{
+			// dropped on purpose, the last statement of a function is its result
+			input: `
+import "array"
+arr := [3, 1, 2]
+_ = len(arr)
+array.sort(arr)
+size :: fn(a) { len(a) }
+size(arr)
+`,
+			expected: []string{},
+		},
+		{
+			input: `
+count :: fn(a) {
+    len(a)
+    return 0
+}
+count("abc")
+`,
+			expected: []string{"3:5: WARNING: the result of len is unused"},
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		evaluator := interpreter.NewInterpreter(nil, "")
+		if result := evaluator.Eval(program); result != nil && result.Type() == object.ERROR_OBJ {
+			t.Fatalf("unexpected error: %s", result.Inspect())
+		}
+		if len(evaluator.Warnings) != len(tt.expected) {
+			t.Fatalf("expected %d warnings, got=%v", len(tt.expected), evaluator.Warnings)
+		}
+		for idx, warning := range evaluator.Warnings {
+			if !strings.Contains(warning.Error(), tt.expected[idx]) {
+				t.Errorf("expected=%q, got=%q", tt.expected[idx], warning.Error())
+			}
+		}
+	}
+}
+
+func TestRuntimeErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		row, col int
+	}{
+		{
+			input: `
+x := [1]
+z := x[3]
+`,
+			row: 3,
+			col: 6,
+		},
+		{
+			input: `
+double :: fn(n) {
+	n * missing
+}
+double(2)
+`,
+			row: 3,
+			col: 6,
+		},
+		{
+			input: `
+k := "a"
+m := {k: 1, "a": 2}
+`,
+			// the duplicate key, the first one is a related span
+			row: 3,
+			col: 13,
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		evaluator := interpreter.NewInterpreter(nil, "")
+		eval := evaluator.Eval(program)
+		err, ok := eval.(*object.Error)
+		if !ok {
+			t.Fatalf("expected an error, got=%v", eval)
+		}
+		if err.Row != tt.row || err.Col != tt.col {
+			t.Errorf("expected position %d:%d, got=%d:%d", tt.row, tt.col, err.Row, err.Col)
+		}
+	}
+}
+
+func TestArityErrors(t *testing.T) {
+	setup := "add :: fn(a, b) {\n\ta + b\n}\nUser :: struct {\n\tname := \"\",\n\tgreet: fn(self, other) { other }\n}\n"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`add(1, "x", [2])`, "ERROR: wrong number of arguments to add(a, b), declared at main.blk:1:8. got=3 (INTEGER, STRING, ARRAY), want=2"},
+		{"add()", "ERROR: wrong number of arguments to add(a, b), declared at main.blk:1:8. got=0, want=2"},
+		{"u := User{}\nu.greet()", "ERROR: wrong number of arguments to User.greet(other), declared at main.blk:6:9. got=0, want=1"},
+		{"apply :: fn(f) { f(1, 2.5) }\napply(fn(x) { x })", "ERROR: wrong number of arguments to fn(x), declared at main.blk:9:7. got=2 (INTEGER, FLOAT), want=1"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", setup+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "/tmp/main.blk").Eval(program)
+		err, ok := eval.(*object.Error)
+		if !ok {
+			t.Fatalf("%q: expected an error, got=%v", tt.input, eval)
+		}
+		if err.Message != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, err.Message)
+		}
+	}
+}
+
+func TestMapDuplicateComputedKeys(t *testing.T) {
+	input := "k := \"a\"\nm := {k: 1, \"a\": 2}"
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	if len(p.Errors) > 0 {
+		t.Fatalf("computed keys should be left to the runtime, got=%v", p.Errors)
+	}
+	eval := interpreter.NewInterpreter(nil, "").Eval(program)
+	err, ok := eval.(*object.Error)
+	if !ok {
+		t.Fatalf("expected an error, got=%v", eval)
+	}
+	if !strings.Contains(err.Message, "duplicate key a in map literal") {
+		t.Errorf("unexpected error message: %q", err.Message)
+	}
+}
+
+func TestMapValueTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": 1, "b": "x"}`, "ERROR: multitude of types detected, value elements of a map should be of one type"},
+		{"import \"hashmap\"\nhashmap.values({\"a\": 1, \"b\": \"x\"})", "ERROR: multitude of types detected, value elements of a map should be of one type"},
+		// only the options map written in the call of a builtin can mix them
+		{"import \"pipeline\"\nopts := {\"concurrency\": 2, \"on_error\": \"stop\"}\npipeline.run([1], fn(n) { n }, opts)", "ERROR: multitude of types detected, value elements of a map should be of one type"},
+		{"import \"pipeline\"\nr := pipeline.run([1], fn(n) { return {\"a\": n, \"b\": \"x\"} }, {\"concurrency\": 2, \"on_error\": \"continue\"})\nr[0].error", "multitude of types detected, value elements of a map should be of one type"},
+		{"import \"pipeline\"\nr := pipeline.run([1], fn(n) { n * 2 }, {\"concurrency\": 2, \"on_error\": \"stop\"})\nr[0].value", "2"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil {
+			t.Fatalf("evaluation of %q is null", tt.input)
+		}
+		actual := eval.Inspect()
+		if err, ok := eval.(*object.Error); ok {
+			actual = err.Message
+		}
+		if actual != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestRedeclarationSpans(t *testing.T) {
+	tests := []struct {
+		input   string
+		row     int
+		col     int
+		related string
+	}{
+		{
+			input:   "x := 1\ny := 2\nx := 3",
+			row:     3,
+			col:     1,
+			related: "main.blk:1:1 first declared here",
+		},
+		{
+			input:   "k := \"a\"\nm := {k: 1,\n\"a\": 2}",
+			row:     3,
+			col:     1,
+			related: "main.blk:2:7 first defined here",
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("main.blk", tt.input)
+		p := parser.NewParser(l.Tokenize(), "main.blk")
+		program := p.Parse()
+		eval := interpreter.NewInterpreter(nil, "main.blk").Eval(program)
+		err, ok := eval.(*object.Error)
+		if !ok {
+			t.Fatalf("expected an error, got=%v", eval)
+		}
+		if err.Row != tt.row || err.Col != tt.col {
+			t.Errorf("expected position %d:%d, got=%d:%d", tt.row, tt.col, err.Row, err.Col)
+		}
+		if len(err.Related) != 1 {
+			t.Fatalf("expected one related span, got=%v", err.Related)
+		}
+		span := err.Related[0]
+		if actual := fmt.Sprintf("%s:%d:%d %s", span.File, span.Row, span.Col, span.Label); actual != tt.related {
+			t.Errorf("expected=%q, got=%q", tt.related, actual)
+		}
+	}
+}
+
+func TestColorModes(t *testing.T) {
+	t.Cleanup(func() { diagnostics.SetColorMode(diagnostics.ColorAuto) })
+	err := &object.Error{Message: "ERROR: boom", File: "main.blk", Row: 2, Col: 5}
+
+	diagnostics.SetColorMode(diagnostics.ColorAlways)
+	if got := err.Inspect(); got != "\033[1;90mmain.blk:2:5:\033[0m ERROR: boom" {
+		t.Errorf("expected a colored position, got=%q", got)
+	}
+
+	diagnostics.SetColorMode(diagnostics.ColorNever)
+	if got := err.Inspect(); got != "main.blk:2:5: ERROR: boom" {
+		t.Errorf("expected a plain position, got=%q", got)
+	}
+
+	// NO_COLOR only applies to the auto mode
+	t.Setenv("NO_COLOR", "1")
+	diagnostics.SetColorMode(diagnostics.ColorAuto)
+	if got := err.Inspect(); strings.Contains(got, "\033") {
+		t.Errorf("expected NO_COLOR to disable the colors, got=%q", got)
+	}
+
+	if err := diagnostics.SetColorMode("sometimes"); err == nil {
+		t.Errorf("expected an invalid color mode to be refused")
+	}
+}
+
+func TestMatchStringPatterns(t *testing.T) {
+	kind := `# blk:feature match
+kind :: fn(file) {
+	match file {
+		"*.mp4" => { "video" },
+		"*.tar.*" => { "archive" },
+		"img_??.*" => { "image" },
+		"README" => { "readme" },
+		"*draft*" => { "draft" },
+		"tmp_*" => { "temp" },
+		_ => { "unknown" }
+	}
+}
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: `kind("clip.mp4")`, expected: "video"},
+		{input: `kind("backup.tar.gz")`, expected: "archive"},
+		{input: `kind("img_01.png")`, expected: "image"},
+		{input: `kind("img_001.png")`, expected: "unknown"},
+		{input: `kind("README")`, expected: "readme"},
+		{input: `kind("post_draft_2")`, expected: "draft"},
+		{input: `kind("tmp_42")`, expected: "temp"},
+		{input: `kind("notes.txt")`, expected: "unknown"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", kind+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		evaluator := interpreter.NewInterpreter(nil, "")
+		eval := evaluator.Eval(program)
+		if eval == nil {
+			t.Fatalf("evaluation is null for %q", tt.input)
+		}
+		if eval.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, eval.Inspect())
+		}
+	}
+}
+
+func TestVerifiedImports(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	module := []byte("greet :: fn() { \"hi\" }\n")
+	if err := os.WriteFile("util.blk", module, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := "# scripts allowed to run\n[checksums]\n\"util.blk\" = \"" + internals.Checksum(module) + "\"\n"
+	if err := os.WriteFile(internals.ManifestName, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func() object.Object {
+		m, err := internals.LoadManifest(filepath.Join(dir, internals.ManifestName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := lexer.NewLexer("", "import \"./util.blk\" as u\nu.greet()")
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		evaluator := interpreter.NewInterpreter(nil, "")
+		evaluator.SetVerifier(m.Verify)
+		return evaluator.Eval(program)
+	}
+
+	if eval := run(); eval == nil || eval.Inspect() != "hi" {
+		t.Fatalf("expected the verified import to run, got=%v", eval)
+	}
+
+	if err := os.WriteFile("util.blk", []byte("greet :: fn() { \"changed\" }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if eval := run(); eval == nil || eval.Type() != object.ERROR_OBJ || !strings.Contains(eval.Inspect(), "refusing to run util.blk") {
+		t.Errorf("expected the modified import to be refused, got=%v", eval)
+	}
+}
+
+func TestInterpreterHooks(t *testing.T) {
+	input := `
+import "strings"
+double :: fn(x) { x * 2 }
+User :: struct {
+	Name := "lofi",
+	getName : fn(self) {
+		return self.Name
+	}
+}
+user := User{}
+double(strings.count("banana", "a"))
+user.getName()
+missing()
+`
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	evaluator := interpreter.NewInterpreter(nil, "")
+
+	calls := []string{}
+	returns := 0
+	nodes := 0
+	errors := []string{}
+	evaluator.SetHooks(&interpreter.Hooks{
+		OnEnterNode: func(node ast.Node) { nodes++ },
+		OnCall:      func(call interpreter.CallEvent) { calls = append(calls, call.Name) },
+		OnReturn:    func(call interpreter.CallEvent, result object.Object) { returns++ },
+		OnError:     func(node ast.Node, err *object.Error) { errors = append(errors, err.Message) },
+	})
+	evaluator.Eval(program)
+
+	expected := []string{"strings.count", "double", "user.getName"}
+	if strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected calls=%v, got=%v", expected, calls)
+	}
+	if returns != len(expected) {
+		t.Errorf("expected %d returns, got=%d", len(expected), returns)
+	}
+	if nodes == 0 {
+		t.Errorf("expected OnEnterNode to be called")
+	}
+	if len(errors) != 1 || !strings.Contains(errors[0], "missing") {
+		t.Errorf("expected a single error for the missing function, got=%v", errors)
+	}
+}
+
+func TestQuickFixes(t *testing.T) {
+	tests := []struct {
+		input string
+		title string
+		fixed string // empty when the fix is only a hint
+	}{
+		{
+			input: "nums := [1, 2]\nn := lne(nums)",
+			title: "replace lne with len",
+			fixed: "nums := [1, 2]\nn := len(nums)",
+		},
+		{
+			input: "total := 1\nn := totl + 1",
+			title: "replace totl with total",
+			fixed: "total := 1\nn := total + 1",
+		},
+		{
+			input: "fmt.println(1)",
+			title: `add import "fmt"`,
+			fixed: "import \"fmt\"\nfmt.println(1)",
+		},
+		{
+			input: "import \"fmt\"\nfmt.printn(1)",
+			title: "replace printn with println",
+			fixed: "import \"fmt\"\nfmt.println(1)",
+		},
+		{
+			input: "limit :: 10\nlimit = 20",
+			title: "declare limit with := instead of :: to make it mutable",
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		err, ok := eval.(*object.Error)
+		if !ok {
+			t.Fatalf("%q: expected an error, got=%v", tt.input, eval)
+		}
+		if err.Fix == nil {
+			t.Fatalf("%q: expected a quick fix for %q", tt.input, err.Message)
+		}
+		if err.Fix.Title != tt.title {
+			t.Errorf("%q: expected fix %q, got=%q", tt.input, tt.title, err.Fix.Title)
+		}
+
+		fixed, ok := err.Fix.Apply(tt.input)
+		if len(tt.fixed) == 0 {
+			if ok {
+				t.Errorf("%q: expected a hint only fix, got an edit", tt.input)
+			}
+			continue
+		}
+		if fixed != tt.fixed {
+			t.Errorf("%q: expected fixed source %q, got=%q", tt.input, tt.fixed, fixed)
+		}
+	}
+
+	// the names too far from the one written get no suggestion, print is 2 edits away from int
+	for _, input := range []string{"print(1)", "x := 1\nxyz + 1"} {
+		l := lexer.NewLexer("", input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		err, ok := eval.(*object.Error)
+		if !ok {
+			t.Fatalf("%q: expected an error, got=%v", input, eval)
+		}
+		if err.Fix != nil {
+			t.Errorf("%q: expected no suggestion, got=%q", input, err.Fix.Title)
+		}
+	}
+
+	l := lexer.NewLexer("", "n := zzqqxx + 1")
+	program := parser.NewParser(l.Tokenize(), "").Parse()
+	err, ok := interpreter.NewInterpreter(nil, "").Eval(program).(*object.Error)
+	if !ok {
+		t.Fatalf("expected an error")
+	}
+	if err.Fix != nil {
+		t.Errorf("expected no fix for a name unlike any other, got=%q", err.Fix.Title)
+	}
+}
+
+func TestRecursiveStructs(t *testing.T) {
+	node := "Node :: struct {\n\tvalue := 0,\n\tlink := Node\n}\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a := Node{value: 1}\nb := Node{value: 2, link: a}\nb.link.value", "1"},
+		{"a := Node{value: 1}\na.link", "nul"},
+		{"a := Node{value: 1}\na.link = Node{value: 3}\na.link.value", "3"},
+		{"a := Node{value: 1}\na.link = a\na.link.link.value", "1"},
+		{"Other :: struct { x := 0 }\nNode{link: Other{}}", "ERROR: type mismatch on link, definition type Node, got Other"},
+		{"a := Node{}\na.link = 3", "ERROR: type mismatch on link, definition type Node, got INTEGER"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", node+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil {
+			t.Fatalf("evaluation of %q is null", tt.input)
+		}
+		actual := eval.Inspect()
+		if err, ok := eval.(*object.Error); ok {
+			actual = err.Message
+		}
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	// printing a cycle stops at the instance it started from
+	cycle := &object.StructInstance{Fields: map[string]object.Object{}}
+	cycle.Fields["link"] = object.ItemObject{Object: cycle, IsMutable: true}
+	if actual := cycle.Inspect(); actual != "struct {link := struct {...}, }" {
+		t.Errorf("unexpected inspect of a cycle: %q", actual)
+	}
+	copied := cycle.Copy().(*object.StructInstance)
+	if link, _ := object.Cast(copied.Fields["link"]); link != copied {
+		t.Errorf("expected the copy to keep the cycle, got=%v", link)
+	}
+}
+
+func TestStableInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"User :: struct {\n\tname := \"\",\n\tage := 0,\n\temail := \"\",\n\tid := 0\n}\nUser{id: 7, name: \"lofi\"}",
+			"struct {name := lofi, age := 0, email := , id := 7, }",
+		},
+		{"{\"b\": 1, \"a\": 2, \"c\": 3}", "{a: 2, b: 1, c: 3}"},
+		{"{10: \"x\", 9: \"y\", 100: \"z\"}", "{9: y, 10: x, 100: z}"},
+		{"Pair :: struct {\n\tz := 0,\n\ta := 0\n}\n[Pair{}, Pair{a: 1}]", "[struct {z := 0, a := 0, }, struct {z := 0, a := 1, }]"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		// the go maps under the values range in a new order every time
+		for range 20 {
+			eval := interpreter.NewInterpreter(nil, "").Eval(program)
+			if eval == nil || eval.Inspect() != tt.expected {
+				t.Fatalf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+			}
+		}
+	}
+
+	// the records of the stdlib have no declaration order, their fields are sorted
+	record := &object.StructInstance{Fields: map[string]object.Object{
+		"size": &object.Integer{Value: 3},
+		"name": &object.String{Value: "a"},
+		"dir":  object.FALSE,
+	}}
+	if actual := record.Inspect(); actual != "struct {dir := false, name := a, size := 3, }" {
+		t.Errorf("unexpected inspect of a record: %q", actual)
+	}
+}
+
+func TestStructIteration(t *testing.T) {
+	setup := "User :: struct {\n\tname := \"\",\n\tage := 0,\n\temail := \"\",\n\tgreet : fn(self) { \"hi \" + self.name }\n}\n"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"names := \"\"\nu := User{age: 3}\nfor name, _ in u {\nnames = names + name + \" \"\n}\nnames", "name age email "},
+		{"out := \"\"\nu := User{name: \"lofi\", age: 22}\nfor name, value in u {\nout = out + name + \"=\" + string(value) + \";\"\n}\nout", "name=lofi;age=22;email=;"},
+		{"count := 0\nu := User{}\nfor _, _ in u {\ncount = count + 1\n}\ncount", "3"},
+		// to_map takes the fields, the methods are left out and the instance keeps its values
+		{"Pair :: struct {\nfirst := \"\",\nlast := \"\",\nswap : fn(self) { self.last }\n}\nm := to_map(Pair{first: \"lo\", last: \"fi\"})\ncount := 0\nfor _, _ in m {\ncount = count + 1\n}\nm[\"first\"] + m[\"last\"] + string(count)", "lofi2"},
+		{"Pair :: struct {\nfirst := 0,\nlast := 0\n}\np := Pair{first: 22}\nm := to_map(p)\nm[\"first\"] = 30\nstring(p.first)", "22"},
+		// the map values share a type, the fields of User don't
+		{"to_map(User{name: \"lofi\", age: 22})", "ERROR: the fields need to share a type to make a map, got string and int"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", setup+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		for range 20 {
+			eval := interpreter.NewInterpreter(nil, "").Eval(program)
+			if eval == nil {
+				t.Fatalf("evaluation of %q is null", tt.input)
+			}
+			actual := eval.Inspect()
+			if err, ok := eval.(*object.Error); ok {
+				actual = err.Message
+			}
+			if actual != tt.expected {
+				t.Fatalf("%q: expected=%q, got=%q", tt.input, tt.expected, actual)
+			}
+		}
+	}
+}
+
+func TestLoopClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"fns := [fn() { 0 }]\nfor idx, val in [10, 20, 30] {\nf :: fn() { val + idx }\nfns = fns + [f]\n}\nout := \"\"\nfor _, f in fns {\nout = out + string(f()) + \" \"\n}\nout",
+			"0 10 21 32 ",
+		},
+		{
+			"fns := [fn() { 0 }]\nfor k in 1..4 {\ng :: fn() { k }\nfns = fns + [g]\n}\nsum := 0\nfor _, g in fns {\nsum = sum + g()\n}\nsum",
+			"6",
+		},
+		// assignments in blocks change the variable declared outside of them
+		{"nums := [1]\nfor _, x in [2, 3] {\nnums = nums + [x]\n}\nnums", "[1, 2, 3]"},
+		{"m := nul\nif true {\nm = 4\n}\nm", "4"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || eval.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestEnumMembers(t *testing.T) {
+	setup := "Status :: enum {\n\tOk,\n\tNotFound = 404,\n\tGone\n}\n"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Status.Ok", "0"},
+		{"Status.NotFound", "404"},
+		{"Status.Gone", "405"},
+		{"Status.Gone == Status.NotFound + 1", "true"},
+		{"Status", "enum {Ok = 0, NotFound = 404, Gone = 405, }"},
+		{"Status.Missing", ":6:1: ERROR: Missing isn't a member of the enum Status"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", setup+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || eval.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestImportBindings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"import \"math\" as m\nf :: fn() {\nimport \"fmt\" as m\n}\nf()", "can't import \"fmt\" as m, m is already the import of \"math\""},
+		{"m := 1\nimport \"math\" as m", "can't import \"math\" as m, m is already declared"},
+		{"import \"math\" as m\nf :: fn() {\nimport \"math\" as m\n1\n}\nf()", "1"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestImportModule(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	files := map[string]string{
+		"greet.blk": "greet :: fn(name) { \"hi \" + name }\n_secret := 1\n",
+		"bad.blk":   "x := (1 +\n",
+		"boom.blk":  "x := 1\ny := x + [1]\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let Ok(m) = import_module(\"greet.blk\") else { return }\nm.greet(\"bob\")", "hi bob"},
+		// the same file twice isn't a cycle
+		{"a := import_module(\"greet.blk\")\nb := import_module(\"greet.blk\")\nis_ok(b)", "true"},
+		{"let Ok(m) = import_module(\"greet.blk\") else { return }\nm._secret", "doesn't exist"},
+		{"let Err(msg) = import_module(\"missing.blk\") else { return }\nmsg", "no such file or directory"},
+		{"let Err(msg) = import_module(\"bad.blk\") else { return }\nmsg", "bad.blk:2:1: "},
+		{"let Err(msg) = import_module(\"boom.blk\") else { return }\nmsg", "boom.blk:2:8: "},
+		{"import_module(1)", "import_module takes the path of a module, got int"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+
+	// the modules out of the fs grants aren't loaded
+	permissions, err := internals.NewPermissions(t.TempDir(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(granted *internals.Permissions) { stdlib.Permissions = granted }(stdlib.Permissions)
+	stdlib.Permissions = permissions
+
+	input := "let Err(msg) = import_module(\"greet.blk\") else { return \"loaded\" }\nmsg"
+	program := parser.NewParser(lexer.NewLexer("", input).Tokenize(), "").Parse()
+	eval := interpreter.NewInterpreter(nil, "").Eval(program)
+	if eval == nil || !strings.Contains(eval.Inspect(), "greet.blk denied") {
+		t.Errorf("expected greet.blk to be denied, got=%v", eval)
+	}
+}
+
+func TestSharedModules(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var out bytes.Buffer
+	stdlib.Stdout = &out
+	defer func() { stdlib.Stdout = os.Stdout }()
+
+	files := map[string]string{
+		"utils.blk": "import \"fmt\"\nfmt.println(\"loading utils\")\nitems := [1]\n",
+		"a.blk":     "import \"./utils.blk\" as u\nx := u.items\nx[0] = 5\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// both imports get the one evaluation of utils.blk, the change made through a is seen by main
+	input := "import \"./utils.blk\" as utils\nimport \"./a.blk\" as a\nutils.items"
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	eval := interpreter.NewInterpreter(nil, "").Eval(program)
+	if eval == nil || eval.Inspect() != "[5]" {
+		t.Errorf("expected the items changed by a.blk, got=%v", eval)
+	}
+	if out.String() != "loading utils\n" {
+		t.Errorf("expected utils.blk to be evaluated once, got=%q", out.String())
+	}
+}
+
+func TestDiamondImports(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var out bytes.Buffer
+	stdlib.Stdout = &out
+	defer func() { stdlib.Stdout = os.Stdout }()
+
+	for _, dir := range []string{"lib", "a", "b"} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// b.blk and c.blk both import lib/d.blk, spelled differently
+	files := map[string]string{
+		"lib/d.blk":   "import \"fmt\"\nfmt.println(\"loading d\")\nitems := [0, 0]\n",
+		"b.blk":       "import \"./lib/d.blk\" as d\nitems := d.items\nitems[0] = 1\n",
+		"c.blk":       "import \"lib/../lib/d.blk\" as shared\nitems := shared.items\nitems[1] = 2\n",
+		"a/utils.blk": "name := \"a\"\n",
+		"b/utils.blk": "name := \"b\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+		loads    int
+	}{
+		{"import \"./b.blk\" as b\nimport \"./c.blk\" as c\nimport \"./lib/d.blk\" as d\nd.items", "[1, 2]", 1},
+		// the same file under two aliases, and twice under the same one
+		{"import \"./lib/d.blk\" as x\nimport \"lib/d.blk\" as y\nitems := y.items\nitems[0] = 3\nx.items", "[3, 0]", 1},
+		{"import \"./lib/d.blk\" as d\nf :: fn() {\nimport \"lib/d.blk\" as d\nd.items\n}\nf()", "[0, 0]", 1},
+		// two files with the same name are two modules
+		{"import \"a/utils.blk\" as u\nf :: fn() {\nimport \"b/utils.blk\" as u\n}\nf()", "can't import \"b/utils.blk\" as u, u is already the import of \"a/utils.blk\"", 0},
+		{"import \"a/utils.blk\" as u\nimport \"b/utils.blk\" as v\nu.name + v.name", "ab", 0},
+	}
+	for _, tt := range tests {
+		out.Reset()
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+		if loads := strings.Count(out.String(), "loading d"); loads != tt.loads {
+			t.Errorf("%q: expected lib/d.blk to be evaluated %d times, got %d", tt.input, tt.loads, loads)
+		}
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"# blk:strict\n1 + 2.5", "INTEGER + FLOAT mixes an int and a float"},
+		{"# blk:strict\nx := 2.0\nx * 3", "FLOAT * INTEGER mixes an int and a float"},
+		{"# blk:strict\n\"n = \" + 1", "STRING + INTEGER concatenates a value that isn't a string"},
+		{"# blk:strict\nx := 1\nif true {\nx := 2\n}", "x shadows a name of an outer scope"},
+		{"# blk:strict\nn := 1\n_f :: fn(n) { n }\n_f(2)", "n shadows a name of an outer scope"},
+		{"# blk:strict\n1.0 + 2.5", "3.5"},
+		{"# blk:strict\n\"a\" + \"b\"", "ab"},
+		// without the pragma the values get converted
+		{"1 + 2.5", "3.5"},
+		{"\"n = \" + 1", "n = 1"},
+		{"x := 1\nif true {\nx := 2\nx\n}", "2"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+
+	// --strict does what the pragma does
+	l := lexer.NewLexer("", "1 + 2.5")
+	i := interpreter.NewInterpreter(nil, "")
+	i.EnableStrict()
+	if eval := i.Eval(parser.NewParser(l.Tokenize(), "").Parse()); eval == nil || !strings.Contains(eval.Inspect(), "mixes an int and a float") {
+		t.Errorf("expected EnableStrict to refuse the mix, got=%v", eval)
+	}
+}
+
+func TestTypeBoundaries(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn add(a: int, b: int): int { a + b }\nx := \"2\"\nadd(1, x)", "arg b of add needs to be int, got string"},
+		{"fn half(a: int): int { a / 2.0 }\nhalf(3)", "half needs to return int, got float"},
+		{"fn none(): void { 1 }\nnone()", "none needs to return void, got int"},
+		{"fn none(): void { }\nnone()\n1", "1"},
+		{"fn show(v: any): any { v }\nshow([1, 2])", "[1, 2]"},
+		{"Vec2 :: struct { x := 1.0 }\nfn len(v: Vec2): float { v.x }\nlen(Vec2{x: 2.0})", "2"},
+		{"Vec2 :: struct { x := 1.0 }\nfn len(v: Vec2): float { v.x }\nm := {\"x\": 2.0}\nlen(m)", "arg v of len needs to be Vec2, got map"},
+		{"Status :: enum { Ok, Gone }\nfn code(s: Status): int { s }\ncode(Status.Gone)", "1"},
+		{"Status :: enum { Ok, Gone }\nfn code(s: Status): int { s }\nv := 7\ncode(v)", "arg s of code needs to be Status, got int"},
+		{"fn apply(f: fn, v: int): int { f(v) }\napply(fn(x) { x * 2 }, 4)", "8"},
+		{"fn early(a: int): string { if a > 0 { return \"pos\" }\n\"neg\" }\nearly(1)", "pos"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestTypeAssertions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3 as float", "3.000000"},
+		{"4.0 as int", "4"},
+		{"x := 2.5\nx as int", "2.500000 doesn't fit an int without losing its fraction"},
+		{"x := \"a\"\nx as int", "can't cast string to int"},
+		{"x := [1]\nx as any", "[1]"},
+		{"Vec2 :: struct { x := 0.0 }\nv := Vec2{}\nv is Vec2", "true"},
+		{"Vec2 :: struct { x := 0.0 }\nv := {\"x\": 0.0}\nv is Vec2", "false"},
+		{"Status :: enum { Ok, Gone }\nStatus.Gone is Status", "true"},
+		{"x := nul\nx is nul", "true"},
+		{"fmt :: 1\nx := 1\nx is fmt", "fmt isn't a type"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestMatchType(t *testing.T) {
+	kind := `# blk:feature match
+Vec2 :: struct { x := 0.0 }
+kind :: fn(v) {
+	match type v {
+		int => { "int" },
+		string => { "string" },
+		Vec2 => { "vec" },
+		fn => { "fn" },
+		_ => { "other" }
+	}
+}
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: `kind(1)`, expected: "int"},
+		{input: `kind("a")`, expected: "string"},
+		{input: `kind(Vec2{})`, expected: "vec"},
+		{input: `kind(kind)`, expected: "fn"},
+		{input: `kind(2.5)`, expected: "other"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", kind+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || eval.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestResults(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"unwrap(ok(1))", "1"},
+		{"unwrap(err(\"bad\"))", "unwrap of an err result: bad"},
+		{"unwrap_or(err(\"bad\"), 2)", "2"},
+		{"unwrap_or(ok(1), 2)", "1"},
+		{"[is_ok(ok(1)), is_err(ok(1)), is_err(err(\"x\"))]", "[true, false, true]"},
+		{"is_err(1)", "is_err takes a result made by ok or err, got int"},
+		{"err(1)", "the message of err needs to be a string"},
+		{"ok(1) is Result", "true"},
+		{"half :: fn(n) { if n % 2 == 1 { return err(\"odd\") }\nok(n / 2) }\nquarter :: fn(n) { ok(half(half(n)!)!) }\n[unwrap(quarter(8)), unwrap_or(quarter(6), 0)]", "[2, 0]"},
+		{"f :: fn(n: int): Result { err(\"no\") }\ng :: fn(): int { f(1)!\n1 }\nis_err(g())", "g needs to return int, got Result"},
+		{"f :: fn() { 1! }\nf()", "! takes a result made by ok or err, got int"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestLetElse(t *testing.T) {
+	half := `half :: fn(n) {
+	if n % 2 == 1 { return err("odd") }
+	ok(n / 2)
+}
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f :: fn(n) {\nlet Ok(h) = half(n) else { return -1 }\nh\n}\n[f(8), f(3)]", "[4, -1]"},
+		{"f :: fn(n) {\nlet Err(msg) = half(n) else { return \"even\" }\nmsg\n}\n[f(8), f(3)]", "[even, odd]"},
+		{"total := 0\nfor idx, n in [2, 3, 4] {\nlet Ok(h) = half(n) else { next }\ntotal += h\n}\ntotal", "3"},
+		{"f :: fn() {\nlet Ok(h) = 1 + 1 else { return 0 }\n}\nf()", "let Ok(...) takes a result made by ok or err, got int"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", half+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
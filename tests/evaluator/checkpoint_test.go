@@ -0,0 +1,155 @@
+package evaluator_tests
+
+import (
+	"blk/checkpoint"
+	"blk/internals"
+	"blk/interpreter"
+	"blk/lexer"
+	"blk/object"
+	"blk/parser"
+	"blk/stdlib"
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// runs the program of the file, resumed from the checkpoint when there is one
+func runCheckpointed(t *testing.T, path string, cp *checkpoint.Checkpoint) (string, object.Object) {
+	t.Helper()
+	var out bytes.Buffer
+	stdlib.Stdout = &out
+	t.Cleanup(func() { stdlib.Stdout = os.Stdout })
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parser.NewParser(lexer.NewLexer(path, string(content)).Tokenize(), path)
+	program := p.Parse()
+	if len(p.Errors) > 0 {
+		t.Fatalf("parse errors: %v", p.Errors)
+	}
+	i := interpreter.NewInterpreter(nil, path)
+	var evaluated object.Object
+	if cp != nil {
+		evaluated = i.Resume(program, cp)
+	} else {
+		evaluated = i.Eval(program)
+	}
+	return out.String(), evaluated
+}
+
+func TestCheckpointResume(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	permissions, err := internals.NewPermissions(dir, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(granted *internals.Permissions) { stdlib.Permissions = granted }(stdlib.Permissions)
+	stdlib.Permissions = permissions
+
+	// the run stops at the fourth item while the stop file is there
+	program := `import "fmt"
+import "fs"
+import "runtime"
+
+Node :: struct {
+    value := 0,
+    link := Node
+}
+double :: fn(n) { n * 2 }
+
+done := 0
+results := []
+head := Node{value: 7}
+head.link = head
+shared := [1]
+pair := {"a": shared, "b": shared}
+found := ok('x')
+
+for idx, item in [1, 2, 3, 4, 5] {
+    if idx < done {
+        next
+    }
+    runtime.checkpoint("job.ckpt")
+    if item == 4 && is_ok(fs.read("stop")) {
+        stop_here()
+    }
+    results = results + [double(item)]
+    done = idx + 1
+    fmt.println("processed", item)
+}
+pair["a"][0] = 2
+fmt.println(results, head.link.link.value, pair["b"], unwrap(found))
+`
+	path := filepath.Join(dir, "job.blk")
+	os.WriteFile(path, []byte(program), 0644)
+	os.WriteFile("stop", []byte{}, 0644)
+
+	out, evaluated := runCheckpointed(t, path, nil)
+	if err, ok := evaluated.(*object.Error); !ok || !strings.Contains(err.Message, "stop_here") {
+		t.Fatalf("expected the run to stop at stop_here, got %v", evaluated)
+	}
+	if !strings.HasSuffix(out, "processed 3\n") {
+		t.Fatalf("expected 3 items processed, got %q", out)
+	}
+
+	os.Remove("stop")
+	cp, err := checkpoint.ReadFile("job.ckpt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cp.Functions, []string{"double"}) {
+		t.Errorf("expected the functions to be [double], got %v", cp.Functions)
+	}
+	out, evaluated = runCheckpointed(t, path, cp)
+	if err, ok := evaluated.(*object.Error); ok {
+		t.Fatalf("expected the resumed run to finish, got %s", err.Inspect())
+	}
+	// the shared array and the cycle come back as they were
+	expected := "processed 4\nprocessed 5\n[2, 4, 6, 8, 10] 7 [2] x\n"
+	if out != expected {
+		t.Errorf("expected=%q, got=%q", expected, out)
+	}
+}
+
+func TestCheckpointErrors(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	permissions, _ := internals.NewPermissions(dir, "", "")
+	defer func(granted *internals.Permissions) { stdlib.Permissions = granted }(stdlib.Permissions)
+	stdlib.Permissions = permissions
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"handlers := [fn() { 1 }]\nruntime.checkpoint(\"x.ckpt\")", "runtime.checkpoint: can't save handlers[0], a function can't be kept, only the data can"},
+		{"runtime.checkpoint(\"/elsewhere/x.ckpt\")", "PermissionError"},
+	}
+	for idx, tt := range tests {
+		path := filepath.Join(dir, "prog"+string(rune('a'+idx))+".blk")
+		os.WriteFile(path, []byte("import \"runtime\"\n"+tt.input), 0644)
+		_, evaluated := runCheckpointed(t, path, nil)
+		if evaluated == nil || !strings.Contains(evaluated.Inspect(), tt.expected) {
+			t.Errorf("%q: expected an error containing %q, got=%v", tt.input, tt.expected, evaluated)
+		}
+	}
+
+	// a global holding a function made while running isn't declared again
+	path := filepath.Join(dir, "made.blk")
+	os.WriteFile(path, []byte("import \"runtime\"\nmake :: fn() { fn() { 1 } }\nf :: make()\nruntime.checkpoint(\"made.ckpt\")\nf()"), 0644)
+	runCheckpointed(t, path, nil)
+	cp, err := checkpoint.ReadFile("made.ckpt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, evaluated := runCheckpointed(t, path, cp)
+	if evaluated == nil || !strings.Contains(evaluated.Inspect(), "f held a function made while the program ran") {
+		t.Errorf("expected the resume to refuse f, got=%v", evaluated)
+	}
+}
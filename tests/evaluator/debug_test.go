@@ -0,0 +1,55 @@
+package evaluator_tests
+
+import (
+	"blk/internals"
+	"blk/interpreter"
+	"blk/lexer"
+	"blk/parser"
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDebugLogs(t *testing.T) {
+	var out bytes.Buffer
+	internals.DebugOutput = &out
+	t.Cleanup(func() {
+		internals.DebugOutput = os.Stderr
+		internals.SetDebug("")
+	})
+	if err := internals.SetDebug("modules, eval:trace"); err != nil {
+		t.Fatal(err)
+	}
+
+	input := "import \"math\"\nimport \"math\" as m\nadd :: fn(a, b) { a + b }\nadd(1, [2])"
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	interpreter.NewInterpreter(nil, "main.blk").Eval(program)
+
+	logs := out.String()
+	for _, expected := range []string{
+		`level=DEBUG msg="builtin module" area=modules import=math name=math`,
+		`msg="builtin module" area=modules import=math name=m`,
+		`level=TRACE msg=statement area=eval file=main.blk row=3 col=1 kind=VarDeclaration`,
+		`level=DEBUG msg=call area=eval fn=add args=2`,
+		`msg="error raised" area=eval file=main.blk row=3 col=21`,
+	} {
+		if !strings.Contains(logs, expected) {
+			t.Errorf("expected the logs to have %q, got:\n%s", expected, logs)
+		}
+	}
+	if strings.Contains(logs, "area=parser") {
+		t.Errorf("expected no logs of the parser, got:\n%s", logs)
+	}
+
+	for list, expected := range map[string]string{
+		"lexer":       "unknown debug area lexer, expected all or one of (modules, parser, eval)",
+		"eval:chatty": "unknown debug level chatty, expected trace or debug",
+	} {
+		if err := internals.SetDebug(list); err == nil || err.Error() != expected {
+			t.Errorf("%q: expected=%q, got=%v", list, expected, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package evaluator_tests
+
+import (
+	"blk/interpreter"
+	"blk/lexer"
+	"blk/object"
+	"blk/parser"
+	"fmt"
+	"testing"
+)
+
+func TestShadowedGlobals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x := 1\nread :: fn() { x }\nshadow :: fn() {\nx := 5\nreturn read() + x\n}\nshadow()", "6"},
+		{"x := 1\nouter :: fn() {\nx := 2\ninner :: fn() { x }\nreturn inner()\n}\nouter() + x", "3"},
+		{"n := 1\nfor n in 0..3 {\nn\n}\nn", "1"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || eval.Inspect() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+
+	// a name an inner scope defines after the global got looked up from it
+	globals := object.NewEnvironment(nil)
+	globals.Define("name", object.ItemObject{Object: &object.String{Value: "global"}})
+	block := object.NewEnvironment(globals)
+	nested := object.NewEnvironment(block)
+	if item, _ := nested.Resolve("name"); item.Inspect() != "global" {
+		t.Fatalf("expected the global, got=%v", item)
+	}
+	block.Define("name", object.ItemObject{Object: &object.String{Value: "block"}})
+	if item, _ := nested.Resolve("name"); item.Inspect() != "block" {
+		t.Errorf("expected the block one, got=%v", item)
+	}
+}
+
+// a global read from a scope nested depth times
+func BenchmarkGlobalLookup(b *testing.B) {
+	for _, depth := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			globals := object.NewEnvironment(nil)
+			globals.Define("helper", object.ItemObject{Object: &object.Integer{Value: 1}})
+			env := globals
+			for idx := range depth {
+				env = object.NewEnvironment(env)
+				env.Define(fmt.Sprintf("local%d", idx), object.ItemObject{Object: &object.Integer{Value: 2}})
+			}
+			// the interpreter resolves the symbol the identifier got interned to
+			helper := lexer.Intern("helper")
+			b.ResetTimer()
+			for range b.N {
+				if _, ok := env.ResolveSymbol(helper); !ok {
+					b.Fatal("expected helper to resolve")
+				}
+			}
+		})
+	}
+}
+
+// helpers called from nested blocks of a loop, the usual shape of a script
+func BenchmarkGlobalCalls(b *testing.B) {
+	input := `
+inc :: fn(n) { n + 1 }
+double :: fn(n) { n * 2 }
+total := 0
+for k in 0..200 {
+    if k > 0 {
+        if k != 7 {
+            total = total + double(inc(k))
+        }
+    }
+}
+total
+`
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	b.ResetTimer()
+	for range b.N {
+		if eval := interpreter.NewInterpreter(nil, "").Eval(program); eval == nil || eval.Inspect() != "40182" {
+			b.Fatalf("unexpected result %v", eval)
+		}
+	}
+}
+
+// vector math, a method call per operation
+func BenchmarkMethodCalls(b *testing.B) {
+	input := `
+Vec2 :: struct {
+    x := 0,
+    y := 0,
+    add: fn(self, o) { Vec2{x: self.x + o.x, y: self.y + o.y} },
+    dot: fn(self, o) { self.x * o.x + self.y * o.y }
+}
+acc := Vec2{}
+step := Vec2{x: 1, y: 2}
+total := 0
+for k in 0..200 {
+    acc = acc.add(step)
+    total = total + acc.dot(step)
+}
+total
+`
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	b.ResetTimer()
+	for range b.N {
+		if eval := interpreter.NewInterpreter(nil, "").Eval(program); eval == nil || eval.Inspect() != "100500" {
+			b.Fatalf("unexpected result %v", eval)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package evaluator_tests
+
+import (
+	"blk/examples"
+	"blk/internals"
+	"blk/interpreter"
+	"blk/lexer"
+	"blk/object"
+	"blk/parser"
+	"blk/stdlib"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// every program of the gallery runs and prints what examples/gallery/<name>.out holds
+func TestExamplesGallery(t *testing.T) {
+	// the page http_fetch downloads, served locally in place of example.com
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><head><title>Example Domain</title></head><body>This domain is for use in examples.</body></html>"))
+	}))
+	defer server.Close()
+
+	outputs, _ := filepath.Abs("../../examples/gallery")
+	t.Chdir(t.TempDir())
+
+	permissions, err := internals.NewPermissions(".", strings.TrimPrefix(server.URL, "http://"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(granted *internals.Permissions) { stdlib.Permissions = granted }(stdlib.Permissions)
+	stdlib.Permissions = permissions
+
+	var out bytes.Buffer
+	stdlib.Stdout = &out
+	t.Cleanup(func() { stdlib.Stdout = os.Stdout })
+
+	names := examples.Names()
+	if len(names) < 4 {
+		t.Fatalf("expected the gallery to hold at least 4 programs, got %v", names)
+	}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			out.Reset()
+			source, _ := examples.Source(name)
+			input := strings.ReplaceAll(string(source), "https://example.com/", server.URL+"/")
+
+			l := lexer.NewLexer(name+".blk", input)
+			p := parser.NewParser(l.Tokenize(), name+".blk")
+			program := p.Parse()
+			if len(p.Errors) > 0 {
+				t.Fatalf("parse errors: %v", p.Errors)
+			}
+			i := interpreter.NewInterpreter(nil, name+".blk")
+			if err, ok := i.Eval(program).(*object.Error); ok {
+				t.Fatalf("expected the example to run, got %s", err.Inspect())
+			}
+			if len(i.Warnings) > 0 {
+				t.Errorf("expected no warnings, got %v", i.Warnings)
+			}
+
+			expected, err := os.ReadFile(filepath.Join(outputs, name+".out"))
+			if err != nil {
+				t.Fatalf("expected the output of the example next to it: %v", err)
+			}
+			if out.String() != string(expected) {
+				t.Errorf("expected the output:\n%s\ngot:\n%s", expected, out.String())
+			}
+		})
+	}
+}
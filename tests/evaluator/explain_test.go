@@ -0,0 +1,74 @@
+package evaluator_tests
+
+import (
+	"blk/explain"
+	"blk/interpreter"
+	"blk/lexer"
+	"blk/parser"
+	"blk/stdlib"
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func runExplained(t *testing.T, input string, limit int) string {
+	t.Helper()
+	var out bytes.Buffer
+	stdlib.Stdout = &out
+	t.Cleanup(func() { stdlib.Stdout = os.Stdout })
+
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	if len(p.Errors) > 0 {
+		t.Fatalf("parse errors: %v", p.Errors)
+	}
+	evaluator := interpreter.NewInterpreter(nil, "main.blk")
+	evaluator.SetHooks(explain.New(&out, limit).Hooks())
+	evaluator.Eval(program)
+	return out.String()
+}
+
+func TestExplain(t *testing.T) {
+	input := `import "fmt"
+add :: fn(a, b) { a + b }
+total := add(1, 2) * 3
+if total > 5 {
+    total = total - 1
+}
+fmt.println("total", total)
+`
+	// the program prints between the steps, in the order it runs
+	expected := `1 | import "fmt"
+2 | add :: fn(a, b){ (a + b) }
+3 | total := (add(1, 2) * 3)
+    call add(1, 2)
+      2 | (a + b)
+          a → 1
+          b → 2
+        (a + b) → 3
+    add returned 3
+  (add(1, 2) * 3) → 9
+  total := 9
+4 | if (total > 5) { total = (total - 1) }
+    total → 9
+  (total > 5) → true
+  5 | total = (total - 1)
+      total → 9
+    (total - 1) → 8
+    total = 8
+7 | fmt.println("total", total)
+  total → 8
+  call fmt.println("total", 8)
+total 8
+`
+	if out := runExplained(t, input, explain.MaxSteps); out != expected {
+		t.Errorf("expected=\n%s\ngot=\n%s", expected, out)
+	}
+
+	out := runExplained(t, "n := 0\nwhile n < 100 {\nn = n + 1\n}", 10)
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 11 || lines[10] != "... the run goes on, only the first 10 steps are explained" {
+		t.Errorf("expected the explanation to stop after 10 steps, got=\n%s", out)
+	}
+}
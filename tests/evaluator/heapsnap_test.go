@@ -0,0 +1,95 @@
+package evaluator_tests
+
+import (
+	"blk/heapsnap"
+	"blk/interpreter"
+	"blk/lexer"
+	"blk/object"
+	"blk/parser"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHeapSnapshot(t *testing.T) {
+	path := filepath.ToSlash(filepath.Join(t.TempDir(), "heap.json"))
+	input := `
+import "runtime"
+Node :: struct {
+    value := 0,
+    link := Node
+}
+make_counter :: fn() {
+    state := {"count": 0}
+    bump :: fn() {
+        state["count"] = state["count"] + 1
+        return state["count"]
+    }
+    return bump
+}
+counter :: make_counter()
+head := Node{value: 1, link: Node{value: 2}}
+head.link.link = head
+runtime.heap_snapshot("` + path + `")
+names := ["a", "b"]
+`
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	env := object.NewEnvironment(nil)
+	evaluator := interpreter.NewInterpreter(env, "main.blk")
+	if err, ok := evaluator.Eval(program).(*object.Error); ok {
+		t.Fatal(err.Message)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mid heapsnap.Graph
+	if err := json.Unmarshal(content, &mid); err != nil {
+		t.Fatal(err)
+	}
+	// names wasn't declared yet when the program took the snapshot
+	if mid.Totals["ARRAY"].Count != 0 || mid.Totals["STRUCT_INSTANCE"].Count != 2 || mid.Totals["scope"].Count != 3 {
+		t.Errorf("unexpected totals of the snapshot %v", mid.Totals)
+	}
+
+	g := heapsnap.Snapshot(env)
+	edges := map[string]bool{}
+	for _, edge := range g.Edges {
+		edges[g.Nodes[edge.From].Kind+" -"+edge.Label+"-> "+g.Nodes[edge.To].Kind] = true
+	}
+	for _, edge := range []string{
+		"scope -names-> ARRAY",
+		"ARRAY -[1]-> STRING",
+		"FUNCTION -env-> scope",
+		"scope -state-> MAP",
+		"MAP -[count]-> INTEGER",
+		"STRUCT_INSTANCE -.link-> STRUCT_INSTANCE",
+		"STRUCT_INSTANCE -struct-> STRUCT",
+	} {
+		if !edges[edge] {
+			t.Errorf("expected the edge %q, got=%v", edge, edges)
+		}
+	}
+	if g.Totals["STRUCT_INSTANCE"].Count != 2 {
+		t.Errorf("expected the cycle to be walked once, got=%v", g.Totals)
+	}
+	for _, node := range g.Nodes {
+		if node.Size <= 0 && node.Kind != "NUL" {
+			t.Errorf("expected a size for %+v", node)
+		}
+	}
+
+	var dot bytes.Buffer
+	if err := g.WriteDOT(&dot); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dot.String(), "digraph heap {") || !strings.Contains(dot.String(), `[label="names"]`) {
+		t.Errorf("unexpected dot output %q", dot.String())
+	}
+}
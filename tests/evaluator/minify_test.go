@@ -0,0 +1,92 @@
+package evaluator_tests
+
+import (
+	"blk/interpreter"
+	"blk/lexer"
+	"blk/minify"
+	"blk/parser"
+	"strings"
+	"testing"
+)
+
+func TestMinify(t *testing.T) {
+	tests := []string{
+		// locals, shadowing and closures
+		`# adds the squares
+total := 0
+fn squares(limit) {
+	sum := 0
+	for idx in 0..limit {
+		square := idx * idx
+		sum = sum + square
+	}
+	sum
+}
+make_adder :: fn(step) {
+	fn(value) { value + step }
+}
+add :: make_adder(3)
+total = squares(4) + add(1)
+total`,
+		// fields, keys and properties keep their names
+		`Point :: struct {
+	x := 0,
+	y := 0,
+	shift: fn(self, x) {
+		Point{x: self.x + x, y: self.y}
+	}
+}
+fn norm(p) {
+	x := p.x
+	y := if x > 2 ? x : 0
+	m := {"x": x}
+	m["x"] + y + p.shift(1).x
+}
+norm(Point{x: 3, y: 4})`,
+		// the line breaks still end the expressions
+		`fn f(a) {
+	b := a
+	-1
+}
+f(2)`,
+	}
+
+	for _, input := range tests {
+		minified, err := minify.Minify("", input)
+		if err != nil {
+			t.Fatalf("%q: %v", input, err)
+		}
+		if strings.Contains(minified, "#") || strings.Contains(minified, "\t") {
+			t.Errorf("expected the comments and the indentation to be dropped, got=\n%s", minified)
+		}
+
+		expected := evalSource(t, input)
+		if actual := evalSource(t, minified); actual != expected {
+			t.Errorf("%q: expected=%q, got=%q from\n%s", input, expected, actual, minified)
+		}
+	}
+
+	minified, _ := minify.Minify("", "fn area(width, height) {\n\twidth * height\n}\narea(2, 3)")
+	if expected := "fn area(a,b){\na*b\n}\narea(2,3)\n"; minified != expected {
+		t.Errorf("expected=%q, got=%q", expected, minified)
+	}
+
+	if _, err := minify.Minify("", "x := ("); err == nil {
+		t.Errorf("expected the programs with errors to be refused")
+	}
+}
+
+func evalSource(t *testing.T, input string) string {
+	t.Helper()
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	if len(p.Errors) > 0 {
+		t.Fatalf("parse errors for %q: %v", input, p.Errors)
+	}
+	eval := interpreter.NewInterpreter(nil, "").Eval(program)
+	if eval == nil {
+		return ""
+	}
+	return eval.Inspect()
+}
@@ -0,0 +1,62 @@
+package evaluator_tests
+
+import (
+	"blk/interpreter"
+	"blk/lexer"
+	"blk/parser"
+	"blk/profiler"
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProfilerFoldedStacks(t *testing.T) {
+	input := `
+import "strings"
+inner :: fn() { strings.count("banana", "a") }
+outer :: fn() { inner() }
+outer()
+inner()
+`
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	evaluator := interpreter.NewInterpreter(nil, "main.blk")
+
+	prof := profiler.New("main.blk")
+	evaluator.SetHooks(prof.Hooks())
+	evaluator.Eval(program)
+	prof.Stop()
+
+	var out bytes.Buffer
+	if err := prof.WriteFolded(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	stacks := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		idx := strings.LastIndex(line, " ")
+		if weight, err := strconv.Atoi(line[idx+1:]); err != nil || weight < 1 {
+			t.Errorf("expected a positive weight on %q", line)
+		}
+		stacks[line[:idx]] = true
+	}
+
+	expected := []string{
+		"main.blk",
+		"main.blk;outer (main.blk:5)",
+		"main.blk;outer (main.blk:5);inner (main.blk:4)",
+		"main.blk;outer (main.blk:5);inner (main.blk:4);strings.count (main.blk:3)",
+		"main.blk;inner (main.blk:6)",
+		"main.blk;inner (main.blk:6);strings.count (main.blk:3)",
+	}
+	for _, stack := range expected {
+		if !stacks[stack] {
+			t.Errorf("expected the stack %q, got=%q", stack, out.String())
+		}
+	}
+	if len(stacks) != len(expected) {
+		t.Errorf("expected %d stacks, got=%q", len(expected), out.String())
+	}
+}
@@ -0,0 +1,96 @@
+package evaluator_tests
+
+import (
+	"blk/internals"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRelease(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := []byte("the blk binary")
+	checksum := internals.Checksum(binary)
+	signature := hex.EncodeToString(ed25519.Sign(privateKey, internals.SignedMessage("0.2.0", "stable", "linux/amd64", checksum)))
+	// the genuine signature of an older release
+	oldSignature := hex.EncodeToString(ed25519.Sign(privateKey, internals.SignedMessage("0.1.0", "nightly", "linux/amd64", checksum)))
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stable.json":
+			fmt.Fprintf(w, `{"version": "0.2.0", "assets": {"linux/amd64": {"url": %q, "checksum": %q, "signature": %q}, "darwin/arm64": {"url": %q, "checksum": %q, "signature": %q}}}`,
+				server.URL+"/blk-linux-amd64", checksum, signature, server.URL+"/blk-linux-amd64", checksum, signature)
+		case "/nightly.json":
+			fmt.Fprintf(w, `{"version": "0.2.0", "assets": {"linux/amd64": {"url": %q, "checksum": %q, "signature": %q}}}`,
+				server.URL+"/blk-linux-amd64", checksum, oldSignature)
+		case "/blk-linux-amd64":
+			w.Write(binary)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	release, err := internals.FetchRelease(server.URL, "stable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Version != "0.2.0" {
+		t.Errorf("expected version 0.2.0, got=%s", release.Version)
+	}
+	if _, err := release.Asset("plan9/386"); err == nil || !strings.Contains(err.Error(), "no binary for plan9/386") {
+		t.Errorf("expected a missing platform error, got=%v", err)
+	}
+
+	asset, err := release.Asset("linux/amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloaded, err := asset.Download()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := hex.EncodeToString(publicKey)
+	if err := asset.Verify(downloaded, key); err != nil {
+		t.Errorf("expected the download to verify, got=%v", err)
+	}
+
+	// a tampered binary, or a genuine checksum signed by another key
+	if err := asset.Verify([]byte("tampered"), key); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected a checksum error, got=%v", err)
+	}
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	if err := asset.Verify(downloaded, hex.EncodeToString(otherKey)); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("expected a signature error, got=%v", err)
+	}
+
+	// the signature is bound to the version, the channel and the platform it was made for
+	nightly, err := internals.FetchRelease(server.URL, "nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset, _ = nightly.Asset("linux/amd64")
+	if err := asset.Verify(downloaded, key); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("expected an older release relabeled as 0.2.0 to be refused, got=%v", err)
+	}
+	asset, _ = release.Asset("darwin/arm64")
+	if err := asset.Verify(downloaded, key); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("expected the linux signature to be refused for darwin, got=%v", err)
+	}
+
+	server.Config.Handler = http.NotFoundHandler()
+	if _, err := internals.FetchRelease(server.URL, "nightly"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected the missing nightly index to fail, got=%v", err)
+	}
+	if _, err := internals.FetchRelease(server.URL, "beta"); err == nil || !strings.Contains(err.Error(), "unknown channel beta") {
+		t.Errorf("expected an unknown channel error, got=%v", err)
+	}
+}
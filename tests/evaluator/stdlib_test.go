@@ -0,0 +1,1407 @@
+package evaluator_tests
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"blk/diagnostics"
+	"blk/internals"
+	"blk/interpreter"
+	"blk/lexer"
+	"blk/object"
+	"blk/parser"
+	"blk/stdlib"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRuntimeModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{
+			input: `
+import "runtime"
+runtime.version
+`,
+			expected: &object.String{Value: "0.1.0"},
+		},
+		{
+			input: `
+import "runtime"
+before :: runtime.eval_steps()
+after :: runtime.eval_steps()
+after > before
+`,
+			expected: object.TRUE,
+		},
+		{
+			input: `
+import "runtime"
+runtime.gc()
+usage :: runtime.mem_usage()
+usage["num_gc"] > 0
+`,
+			expected: object.TRUE,
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		evaluator := interpreter.NewInterpreter(nil, "")
+		eval := evaluator.Eval(program)
+		if eval == nil {
+			t.Errorf("evaluation is null")
+		}
+		if eval.Inspect() != tt.expected.Inspect() {
+			t.Errorf("expected=%q, got=%q", tt.expected, eval.Inspect())
+		}
+	}
+}
+
+func TestPathModule(t *testing.T) {
+	dir := filepath.ToSlash(t.TempDir())
+	for _, name := range []string{"a.mp4", "b.mp4", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{
+			input:    `path.join("videos", "2024", "clip.mp4")`,
+			expected: &object.String{Value: filepath.Join("videos", "2024", "clip.mp4")},
+		},
+		{
+			input:    `path.base("videos/clip.mp4")`,
+			expected: &object.String{Value: "clip.mp4"},
+		},
+		{
+			input:    `path.ext("videos/clip.mp4")`,
+			expected: &object.String{Value: ".mp4"},
+		},
+		{
+			input:    `path.dir("videos/clip.mp4")`,
+			expected: &object.String{Value: "videos"},
+		},
+		{
+			input:    `path.rel("/home/user", "/home/user/videos/clip.mp4")`,
+			expected: &object.String{Value: filepath.Join("videos", "clip.mp4")},
+		},
+		{
+			input:    fmt.Sprintf(`len(path.glob("%s/*.mp4"))`, dir),
+			expected: &object.Integer{Value: 2},
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", "import \"path\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		evaluator := interpreter.NewInterpreter(nil, "")
+		eval := evaluator.Eval(program)
+		if eval == nil {
+			t.Fatalf("evaluation is null for %q", tt.input)
+		}
+		if eval.Inspect() != tt.expected.Inspect() {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected.Inspect(), eval.Inspect())
+		}
+	}
+}
+
+func TestFsWalk(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"1.mp4", "x.txt", "a/2.MP4", "a/b/3.mp4", "a/b/c/4.mp4"} {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := filepath.ToSlash(dir)
+
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{
+			input:    fmt.Sprintf(`len(fs.walk("%s"))`, root),
+			expected: &object.Integer{Value: 5},
+		},
+		{
+			input:    fmt.Sprintf(`len(fs.walk("%s", {"ext": [".mp4"]}))`, root),
+			expected: &object.Integer{Value: 4},
+		},
+		{
+			input:    fmt.Sprintf(`len(fs.walk("%s", {"ext": [".mp4"], "max_depth": 2}))`, root),
+			expected: &object.Integer{Value: 2},
+		},
+		{
+			input:    fmt.Sprintf(`len(fs.walk("%s", {"ext": [], "max_depth": 1}))`, root),
+			expected: &object.Integer{Value: 2},
+		},
+		{
+			input: fmt.Sprintf(`
+count := 0
+for p in fs.walk_iter("%s", {"ext": [".mp4"]}) {
+	count += 1
+	if count == 3 { break }
+}
+count
+`, root),
+			expected: &object.Integer{Value: 3},
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", "import \"fs\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		evaluator := interpreter.NewInterpreter(nil, "")
+		eval := evaluator.Eval(program)
+		if eval == nil {
+			t.Fatalf("evaluation is null for %q", tt.input)
+		}
+		if eval.Inspect() != tt.expected.Inspect() {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected.Inspect(), eval.Inspect())
+		}
+	}
+}
+
+func TestFsReadWrite(t *testing.T) {
+	root := filepath.ToSlash(t.TempDir())
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{fmt.Sprintf(`is_ok(fs.write("%s/a.txt", "hello"))`, root), "true"},
+		{fmt.Sprintf(`fs.write("%s/b.txt", "hi")
+unwrap(fs.read("%s/b.txt"))`, root, root), "hi"},
+		{fmt.Sprintf(`is_err(fs.read("%s/missing.txt"))`, root), "true"},
+		{fmt.Sprintf(`load :: fn(path) {
+	text := fs.read(path)!
+	ok(len(text))
+}
+fs.write("%s/c.txt", "abc")
+[unwrap(load("%s/c.txt")), unwrap_or(load("%s/missing.txt"), -1)]`, root, root, root), "[3, -1]"},
+		{`fs.read(1)`, "path needs to be of type string"},
+		{fmt.Sprintf(`fs.walk("%s", {"ext": [1]})`, root), "option ext needs to be an array of strings, got=ARRAY"},
+		{fmt.Sprintf(`fs.walk("%s", {"depth": 1})`, root), "unknown option depth, expected one of (ext, max_depth)"},
+		{fmt.Sprintf(`fs.walk("%s", {"max_depth": -1})`, root), "max_depth needs to be a positive integer, got=-1"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", "import \"fs\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%s: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestFsPermissions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"data", "secret"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// links in the grant pointing out of it, one to a file that doesn't exist yet
+	if err := os.Symlink(filepath.Join("..", "secret"), filepath.Join(dir, "data", "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "secret", "new.txt"), filepath.Join(dir, "data", "dangling")); err != nil {
+		t.Fatal(err)
+	}
+
+	permissions, err := internals.NewPermissions(filepath.Join(dir, "data"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(granted *internals.Permissions) { stdlib.Permissions = granted }(stdlib.Permissions)
+	stdlib.Permissions = permissions
+
+	root := filepath.ToSlash(dir)
+	tests := []struct {
+		input  string
+		denied bool
+	}{
+		{input: fmt.Sprintf(`fs.walk("%s/data")`, root), denied: false},
+		{input: fmt.Sprintf(`fs.walk("%s/secret")`, root), denied: true},
+		{input: fmt.Sprintf(`fs.walk("%s/data/../secret")`, root), denied: true},
+		{input: fmt.Sprintf(`path.glob("%s/secret/*")`, root), denied: true},
+		{input: fmt.Sprintf(`path.glob("%s/data/*")`, root), denied: false},
+		{input: fmt.Sprintf(`fs.write("%s/data/link/state.json", "{}")`, root), denied: true},
+		{input: fmt.Sprintf(`fs.write("%s/data/link/new/state.json", "{}")`, root), denied: true},
+		{input: fmt.Sprintf(`fs.write("%s/data/dangling", "{}")`, root), denied: true},
+		{input: fmt.Sprintf(`fs.write("%s/data/fresh.json", "{}")`, root), denied: false},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", "import \"fs\"\nimport \"path\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		evaluator := interpreter.NewInterpreter(nil, "")
+		eval := evaluator.Eval(program)
+		if eval == nil {
+			t.Fatalf("evaluation is null for %q", tt.input)
+		}
+
+		errObj, isErr := eval.(*object.Error)
+		denied := isErr && errObj.Kind == object.PermissionError
+		if denied != tt.denied {
+			t.Errorf("%s: expected denied=%v, got=%q", tt.input, tt.denied, eval.Inspect())
+		}
+		if denied && !strings.Contains(errObj.Message, "--allow-fs") {
+			t.Errorf("%s: expected the error to point to --allow-fs, got=%q", tt.input, errObj.Message)
+		}
+	}
+}
+
+// native value the way a plugin would write it, without importing blk
+type counter struct{ count int64 }
+
+func (c *counter) Type() string          { return "test.Counter" }
+func (c *counter) Inspect() string       { return fmt.Sprintf("counter(%d)", c.count) }
+func (c *counter) Equals(other any) bool { return other == any(c) }
+func (c *counter) Hash() uint64          { return uint64(c.count) }
+func (c *counter) Call(args []any) (any, error) {
+	c.count++
+	return c.count, nil
+}
+
+func TestNativeModule(t *testing.T) {
+	err := stdlib.RegisterNative("native_test", 1, map[string]any{
+		"version": int64(3),
+		"greet": func(args []any) (any, error) {
+			name, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, got=%T", args[0])
+			}
+			return "hello " + name, nil
+		},
+		"counter": func(args []any) (any, error) { return &counter{}, nil },
+		"read": func(args []any) (any, error) {
+			c, ok := args[0].(*counter)
+			if !ok {
+				return nil, fmt.Errorf("expected a counter, got=%T", args[0])
+			}
+			return c.count, nil
+		},
+		"sum": func(args []any) (any, error) {
+			total := int64(0)
+			for _, elem := range args[0].([]any) {
+				total += elem.(int64)
+			}
+			return total, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { delete(stdlib.BuiltinModules, "native_test") })
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`native_test.version`, "3"},
+		{`native_test.greet("blk")`, "hello blk"},
+		{`native_test.sum([1, 2, 3])`, "6"},
+		{"c := native_test.counter()\nc()\nc()\nnative_test.read(c)", "2"},
+		{"c := native_test.counter()\nc()\nc", "counter(1)"},
+		{`native_test.greet(1)`, "native_test.greet: expected a string, got=int64"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", "import \"native_test\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		evaluator := interpreter.NewInterpreter(nil, "")
+		eval := evaluator.Eval(program)
+		if eval == nil {
+			t.Fatalf("evaluation is null for %q", tt.input)
+		}
+		if !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, eval.Inspect())
+		}
+	}
+
+	if err := stdlib.RegisterNative("native_next", object.ABIVersion+1, nil); err == nil {
+		t.Errorf("expected a module built against a newer abi to be refused")
+	}
+	if err := stdlib.RegisterNative("fmt", 1, nil); err == nil {
+		t.Errorf("expected a native module to not replace a builtin one")
+	}
+}
+
+func TestLazyModules(t *testing.T) {
+	made := 0
+	err := stdlib.RegisterModule("lazy_test", func() object.Module {
+		made++
+		return object.Module{"answer": &object.Integer{Value: 42}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { delete(stdlib.BuiltinModules, "lazy_test") })
+
+	run := func(input string) object.Object {
+		l := lexer.NewLexer("", input)
+		p := parser.NewParser(l.Tokenize(), "")
+		return interpreter.NewInterpreter(nil, "").Eval(p.Parse())
+	}
+
+	run("1 + 1")
+	if made != 0 {
+		t.Fatalf("expected the module to be made on its import, it got made %d times", made)
+	}
+	for range 2 {
+		if eval := run("import \"lazy_test\"\nlazy_test.answer"); eval == nil || eval.Inspect() != "42" {
+			t.Fatalf("expected=42, got=%v", eval)
+		}
+	}
+	if made != 1 {
+		t.Errorf("expected the imports to share the module, it got made %d times", made)
+	}
+}
+
+// the plugins register their modules while the programs import others, go test -race checks it
+func TestRegisterModuleConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	for idx := range 8 {
+		name := fmt.Sprintf("concurrent_test_%d", idx)
+		t.Cleanup(func() { delete(stdlib.BuiltinModules, name) })
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := stdlib.RegisterModule(name, func() object.Module { return object.Module{} }); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, ok := stdlib.Module("fmt"); !ok {
+				t.Error("expected the fmt module")
+			}
+		}()
+	}
+	wg.Wait()
+	for idx := range 8 {
+		if _, ok := stdlib.Module(fmt.Sprintf("concurrent_test_%d", idx)); !ok {
+			t.Errorf("expected concurrent_test_%d to be registered", idx)
+		}
+	}
+}
+
+func TestJSONModule(t *testing.T) {
+	structs := "import \"json\"\n" +
+		"Address :: struct {\n\tcity := \"\"\n}\n" +
+		"User :: struct {\n\tname := \"\",\n\tage := 0,\n\ttags := [\"\"],\n\taddress := Address{},\n\textra := nul,\n\tgreet : fn(self) { \"hi \" + self.name }\n}\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"json.marshal([1, 2])", "[1,2]"},
+		{`json.marshal({"a": 1.5})`, `{"a":1.5}`},
+		{"json.unmarshal(`[\"a\", \"b\"]`)", "[a, b]"},
+		{
+			"u := json.unmarshal(`{\"name\": \"lofi\", \"age\": 22, \"tags\": [], \"address\": {\"city\": \"algiers\"}, \"extra\": true}`, User)\nu.address.city",
+			"algiers",
+		},
+		{
+			"u := json.unmarshal(`{\"name\": \"lofi\", \"age\": 22, \"tags\": [], \"address\": {\"city\": \"\"}, \"extra\": null}`, User)\nu.greet()",
+			"hi lofi",
+		},
+		{
+			"u := User{name: \"lofi\", tags: [\"a\"]}\njson.marshal(u)",
+			`{"name":"lofi","age":0,"tags":["a"],"address":{"city":""},"extra":null}`,
+		},
+		{
+			"json.unmarshal(`{\n  \"name\": \"lofi\",\n  \"age\": 2.5\n}`, User)",
+			"json 3:10: $.age: expected an int, got 2.5",
+		},
+		{
+			"json.unmarshal(`{\"name\": \"lofi\", \"age\": 1, \"tags\": [], \"address\": {}, \"extra\": 1}`, User)",
+			"json 1:51: $.address: missing required field city",
+		},
+		{
+			"json.unmarshal(`{\"name\": \"lofi\"}`, User)",
+			"json 1:1: $: missing required fields address, age, extra, tags",
+		},
+		{"json.unmarshal(`[1, \"a\"]`)", "json 1:5: $[1]: array elements need to be of one type"},
+		{"json.unmarshal(`{\"a\": 1, \"b\": \"x\"}`)", "json 1:15: $.b: map values need to be of one type"},
+		{"json.unmarshal(`[1, 2`)", "json 1:6"},
+		{
+			"Node :: struct {\n\tvalue := 0,\n\tlink := Node\n}\nn := json.unmarshal(`{\"value\": 1, \"link\": {\"value\": 2, \"link\": null}}`, Node)\nn.link.value",
+			"2",
+		},
+		{"Node :: struct {\n\tlink := Node\n}\nn := Node{}\nn.link = n\njson.marshal(n)", "json: field link: the value refers to itself, json can't represent cycles"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", structs+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		evaluator := interpreter.NewInterpreter(nil, "")
+		eval := evaluator.Eval(program)
+		if eval == nil {
+			t.Fatalf("evaluation is null for %q", tt.input)
+		}
+		if !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, eval.Inspect())
+		}
+	}
+}
+
+// installs shell scripts standing for the tools the stdlib starts, they log their args to args.log
+func fakeTools(t *testing.T, scripts map[string]string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake tools are shell scripts")
+	}
+
+	dir := t.TempDir()
+	for name, script := range scripts {
+		content := "#!/bin/sh\necho \"" + name + " $@\" >> \"" + filepath.Join(dir, "args.log") + "\"\n" + script + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return dir
+}
+
+func TestMediaModule(t *testing.T) {
+	probe := `{"format": {"format_name": "mov,mp4", "duration": "12.5", "size": "2048", "bit_rate": "N/A"},
+"streams": [{"index": 0, "codec_type": "video", "codec_name": "h264", "width": 1280, "height": 720},
+{"index": 1, "codec_type": "audio", "codec_name": "aac", "sample_rate": "48000", "channels": 2}]}`
+	dir := fakeTools(t, map[string]string{
+		"ffprobe": "cat <<'EOF'\n" + probe + "\nEOF",
+		"ffmpeg":  "case \"$*\" in *broken*) echo 'broken.mp4: Invalid data found when processing input' >&2; exit 1;; esac",
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+		args     string // what the tool got, empty when it isn't started
+	}{
+		{"info := media.probe(\"clip.mp4\")\ninfo.duration", "12.5", "ffprobe -v error -print_format json -show_format -show_streams clip.mp4"},
+		{"info := media.probe(\"clip.mp4\")\ninfo.streams[1].sample_rate", "48000", ""},
+		{"info := media.probe(\"clip.mp4\")\ninfo.bit_rate", "0", ""},
+		{
+			`media.trim("talk.mp4", "intro.mp4", {"start": 1.5, "end": 30.0})`,
+			"intro.mp4",
+			"ffmpeg -y -v error -i talk.mp4 -ss 1.5 -to 30 -c copy intro.mp4",
+		},
+		{
+			`media.thumbnail("clip.mp4", "cover.jpg", {"at": 2, "width": 320})`,
+			"cover.jpg",
+			"ffmpeg -y -v error -ss 2 -i clip.mp4 -frames:v 1 -vf scale=320:-1 cover.jpg",
+		},
+		{`media.concat(["a.mp4", "b.mp4"], "full.mp4")`, "full.mp4", "-f concat -safe 0 -i"},
+		{`media.trim("broken.mp4", "out.mp4", {})`, "media.trim: ffmpeg failed: broken.mp4: Invalid data found when processing input", ""},
+		{`media.trim("a.mp4", "b.mp4", {"end": 1, "duration": 2})`, "options end and duration can't be used together", ""},
+		{`media.trim("a.mp4", "b.mp4", {"stop": 1})`, "unknown option stop, expected one of (duration, end, start)", ""},
+		{`media.thumbnail("a.mp4", "b.jpg", {"at": "2s"})`, "option at needs to be a number, got=STRING", ""},
+	}
+	for _, tt := range tests {
+		os.Remove(filepath.Join(dir, "args.log"))
+
+		l := lexer.NewLexer("", "import \"media\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil {
+			t.Fatalf("evaluation is null for %q", tt.input)
+		}
+		if !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, eval.Inspect())
+		}
+
+		if len(tt.args) > 0 {
+			logged, _ := os.ReadFile(filepath.Join(dir, "args.log"))
+			if !strings.Contains(string(logged), tt.args) {
+				t.Errorf("%q: expected the args %q, got=%q", tt.input, tt.args, logged)
+			}
+		}
+	}
+
+	// the tools only start when granted
+	permissions, err := internals.NewPermissions("*", "", "ffprobe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(granted *internals.Permissions) { stdlib.Permissions = granted }(stdlib.Permissions)
+	stdlib.Permissions = permissions
+
+	l := lexer.NewLexer("", "import \"media\"\nmedia.trim(\"a.mp4\", \"b.mp4\", {})")
+	p := parser.NewParser(l.Tokenize(), "")
+	eval := interpreter.NewInterpreter(nil, "").Eval(p.Parse())
+	if errObj, ok := eval.(*object.Error); !ok || errObj.Kind != object.PermissionError || !strings.Contains(errObj.Message, "--allow-run=ffmpeg") {
+		t.Errorf("expected a permission error pointing to --allow-run, got=%v", eval.Inspect())
+	}
+}
+
+func TestDownloadModule(t *testing.T) {
+	dir := fakeTools(t, map[string]string{
+		"yt-dlp": `case "$*" in
+*private*) echo 'ERROR: [youtube] private: Private video' >&2; exit 1;;
+esac
+echo '[youtube] Extracting URL'
+echo '[download]  25.0% of 3.20MiB at 1.2MiB/s ETA 00:02'
+echo '[download] 100.0% of 3.20MiB at 1.2MiB/s ETA 00:00'
+echo 'tracks/song.wav'`,
+	})
+
+	state := "import \"download\"\nState :: struct { last := 0.0, calls := 0 }\ns := State{}\n"
+	tests := []struct {
+		input    string
+		expected string
+		args     string
+	}{
+		{
+			"r := download.audio(\"https://www.youtube.com/watch?v=id\", {\"format\": \"wav\", \"out\": \"tracks/%(title)s.%(ext)s\", \"on_progress\": fn(pct) { s.last = pct\ns.calls = s.calls + 1 }})\nr[\"path\"] + \" \" + string(s.calls) + \" \" + string(s.last)",
+			"tracks/song.wav 2 100",
+			"yt-dlp -x --audio-format wav -o tracks/%(title)s.%(ext)s --no-playlist --newline --progress --no-simulate --print after_move:filepath https://www.youtube.com/watch?v=id",
+		},
+		{
+			"r := download.video(\"https://www.youtube.com/watch?v=id\")\nr[\"path\"]",
+			"tracks/song.wav",
+			"--merge-output-format mp4",
+		},
+		{
+			"r := download.audio(\"https://www.youtube.com/watch?v=private\")\nr[\"error\"]",
+			"download.audio: yt-dlp failed: ERROR: [youtube] private: Private video",
+			"",
+		},
+		{
+			"download.audio(\"https://www.youtube.com/watch?v=id\", {\"on_progress\": fn(pct) { missing }})",
+			"identifier not found: missing",
+			"",
+		},
+		{`download.audio("not a url")`, "invalid url not a url", ""},
+		{`download.audio("https://a.com", {"quality": "best"})`, "unknown option quality, expected one of (format, on_progress, out)", ""},
+		{`download.audio("https://a.com", {"on_progress": "50%"})`, "option on_progress needs to be a function, got=STRING", ""},
+	}
+	for _, tt := range tests {
+		os.Remove(filepath.Join(dir, "args.log"))
+
+		l := lexer.NewLexer("", state+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil {
+			t.Fatalf("evaluation is null for %q", tt.input)
+		}
+		if !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, eval.Inspect())
+		}
+
+		if len(tt.args) > 0 {
+			logged, _ := os.ReadFile(filepath.Join(dir, "args.log"))
+			if !strings.Contains(string(logged), tt.args) {
+				t.Errorf("%q: expected the args %q, got=%q", tt.input, tt.args, logged)
+			}
+		}
+	}
+}
+
+func TestPipelineModule(t *testing.T) {
+	// every ffprobe waits for the others to start, it only gets through when they run in parallel
+	marks := t.TempDir()
+	fakeTools(t, map[string]string{
+		"ffprobe": `touch "` + marks + `/$$"
+for i in $(seq 100); do
+	[ $(ls "` + marks + `" | wc -l) -ge 3 ] && echo '{}' && exit 0
+	sleep 0.05
+done
+echo 'started alone' >&2; exit 1`,
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"r := pipeline.run([1, 2, 3], fn(n) { return n * 2 }, {\"concurrency\": 2})\nr[2].value", "6"},
+		{"r := pipeline.run([1, 2, 3], fn(n) { return n * 2 })\nr[0].item", "1"},
+		{"r := pipeline.run([], fn(n) { return n })\nlen(r)", "0"},
+		{
+			"r := pipeline.run([1, 2, 3], fn(n) { if n == 2 { return missing }\nreturn n }, {\"concurrency\": 4, \"on_error\": \"continue\"})\nstring(r[1].ok) + \" \" + r[1].error + \" \" + string(r[2].value)",
+			"false identifier not found: missing 3",
+		},
+		{"pipeline.run([1, 2, 3], fn(n) { return missing })", "identifier not found: missing"},
+		{
+			"import \"media\"\nr := pipeline.run([\"a.mp4\", \"b.mp4\", \"c.mp4\"], fn(path) { return media.probe(path) }, {\"concurrency\": 3, \"on_error\": \"continue\"})\nstring(r[0].ok) + string(r[1].ok) + string(r[2].ok) + r[0].error",
+			"truetruetrue",
+		},
+		{`pipeline.run([1], fn(n) { return n }, {"concurrency": 0})`, "concurrency needs to be at least 1, got=0"},
+		{`pipeline.run([1], fn(n) { return n }, {"on_error": "retry"})`, "unknown on_error retry, expected one of (continue, stop)"},
+		{`pipeline.run([1], fn(n) { return n }, {"concurrency": 1.5})`, "concurrency needs to be of type int, got=FLOAT"},
+		{`pipeline.run([1], fn(n) { return n }, {"workers": 2})`, "unknown option workers, expected one of (concurrency, on_error)"},
+		{`pipeline.run([1], 2)`, "worker needs to be a function, got=INTEGER"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", "import \"pipeline\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil {
+			t.Fatalf("evaluation is null for %q", tt.input)
+		}
+		if !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, eval.Inspect())
+		}
+	}
+}
+
+func TestTermModule(t *testing.T) {
+	var out bytes.Buffer
+	stdlib.Stdout = &out
+	diagnostics.SetColorMode(diagnostics.ColorAlways)
+	t.Cleanup(func() {
+		stdlib.Stdout = os.Stdout
+		diagnostics.SetColorMode(diagnostics.ColorAuto)
+	})
+	t.Setenv("COLUMNS", "72")
+
+	tests := []struct {
+		input    string
+		expected string
+		output   string // what got written, redirected output doesn't redraw lines
+	}{
+		{
+			"bar := term.progress(20, \"tracks\")\nbar.update(1)\nbar.update(3)\nbar.update(10)\nbar.finish()\nbar.finish()",
+			"",
+			"tracks   5% 1/20\ntracks  15% 3/20\ntracks  50% 10/20\ntracks 100% 20/20\n",
+		},
+		{"bar := term.progress(2)\nbar.update(5)", "", "100% 2/2\n"},
+		{"bar := term.progress(2)\nbar.finish()\nbar.update(1)", "the progress bar is finished already", "100% 2/2\n"},
+		{"term.progress(0)", "total needs to be at least 1, got=0", ""},
+		{"s := term.spinner(\"downloading\")\ns.stop(\"downloaded\")", "", "downloading\ndownloaded\n"},
+		{"import \"fmt\"\nfmt.println(term.green(\"ok\"), term.is_tty(), term.columns())", "", "\033[32mok\033[0m false 72\n"},
+		{"term.bold(3)", "text needs to be of type string, got=INTEGER", ""},
+	}
+	for _, tt := range tests {
+		out.Reset()
+
+		l := lexer.NewLexer("", "import \"term\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if len(tt.expected) > 0 && (eval == nil || !strings.Contains(eval.Inspect(), tt.expected)) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+		if len(tt.expected) == 0 && eval != nil && eval.Type() == object.ERROR_OBJ {
+			t.Errorf("%q: unexpected error %s", tt.input, eval.Inspect())
+		}
+		if out.String() != tt.output {
+			t.Errorf("%q: expected the output %q, got=%q", tt.input, tt.output, out.String())
+		}
+	}
+}
+
+func TestPromptModule(t *testing.T) {
+	var out bytes.Buffer
+	stdlib.Stdout = &out
+	diagnostics.SetColorMode(diagnostics.ColorNever)
+	t.Cleanup(func() {
+		stdlib.Stdout, stdlib.Stdin = os.Stdout, os.Stdin
+		diagnostics.SetColorMode(diagnostics.ColorAuto)
+	})
+
+	tests := []struct {
+		input    string
+		answers  string
+		expected string
+		output   string
+	}{
+		{`prompt.confirm("overwrite?")`, "yes\n", "true", "? overwrite? [y/N] "},
+		{`prompt.confirm("overwrite?")`, "\n", "false", ""},
+		{`prompt.confirm("keep going?", true)`, "maybe\n\n", "true", "? keep going? [Y/n] answer with y or n\n? keep going? [Y/n] "},
+		{"if prompt.confirm(\"sure?\") { \"yes\" } else { \"no\" }", "n\n", "no", ""},
+		{
+			`prompt.select("format?", ["mp3", "wav"])`,
+			"3\n2\n",
+			"wav",
+			"? format? \n  1) mp3\n  2) wav\n> pick a number between 1 and 2\n> ",
+		},
+		{`prompt.select("format?", ["mp3", "wav"])`, "MP3\n", "mp3", ""},
+		{`prompt.text("name?", "blk-app")`, "\n", "blk-app", "? name? (blk-app) "},
+		{`prompt.text("name?")`, "lofi", "lofi", ""},
+		{"a := prompt.text(\"first?\")\nb := prompt.password(\"token?\")\na + b", "x\ny\n", "xy", "? first? ? token? "},
+		{`prompt.text("name?")`, "", "prompt.text: no answer, the input is closed", ""},
+		{`prompt.select("format?", [])`, "", "prompt.select: options can't be empty", ""},
+	}
+	for _, tt := range tests {
+		out.Reset()
+		stdlib.Stdin = strings.NewReader(tt.answers)
+
+		l := lexer.NewLexer("", "import \"prompt\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+		if len(tt.output) > 0 && out.String() != tt.output {
+			t.Errorf("%q: expected the output %q, got=%q", tt.input, tt.output, out.String())
+		}
+	}
+}
+
+func TestStoreModule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	open := fmt.Sprintf("import \"store\"\nState :: struct { track := \"\", seconds := 0.0 }\ndb := store.open(%q)\n", path)
+
+	// the programs run one after the other on the same file, like the runs of a script
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`db.get("done", "none")`, "none"},
+		{"db.set(\"done\", [\"intro.mp3\"])\ndb.set(\"last\", State{track: \"intro.mp3\", seconds: 2.5})\ndb.set(\"ok\", false)\ndb.keys()", "[done, last, ok]"},
+		{`db.get("done", [""])`, "[intro.mp3]"},
+		{"last := db.get(\"last\", State{})\nlast.seconds", "2.5"},
+		{`if db.get("ok") { "yes" } else { "no" }`, "no"},
+		{`db.get("done", 0)`, "$.done: expected an int, got array"},
+		{"db.delete(\"ok\")\nstring(db.delete(\"ok\")) + string(db.has(\"ok\")) + string(db.has(\"done\"))", "falsefalsetrue"},
+		{`db.set("f", fn() {})`, "store.set: values of type FUNCTION can't be written as json"},
+		{"db.path", path},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", open+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"done": [`) {
+		t.Errorf("expected the store to be json, got=%s", content)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected the temporary files to be gone, got %d files", len(entries))
+	}
+
+	os.WriteFile(path, []byte("[1, 2]"), 0644)
+	l := lexer.NewLexer("", open)
+	eval := interpreter.NewInterpreter(nil, "").Eval(parser.NewParser(l.Tokenize(), "").Parse())
+	if eval == nil || !strings.Contains(eval.Inspect(), "isn't a store, expected a json object") {
+		t.Errorf("expected the store to be rejected, got=%v", eval)
+	}
+}
+
+func TestHTTPDownload(t *testing.T) {
+	body := strings.Repeat("0123456789", 20000)
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		switch r.URL.Path {
+		case "/plain":
+			// no ranges, the download starts over
+			w.Write([]byte(body))
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			http.ServeContent(w, r, "talk.mp4", time.Time{}, strings.NewReader(body))
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	tests := []struct {
+		input    string
+		part     string // what an earlier download left in dest.part
+		expected string
+		rng      string // the range the server got
+	}{
+		{
+			"State :: struct { last := 0.0, calls := 0 }\ns := State{}\nprogress :: fn(pct) { s.last = pct\ns.calls = s.calls + 1 }\nhttp.download(url + \"/talk.mp4\", dest, {\"on_progress\": progress})\nstring(s.last) + \" \" + string(s.calls > 1)",
+			"",
+			"100 true",
+			"",
+		},
+		{"http.download(url + \"/talk.mp4\", dest)", body[:150000], "dest", "bytes=150000-"},
+		{"http.download(url + \"/plain\", dest)", body[:1000], "dest", "bytes=1000-"},
+		{"http.download(url + \"/talk.mp4\", dest)", body, "dest", "bytes=200000-"},
+		{"http.download(url + \"/missing\", dest)", "", "http.download: GET " + server.URL + "/missing: 404 Not Found", ""},
+		{"http.download(url + \"/talk.mp4\", dest, {\"on_progress\": fn(pct) { missing }})", "", "identifier not found: missing", ""},
+		{"http.download(url, dest, {\"on_progress\": 3})", "", "option on_progress needs to be a function, got=INTEGER", ""},
+	}
+	for idx, tt := range tests {
+		ranges = nil
+		dest := filepath.Join(dir, fmt.Sprintf("talk%d.mp4", idx))
+		if len(tt.part) > 0 {
+			os.WriteFile(dest+".part", []byte(tt.part), 0644)
+		}
+
+		input := fmt.Sprintf("import \"http\"\nurl := %q\ndest := %q\n", server.URL, dest) + tt.input
+		l := lexer.NewLexer("", input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		expected := strings.ReplaceAll(tt.expected, "dest", dest)
+		if eval == nil || !strings.Contains(eval.Inspect(), expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, expected, eval)
+			continue
+		}
+		if len(tt.rng) > 0 && (len(ranges) == 0 || ranges[0] != tt.rng) {
+			t.Errorf("%q: expected the range %q, got=%q", tt.input, tt.rng, ranges)
+		}
+		if eval.Type() != object.ERROR_OBJ {
+			if content, _ := os.ReadFile(dest); string(content) != body {
+				t.Errorf("%q: expected the whole body in %s, got %d bytes", tt.input, dest, len(content))
+			}
+		}
+	}
+}
+
+func TestRateModule(t *testing.T) {
+	// the sleeps of the scripts are limiter waits, pause.wait() returns 60ms after the previous one
+	setup := "import \"rate\"\nState :: struct { calls := 0 }\ns := State{}\ncount :: fn(n) { s.calls = s.calls + 1\nreturn n }\npause := rate.new(1, \"60ms\")\npause.wait()\n"
+	tests := []struct {
+		input    string
+		expected string
+		duration time.Duration // the least it takes
+	}{
+		{"l := rate.new(2, \"100ms\")\nl.wait()\nl.wait()\nl.wait()\nl.wait()\nl.wait()", "", 200 * time.Millisecond},
+		{"l := rate.new(2, \"1s\")\nstring(l.try()) + string(l.try()) + string(l.try())", "truetruefalse", 0},
+		{"t :: rate.throttle(count, 1000)\nfirst := t(1)\nsecond := t(2)\nstring(first) + string(s.calls) + string(second == nul)", "11true", 0},
+		{"d :: rate.debounce(count, 100)\nd(1)\npause.wait()\nd(2)\npause.wait()\nd(3)\ns.calls", "1", 0},
+		{"t :: rate.throttle(count, 100)\nt(1)\npause.wait()\nt(2)\npause.wait()\nt(3)\ns.calls", "2", 0},
+		{`rate.new(5, "soon")`, "invalid period soon, expected a duration like 1s, 500ms or 1m", 0},
+		{`rate.throttle(count, -1)`, "ms needs to be a positive int, got=-1", 0},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", setup+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		start := time.Now()
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if elapsed := time.Since(start); elapsed < tt.duration {
+			t.Errorf("%q: expected it to take %v at least, took %v", tt.input, tt.duration, elapsed)
+		}
+		if len(tt.expected) > 0 && (eval == nil || !strings.Contains(eval.Inspect(), tt.expected)) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestURLModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"u := url.parse(\"https://bob@api.example.com:8080/v1/tracks?page=2&page=3#top\")\nu.user + \" \" + u.host + \" \" + u.port + \" \" + u.path + \" \" + u.query[\"page\"] + \" \" + u.fragment", "bob api.example.com 8080 /v1/tracks 2 top"},
+		{"u := url.parse(\"/relative\")\nu.scheme + u.host + u.path", "/relative"},
+		{"u := url.parse(\"https://example.com/search?page=2\")\nhashmap.insert(u.query, \"q\", \"lofi beats\")\nurl.build(u)", "https://example.com/search?page=2&q=lofi+beats"},
+		{"Link :: struct { host := \"\", path := \"\", port := \"\" }\nurl.build(Link{host: \"localhost\", path: \"v1\", port: \"3000\"})", "//localhost:3000/v1"},
+		{"Link :: struct { hostname := \"\" }\nurl.build(Link{})", "unknown field hostname, expected one of (scheme, user, host, port, path, query, fragment)"},
+		{`url.join("https://example.com/v1/tracks", "../users")`, "https://example.com/users"},
+		{`url.join("https://example.com/v1/", "tracks?page=2")`, "https://example.com/v1/tracks?page=2"},
+		{`url.encode("lofi beats&more")`, "lofi+beats%26more"},
+		{`url.decode("lofi+beats%21")`, "lofi beats!"},
+		{`url.decode("%zz")`, `url.decode: invalid URL escape "%zz"`},
+		{`url.parse("http://[::1")`, "url.parse:"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", "import \"url\"\nimport \"hashmap\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestMimeModule(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"cover.bin":  {0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n', 0, 0, 0, 0x0D},
+		"song.bin":   append([]byte("ID3\x04\x00\x00"), make([]byte, 16)...),
+		"frame.bin":  {0xFF, 0xFB, 0x90, 0x64, 0, 0},
+		"lossless":   []byte("fLaC\x00\x00\x00\x22"),
+		"clip.dat":   append([]byte{0, 0, 0, 0x18}, []byte("ftypisom\x00\x00\x02\x00")...),
+		"voice.dat":  append([]byte{0, 0, 0, 0x20}, []byte("ftypM4A \x00\x00\x00\x00")...),
+		"movie.dat":  append([]byte{0x1A, 0x45, 0xDF, 0xA3, 0x9F, 0x42, 0x82, 0x88}, []byte("matroska")...),
+		"notes.dat":  []byte("just some notes\n"),
+		"empty.dat":  {},
+		"random.dat": {0x00, 0x01, 0x02, 0x03},
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := filepath.ToSlash(dir)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`mime.by_ext(".mp4")`, "video/mp4"},
+		{`mime.by_ext("MKV")`, "video/x-matroska"},
+		{`mime.by_ext("covers/front.JPG")`, "image/jpeg"},
+		{`mime.by_ext(".txt")`, "text/plain"},
+		{`mime.by_ext(".nope")`, "application/octet-stream"},
+		{`mime.by_ext(4)`, "ext needs to be of type string, got=INTEGER"},
+		{fmt.Sprintf(`mime.sniff("%s/cover.bin")`, root), "image/png"},
+		{fmt.Sprintf(`mime.sniff("%s/song.bin")`, root), "audio/mpeg"},
+		{fmt.Sprintf(`mime.sniff("%s/frame.bin")`, root), "audio/mpeg"},
+		{fmt.Sprintf(`mime.sniff("%s/lossless")`, root), "audio/flac"},
+		{fmt.Sprintf(`mime.sniff("%s/clip.dat")`, root), "video/mp4"},
+		{fmt.Sprintf(`mime.sniff("%s/voice.dat")`, root), "audio/mp4"},
+		{fmt.Sprintf(`mime.sniff("%s/movie.dat")`, root), "video/x-matroska"},
+		{fmt.Sprintf(`mime.sniff("%s/notes.dat")`, root), "text/plain"},
+		{fmt.Sprintf(`mime.sniff("%s/empty.dat")`, root), "text/plain"},
+		{fmt.Sprintf(`mime.sniff("%s/random.dat")`, root), "application/octet-stream"},
+		{fmt.Sprintf(`mime.sniff("%s/missing.dat")`, root), "mime.sniff: open"},
+		{`mime.sniff([71, 73, 70, 56, 57, 97])`, "image/gif"},
+		{`mime.sniff([255, 216, 255, 224])`, "image/jpeg"},
+		{`mime.sniff([256])`, "bytes need to be ints between 0 and 255, got=256"},
+		{`mime.sniff(1.5)`, "arg needs to be a path or an array of bytes, got=FLOAT"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", "import \"mime\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestScheduleModule(t *testing.T) {
+	setup := "import \"schedule\"\nState :: struct { runs := 0 }\ns := State{}\n"
+	now := time.Now()
+	at3 := time.Date(now.Year(), now.Month(), now.Day(), 3, 0, 0, 0, now.Location())
+	if !at3.After(now) {
+		at3 = at3.AddDate(0, 0, 1)
+	}
+	quarter := now.Truncate(time.Minute).Add(time.Minute)
+	for quarter.Minute()%15 != 0 {
+		quarter = quarter.Add(time.Minute)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+		duration time.Duration // the least it takes
+	}{
+		{"tick :: fn() {\ns.runs = s.runs + 1\nif s.runs == 3 {\njob.cancel()\n}\n}\njob := schedule.every(\"20ms\", tick)\nschedule.run_forever()\ns.runs", "3", 60 * time.Millisecond},
+		{"tick :: fn() {\ns.runs = s.runs + 1\n}\nfast := schedule.every(\"10ms\", tick)\nstop :: fn() {\nfast.cancel()\nlate.cancel()\n}\nlate := schedule.every(\"55ms\", stop)\nschedule.run_forever()\ns.runs > 0 && s.runs <= 5", "true", 55 * time.Millisecond},
+		{"fail :: fn() { schedule.cron(\"nope\", fail) }\nschedule.every(\"10ms\", fail)\nschedule.run_forever()", `invalid expression "nope": expected 5 fields, got 1`, 0},
+		{"schedule.run_forever()\n\"done\"", "done", 0},
+		{"tick :: fn() {}\nj := schedule.cron(\"0 3 * * *\", tick)\nj.cancel()\nj.next_run()", at3.Format("2006-01-02 15:04:05"), 0},
+		{"tick :: fn() {}\nj := schedule.cron(\"*/15 * * * *\", tick)\nj.cancel()\nj.next_run()", quarter.Format("2006-01-02 15:04:05"), 0},
+		{"tick :: fn() {}\nschedule.cron(\"61 * * * *\", tick)", "minute 61 is out of range, expected values from 0 to 59", 0},
+		{"tick :: fn() {}\nschedule.cron(\"0 0 30 2 *\", tick)", `"0 0 30 2 *" never matches`, 0},
+		{"tick :: fn() {}\nschedule.cron(\"*/0 * * * *\", tick)", "minute has an invalid step 0", 0},
+		{"tick :: fn() {}\nschedule.every(\"soon\", tick)", "invalid interval soon, expected a duration like 30s, 5m or 1h", 0},
+		{`schedule.every("1m", 3)`, "fn needs to be a function, got=INTEGER", 0},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", setup+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		start := time.Now()
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if elapsed := time.Since(start); elapsed < tt.duration {
+			t.Errorf("%q: expected it to take %v at least, took %v", tt.input, tt.duration, elapsed)
+		}
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestValidateModule(t *testing.T) {
+	setup := "import \"validate\"\nimport \"json\"\nUser :: struct { name := \"\", age := 0, tags := [\"\"], role := \"\" }\n" +
+		"report :: fn(issues) {\nout := \"\"\nfor _, i in issues {\nout = out + i.path + \" \" + i.rule + \": \" + i.message + \"; \"\n}\nreturn out\n}\n"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len(validate.check(User{name: "lofi", age: 22, tags: ["chill"], role: "admin"}, {"name": "string,min=1", "age": "int,min=0", "tags[]": "string"}))`, "0"},
+		{`report(validate.check(User{name: "", age: -3}, {"name": "string,min=1", "age": "int,min=0,max=130"}))`, "$.age min: value -3 is below the min of 0; $.name min: length 0 is below the min of 1; "},
+		{`report(validate.check(User{tags: ["a", ""]}, {"tags[]": "string,min=1", "tags": "array,max=1"}))`, "$.tags max: length 2 is above the max of 1; $.tags[1] min: length 0 is below the min of 1; "},
+		{`report(validate.check(User{role: "root"}, {"role": "string,oneof=admin|user"}))`, "$.role oneof: root isn't one of (admin, user); "},
+		{`report(validate.check(User{name: "bob.example.com"}, {"name": "string,pattern=^[^@]+@[a-z]{1,3}$"}))`, "$.name pattern: bob.example.com doesn't match ^[^@]+@[a-z]{1,3}$; "},
+		{`report(validate.check(User{}, {"email": "string", "phone": "string,optional"}))`, "$.email required: is required; "},
+		{`report(validate.check(User{}, {"address": "map", "address.city": "string", "billing.zip": "string"}))`, "$.address required: is required; $.billing.zip required: is required; "},
+		{"m := json.unmarshal(`{\"a\": 1, \"b\": 2}`)\nreport(validate.check(m, {\"a\": \"string\", \"b\": \"number,max=1\"}))", "$.a type: expected string, got int; $.b max: value 2 is above the max of 1; "},
+		{`validate.check(User{}, {"name": "text"})`, "validate.check: name: unknown type text, expected one of (any, string, int, float, number, bool, array, map)"},
+		{`validate.check(User{}, {"name": "string,long"})`, "name: unknown rule long, expected optional, min, max, oneof or pattern"},
+		{`validate.check(User{}, {"age": "int,min=zero"})`, `age: min needs a number, got "zero"`},
+		{`validate.check([1], {"age": "int"})`, "value needs to be a map or a struct instance, got=ARRAY"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", setup+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestDiffModule(t *testing.T) {
+	setup := "import \"diff\"\nold := \"1\\n2\\n3\\n4\\n5\\n6\\n7\\n8\\n9\\n10\\n11\\n12\\n\"\nnew := \"1\\n2\\nthree\\n4\\n5\\n6\\n7\\n8\\n9\\n10\\n12\\n13\\n\"\n"
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`diff.lines("a\nb\nc\n", "a\nx\nc\n")`, "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n"},
+		{`diff.lines(old, new, {"from": "golden.txt", "to": "output"})`, "--- golden.txt\n+++ output\n@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n@@ -8,5 +8,5 @@\n 8\n 9\n 10\n-11\n 12\n+13\n"},
+		{`diff.lines("a\nb", "a\nc")`, "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"},
+		{`diff.lines("", "a\n")`, "@@ -0,0 +1 @@\n+a\n"},
+		{`diff.lines(old, old) == ""`, "true"},
+		{`diff.apply(old, diff.lines(old, new)) == new`, "true"},
+		{`diff.apply("a\nb", diff.lines("a\nb", "a\nb\nc\n")) == "a\nb\nc\n"`, "true"},
+		{`diff.apply("0\n0\n" + old, diff.lines(old, new)) == "0\n0\n" + new`, "true"},
+		{`diff.apply("1\n2\n4\n", diff.lines(old, new))`, "diff.apply: @@ -1,6 +1,6 @@: the lines of the hunk aren't in the text"},
+		{`diff.apply(old, "@@ -1,2 +1,2 @@\n 1\n")`, "diff.apply: @@ -1,2 +1,2 @@: the hunk ends early"},
+		{`diff.apply(old, "@@ -x +1 @@\n")`, `diff.apply: invalid hunk header "@@ -x +1 @@"`},
+		{`diff.lines(old, new, {"context": "5"})`, "unknown option context, expected one of (from, to)"},
+		{`diff.lines(1, new)`, "a needs to be of type string, got=INTEGER"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", setup+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+}
+
+func TestRandModule(t *testing.T) {
+	run := func(input string) string {
+		l := lexer.NewLexer("", "import \"rand\"\nimport \"runtime\"\n"+input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil {
+			t.Fatalf("%q: evaluation is null", input)
+		}
+		return eval.Inspect()
+	}
+
+	draw := "cards := [1, 2, 3, 4, 5, 6]\nrand.shuffle(cards)\n[rand.int(0, 1000), rand.float(), rand.choice(cards), cards]"
+	stdlib.SetSeed(7)
+	first := run(draw)
+	stdlib.SetSeed(7)
+	if second := run(draw); second != first {
+		t.Errorf("expected the same seed to draw the same values, got=%s then %s", first, second)
+	}
+	if seed := run("runtime.seed()"); seed != "7" {
+		t.Errorf("expected runtime.seed() to be 7, got=%s", seed)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"n := rand.int(3, 4)\nn", "3"},
+		{"rand.choice([\"a\"])", "a"},
+		{"rand.int(4, 4)", "rand.int needs min < max, got 4 and 4"},
+		{"rand.int(1.5, 4)", "the bounds of rand.int need to be of type int"},
+		{"rand.choice([])", "rand.choice needs an array with elements"},
+		{"const cards = [1, 2]\nrand.shuffle(cards)", "can't mutate"},
+	}
+	for _, tt := range tests {
+		if got := run(tt.input); !strings.Contains(got, tt.expected) {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestArchiveModule(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "app", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "app", "bin", "run"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("readme\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an archive\n"), 0644)
+
+	// an entry climbing out of the destination
+	evil, err := os.Create(filepath.Join(dir, "evil.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(evil)
+	w, _ := zw.Create("../escaped.txt")
+	w.Write([]byte("gotcha"))
+	zw.Close()
+	evil.Close()
+
+	root := filepath.ToSlash(dir)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{fmt.Sprintf("archive.zip([\"%[1]s/app\", \"%[1]s/README.md\"], \"%[1]s/app.zip\")\nnames := \"\"\nfor _, e in archive.list(\"%[1]s/app.zip\") {\nnames = names + e.name + \" \"\n}\nnames", root), "app app/bin app/bin/run README.md "},
+		{fmt.Sprintf("archive.tar_gz([\"%[1]s/app\"], \"%[1]s/app.tar.gz\")\nentries := archive.list(\"%[1]s/app.tar.gz\")\nstring(entries[0].dir) + \" \" + string(entries[2].size)", root), "true 10"},
+		{fmt.Sprintf("archive.zip([\"%[1]s/app\"], \"%[1]s/a.zip\")\narchive.unzip(\"%[1]s/a.zip\", \"%[1]s/out/zip\")", root), fmt.Sprintf("[%s/out/zip/app/bin/run]", root)},
+		{fmt.Sprintf("archive.tar_gz([\"%[1]s/app\", \"%[1]s/README.md\"], \"%[1]s/a.tar.gz\")\nlen(archive.untar_gz(\"%[1]s/a.tar.gz\", \"%[1]s/out/tar\"))", root), "2"},
+		{fmt.Sprintf(`archive.unzip("%[1]s/evil.zip", "%[1]s/out/evil")`, root), "archive.unzip: entry ../escaped.txt is outside of the destination"},
+		{fmt.Sprintf(`archive.list("%s/notes.txt")`, root), "notes.txt isn't a zip or a tar.gz archive"},
+		{fmt.Sprintf(`archive.zip(["%[1]s/missing"], "%[1]s/missing.zip")`, root), "archive.zip: lstat"},
+		{`archive.zip("app", "app.zip")`, "paths need to be of type array, got=STRING"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", "import \"archive\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+	}
+
+	// the extracted files keep their content and their mode
+	info, err := os.Stat(filepath.Join(dir, "out", "tar", "app", "bin", "run"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("expected the extracted run to be executable, got=%v, %v", info, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "escaped.txt")); err == nil {
+		t.Errorf("expected ../escaped.txt to be left out")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.zip")); err == nil {
+		t.Errorf("expected the failed archive to be removed")
+	}
+}
+
+func TestArchiveChainedSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	os.MkdirAll(out, 0755)
+
+	// each link stays in the destination by itself, chained they climb out of it
+	file, err := os.Create(filepath.Join(dir, "chained.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "a", Typeflag: tar.TypeSymlink, Linkname: ".", Mode: 0777})
+	tw.WriteHeader(&tar.Header{Name: "a/b", Typeflag: tar.TypeSymlink, Linkname: "..", Mode: 0777})
+	tw.WriteHeader(&tar.Header{Name: "b/escaped.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 6})
+	tw.Write([]byte("gotcha"))
+	tw.Close()
+	gz.Close()
+	file.Close()
+
+	permissions, err := internals.NewPermissions(out+","+filepath.Join(dir, "chained.tar.gz"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(granted *internals.Permissions) { stdlib.Permissions = granted }(stdlib.Permissions)
+	stdlib.Permissions = permissions
+
+	input := fmt.Sprintf("import \"archive\"\narchive.untar_gz(\"%s/chained.tar.gz\", \"%s\")", filepath.ToSlash(dir), filepath.ToSlash(out))
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	if len(p.Errors) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors)
+	}
+	eval := interpreter.NewInterpreter(nil, "").Eval(program)
+	if eval == nil || !strings.Contains(eval.Inspect(), "entry a/b goes through the symlink") {
+		t.Errorf("expected a/b to be refused, got=%v", eval)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.txt")); err == nil {
+		t.Errorf("expected escaped.txt to stay out of %s", dir)
+	}
+}
+
+func TestArchiveExistingSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	os.MkdirAll(out, 0755)
+	// left by an earlier extract or by anything else, the archive only has plain files
+	if err := os.Symlink("..", filepath.Join(out, "up")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "target.txt"), filepath.Join(out, "file.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		header   tar.Header
+		expected string
+	}{
+		{"through.tar.gz", tar.Header{Name: "up/escaped.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 6}, "entry up/escaped.txt goes through the symlink"},
+		{"over.tar.gz", tar.Header{Name: "file.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 6}, "entry file.txt overwrites the symlink"},
+		{"link.tar.gz", tar.Header{Name: "inner", Typeflag: tar.TypeSymlink, Linkname: "up/..", Mode: 0777}, "entry inner links through the symlink up"},
+	}
+	grants := out
+	for _, tt := range tests {
+		grants += "," + filepath.Join(dir, tt.name)
+		file, err := os.Create(filepath.Join(dir, tt.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz := gzip.NewWriter(file)
+		tw := tar.NewWriter(gz)
+		tw.WriteHeader(&tt.header)
+		if tt.header.Size > 0 {
+			tw.Write([]byte("gotcha"))
+		}
+		tw.Close()
+		gz.Close()
+		file.Close()
+	}
+
+	permissions, err := internals.NewPermissions(grants, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(granted *internals.Permissions) { stdlib.Permissions = granted }(stdlib.Permissions)
+	stdlib.Permissions = permissions
+
+	for _, tt := range tests {
+		input := fmt.Sprintf("import \"archive\"\narchive.untar_gz(\"%s/%s\", \"%s\")", filepath.ToSlash(dir), tt.name, filepath.ToSlash(out))
+		l := lexer.NewLexer("", input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parser errors: %v", p.Errors)
+		}
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if eval == nil || !strings.Contains(eval.Inspect(), tt.expected) {
+			t.Errorf("%s: expected=%q, got=%v", tt.name, tt.expected, eval)
+		}
+	}
+	for _, name := range []string{"escaped.txt", "target.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("expected %s to stay out of %s", name, dir)
+		}
+	}
+}
+
+func TestFmtFloatFormat(t *testing.T) {
+	var out bytes.Buffer
+	stdlib.Stdout = &out
+	t.Cleanup(func() {
+		stdlib.Stdout = os.Stdout
+		object.FloatOutput = object.DefaultFloatFormat
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+		output   string
+	}{
+		{"fmt.println(1 / 3.0)", "", "0.333333\n"},
+		{"fmt.format(3.14159, 2)", "3.14", ""},
+		{`fmt.format(1234.5, 3, "scientific")`, "1.234e+03", ""},
+		{"fmt.format({\"a\": [0.1, 2.5]}, -1)", "{a: [0.1, 2.5]}", ""},
+		{"s := fmt.format(2.5, 0)\nfmt.println(2.5)", "", "2.500000\n"},
+		{"fmt.set_float_format(-1)\nfmt.println(0.1 + 0.2, [1.5])", "", "0.30000000000000004 [1.5]\n"},
+		{"fmt.set_float_format(2, \"scientific\")\nfmt.println(123456.0)", "", "1.23e+05\n"},
+		{"fmt.format(1.0, 101)", "precision needs to be between -1 and 100, got=101", ""},
+		{"fmt.format(1.0, 2.0)", "precision needs to be of type int, got=FLOAT", ""},
+		{`fmt.set_float_format(2, "engineering")`, "invalid notation engineering, expected one of (fixed, scientific)", ""},
+	}
+	for _, tt := range tests {
+		out.Reset()
+
+		l := lexer.NewLexer("", "import \"fmt\"\n"+tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors)
+		}
+		// every interpreter starts over with 6 digits in the fixed notation
+		eval := interpreter.NewInterpreter(nil, "").Eval(program)
+		if len(tt.expected) > 0 && (eval == nil || !strings.Contains(eval.Inspect(), tt.expected)) {
+			t.Errorf("%q: expected=%q, got=%v", tt.input, tt.expected, eval)
+		}
+		if out.String() != tt.output {
+			t.Errorf("%q: expected the output %q, got=%q", tt.input, tt.output, out.String())
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package evaluator_tests
+
+import (
+	"blk/interpreter"
+	"blk/lexer"
+	"blk/object"
+	"blk/parser"
+	"blk/tracelog"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTraceLog(t *testing.T) {
+	input := `
+double :: fn(n) {
+    return n * 2
+}
+x := double(2)
+missing()
+`
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	evaluator := interpreter.NewInterpreter(nil, "main.blk")
+
+	var out bytes.Buffer
+	trace := tracelog.New(&out)
+	evaluator.SetHooks(trace.Hooks())
+	evaluator.Eval(program)
+	if err := trace.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	type entry struct {
+		File      string `json:"file"`
+		Line      int    `json:"line"`
+		Kind      string `json:"kind"`
+		StartUs   int64  `json:"start_us"`
+		ElapsedUs int64  `json:"elapsed_us"`
+		Error     string `json:"error"`
+	}
+	var entries []entry
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var e entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("expected a json line, got=%q: %v", line, err)
+		}
+		if e.File != "main.blk" || e.StartUs < 0 || e.ElapsedUs < 0 {
+			t.Errorf("unexpected entry %q", line)
+		}
+		entries = append(entries, e)
+	}
+
+	// the statements are written once they're done, the return before the declaration calling it
+	expected := []struct {
+		line int
+		kind string
+	}{
+		{2, "VarDeclaration"},
+		{3, "ReturnStatement"},
+		{5, "VarDeclaration"},
+		{6, "ExpressionStatement"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got=%q", len(expected), out.String())
+	}
+	for idx, want := range expected {
+		if entries[idx].Line != want.line || entries[idx].Kind != want.kind {
+			t.Errorf("entry %d: expected %s at line %d, got=%+v", idx, want.kind, want.line, entries[idx])
+		}
+	}
+	if !strings.Contains(entries[3].Error, "missing") {
+		t.Errorf("expected the error of the last statement, got=%q", entries[3].Error)
+	}
+	if entries[1].Error != "" {
+		t.Errorf("expected no error on the return, got=%q", entries[1].Error)
+	}
+}
+
+func TestTraceLogReplay(t *testing.T) {
+	var out bytes.Buffer
+	for _, input := range []string{"x := 1\nx = x + 1\n", "nums := [1, 2]\ny := nums[5]\n"} {
+		l := lexer.NewLexer("", input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		env := object.NewEnvironment(nil)
+		evaluator := interpreter.NewInterpreter(env, "/tmp/main.blk")
+
+		trace := tracelog.New(&out)
+		trace.Begin("/tmp/main.blk", 42)
+		evaluator.SetHooks(trace.Hooks())
+		evaluator.Eval(program)
+		trace.Snapshot(env)
+		if err := trace.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a run killed before its snapshot, with its last line cut short
+	out.WriteString(`{"run":"/tmp/main.blk","time":"2026-01-02T15:04:05Z"}` + "\n")
+	out.WriteString(`{"file":"main.blk","line":1,"col":1,"kind":"VarDeclaration","start_us":3,"elapsed_us":1}` + "\n")
+	out.WriteString(`{"file":"main.blk","li`)
+
+	runs, err := tracelog.Read(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 runs, got=%d", len(runs))
+	}
+
+	first := runs[0]
+	if first.Program != "/tmp/main.blk" || len(first.Steps) != 2 || first.Vars["x"] != "2" {
+		t.Errorf("unexpected first run %+v", first)
+	}
+	if first.Seed == nil || *first.Seed != 42 {
+		t.Errorf("expected the seed of the run, got=%v", first.Seed)
+	}
+	// the logs written before the seeds don't have one
+	if runs[2].Seed != nil {
+		t.Errorf("expected no seed for the run without one, got=%d", *runs[2].Seed)
+	}
+	second := runs[1]
+	if len(second.Steps) != 2 || !strings.Contains(second.Steps[1].Error, "index") {
+		t.Errorf("expected the index error on the last step, got=%+v", second.Steps)
+	}
+	if second.Vars["nums"] != "[1, 2]" {
+		t.Errorf("expected nums in the snapshot, got=%v", second.Vars)
+	}
+	if _, ok := second.Vars["y"]; ok {
+		t.Errorf("expected y to be left undeclared, got=%v", second.Vars)
+	}
+	if len(runs[2].Steps) != 1 || runs[2].Vars != nil {
+		t.Errorf("expected the killed run to have a step and no snapshot, got=%+v", runs[2])
+	}
+}
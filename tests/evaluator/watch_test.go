@@ -0,0 +1,76 @@
+package evaluator_tests
+
+import (
+	"blk/interpreter"
+	"blk/lexer"
+	"blk/parser"
+	"blk/tracelog"
+	"blk/watch"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWatch(t *testing.T) {
+	input := `
+sum :: fn(n) {
+    acc := 0
+    for i in 0..n {
+        acc = acc + i
+    }
+    return acc
+}
+total := sum(3)
+total = total + 1
+nums := [1, 2]
+nums[0] = 5
+other := 1
+`
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	evaluator := interpreter.NewInterpreter(nil, "main.blk")
+
+	var log bytes.Buffer
+	trace := tracelog.New(&log)
+	watcher := watch.New([]string{"total", "nums"})
+	evaluator.SetHooks(interpreter.MergeHooks(trace.Hooks(), watcher.Hooks()))
+	evaluator.Eval(program)
+	trace.Close()
+
+	expected := []watch.Change{
+		// the declaration started before the statements of sum
+		{Step: 2, Name: "total", File: "main.blk", Row: 9, Col: 1, Value: "3"},
+		{Step: 9, Name: "total", File: "main.blk", Row: 10, Col: 1, Value: "4"},
+		{Step: 10, Name: "nums", File: "main.blk", Row: 11, Col: 1, Value: "[1, 2]"},
+		{Step: 11, Name: "nums[0]", File: "main.blk", Row: 12, Col: 1, Value: "5"},
+	}
+	history := watcher.History()
+	if len(history) != len(expected) {
+		t.Fatalf("expected %d changes, got=%+v", len(expected), history)
+	}
+	for idx, change := range expected {
+		if history[idx] != change {
+			t.Errorf("change %d: expected %+v, got=%+v", idx, change, history[idx])
+		}
+	}
+
+	// the merged hooks fed the trace log too, its steps are the ones the watch counts
+	runs, err := tracelog.Read(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps := runs[0].Steps; len(steps) != 12 || steps[1].Line != 9 || steps[8].Line != 10 {
+		t.Errorf("expected the trace log to number the statements like the watch, got=%+v", steps)
+	}
+
+	var out bytes.Buffer
+	if err := watcher.WriteHistory(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"watch total (2 changes)", "step 9    main.blk:10:1  4", "main.blk:12:1  nums[0] = 5"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected %q in the history, got=%q", line, out.String())
+		}
+	}
+}
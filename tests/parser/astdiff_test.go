@@ -0,0 +1,125 @@
+package parser_tests
+
+import (
+	"blk/ast"
+	"blk/astdiff"
+	"blk/lexer"
+	"blk/parser"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func parseProgram(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	l := lexer.NewLexer("", input)
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	if len(p.Errors) > 0 {
+		t.Fatalf("parse errors for %q: %v", input, p.Errors)
+	}
+	return program
+}
+
+func TestAstDiff(t *testing.T) {
+	old := parseProgram(t, `fn add(a, b) { a + b }
+_helper :: fn() { 1 }
+User :: struct {
+	name := "",
+	age := 0,
+	greet: fn(self) { self.name }
+}
+Level :: enum { Low, High }
+limit :: 10`)
+	new := parseProgram(t, `fn add(a, b, c) { a + b + c }
+User :: struct {
+	name := "",
+	age := "",
+	email := "",
+	greet: fn(self, other) { self.name }
+}
+Level :: enum { Low, Mid, High }
+limit :: 10
+@deprecated("use add")
+fn sum(a, b) { a + b }`)
+
+	expected := []string{
+		"removed fn _helper []",
+		"changed fn add [changed params  (a, b) (a, b, c)]",
+		"changed struct User [changed field age int string added field email   changed method greet () (other)]",
+		"changed enum Level [added member Mid   changed member High 1 2]",
+		"added fn sum []",
+	}
+
+	changes := astdiff.Diff(old, new)
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got=%+v", len(expected), changes)
+	}
+	for idx, change := range changes {
+		details := ""
+		for _, detail := range change.Details {
+			details += fmt.Sprintf(" %s %s %s %s %s", detail.Change, detail.What, detail.Name, detail.Old, detail.New)
+		}
+		actual := fmt.Sprintf("%s %s %s [%s]", change.Change, change.Kind, change.Name, strings.TrimPrefix(details, " "))
+		if actual != expected[idx] {
+			t.Errorf("expected=%q, got=%q", expected[idx], actual)
+		}
+	}
+	if !changes[4].New.Deprecated {
+		t.Errorf("expected sum to be deprecated")
+	}
+
+	if changes := astdiff.Diff(old, old); len(changes) != 0 {
+		t.Errorf("expected no changes between a program and itself, got=%+v", changes)
+	}
+}
+
+func TestBreakingChanges(t *testing.T) {
+	old := parseProgram(t, `fn add(a, b) { a + b }
+fn sub(a, b) { a - b }
+fn scale(v, k) { v * k }
+_helper :: fn() { 1 }
+Point :: struct {
+	x := 0,
+	y := 0,
+	label := nul,
+	len: fn(self) { self.x },
+	move: fn(self, dx) { self.x + dx }
+}
+Level :: enum { Low, High }
+limit :: 10
+count := 0`)
+	new := parseProgram(t, `fn add(x, y) { x + y }
+fn scale(v, k, round) { v * k }
+_helper :: fn(a) { a }
+Point :: struct {
+	x := 0,
+	y := nul,
+	label := "",
+	z := 0,
+	move: fn(self, dx, dy) { self.x + dx }
+}
+Level :: enum { Low, Mid, High }
+limit := 10
+count :: 0`)
+
+	expected := []string{
+		"fn sub: fn removed",
+		"fn scale: takes 3 args instead of 2, (v, k) became (v, k, round)",
+		"struct Point: field label only takes string values instead of nul ones",
+		"struct Point: method len removed",
+		"struct Point: method move takes (dx, dy) instead of (dx)",
+		"enum Level: member High changed value from 1 to 2",
+		"const count: was a var, is a const",
+	}
+
+	breaks := astdiff.Breaking(astdiff.Diff(old, new))
+	if len(breaks) != len(expected) {
+		t.Fatalf("expected %d breaking changes, got=%+v", len(expected), breaks)
+	}
+	for idx, b := range breaks {
+		if actual := fmt.Sprintf("%s %s: %s", b.Kind, b.Name, b.Reason); actual != expected[idx] {
+			t.Errorf("expected=%q, got=%q", expected[idx], actual)
+		}
+	}
+}
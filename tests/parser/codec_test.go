@@ -0,0 +1,81 @@
+package parser_tests
+
+import (
+	"blk/ast"
+	"blk/lexer"
+	"blk/parser"
+	"bytes"
+	"testing"
+)
+
+func TestCompiledProgramRoundTrip(t *testing.T) {
+	tests := []string{
+		`let x = 5 + 3 * 2`,
+		`add :: fn(a, b) { return a + b }`,
+		`if a < 0 {} else if a > 0 && true { return a } else { return (a - 1) }`,
+		`res :: if a ? if b ? 1 : 2 : 3`,
+		`for i in 0..10 { if i % 2 == 0 { next } }`,
+		`User :: struct {
+			Name := "lofi",
+			getName : fn(self) {
+				return self.Name
+			}
+		}`,
+		`# blk:feature match
+match file { "*.mp4" => { "video" }, _ => { "other" } }`,
+	}
+	for _, input := range tests {
+		l := lexer.NewLexer("", input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+
+		var buf bytes.Buffer
+		if err := ast.EncodeProgram(&buf, &ast.CompiledProgram{Source: "main.blk", Program: program}); err != nil {
+			t.Fatalf("failed to encode %q: %v", input, err)
+		}
+
+		compiled, err := ast.DecodeProgram(&buf)
+		if err != nil {
+			t.Fatalf("failed to decode %q: %v", input, err)
+		}
+		if compiled.Source != "main.blk" {
+			t.Errorf("expected source=%q, got=%q", "main.blk", compiled.Source)
+		}
+		if compiled.Program.String() != program.String() {
+			t.Errorf("expected=%q, got=%q", program.String(), compiled.Program.String())
+		}
+	}
+
+	if _, err := ast.DecodeProgram(bytes.NewBufferString("x := 1")); err == nil {
+		t.Errorf("expected an error when decoding a source file")
+	}
+}
+
+func TestIdentifierSymbols(t *testing.T) {
+	l := lexer.NewLexer("", "total := count + count\nself")
+	tokens := l.Tokenize()
+	if tokens[2].Symbol() != tokens[4].Symbol() || tokens[2].Symbol() == tokens[0].Symbol() {
+		t.Errorf("expected the same symbol for the same name only, got=%v", tokens[:5])
+	}
+	if tokens[2].Symbol() != lexer.Intern("count") || lexer.Intern("count").String() != "count" {
+		t.Errorf("expected count to be interned once")
+	}
+	if tokens[1].Symbol() != 0 {
+		t.Errorf("expected no symbol on the operators, got=%d", tokens[1].Symbol())
+	}
+
+	// the ids don't survive a compiled program, the names get interned again on load
+	program := parser.NewParser(tokens, "").Parse()
+	var buf bytes.Buffer
+	if err := ast.EncodeProgram(&buf, &ast.CompiledProgram{Program: program}); err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := ast.DecodeProgram(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decl := compiled.Program.Statements[0].(*ast.VarDeclaration)
+	if decl.Name[0].Symbol() != lexer.Intern("total") {
+		t.Errorf("expected the decoded identifier to have the symbol of total")
+	}
+}
@@ -3,6 +3,7 @@ package parser_tests
 import (
 	"blk/lexer"
 	"blk/parser"
+	"strings"
 	"testing"
 )
 
@@ -23,6 +24,22 @@ func TestIfExpressionsParsing(t *testing.T) {
 			input:    "if a < 0 {} else if a > 0 && true { return a } else { return a - 1 }",
 			expected: "if (a < 0) {  } else if ((a > 0) && true) { return a } else { return (a - 1) }",
 		},
+		{
+			input:    "a + if b > 0 ? b : 0",
+			expected: "(a + (if (b > 0) ? b : 0))",
+		},
+		{
+			input:    "if a ? if b ? 1 : 2 : 3",
+			expected: "(if a ? (if b ? 1 : 2) : 3)",
+		},
+		{
+			input:    "if a ? 1 : if b ? 2 : 3",
+			expected: "(if a ? 1 : (if b ? 2 : 3))",
+		},
+		{
+			input:    "if a use b + 1 else c * 2",
+			expected: "(if a ? (b + 1) : (c * 2))",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.NewLexer("", tt.input)
@@ -51,6 +68,19 @@ func TestMatchExpressionParsing(t *testing.T) {
 			input:    `match kind {}`,
 			expected: "match kind {  }",
 		},
+		{
+			input: `match type value {
+				int => {},
+				fn => {},
+				_ => {}
+			}`,
+			expected: "match type value { int => { }, fn => { }_ => { } }",
+		},
+		// type is a var there
+		{
+			input:    `match type {}`,
+			expected: "match type {  }",
+		},
 	}
 
 	for _, tt := range tests {
@@ -63,3 +93,91 @@ func TestMatchExpressionParsing(t *testing.T) {
 		}
 	}
 }
+
+func TestIfValueParsing(t *testing.T) {
+	tests := []string{
+		`x := if false { 1 }`,
+		`x := if true { y := 2 } else { 3 }`,
+		`x := if true { 1 } else { "one" }`,
+		`x := if true { 1 } else if false { nul } else { 2.5 }`,
+		`x :: if true { } else { 3 }`,
+	}
+	for _, input := range tests {
+		l := lexer.NewLexer("", input)
+		p := parser.NewParser(l.Tokenize(), "")
+		p.Parse()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestConditionalExpressionErrors(t *testing.T) {
+	tests := []string{
+		`x := if a ? 1`,
+		`x := if a ? 1 else 2`,
+		`x := if a use 1 : 2`,
+		`x := if a ? : 2`,
+	}
+	for _, input := range tests {
+		l := lexer.NewLexer("", input)
+		p := parser.NewParser(l.Tokenize(), "")
+		p.Parse()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestGuardStatementParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "guard a > b else { return a }",
+			expected: "if (!(a > b)) { return a }",
+		},
+		{
+			input:    "guard ok else { break }",
+			expected: "if (!ok) { break }",
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	errTests := []string{
+		`guard ok { return }`,
+		`guard ok else { 1 }`,
+		`guard ok else { }`,
+	}
+	for _, input := range errTests {
+		l := lexer.NewLexer("", input)
+		p := parser.NewParser(l.Tokenize(), "")
+		p.Parse()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestPropagateOutsideFunction(t *testing.T) {
+	p := parser.NewParser(lexer.NewLexer("", "x := read()!").Tokenize(), "")
+	p.Parse()
+	if len(p.Errors) == 0 || !strings.Contains(p.Errors[0].Error(), "it can't be used outside of one") {
+		t.Errorf("expected ! to be refused outside of a function, got=%v", p.Errors)
+	}
+
+	p = parser.NewParser(lexer.NewLexer("", "f :: fn() { x := read()!\nok(x) }").Tokenize(), "")
+	p.Parse()
+	if len(p.Errors) > 0 {
+		t.Errorf("expected ! to be accepted in a function, got=%v", p.Errors)
+	}
+}
@@ -0,0 +1,249 @@
+package parser_tests
+
+import (
+	"blk/diagnostics"
+	"blk/lexer"
+	"blk/parser"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticRendering(t *testing.T) {
+	diagnostics.SetColorMode(diagnostics.ColorNever)
+	t.Cleanup(func() { diagnostics.SetColorMode(diagnostics.ColorAuto) })
+
+	tests := []struct {
+		input    string
+		width    int
+		expected string
+	}{
+		{
+			input: "x := 1\nlet y := 3",
+			expected: `main.blk:2:7: ERROR: expected assign (=), got shit
+  |
+2 | let y := 3
+  |       ^^`,
+		},
+		{
+			input: "\tlet name := 3",
+			width: 30,
+			expected: `main.blk:1:11: ERROR: expected
+    assign (=), got shit
+  |
+1 | 	let name := 3
+  | 	         ^^`,
+		},
+		{
+			input: `m := {"a": 1, "a": 2}`,
+			expected: `main.blk:1:15: ERROR: duplicate key "a" in map literal, first defined at 1:7
+  |
+1 | m := {"a": 1, "a": 2}
+  |       --- first defined here
+  |               ^^^`,
+		},
+		{
+			// saved by a windows editor
+			input: "\uFEFFx := 1\r\nlet y := 3\r\n",
+			expected: `main.blk:2:7: ERROR: expected assign (=), got shit
+  |
+2 | let y := 3
+  |       ^^`,
+		},
+		{
+			input: "\uFEFFlet y := 3",
+			expected: `main.blk:1:7: ERROR: expected assign (=), got shit
+  |
+1 | let y := 3
+  |       ^^`,
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer("main.blk", tt.input)
+		p := parser.NewParser(l.Tokenize(), "main.blk")
+		p.Parse()
+		if len(p.Errors) == 0 {
+			t.Fatalf("expected errors for %q", tt.input)
+		}
+
+		d, ok := p.Errors[0].(*diagnostics.Diagnostic)
+		if !ok {
+			t.Fatalf("expected a diagnostic, got %T", p.Errors[0])
+		}
+
+		renderer := diagnostics.NewRenderer()
+		renderer.Width = tt.width
+		renderer.AddSource("main.blk", tt.input)
+		if actual := renderer.Render(d); actual != tt.expected {
+			t.Errorf("expected=\n%s\ngot=\n%s", tt.expected, actual)
+		}
+	}
+}
+
+// the files saved on windows lex to the tokens of the same file saved elsewhere
+func TestWindowsLineEndings(t *testing.T) {
+	source := "import \"fmt\"\nx := 1\nif x > 0 {\n\tfmt.println(`a\nb`, x) # done\n}\n\ty := x\n"
+	fixtures := map[string]string{
+		"crlf":     strings.ReplaceAll(source, "\n", "\r\n"),
+		"bom":      "\uFEFF" + source,
+		"bom crlf": "\uFEFF" + strings.ReplaceAll(source, "\n", "\r\n"),
+	}
+
+	expected := lexer.NewLexer("main.blk", source).Tokenize()
+	for name, fixture := range fixtures {
+		tokens := lexer.NewLexer("main.blk", fixture).Tokenize()
+		if len(tokens) != len(expected) {
+			t.Fatalf("%s: expected %d tokens, got %d", name, len(expected), len(tokens))
+		}
+		for idx, tok := range tokens {
+			want := expected[idx]
+			if tok.Kind != want.Kind || tok.Text != want.Text || tok.Row != want.Row || tok.Col != want.Col {
+				t.Errorf("%s: expected %s %q at %d:%d, got %s %q at %d:%d", name, want.Kind, want.Text, want.Row, want.Col, tok.Kind, tok.Text, tok.Row, tok.Col)
+			}
+		}
+	}
+
+	// a tab is a single col
+	tokens := lexer.NewLexer("main.blk", "\t\ty := 1").Tokenize()
+	if tokens[0].Row != 1 || tokens[0].Col != 3 {
+		t.Errorf("expected y at 1:3, got %d:%d", tokens[0].Row, tokens[0].Col)
+	}
+}
+
+func TestDiagnosticLabels(t *testing.T) {
+	diagnostics.SetColorMode(diagnostics.ColorNever)
+	t.Cleanup(func() { diagnostics.SetColorMode(diagnostics.ColorAuto) })
+
+	source := "x := 1\ny := 2\nz := 3\ntotal := x + y\nx := 4"
+	d := diagnostics.New(diagnostics.Error, diagnostics.Span{File: "main.blk", Row: 5, Col: 1, Label: "redeclared here"}, "x is already declared")
+	d.WithRelated(diagnostics.Span{File: "main.blk", Row: 1, Col: 1}, "first declared here")
+	d.WithRelated(diagnostics.Span{File: "util.blk", Row: 2, Col: 4, Length: 3}, "exported here")
+	d.WithNote("help: rename one of them")
+
+	renderer := diagnostics.NewRenderer()
+	renderer.Width = 0
+	renderer.AddSource("main.blk", source)
+	renderer.AddSource("util.blk", "# util\nfn add() {}")
+
+	expected := `main.blk:5:1: ERROR: x is already declared
+  |
+1 | x := 1
+  | - first declared here
+ ...
+5 | x := 4
+  | ^ redeclared here
+ --> util.blk:2:4
+  |
+2 | fn add() {}
+  |    --- exported here
+  = help: rename one of them`
+	if actual := renderer.Render(d); actual != expected {
+		t.Errorf("expected=\n%s\ngot=\n%s", expected, actual)
+	}
+
+	if actual := d.Error(); actual != "main.blk:5:1: ERROR: x is already declared" {
+		t.Errorf("expected the one line form, got=%q", actual)
+	}
+}
+
+func TestErrorCascadeLimits(t *testing.T) {
+	input := "fn f() {\n  let a = ) ) ) ) ) )\n  a := 1 )\n}"
+	l := lexer.NewLexer("main.blk", input)
+	p := parser.NewParser(l.Tokenize(), "main.blk")
+	p.Parse()
+
+	if len(p.Errors) != 3 {
+		t.Fatalf("expected the errors of the statement to be capped at 3, got=%d (%v)", len(p.Errors), p.Errors)
+	}
+	seen := map[string]bool{}
+	for _, err := range p.Errors {
+		if seen[err.Error()] {
+			t.Errorf("duplicate error %q", err)
+		}
+		seen[err.Error()] = true
+	}
+	if p.Dropped == 0 {
+		t.Fatalf("expected some errors to be dropped")
+	}
+	if expected := fmt.Sprintf("and %d more errors", p.Dropped); p.Dropped > 1 && p.ErrorSummary() != expected {
+		t.Errorf("expected summary %q, got=%q", expected, p.ErrorSummary())
+	}
+}
+
+func TestStaticRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors []string
+	}{
+		{"x := 1\ny := x / 0", []string{"2:10: ERROR: division by zero, the divisor is the literal 0"}},
+		{"x := 1\ny := x % 0", []string{"modulo by zero"}},
+		{"x := 4\nx /= 0", []string{"2:6: ERROR: division by zero"}},
+		{"a :: [1, 2, 3]\nb := a[3]", []string{"2:8: ERROR: index 3 is out of bounds, a has 3 elements"}},
+		{"b := [1, 2][-1]", []string{"index -1 is out of bounds"}},
+		{"b := \"hello\"[5]", []string{"has 5 chars"}},
+		{"a :: [1, 2, 3]\nb := a[2]\nc := a[1:10]", nil},
+		// a can be another array where it gets bound again
+		{"a :: [1, 2, 3]\nf :: fn(a) { a[5] }", nil},
+		{"a := [1, 2, 3]\nb := a[5]", nil},
+		{"x := 1\ny := x / 2", nil},
+		// a float divided by 0.0 is +Inf
+		{"x := 1.5\ny := x / 0.0", nil},
+	}
+	for _, tt := range tests {
+		p := parser.NewParser(lexer.NewLexer("", tt.input).Tokenize(), "")
+		p.Parse()
+		if len(p.Errors) != len(tt.errors) {
+			t.Errorf("%q: expected %d errors, got=%v", tt.input, len(tt.errors), p.Errors)
+			continue
+		}
+		for idx, expected := range tt.errors {
+			if !strings.Contains(p.Errors[idx].Error(), expected) {
+				t.Errorf("%q: expected error containing %q, got=%q", tt.input, expected, p.Errors[idx].Error())
+			}
+		}
+	}
+}
+
+func TestMessageCatalog(t *testing.T) {
+	t.Cleanup(diagnostics.ResetCatalog)
+	dir := t.TempDir()
+
+	tests := []struct {
+		catalog  string
+		expected string
+	}{
+		{`{"E0007": "an array holds values of one type, here %[2]s follows %[1]s"}`, ""},
+		{`{"E9999": "x"}`, "unknown message code E9999"},
+		{`{"E0007": "one type, got %d"}`, `the template of E0007 doesn't take the values of "array elements need to be of one type, got (%s, %s)"`},
+		{`["E0007"]`, "isn't a catalog of messages"},
+	}
+	for idx, tt := range tests {
+		path := filepath.Join(dir, fmt.Sprintf("catalog%d.json", idx))
+		os.WriteFile(path, []byte(tt.catalog), 0644)
+		err := diagnostics.LoadCatalog(path)
+		if len(tt.expected) == 0 && err != nil {
+			t.Errorf("%s: expected the catalog to load, got %v", tt.catalog, err)
+		} else if len(tt.expected) > 0 && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.catalog, tt.expected, err)
+		}
+	}
+
+	// the catalogs that don't load leave the last one in place
+	p := parser.NewParser(lexer.NewLexer("", "x := [1, \"a\"]").Tokenize(), "")
+	p.Parse()
+	if len(p.Errors) != 1 {
+		t.Fatalf("expected a single error, got %v", p.Errors)
+	}
+	d, ok := p.Errors[0].(*diagnostics.Diagnostic)
+	if !ok || d.Code != diagnostics.ArrayElemTypes || d.Message != "an array holds values of one type, here string follows int" {
+		t.Errorf("expected the reworded E0007, got %v", p.Errors[0])
+	}
+
+	diagnostics.ResetCatalog()
+	if message := diagnostics.Message(diagnostics.ArrayElemTypes, "int", "string"); message != "array elements need to be of one type, got (int, string)" {
+		t.Errorf("expected the default template back, got %q", message)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"blk/ast"
 	"blk/lexer"
 	"blk/parser"
+	"strings"
 	"testing"
 )
 
@@ -28,3 +29,126 @@ func TestFunctionParameterParsing(t *testing.T) {
 		}
 	}
 }
+
+func TestFunctionAnnotationParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input: `@deprecated
+			old :: fn() {}`,
+			expected: "@deprecated const old = fn(){  }",
+		},
+		{
+			input: `@deprecated("use add")
+			two :: fn() { 1 + 1 }`,
+			expected: `@deprecated("use add") const two = fn(){ (1 + 1) }`,
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestNamedFunctionDeclaration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "fn void() {}", expected: "const void = fn(){  }"},
+		{input: "fn add(x, y) { x + y }", expected: "const add = fn(x, y){ (x + y) }"},
+		{input: `@deprecated("use add")
+			fn plus(x, y) { x + y }`, expected: `@deprecated("use add") const plus = fn(x, y){ (x + y) }`},
+		// without a name it stays an expression
+		{input: "fn(x) { x }", expected: "fn(x){ x }"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	l := lexer.NewLexer("", "x := 1\nfn add(x, y) { x + y }")
+	p := parser.NewParser(l.Tokenize(), "")
+	decl := p.Parse().Statements[1].(*ast.VarDeclaration)
+	if decl.Mutable || decl.Token.Row != 2 || decl.Token.Col != 1 {
+		t.Errorf("expected a const declared at 2:1, got=%+v", decl.Token)
+	}
+}
+
+func TestFunctionTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		annotated bool
+	}{
+		{input: "fn add(a: int, b: int): int { a + b }", expected: "const add = fn(a: int, b: int): int{ (a + b) }", annotated: true},
+		{input: "fn log(msg: string, extra): void { }", expected: "const log = fn(msg: string, extra): void{  }"},
+		{input: "fn apply(f: fn, v: any): nul { }", expected: "const apply = fn(f: fn, v: any): nul{  }", annotated: true},
+		{input: "fn none(): void { }", expected: "const none = fn(): void{  }", annotated: true},
+		{input: "fn add(a, b) { a + b }", expected: "const add = fn(a, b){ (a + b) }"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+		fn := program.Statements[0].(*ast.VarDeclaration).Value.(*ast.FunctionExpression)
+		if fn.Annotated() != tt.annotated {
+			t.Errorf("%q: expected annotated=%t", tt.input, tt.annotated)
+		}
+	}
+}
+
+func TestTypeAnnotationChecks(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors []string
+	}{
+		{"fn add(a: int, b: int): int { a + b }\nadd(1, 2)", nil},
+		{"fn add(a: int, b: int): int { a + b }\nadd(1, \"2\")", []string{"arg b of add needs to be int, got string"}},
+		{"fn show(v: any) { v }\nshow(1)\nshow(\"a\")\nshow([1])", nil},
+		{"fn f(v: Vec3) { v }", []string{"unknown type Vec3"}},
+		{"fn f(v) : Vec3 { v }", []string{"unknown type Vec3"}},
+		{"Vec2 :: struct { x := 0.0 }\nfn len(v: Vec2): float { v.x }", nil},
+		{"fn f(v: void) { v }", []string{"void is only a return type, v can't be void"}},
+		{"x := 1\nx as Vec3", []string{"unknown type Vec3"}},
+		{"fn f(r: Result) { r }", nil},
+		// the values of the vars are only known at runtime
+		{"fn add(a: int, b: int): int { a + b }\nx := \"2\"\nadd(1, x)", nil},
+		// add may be another function by the time of the call
+		{"add := fn(a: int) { a }\nadd(\"a\")", nil},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		p.Parse()
+		if len(p.Errors) != len(tt.errors) {
+			t.Errorf("%q: expected %d errors, got=%v", tt.input, len(tt.errors), p.Errors)
+			continue
+		}
+		for idx, expected := range tt.errors {
+			if !strings.Contains(p.Errors[idx].Error(), expected) {
+				t.Errorf("%q: expected error containing %q, got=%q", tt.input, expected, p.Errors[idx].Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package parser_tests
+
+import (
+	"blk/lexer"
+	"blk/parser"
+	"strings"
+	"testing"
+)
+
+func TestImportCollisions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"import \"math\"\nimport \"math\"", "duplicate import of \"math\", first imported at 1:8"},
+		{"import \"./a/utils.blk\" as u\nimport \"./b/utils.blk\" as u", "u is already bound to the import of \"./a/utils.blk\" at 1:27"},
+		{"import \"math\" as m\nimport \"math\" as m", "duplicate import of \"math\", first imported at 1:18"},
+		{"import \"math\"\nimport \"fmt\" as math", "math is already bound to the import of \"math\" at 1:8"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		p.Parse()
+
+		if len(p.Errors) != 1 {
+			t.Errorf("%q: expected one error, got=%v", tt.input, p.Errors)
+			continue
+		}
+		if !strings.Contains(p.Errors[0].Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got=%q", tt.input, tt.expected, p.Errors[0].Error())
+		}
+	}
+
+	// a module imported again under another alias is intentional
+	l := lexer.NewLexer("", "import \"math\"\nimport \"math\" as m\nimport \"./utils.blk\" as u")
+	p := parser.NewParser(l.Tokenize(), "")
+	p.Parse()
+	if len(p.Errors) != 0 {
+		t.Errorf("expected distinct aliases to parse, got=%v", p.Errors)
+	}
+}
@@ -0,0 +1,110 @@
+package parser_tests
+
+import (
+	"blk/ast"
+	"blk/internals"
+	"blk/lexer"
+	"blk/parser"
+	"blk/stdlib"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	logo := []byte{0x89, 'P', 'N', 'G', 0xFF}
+	if err := os.WriteFile(filepath.Join(dir, "template.html"), []byte("<h1>hi</h1>\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "logo.png"), logo, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := "const page = include_str(\"template.html\")\nconst logo = include_bytes(\"logo.png\")"
+	p := parser.NewParser(lexer.NewLexer("", input).Tokenize(), "")
+	p.Dir = dir
+	program := p.Parse()
+	if len(p.Errors) != 0 {
+		t.Fatalf("expected no errors, got=%v", p.Errors)
+	}
+
+	page, ok := program.Statements[0].(*ast.VarDeclaration).Value.(*ast.StringLiteral)
+	if !ok || page.Value != "<h1>hi</h1>\n" {
+		t.Errorf("expected the template as a string literal, got=%#v", program.Statements[0].(*ast.VarDeclaration).Value)
+	}
+	bytesLit, ok := program.Statements[1].(*ast.VarDeclaration).Value.(*ast.BytesLiteral)
+	if !ok || !bytes.Equal(bytesLit.Value, logo) {
+		t.Errorf("expected the logo as a bytes literal, got=%#v", program.Statements[1].(*ast.VarDeclaration).Value)
+	}
+
+	// the content is part of the compiled program
+	var buf bytes.Buffer
+	if err := ast.EncodeProgram(&buf, &ast.CompiledProgram{Source: "main.blk", Program: program}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ast.DecodeProgram(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Program.Statements[1].(*ast.VarDeclaration).Value.(*ast.BytesLiteral).Value; !bytes.Equal(got, logo) {
+		t.Errorf("expected the logo to survive the codec, got=%v", got)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "logo.png"), []byte{0xFF, 0xFE}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const a = include_str(\"missing.txt\")", "can't include \"missing.txt\", no such file or directory"},
+		{"const a = include_str(\"logo.png\")", "\"logo.png\" isn't utf-8 text"},
+		{"name := \"a.txt\"\nconst a = include_str(name)", "the path of include_str needs to be a string literal"},
+		{"const a = include_bytes()", "include_bytes takes the path of the file"},
+	}
+
+	for _, tt := range tests {
+		p := parser.NewParser(lexer.NewLexer("", tt.input).Tokenize(), "")
+		p.Dir = dir
+		p.Parse()
+
+		if len(p.Errors) != 1 {
+			t.Errorf("%q: expected one error, got=%v", tt.input, p.Errors)
+			continue
+		}
+		if !strings.Contains(p.Errors[0].Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got=%q", tt.input, tt.expected, p.Errors[0].Error())
+		}
+	}
+}
+
+func TestIncludePermissions(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secret, []byte("hunter2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// like blk run --allow-fs= -f main.blk
+	permissions, err := internals.NewPermissions("", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(granted *internals.Permissions) { stdlib.Permissions = granted }(stdlib.Permissions)
+	stdlib.Permissions = permissions
+
+	input := "const a = include_str(\"" + filepath.ToSlash(secret) + "\")"
+	p := parser.NewParser(lexer.NewLexer("", input).Tokenize(), "")
+	p.Dir = dir
+	p.Parse()
+	if len(p.Errors) != 1 || !strings.Contains(p.Errors[0].Error(), "access to "+secret+" denied") {
+		t.Errorf("expected the include to be denied, got=%v", p.Errors)
+	}
+}
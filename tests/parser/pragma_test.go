@@ -0,0 +1,125 @@
+package parser_tests
+
+import (
+	"blk/internals"
+	"blk/lexer"
+	"blk/parser"
+	"strings"
+	"testing"
+)
+
+func TestPragmaParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "# blk:feature match",
+			expected: "# blk:feature match",
+		},
+		{
+			input: `# blk:feature match
+			# regular comment
+			x := 1`,
+			expected: "# blk:feature matchlet x = 1",
+		},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestUnknownPragmaParsing(t *testing.T) {
+	tests := []string{
+		"# blk:feature unknown_feature",
+		"# blk:unknown_pragma",
+	}
+	for _, input := range tests {
+		l := lexer.NewLexer("", input)
+		p := parser.NewParser(l.Tokenize(), "")
+		p.Parse()
+		if len(p.Errors) == 0 {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestStrictAnnotations(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors []string
+	}{
+		{"# blk:strict\nfn add(a: int, b: int): int { a + b }\nfn _helper(a) { a }", nil},
+		{"# blk:strict\nfn add(a: int, b): int { a + b }", []string{"exported function add needs type annotations in strict mode, missing for b"}},
+		// the pragma applies to the whole file
+		{"scale :: fn(v, k) { v * k }\n# blk:strict", []string{"missing for v, k, the return value"}},
+		{"fn add(a, b) { a + b }", nil},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		p.Parse()
+		if len(p.Errors) != len(tt.errors) {
+			t.Errorf("%q: expected %d errors, got=%v", tt.input, len(tt.errors), p.Errors)
+			continue
+		}
+		for idx, expected := range tt.errors {
+			if !strings.Contains(p.Errors[idx].Error(), expected) {
+				t.Errorf("%q: expected error containing %q, got=%q", tt.input, expected, p.Errors[idx].Error())
+			}
+		}
+	}
+
+	// --strict does what the pragma does
+	p := parser.NewParser(lexer.NewLexer("", "fn add(a, b) { a + b }").Tokenize(), "")
+	p.Strict = true
+	p.Parse()
+	if len(p.Errors) != 1 {
+		t.Errorf("expected the strict parser to report add, got=%v", p.Errors)
+	}
+}
+
+func TestVersionPragma(t *testing.T) {
+	tests := []struct {
+		input   string
+		version string
+		errors  []string
+	}{
+		{"x := 1", internals.LanguageVersion, nil},
+		{"# blk 0.1\nx := 1", "0.1", nil},
+		{"# blk:version 0.1\nx := 1", "0.1", nil},
+		// only the digits after # blk make it a pragma
+		{"# blk rocks\nx := 1", internals.LanguageVersion, nil},
+		{"# blk 0.x", internals.LanguageVersion, []string{"invalid version 0.x, expected a major and a minor like"}},
+		{"# blk 0.1\n# blk 0.1", "0.1", []string{"the version is already set at 1:1"}},
+		// the syntax of the newer version isn't read, the only error is about the version
+		{"x := 1 <$> 2\n# blk 99.0\ny :: gen<T> fn() {}", "99.0", []string{"main.blk is written for blk 99.0", "upgrade blk to run it"}},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("main.blk", tt.input)
+		p := parser.NewParser(l.Tokenize(), "main.blk")
+		p.Parse()
+		if p.Version != tt.version {
+			t.Errorf("%q: expected the version %s, got=%s", tt.input, tt.version, p.Version)
+		}
+		if len(tt.errors) == 0 && len(p.Errors) > 0 {
+			t.Errorf("%q: expected no errors, got=%v", tt.input, p.Errors)
+			continue
+		}
+		if len(tt.errors) > 0 && len(p.Errors) != 1 {
+			t.Errorf("%q: expected a single error, got=%v", tt.input, p.Errors)
+			continue
+		}
+		for _, expected := range tt.errors {
+			if !strings.Contains(p.Errors[0].Error(), expected) {
+				t.Errorf("%q: expected error containing %q, got=%q", tt.input, expected, p.Errors[0].Error())
+			}
+		}
+	}
+}
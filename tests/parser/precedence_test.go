@@ -143,6 +143,22 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"~15 | 3 << 1 & 14",
 			"((~15) | ((3 << 1) & 14))",
 		},
+		{
+			"a * b as float",
+			"(a * (b as float))",
+		},
+		{
+			"-a as int",
+			"((-a) as int)",
+		},
+		{
+			"a is string && b is nul",
+			"((a is string) && (b is nul))",
+		},
+		{
+			"fn() { a + b.c(d)! }",
+			"fn(){ (a + (b.c(d)!)) }",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.NewLexer("", tt.input)
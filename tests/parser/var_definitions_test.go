@@ -1,8 +1,10 @@
 package parser_tests
 
 import (
+	"blk/ast"
 	"blk/lexer"
 	"blk/parser"
+	"strings"
 	"testing"
 )
 
@@ -142,6 +144,10 @@ func TestEnumDeclStatementDCL(t *testing.T) {
 			}`,
 			`const Data = enum { Int, Float, String, Bool }`,
 		},
+		{
+			`Status :: enum { Ok, NotFound = 404, Gone, Unknown = -1 }`,
+			`const Status = enum { Ok, NotFound = 404, Gone, Unknown = -1 }`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -155,6 +161,49 @@ func TestEnumDeclStatementDCL(t *testing.T) {
 	}
 }
 
+func TestEnumMemberValues(t *testing.T) {
+	l := lexer.NewLexer("", "Level :: enum { Low, Mid = 10, High, Max = 3, Over }")
+	p := parser.NewParser(l.Tokenize(), "")
+	program := p.Parse()
+	if len(p.Errors) > 0 {
+		t.Fatalf("parse errors: %v", p.Errors)
+	}
+
+	enum := program.Statements[0].(*ast.VarDeclaration).Value.(*ast.EnumExpression)
+	expected := []int64{0, 10, 11, 3, 4}
+	for idx, field := range enum.Body {
+		if field.Value != expected[idx] {
+			t.Errorf("%s: expected=%d, got=%d", field.Name.Value, expected[idx], field.Value)
+		}
+	}
+}
+
+func TestEnumDuplicates(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`E :: enum { A, B, A }`, "duplicate member A in enum, first defined at 1:13"},
+		{`E :: enum { A = 1, B = 1 }`, "B has the value 1, already taken by A"},
+		{`E :: enum { A = 1, B = 0, C }`, "C has the value 1, already taken by A"},
+		{`E :: enum { A = "a" }`, "the value of an enum member needs to be an integer literal"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		p.Parse()
+
+		if len(p.Errors) != 1 {
+			t.Errorf("%q: expected one error, got=%v", tt.input, p.Errors)
+			continue
+		}
+		if !strings.Contains(p.Errors[0].Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got=%q", tt.input, tt.expected, p.Errors[0].Error())
+		}
+	}
+}
+
 func TestMemberShipAccessStatementDCL(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -184,3 +233,96 @@ func TestMemberShipAccessStatementDCL(t *testing.T) {
 		}
 	}
 }
+
+func TestMapLiteralDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`hash := {"a": 1, "a": 2}`, "duplicate key \"a\" in map literal, first defined at 1:10"},
+		{"hash := {\n1: \"one\",\n2: \"two\",\n1: \"uno\"\n}", "duplicate key 1 in map literal, first defined at 2:1"},
+		{`hash := {1.0: 1, 1.00: 2}`, "duplicate key"},
+		{`hash := {'a': 1, 'a': 2}`, "duplicate key"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		p.Parse()
+
+		// the literal is kept, nothing else gets reported
+		if len(p.Errors) != 1 {
+			t.Errorf("%q: expected one duplicate key error, got=%v", tt.input, p.Errors)
+			continue
+		}
+		if !strings.Contains(p.Errors[0].Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got=%q", tt.input, tt.expected, p.Errors[0].Error())
+		}
+	}
+
+	l := lexer.NewLexer("", `hash := {"a": 1, "b": 1}`)
+	p := parser.NewParser(l.Tokenize(), "")
+	p.Parse()
+	if len(p.Errors) != 0 {
+		t.Errorf("expected distinct keys to parse, got=%v", p.Errors)
+	}
+}
+
+func TestArrayOperationTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`res := [1] + [2.0]`, "can't concatenate []int with []float"},
+		{`res := [1] * 2.5`, "arrays can only be repeated an int number of times, got float"},
+		{`res := [1, "a"]`, "array elements need to be of one type, got (int, string)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		p.Parse()
+
+		if len(p.Errors) == 0 {
+			t.Errorf("%q: expected a type error", tt.input)
+			continue
+		}
+		if !strings.Contains(p.Errors[0].Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got=%q", tt.input, tt.expected, p.Errors[0].Error())
+		}
+	}
+}
+
+func TestLetElseStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		err      string
+	}{
+		{input: "f :: fn(s) {\nlet Ok(data) = parse(s) else { return err(\"bad\") }\ndata\n}", expected: "const f = fn(s){ let Ok(data) = parse(s) else {return err(\"bad\") }data }"},
+		{input: "let Err(msg) = r else { return 1 }", expected: "let Err(msg) = r else {return 1 }"},
+		// a var named Ok is still declared the usual way
+		{input: "let Ok = 1", expected: "let Ok = 1"},
+		{input: "let Ok(x) = r else { x }", err: "the else branch of let Ok(...) needs to exit"},
+		{input: "let Ok(x) = r", err: "expected else after let Ok(...)"},
+		{input: "let Ok(x) = 3 else { return 1 }", err: "let Ok(...) takes a result, got int"},
+		{input: "let Ok(x, y) = r else { return 1 }", err: "Ok(...) binds a single name"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewLexer("", tt.input)
+		p := parser.NewParser(l.Tokenize(), "")
+		program := p.Parse()
+		if len(tt.err) > 0 {
+			if len(p.Errors) == 0 || !strings.Contains(p.Errors[0].Error(), tt.err) {
+				t.Errorf("%q: expected error containing %q, got=%v", tt.input, tt.err, p.Errors)
+			}
+			continue
+		}
+		if len(p.Errors) > 0 {
+			t.Fatalf("parse errors for %q: %v", tt.input, p.Errors)
+		}
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package tracelog
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// the longest line read, snapshots of many variables are the long ones
+const maxLineLength = 1 << 20
+
+// a run read back from a log
+type Run struct {
+	Program string // empty when the log has no header for it
+	Time    string
+	Seed    *int64 // nil when the log has no header for it or was written before the seeds
+	// in the order the statements started, which is the order they ran in
+	Steps []Step
+	// nil when the run stopped before its snapshot, the process got killed
+	Vars map[string]string
+}
+
+// every line of a log, the fields it has tell which kind it is
+type record struct {
+	Step
+	header
+	snapshot
+}
+
+// reads the runs of a log, oldest first, a last line cut short by a killed process is skipped
+func Read(r io.Reader) ([]*Run, error) {
+	var runs []*Run
+	current := func() *Run {
+		if len(runs) == 0 || runs[len(runs)-1].Vars != nil {
+			runs = append(runs, &Run{})
+		}
+		return runs[len(runs)-1]
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineLength)
+	var pending error
+	for row := 1; scanner.Scan(); row++ {
+		if pending != nil {
+			return nil, pending
+		}
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			pending = fmt.Errorf("line %d: %v", row, err)
+			continue
+		}
+
+		switch {
+		case len(rec.Run) > 0:
+			runs = append(runs, &Run{Program: rec.Run, Time: rec.Time, Seed: rec.Seed})
+		case rec.Vars != nil:
+			current().Vars = rec.Vars
+		default:
+			run := current()
+			run.Steps = append(run.Steps, rec.Step)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, run := range runs {
+		// a statement and the first one it ran can start on the same microsecond, the outer one lasts longer
+		slices.SortStableFunc(run.Steps, func(a, b Step) int {
+			return cmp.Or(cmp.Compare(a.StartUs, b.StartUs), cmp.Compare(b.ElapsedUs, a.ElapsedUs))
+		})
+	}
+	return runs, nil
+}
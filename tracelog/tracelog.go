@@ -0,0 +1,157 @@
+package tracelog
+
+import (
+	"blk/interpreter"
+	"blk/object"
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"time"
+)
+
+const (
+	// the most bytes held before they're written, bufio writes them out once it's full
+	bufferSize = 64 * 1024
+	// the longest a line waits in the buffer, the buffer is only checked when a statement is done
+	flushInterval = time.Second
+	// values longer than this are cut in the snapshots
+	maxValueLength = 256
+)
+
+// a statement of the log, times are in microseconds, start is counted from the start of the log
+type Step struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Col       int    `json:"col"`
+	Kind      string `json:"kind"`
+	StartUs   int64  `json:"start_us"`
+	ElapsedUs int64  `json:"elapsed_us"`
+	Error     string `json:"error,omitempty"`
+}
+
+// the line starting a run, the program is an absolute path
+type header struct {
+	Run  string `json:"run"`
+	Time string `json:"time"`
+	// the seed of the rand module, blk run --seed with it draws the same values
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+// the line ending a run, the global variables once the program stopped
+type snapshot struct {
+	Vars map[string]string `json:"vars"`
+}
+
+// writes a json line per evaluated statement, a statement is written once it's done, so
+// the ones running when the program failed come after the statements they ran
+// at most a second of lines, or 64KB of them, are lost if the process gets killed
+type Log struct {
+	out       *bufio.Writer
+	closer    io.Closer
+	start     time.Time
+	lastFlush time.Time
+	// the first write error, the log stops writing after it
+	err error
+}
+
+// the log writes to w, Close flushes it
+func New(w io.Writer) *Log {
+	now := time.Now()
+	log := &Log{out: bufio.NewWriterSize(w, bufferSize), start: now, lastFlush: now}
+	if closer, ok := w.(io.Closer); ok {
+		log.closer = closer
+	}
+	return log
+}
+
+// opens the file at path to append to it, creating it if needed
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return New(file), nil
+}
+
+// hooks to install on the interpreter that runs the program
+func (l *Log) Hooks() *interpreter.Hooks {
+	return &interpreter.Hooks{OnStatement: l.Record}
+}
+
+func (l *Log) write(line any) {
+	if l.err != nil {
+		return
+	}
+	data, _ := json.Marshal(line)
+	_, l.err = l.out.Write(append(data, '\n'))
+}
+
+// starts a run of the program, the log of a file can hold many of them
+func (l *Log) Begin(program string, seed int64) {
+	l.start = time.Now()
+	l.write(header{Run: program, Time: l.start.Format(time.RFC3339), Seed: &seed})
+}
+
+func (l *Log) Record(stmt interpreter.StatementEvent) {
+	tok := stmt.Node.GetToken()
+	step := Step{
+		File:      stmt.File,
+		Line:      tok.Row,
+		Col:       tok.Col,
+		Kind:      reflect.TypeOf(stmt.Node).Elem().Name(),
+		StartUs:   stmt.Start.Sub(l.start).Microseconds(),
+		ElapsedUs: stmt.Elapsed.Microseconds(),
+	}
+	if err, ok := stmt.Result.(*object.Error); ok {
+		step.Error = err.Message
+	}
+	l.write(step)
+
+	// an error is what the log is read for, it's written right away
+	if len(step.Error) > 0 || time.Since(l.lastFlush) >= flushInterval {
+		l.Flush()
+	}
+}
+
+// ends the run with the values of the variables of env, the builtins, functions, modules and struct
+// declarations left out
+func (l *Log) Snapshot(env *object.Environment) {
+	vars := map[string]string{}
+	for name, item := range env.GetStore() {
+		if item.IsBuiltIn || item.Object == nil {
+			continue
+		}
+		switch item.Type() {
+		case object.FUNCTION_OBJ, object.BUILTIN_OBJ, object.IMPORT_OBJ, object.STRUCT_OBJ:
+			continue
+		}
+		value := []rune(item.Inspect())
+		if len(value) > maxValueLength {
+			value = append(value[:maxValueLength], []rune("...")...)
+		}
+		vars[name] = string(value)
+	}
+	l.write(snapshot{Vars: vars})
+}
+
+// writes the buffered lines
+func (l *Log) Flush() error {
+	if l.err == nil {
+		l.err = l.out.Flush()
+		l.lastFlush = time.Now()
+	}
+	return l.err
+}
+
+// flushes the log and closes the file under it, returns the first write error if any
+func (l *Log) Close() error {
+	err := l.Flush()
+	if l.closer != nil {
+		if closeErr := l.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package watch
+
+import (
+	"blk/ast"
+	"blk/interpreter"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// a value a watched variable got
+type Change struct {
+	Step  int    // the statement it happened in, counted like blk replay counts them
+	Name  string // the target as written, the variable or one of its fields or elements
+	File  string
+	Row   int
+	Col   int
+	Value string // printed when the assignment happened, later changes to the value don't show in it
+}
+
+// records the values given to some variables while a program runs
+type Watcher struct {
+	names []string
+	steps int
+	// the steps of the statements being evaluated, the innermost last
+	running []int
+	history []Change
+}
+
+func New(names []string) *Watcher {
+	return &Watcher{names: names}
+}
+
+// hooks to install on the interpreter that runs the program
+func (w *Watcher) Hooks() *interpreter.Hooks {
+	return &interpreter.Hooks{OnEnterNode: w.enter, OnStatement: w.leave, OnAssign: w.assign}
+}
+
+// numbers the statements as they start, so a declaration running a function keeps the step
+// it started at instead of the last one of the function
+func (w *Watcher) enter(node ast.Node) {
+	if _, ok := node.(ast.Statement); ok {
+		w.steps++
+		w.running = append(w.running, w.steps)
+	}
+}
+
+func (w *Watcher) leave(stmt interpreter.StatementEvent) {
+	w.running = w.running[:len(w.running)-1]
+}
+
+func (w *Watcher) assign(assign interpreter.AssignEvent) {
+	if !w.watches(assign.Name) || len(w.running) == 0 {
+		return
+	}
+	tok := assign.Node.GetToken()
+	w.history = append(w.history, Change{
+		Step:  w.running[len(w.running)-1],
+		Name:  assign.Name,
+		File:  assign.File,
+		Row:   tok.Row,
+		Col:   tok.Col,
+		Value: assign.Value.Inspect(),
+	})
+}
+
+func (w *Watcher) watches(target string) bool {
+	for _, name := range w.names {
+		if matches(name, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// the changes, in the order they happened
+func (w *Watcher) History() []Change {
+	return w.history
+}
+
+// writes the changes of every watched variable, one variable after the other
+//
+//	watch total (3 changes)
+//	  step 2   main.blk:3:5  0
+//	  step 6   main.blk:5:9  0
+func (w *Watcher) WriteHistory(out io.Writer) error {
+	for _, name := range w.names {
+		var changes []Change
+		for _, change := range w.history {
+			if matches(name, change.Name) {
+				changes = append(changes, change)
+			}
+		}
+
+		if _, err := fmt.Fprintf(out, "watch %s (%d changes)\n", name, len(changes)); err != nil {
+			return err
+		}
+		for _, change := range changes {
+			location := fmt.Sprintf("%s:%d:%d", change.File, change.Row, change.Col)
+			value := change.Value
+			if change.Name != name {
+				value = change.Name + " = " + value
+			}
+			if _, err := fmt.Fprintf(out, "  step %-4d %s  %s\n", change.Step, location, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// whether the target is the name, or a field or an element of it
+func matches(name, target string) bool {
+	rest, ok := strings.CutPrefix(target, name)
+	return ok && (len(rest) == 0 || rest[0] == '.' || rest[0] == '[')
+}